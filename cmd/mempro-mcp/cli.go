@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// CLI exit codes: a tool that reports an error exits with cliFailed, a command line that
+// cannot be run with cliUsage
+const (
+	cliOK     = 0
+	cliFailed = 1
+	cliUsage  = 2
+)
+
+// isCLICommand reports whether the first non-flag argument selects CLI mode
+func isCLICommand(command string) bool {
+	return command == "analyze" || command == "tools"
+}
+
+// runCLI runs a tool directly, without an MCP client, and prints its result to stdout:
+//
+//	mempro-mcp analyze --tool top_leakers --json capture.json --count 20
+//	mempro-mcp tools [name]
+func runCLI(s *server.MCPServer, args []string, stdout, stderr io.Writer) int {
+	tools, err := cliTools(s)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to list tools: %v\n", err)
+		return cliFailed
+	}
+
+	if args[0] == "tools" {
+		if len(args) > 1 {
+			tool, ok := findCLITool(tools, args[1])
+			if !ok {
+				fmt.Fprintf(stderr, "Unknown tool %q; run \"tools\" to list them\n", args[1])
+				return cliUsage
+			}
+			printToolUsage(stdout, tool)
+			return cliOK
+		}
+		for _, tool := range tools {
+			fmt.Fprintf(stdout, "%-32s %s\n", tool.Name, tool.Description)
+		}
+		return cliOK
+	}
+
+	name, arguments, err := parseCLIArgs(args[1:], tools)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return cliUsage
+	}
+	return callCLITool(s, name, arguments, stdout, stderr)
+}
+
+// cliTools returns every tool the server offers, including runtime tools, sorted by name
+func cliTools(s *server.MCPServer) ([]mcp.Tool, error) {
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	response := handleMessage(context.Background(), s, clientNotifier, request)
+	r, ok := response.(mcp.JSONRPCResponse)
+	if !ok {
+		return nil, fmt.Errorf("unexpected tools/list response")
+	}
+	result, ok := r.Result.(mcp.ListToolsResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected tools/list result")
+	}
+	tools := result.Tools
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools, nil
+}
+
+// findCLITool looks a tool up by name, accepting the name without its get_ prefix
+func findCLITool(tools []mcp.Tool, name string) (mcp.Tool, bool) {
+	for _, candidate := range []string{name, "get_" + name} {
+		for _, tool := range tools {
+			if tool.Name == candidate {
+				return tool, true
+			}
+		}
+	}
+	return mcp.Tool{}, false
+}
+
+// parseCLIArgs reads --tool, --json (for json_path), --args with a JSON object of
+// arguments, and one --<parameter> value per tool parameter. Values are converted to the
+// parameter's type; dashes in parameter names stand for underscores.
+func parseCLIArgs(args []string, tools []mcp.Tool) (string, map[string]interface{}, error) {
+	type option struct{ name, value string }
+	var options []option
+	toolName := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			return "", nil, fmt.Errorf("unexpected argument %q (expected --<parameter> <value>)", arg)
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		name = strings.ReplaceAll(name, "-", "_")
+		if !hasValue {
+			// A flag without a value is a boolean set to true
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				value = args[i+1]
+				i++
+			} else {
+				value = "true"
+			}
+		}
+		if name == "tool" {
+			toolName = value
+			continue
+		}
+		options = append(options, option{name, value})
+	}
+	if toolName == "" {
+		return "", nil, fmt.Errorf("--tool is required; run \"tools\" to list them")
+	}
+	tool, ok := findCLITool(tools, toolName)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown tool %q; run \"tools\" to list them", toolName)
+	}
+
+	arguments := make(map[string]interface{})
+	for _, o := range options {
+		switch o.name {
+		case "args":
+			if err := json.Unmarshal([]byte(o.value), &arguments); err != nil {
+				return "", nil, fmt.Errorf("--args: invalid JSON object: %v", err)
+			}
+			continue
+		case "json":
+			o.name = "json_path"
+		}
+
+		schema, ok := tool.InputSchema.Properties[o.name].(map[string]interface{})
+		if !ok {
+			return "", nil, fmt.Errorf("%s has no parameter %q; run \"tools %s\" to list them", tool.Name, o.name, tool.Name)
+		}
+		switch schema["type"] {
+		case "number":
+			n, err := strconv.ParseFloat(o.value, 64)
+			if err != nil {
+				return "", nil, fmt.Errorf("--%s: %q is not a number", o.name, o.value)
+			}
+			arguments[o.name] = n
+		case "boolean":
+			b, err := strconv.ParseBool(o.value)
+			if err != nil {
+				return "", nil, fmt.Errorf("--%s: %q is not true or false", o.name, o.value)
+			}
+			arguments[o.name] = b
+		default:
+			arguments[o.name] = o.value
+		}
+	}
+	for _, required := range tool.InputSchema.Required {
+		if _, ok := arguments[required]; !ok {
+			return "", nil, fmt.Errorf("%s requires --%s", tool.Name, required)
+		}
+	}
+	return tool.Name, arguments, nil
+}
+
+// callCLITool calls a tool and prints each text block of its result; error results go to
+// stderr
+func callCLITool(s *server.MCPServer, name string, arguments map[string]interface{}, stdout, stderr io.Writer) int {
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": arguments},
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to encode arguments: %v\n", err)
+		return cliUsage
+	}
+
+	switch r := handleMessage(context.Background(), s, clientNotifier, request).(type) {
+	case mcp.JSONRPCError:
+		fmt.Fprintf(stderr, "%s\n", r.Error.Message)
+		return cliFailed
+	case mcp.JSONRPCResponse:
+		result, ok := r.Result.(*mcp.CallToolResult)
+		if !ok {
+			fmt.Fprintf(stderr, "Unexpected result from %s\n", name)
+			return cliFailed
+		}
+		out := stdout
+		if result.IsError {
+			out = stderr
+		}
+		for _, content := range result.Content {
+			if text, ok := content.(mcp.TextContent); ok {
+				fmt.Fprintln(out, text.Text)
+			}
+		}
+		if result.IsError {
+			return cliFailed
+		}
+		return cliOK
+	}
+	fmt.Fprintf(stderr, "Unexpected response from %s\n", name)
+	return cliFailed
+}
+
+// printToolUsage lists a tool's parameters with their types and descriptions
+func printToolUsage(w io.Writer, tool mcp.Tool) {
+	fmt.Fprintf(w, "%s: %s\n\nParameters:\n", tool.Name, tool.Description)
+	names := make([]string, 0, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	required := make(map[string]bool)
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+	for _, name := range names {
+		schema, _ := tool.InputSchema.Properties[name].(map[string]interface{})
+		typ, _ := schema["type"].(string)
+		description, _ := schema["description"].(string)
+		if required[name] {
+			typ += ", required"
+		}
+		fmt.Fprintf(w, "  --%s (%s)\n      %s\n", name, typ, description)
+	}
+}
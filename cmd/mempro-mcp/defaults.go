@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"mempromcp/pkg/analysis"
+)
+
+// jsonPathFlag is the capture set with --json-path; it takes precedence over MEMPRO_JSON_PATH
+var jsonPathFlag string
+
+// defaultCaptureName is the sample export MemPro's reader writes
+const defaultCaptureName = "test_memory_analysis.json"
+
+// windowsReaderPath is where MemPro's reader writes its export on Windows
+const windowsReaderPath = `C:\Program Files\PureDevSoftware\MemPro\MemProReader\` + defaultCaptureName
+
+// defaultCaptureCandidates lists the places searched for a capture when none is configured,
+// in order: the working directory, then the platform's usual export locations. Directories
+// stand for their newest capture.
+func defaultCaptureCandidates() []string {
+	candidates := []string{defaultCaptureName}
+
+	home, _ := os.UserHomeDir()
+	if home != "" {
+		candidates = append(candidates, filepath.Join(home, "Documents", "MemPro"))
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		candidates = append(candidates, windowsReaderPath)
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			candidates = append(candidates, filepath.Join(dir, "MemPro"))
+		}
+	case "darwin":
+		if home != "" {
+			candidates = append(candidates, filepath.Join(home, "Library", "Application Support", "MemPro"))
+		}
+	default:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" && home != "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		if dataHome != "" {
+			candidates = append(candidates, filepath.Join(dataHome, "mempro"))
+		}
+	}
+
+	if home != "" {
+		candidates = append(candidates, filepath.Join(home, "MemPro"))
+	}
+	return candidates
+}
+
+// defaultCapture returns the first default capture location holding a capture. When none
+// does, it returns the reader's export path on Windows and the working directory's sample
+// name elsewhere, so the load error names a sensible file.
+func defaultCapture() string {
+	for _, candidate := range defaultCaptureCandidates() {
+		info, err := os.Stat(candidate)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() || analysis.LatestCapture(candidate) != candidate {
+			return candidate
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return windowsReaderPath
+	}
+	return defaultCaptureName
+}
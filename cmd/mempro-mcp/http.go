@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// HTTP transport settings
+const (
+	defaultHTTPAddr     = "127.0.0.1:8080"
+	maxHTTPMessageSize  = 4 << 20
+	httpShutdownTimeout = 5 * time.Second
+	sseEventBuffer      = 16
+)
+
+// sseSession is one client connected to the event stream
+type sseSession struct {
+	events chan []byte
+	done   chan struct{}
+}
+
+// send queues one JSON-RPC message for the session's stream, giving up if it disconnects
+func (s *sseSession) send(data []byte) {
+	select {
+	case s.events <- data:
+	case <-s.done:
+	}
+}
+
+// sseSessions tracks the connected clients. As the notifier's writer it broadcasts each
+// notification to all of them.
+type sseSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*sseSession
+	closing  chan struct{} // Closed when the server shuts down
+}
+
+// Write sends one notification, as written by Notifier.write, to every session
+func (ss *sseSessions) Write(p []byte) (int, error) {
+	data := bytes.Clone(bytes.TrimSpace(p))
+	ss.mu.Lock()
+	sessions := make([]*sseSession, 0, len(ss.sessions))
+	for _, s := range ss.sessions {
+		sessions = append(sessions, s)
+	}
+	ss.mu.Unlock()
+
+	for _, s := range sessions {
+		s.send(data)
+	}
+	return len(p), nil
+}
+
+// lookup finds a connected session by ID
+func (ss *sseSessions) lookup(id string) (*sseSession, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	s, ok := ss.sessions[id]
+	return s, ok
+}
+
+// newSessionID returns a random session ID for the message endpoint
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// serveHTTP runs the MCP server over HTTP with Server-Sent Events: clients open GET /sse,
+// receive the message endpoint as the first event, and POST requests to it. Responses and
+// notifications arrive on the stream. Log level and resource subscriptions are shared by
+// all connected clients.
+func serveHTTP(s *server.MCPServer, n *Notifier, addr string) error {
+	sessions := &sseSessions{sessions: make(map[string]*sseSession), closing: make(chan struct{})}
+	n.attach(sessions)
+	defer n.attach(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", sessions.handleStream)
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		sessions.handleMessage(w, r, s, n)
+	})
+	srv := &http.Server{Addr: addr, Handler: mux}
+	srv.RegisterOnShutdown(func() { close(sessions.closing) })
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigChan
+		ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	log.Printf("Serving MCP over HTTP/SSE at http://%s/sse", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleStream opens a session's event stream and relays its messages until the client
+// disconnects or the server shuts down
+func (ss *sseSessions) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	session := &sseSession{events: make(chan []byte, sseEventBuffer), done: make(chan struct{})}
+	ss.mu.Lock()
+	ss.sessions[id] = session
+	ss.mu.Unlock()
+	defer func() {
+		ss.mu.Lock()
+		delete(ss.sessions, id)
+		ss.mu.Unlock()
+		close(session.done)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "event: endpoint\ndata: /message?sessionId=%s\n\n", id)
+	flusher.Flush()
+
+	for {
+		select {
+		case data := <-session.events:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-ss.closing:
+			return
+		}
+	}
+}
+
+// handleMessage accepts one JSON-RPC message for a session and sends the response, if
+// any, on the session's stream
+func (ss *sseSessions) handleMessage(w http.ResponseWriter, r *http.Request, s *server.MCPServer, n *Notifier) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session, ok := ss.lookup(r.URL.Query().Get("sessionId"))
+	if !ok {
+		http.Error(w, "Unknown or missing sessionId; connect to /sse first", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPMessageSize))
+	if err != nil || !json.Valid(body) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(errorResponse(nil, mcp.PARSE_ERROR, "Parse error"))
+		return
+	}
+
+	if response := handleMessage(r.Context(), s, n, body); response != nil {
+		data, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+		session.send(data)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"mempromcp/pkg/analysis"
+)
+
+// OmittedSummary describes issues left out of a response because of an output cap
+type OmittedSummary struct {
+	Items      int            `json:"items"`
+	TotalSize  int64          `json:"total_size"`
+	BySeverity map[string]int `json:"by_severity"`
+}
+
+// IssuePage describes the page of issues returned when a call passes offset or limit
+type IssuePage struct {
+	Total      int `json:"total"` // Issues before paging and caps
+	Offset     int `json:"offset"`
+	Limit      int `json:"limit,omitempty"`
+	Returned   int `json:"returned"`
+	NextOffset int `json:"next_offset,omitempty"` // Offset of the next page; absent on the last page
+}
+
+// pageArgs returns the offset/limit of a call, and whether it asked for a page
+func pageArgs(args map[string]interface{}) (offset, limit int, paged bool) {
+	if v, ok := args["offset"].(float64); ok {
+		offset, paged = max(int(v), 0), true
+	}
+	if v, ok := args["limit"].(float64); ok {
+		limit, paged = max(int(v), 0), true
+	}
+	return offset, limit, paged
+}
+
+// outputLimits returns the max_items/max_bytes caps for a call; arguments override the config
+func outputLimits(args map[string]interface{}) (maxItems, maxBytes int) {
+	cfg := analysis.CurrentConfig()
+	maxItems, maxBytes = cfg.MaxItems, cfg.MaxBytes
+
+	if v, ok := args["max_items"].(float64); ok && v >= 0 {
+		maxItems = int(v)
+	}
+	if v, ok := args["max_bytes"].(float64); ok && v >= 0 {
+		maxBytes = int(v)
+	}
+	return maxItems, maxBytes
+}
+
+// issueOrder flattens several issue lists and orders them most severe (then largest) first,
+// the order that paging and output caps both follow
+func issueOrder(groups [][]analysis.MemoryIssue) (refs []issueRef, all []analysis.MemoryIssue, order []int) {
+	for g, issues := range groups {
+		for i, issue := range issues {
+			refs = append(refs, issueRef{g, i})
+			all = append(all, issue)
+		}
+	}
+
+	order = make([]int, len(all))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return analysis.IssueLess(all[order[a]], all[order[b]])
+	})
+	return refs, all, order
+}
+
+// issueRef locates an issue within its list
+type issueRef struct{ group, index int }
+
+// keepIssues returns the issue lists reduced to the kept issues, each in its original order
+func keepIssues(groups [][]analysis.MemoryIssue, keep map[issueRef]bool) [][]analysis.MemoryIssue {
+	kept := make([][]analysis.MemoryIssue, len(groups))
+	for g, issues := range groups {
+		kept[g] = []analysis.MemoryIssue{}
+		for i, issue := range issues {
+			if keep[issueRef{g, i}] {
+				kept[g] = append(kept[g], issue)
+			}
+		}
+	}
+	return kept
+}
+
+// pageIssueGroups keeps the limit issues (all with limit 0) after skipping offset issues in
+// priority order across all lists, so the pages of get_all_issues are consistent
+func pageIssueGroups(groups [][]analysis.MemoryIssue, offset, limit int) ([][]analysis.MemoryIssue, *IssuePage) {
+	refs, all, order := issueOrder(groups)
+	page := &IssuePage{Total: len(all), Offset: offset, Limit: limit}
+
+	end := len(order)
+	if limit > 0 {
+		end = min(end, offset+limit)
+	}
+	keep := make(map[issueRef]bool)
+	for _, idx := range order[min(offset, end):end] {
+		keep[refs[idx]] = true
+	}
+	return keepIssues(groups, keep), page
+}
+
+// capIssueGroups applies one shared cap across several issue lists, so that the most severe
+// issues are kept regardless of which list they belong to. Each list keeps its original order.
+func capIssueGroups(groups [][]analysis.MemoryIssue, maxItems, maxBytes int) ([][]analysis.MemoryIssue, *OmittedSummary) {
+	if maxItems <= 0 && maxBytes <= 0 {
+		return groups, nil
+	}
+
+	refs, all, order := issueOrder(groups)
+
+	keep := make(map[issueRef]bool)
+	omitted := &OmittedSummary{BySeverity: make(map[string]int)}
+	items, bytes, full := 0, 0, false
+	for _, idx := range order {
+		if !full {
+			size := 0
+			if maxBytes > 0 {
+				data, _ := json.MarshalIndent(all[idx], "  ", "  ")
+				size = len(data) + 4 // separator and indentation overhead
+			}
+			if (maxItems > 0 && items >= maxItems) || (maxBytes > 0 && bytes+size > maxBytes) {
+				// Stop at the first issue that does not fit so lower-priority issues never displace it
+				full = true
+			} else {
+				keep[refs[idx]] = true
+				items++
+				bytes += size
+				continue
+			}
+		}
+		omitted.Items++
+		omitted.TotalSize += all[idx].Size
+		omitted.BySeverity[all[idx].Severity]++
+	}
+
+	if omitted.Items == 0 {
+		return groups, nil
+	}
+	return keepIssues(groups, keep), omitted
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mempromcp/pkg/analysis"
+)
+
+// fileLogging is set once structured file logging is active
+var fileLogging bool
+
+// backupTimeLayout is the timestamp suffix of rotated backups, e.g. server.log.20260102-150405.000
+const backupTimeLayout = "20060102-150405.000"
+
+// rotateRetryDelay is how long writes go to the current file after a failed rotation
+// before rotating is tried again
+const rotateRetryDelay = time.Minute
+
+// RotatingFile is an io.Writer that rotates the underlying file once it exceeds a
+// maximum size or age, keeping a bounded number of timestamped backups.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	file    *os.File // nil when the file could not be reopened after rotating
+	size    int64
+	started time.Time // When the file's first record was written, for age-based rotation
+	retryAt time.Time // No rotation before this, after a failed one
+}
+
+// NewRotatingFile opens (or appends to) path. Zero limits disable the corresponding rotation trigger.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	r.file = f
+	r.size = info.Size()
+	r.started = fileStart(r.path, info)
+	return nil
+}
+
+// fileStart returns when a log file was started: the time of its first record, else its
+// modification time, or now when it is empty. The first record survives server restarts,
+// so a server restarted more often than the maximum age still rotates its log.
+func fileStart(path string, info os.FileInfo) time.Time {
+	if info.Size() == 0 {
+		return time.Now()
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return info.ModTime()
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadBytes('\n')
+	if err == nil || errors.Is(err, io.EOF) {
+		var record struct {
+			Time time.Time `json:"time"`
+		}
+		if json.Unmarshal(line, &record) == nil && !record.Time.IsZero() {
+			return record.Time
+		}
+	}
+	return info.ModTime()
+}
+
+// Write appends p, rotating first if the write would exceed the size limit or the file is too old
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tooBig := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	tooOld := r.maxAge > 0 && time.Since(r.started) > r.maxAge
+	if (tooBig || tooOld) && time.Now().After(r.retryAt) {
+		// A failed rotation keeps writing to the current file when it could be reopened
+		if err := r.rotate(); err != nil && r.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// rotate renames the file to a timestamped backup and starts a new one. When the rename
+// fails, the original file is reopened so later writes are not lost.
+func (r *RotatingFile) rotate() error {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+
+	backup := fmt.Sprintf("%s.%s", r.path, time.Now().Format(backupTimeLayout))
+	if err := os.Rename(r.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		r.retryAt = time.Now().Add(rotateRetryDelay)
+		err = fmt.Errorf("failed to rotate log file: %w", err)
+		fmt.Fprintln(os.Stderr, err)
+		return errors.Join(err, r.open())
+	}
+
+	r.prune()
+	return r.open()
+}
+
+// backups returns the rotated backups of the log, newest first. Only names made of the log's
+// name and a backup timestamp count, so other files sharing its prefix are left alone.
+func (r *RotatingFile) backups() []string {
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(r.path) + "."
+	var backups []string
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() || len(stamp) != len(backupTimeLayout) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeLayout, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(r.path), e.Name()))
+	}
+	// Timestamp suffixes sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// prune removes the oldest backups beyond maxBackups and any older than maxAge
+func (r *RotatingFile) prune() {
+	for i, backup := range r.backups() {
+		expired := false
+		if r.maxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > r.maxAge {
+				expired = true
+			}
+		}
+		if (r.maxBackups > 0 && i >= r.maxBackups) || expired {
+			os.Remove(backup)
+		}
+	}
+}
+
+// teeHandler fans slog records out to several handlers
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, record.Level) {
+			errs = append(errs, h.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// setupFileLogging sends all log output (including the standard log package) to stderr
+// as text and to a rotating file as JSON. Stdout is never used since it carries MCP traffic.
+func setupFileLogging(path string, maxSizeMB int, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	file, err := NewRotatingFile(path, int64(maxSizeMB)*1024*1024, maxAge, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	level := slog.LevelInfo
+	if analysis.DebugMode {
+		level = slog.LevelDebug
+	}
+
+	logger := slog.New(teeHandler{
+		slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}),
+		slog.NewJSONHandler(file, &slog.HandlerOptions{Level: level}),
+	})
+	// Also routes the standard log package through the handler
+	slog.SetDefault(logger)
+	fileLogging = true
+	return file, nil
+}
+
+// logToolCall records a completed tool call in the structured log
+func logToolCall(name string, duration time.Duration, failed bool) {
+	if !fileLogging && !analysis.DebugMode {
+		return
+	}
+
+	level := slog.LevelInfo
+	if failed {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "tool call",
+		"tool", name,
+		"duration_ms", float64(duration.Microseconds())/1000,
+		"error", failed,
+	)
+}
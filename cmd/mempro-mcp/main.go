@@ -0,0 +1,3871 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mempromcp/pkg/analysis"
+)
+
+var (
+	// symbolIndexCache keeps the index of the most recently searched capture
+	symbolIndexCache = analysis.NewCaptureCache[*analysis.SymbolIndex](1)
+
+	// issueSetCache keeps the analyzed issues of the most recently rescored capture
+	issueSetCache = analysis.NewCaptureCache[[]analysis.MemoryIssue](1)
+)
+
+func main() {
+	configFlag := flag.String("config", "", "Path to JSON configuration file (or set MEMPRO_CONFIG)")
+	checkFlag := flag.Bool("check", false, "Validate the configuration and referenced files, print problems, and exit")
+	flag.StringVar(&jsonPathFlag, "json-path", "", "Default MemPro JSON file, or a directory to use its newest capture (overrides MEMPRO_JSON_PATH)")
+	flag.StringVar(&analysis.DefaultRedactMode, "redact", "", "Redact output: \"users\" hides user names in paths, \"hash\" also hashes directories and session names")
+	flag.BoolVar(&analysis.DefaultAnonymize, "anonymize-symbols", false, "Replace function and type names with stable pseudonyms")
+	flag.StringVar(&analysis.DefaultSymbolMapFile, "symbol-map", "", "File mapping pseudonyms back to symbol names (default: user config dir)")
+	flag.BoolVar(&analysis.DebugMode, "debug", false, "Log per-phase diagnostics to stderr and include them in every tool response")
+	reloadInterval := flag.Duration("reload-interval", 2*time.Second, "How often to check config and rule files for changes (0 disables hot reload)")
+	logFile := flag.String("log-file", "", "Also write structured JSON logs to this file")
+	logMaxSize := flag.Int("log-max-size", 10, "Rotate the log file after this many megabytes (0 disables)")
+	logMaxAge := flag.Duration("log-max-age", 7*24*time.Hour, "Rotate the log file and delete backups older than this (0 disables)")
+	logMaxBackups := flag.Int("log-max-backups", 5, "Number of rotated log files to keep (0 keeps all)")
+	captureCache := flag.Int("capture-cache", analysis.DefaultCaptureCache, "Parsed captures kept in memory between tool calls (0 disables)")
+	transport := flag.String("transport", "stdio", "Transport: \"stdio\" for clients that spawn the server, \"http\" for remote clients over Server-Sent Events")
+	addr := flag.String("addr", defaultHTTPAddr, "Address the http transport listens on")
+	watchInterval := flag.Duration("watch-interval", defaultResourcePollInterval, "How often to check the current capture for changes to notify resource subscribers of (0 disables)")
+	flag.Parse()
+
+	if !analysis.ValidRedactMode(analysis.DefaultRedactMode) {
+		log.Fatalf("Invalid --redact mode %q (expected \"users\" or \"hash\")", analysis.DefaultRedactMode)
+	}
+	if *transport != "stdio" && *transport != "http" {
+		log.Fatalf("Invalid --transport %q (expected \"stdio\" or \"http\")", *transport)
+	}
+	analysis.SetCaptureCacheSize(*captureCache)
+
+	if *logFile != "" {
+		file, err := setupFileLogging(*logFile, *logMaxSize, *logMaxAge, *logMaxBackups)
+		if err != nil {
+			log.Fatalf("Log file error: %v", err)
+		}
+		defer file.Close()
+	}
+
+	// Dry run: validate config without starting the server
+	if *checkFlag {
+		os.Exit(runConfigCheck(getConfigPath(*configFlag)))
+	}
+
+	// Load configuration if provided, and keep it fresh while the server runs
+	if configPath := getConfigPath(*configFlag); configPath != "" {
+		cfg, err := analysis.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("Config error: %v", err)
+		}
+		analysis.SetConfig(cfg)
+
+		if *reloadInterval > 0 && !isCLICommand(flag.Arg(0)) {
+			go watchConfig(configPath, *reloadInterval, clientNotifier)
+		}
+	}
+
+	// Create MCP server
+	s := server.NewMCPServer(
+		"MemPro Memory Analyzer",
+		"1.0.0",
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+		server.WithLogging(),
+	)
+
+	// Add tools for memory analysis
+	setupTools(s)
+
+	// Add resources for quick data access
+	setupResources(s)
+
+	// Add prompts for guided investigations
+	setupPrompts(s)
+
+	// CLI mode: run one tool, print its result, and exit
+	if isCLICommand(flag.Arg(0)) {
+		os.Exit(runCLI(s, flag.Args(), os.Stdout, os.Stderr))
+	}
+
+	// Tell subscribed clients when the capture behind resources changes
+	if *watchInterval > 0 {
+		go watchCaptureResources(*watchInterval, clientNotifier)
+	}
+
+	// Run scheduled analysis when the config has a daemon section
+	go analysis.RunDaemon(clientNotifier)
+
+	// Start server using the selected transport
+	serve := serveStdio
+	if *transport == "http" {
+		serve = func(s *server.MCPServer, n *Notifier) error { return serveHTTP(s, n, *addr) }
+	}
+	if err := serve(s, clientNotifier); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// Helper function to get the config path from the command line or environment
+func getConfigPath(flagPath string) string {
+	if flagPath != "" {
+		return flagPath
+	}
+	return os.Getenv("MEMPRO_CONFIG")
+}
+
+func setupTools(s *server.MCPServer) {
+	// Tool 1: Analyze Memory Leaks
+	analyzeLeaksTool := mcp.NewTool("analyze_leaks",
+		mcp.WithDescription("Analyzes memory leaks from MemPro JSON data and returns prioritized list of issues"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, analyzeLeaksTool, handleAnalyzeLeaks)
+
+	// Tool 2: Get Memory Summary
+	summarizeTool := mcp.NewTool("get_summary",
+		mcp.WithDescription("Provides overall memory usage summary including leak percentage and fragmentation"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, summarizeTool, handleGetSummary)
+
+	// Tool 3: Get Top Leakers
+	topLeakersTool := mcp.NewTool("get_top_leakers",
+		mcp.WithDescription("Returns the top N functions causing the most memory leaks"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("count",
+			mcp.Description("Number of top leakers to return (default: 10)"),
+		),
+		mcp.WithBoolean("summarize_stacks",
+			mcp.Description("Reduce each call stack to its top application frames, skipping allocator/CRT frames"),
+		),
+		mcp.WithNumber("stack_frames",
+			mcp.Description("Application frames to keep when summarizing stacks (default: 3)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, topLeakersTool, handleGetTopLeakers)
+
+	// Tool 4: Analyze Fragmentation
+	fragmentationTool := mcp.NewTool("analyze_fragmentation",
+		mcp.WithDescription("Analyzes memory fragmentation and provides recommendations"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, fragmentationTool, handleAnalyzeFragmentation)
+
+	// Tool 5: Find Large Allocations
+	largeAllocsTool := mcp.NewTool("find_large_allocations",
+		mcp.WithDescription("Identifies unusually large memory allocations that may need optimization"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, largeAllocsTool, handleFindLargeAllocations)
+
+	// Tool 6: Get All Issues
+	allIssues := mcp.NewTool("get_all_issues",
+		mcp.WithDescription("Returns comprehensive analysis of all memory issues including leaks, fragmentation, and large allocations"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, allIssues, handleGetAllIssues)
+
+	// Tool 7: Validate Configuration
+	validateConfigTool := mcp.NewTool("validate_config",
+		mcp.WithDescription("Validates the configuration file and the rule files it references without applying them, reporting problems with line numbers"),
+		mcp.WithString("config_path",
+			mcp.Description("Path to configuration file (default: the config the server was started with)"),
+		),
+	)
+
+	addTool(s, validateConfigTool, handleValidateConfig)
+
+	// Tool 8: De-anonymize Text
+	deanonymizeTool := mcp.NewTool("deanonymize",
+		mcp.WithDescription("Replaces symbol pseudonyms (fn_..., type_...) in text with the original names from the local symbol map"),
+		mcp.WithString("text",
+			mcp.Description("Text containing pseudonyms, e.g. a report returned by an external reviewer"),
+			mcp.Required(),
+		),
+		mcp.WithString("map_path",
+			mcp.Description("Path to symbol map file (default: the configured map)"),
+		),
+	)
+
+	addTool(s, deanonymizeTool, handleDeanonymize)
+
+	// Tool 9: Explain Leak
+	explainLeakTool := mcp.NewTool("explain_leak",
+		mcp.WithDescription("Explains one issue in depth: call stack with source snippets, related functions and leaks, correlated allocation types, trend across earlier captures, and tailored fix options"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("issue_id",
+			mcp.Description("Issue ID as returned by analyze_leaks or get_all_issues, e.g. leak-3f2a9c1b"),
+			mcp.Required(),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Local checkout used to find source files recorded on another machine (default: config source_root)"),
+		),
+		mcp.WithNumber("context_lines",
+			mcp.Description("Source lines shown around each frame, 0 to omit snippets (default: 3)"),
+		),
+		mcp.WithString("history",
+			mcp.Description("Comma-separated paths of earlier captures of the same program, oldest first, for the trend"),
+		),
+		mcp.WithString("tolerance",
+			mcp.Description("Tolerance profile for the trend: strict, normal, lenient, or a configured profile (default: tolerance_profile or normal)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, explainLeakTool, handleExplainLeak)
+
+	// Tool 10: Compare Function Across Sessions
+	compareFunctionTool := mcp.NewTool("compare_function",
+		mcp.WithDescription("Compares one function's allocation count, total size, and leak size between two captures, with deltas, to verify whether a fix reduced its footprint"),
+		mcp.WithString("function",
+			mcp.Description("Exact function name, e.g. Mesh::Load"),
+			mcp.Required(),
+		),
+		mcp.WithString("before_path",
+			mcp.Description("Path to the MemPro JSON capture taken before the change"),
+			mcp.Required(),
+		),
+		mcp.WithString("after_path",
+			mcp.Description("Path to the MemPro JSON capture taken after the change (default: json_path or the default capture)"),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file, used when after_path is not given"),
+		),
+		mcp.WithString("tolerance",
+			mcp.Description("Tolerance profile for the verdict: strict, normal, lenient, or a configured profile (default: tolerance_profile or normal)"),
+		),
+	)
+
+	addTool(s, compareFunctionTool, handleCompareFunction)
+
+	// Tool 11: Watch Session
+	watchSessionTool := mcp.NewTool("watch_session",
+		mcp.WithDescription("Polls a capture that MemPro keeps re-exporting (e.g. during soak tests) and reports what changed since the last report: totals, new, resolved, and changed leaks"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file to watch"),
+		),
+		mcp.WithString("action",
+			mcp.Description("\"changes\" (default) reports changes since the last report and starts watching if needed; \"start\", \"stop\", or \"status\" of all watches"),
+		),
+		mcp.WithNumber("interval_seconds",
+			mcp.Description("Polling interval in seconds (default: 30)"),
+		),
+	)
+
+	addTool(s, watchSessionTool, handleWatchSession)
+
+	// Tool 12: Batch Analysis
+	analyzeBatchTool := mcp.NewTool("analyze_batch",
+		mcp.WithDescription("Runs the standard analysis on many captures concurrently, e.g. a soak-test result directory, and returns per-file verdicts plus the combined top issues"),
+		mcp.WithString("paths",
+			mcp.Description("Comma-separated capture files, directories (their *.json files), or glob patterns"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of combined top issues to return (default: 10)"),
+		),
+		mcp.WithNumber("concurrency",
+			mcp.Description("Captures analyzed in parallel (default: number of CPUs)"),
+		),
+	)
+
+	addTool(s, analyzeBatchTool, handleAnalyzeBatch)
+
+	// Tool 13: Analyze Multi-Process Application
+	analyzeApplicationTool := mcp.NewTool("analyze_application",
+		mcp.WithDescription("Aggregates issues across the captures of one multi-process application (e.g. server, client, editor), attributing each finding to its process and listing leaks shared between processes"),
+		mcp.WithString("captures",
+			mcp.Description("Comma-separated process=path pairs, e.g. server=server.json,client=client.json; without a name the file name is used"),
+			mcp.Required(),
+		),
+		mcp.WithString("application",
+			mcp.Description("Application name shown in the result"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of combined issues to return, most severe first (default: 20)"),
+		),
+	)
+
+	addTool(s, analyzeApplicationTool, handleAnalyzeApplication)
+
+	// Tool 14: Merge Sessions
+	mergeSessionsTool := mcp.NewTool("merge_sessions",
+		mcp.WithDescription("Merges two captures of the same binary (e.g. two soak runs): sums per-function statistics and intersects leak fingerprints to separate deterministic leaks from run-specific noise"),
+		mcp.WithString("first_path",
+			mcp.Description("Path to the first MemPro JSON capture"),
+			mcp.Required(),
+		),
+		mcp.WithString("second_path",
+			mcp.Description("Path to the second MemPro JSON capture"),
+			mcp.Required(),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Optional path to write the merged capture as MemPro JSON, for use with the other tools"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of top functions and run-specific leaks to list (default: 20)"),
+		),
+	)
+
+	addTool(s, mergeSessionsTool, handleMergeSessions)
+
+	// Tool 15: Find Duplicate Allocations
+	duplicateAllocsTool := mcp.NewTool("find_duplicate_allocations",
+		mcp.WithDescription("Finds call sites that repeatedly allocate the same size at high counts, candidates for caching or buffer reuse, with estimated bytes and allocation calls saved"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, duplicateAllocsTool, handleFindDuplicateAllocations)
+
+	// Tool 16: Find Pool Candidates
+	poolCandidatesTool := mcp.NewTool("find_pool_candidates",
+		mcp.WithDescription("Finds clusters of same-size, high-count allocations spread across call sites and recommends a dedicated pool, with slot size and expected occupancy"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, poolCandidatesTool, handleFindPoolCandidates)
+
+	// Tool 17: Find Alignment Waste
+	alignmentWasteTool := mcp.NewTool("find_alignment_waste",
+		mcp.WithDescription("Estimates bytes lost to allocator padding for fixed-size types just over a power-of-two or cache-line boundary, with struct packing suggestions"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, alignmentWasteTool, handleFindAlignmentWaste)
+
+	// Tool 18: Find Tiny Allocations
+	tinyAllocsTool := mcp.NewTool("find_tiny_allocations",
+		mcp.WithDescription("Finds call sites making zero-byte or 1-8 byte heap allocations at scale, which are usually bugs or design smells"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, tinyAllocsTool, handleFindTinyAllocations)
+
+	// Tool 19: Analyze Lifetimes
+	lifetimesTool := mcp.NewTool("analyze_lifetimes",
+		mcp.WithDescription("Classifies allocation sites by lifetime (short-lived churn vs long-lived residents) for captures exported with allocation timing data"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, lifetimesTool, handleAnalyzeLifetimes)
+
+	// Tool 20: Get Thread Breakdown
+	threadBreakdownTool := mcp.NewTool("get_thread_breakdown",
+		mcp.WithDescription("Splits leaks and allocations by the thread that made them, for captures that record thread IDs"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of top leaking functions to list per thread (default: 3)"),
+		),
+	)
+
+	addTool(s, threadBreakdownTool, handleGetThreadBreakdown)
+
+	// Tool 21: Get Heap Breakdown
+	heapBreakdownTool := mcp.NewTool("get_heap_breakdown",
+		mcp.WithDescription("Reports memory, leaks, fragmentation, and budget status per named heap, for captures that partition memory into heaps"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of top leaking functions to list per heap (default: 3)"),
+		),
+	)
+
+	addTool(s, heapBreakdownTool, handleGetHeapBreakdown)
+
+	// Tool 22: Get Tag Rollup
+	tagRollupTool := mcp.NewTool("get_tag_rollup",
+		mcp.WithDescription("Rolls memory and leaks up by allocation tag (Textures, Audio, Gameplay, ...) with per-tag budget status"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("Roll hierarchical tags such as Textures/UI/Icons up to this many levels (default: full tags)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of top leaking functions to list per tag (default: 3)"),
+		),
+	)
+
+	addTool(s, tagRollupTool, handleGetTagRollup)
+
+	// Tool 23: Compare Allocator Size Classes
+	sizeClassTool := mcp.NewTool("compare_size_classes",
+		mcp.WithDescription("Maps the observed allocation sizes onto jemalloc, mimalloc, and tcmalloc size classes and reports the slack each allocator would waste"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of call sites with the most slack to list per allocator (default: 5)"),
+		),
+	)
+
+	addTool(s, sizeClassTool, handleCompareSizeClasses)
+
+	// Tool 24: Address-Space Headroom
+	addressSpaceTool := mcp.NewTool("get_address_space_headroom",
+		mcp.WithDescription("Computes committed, reserved, and remaining address space from page views, the largest free block, and time to exhaustion given a growth rate"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("address_space",
+			mcp.Description("User address space in bytes (default: recorded in the capture, else inferred: 2 GB, 4 GB, or 128 TB)"),
+		),
+		mcp.WithNumber("growth_per_hour",
+			mcp.Description("Address-space growth in bytes per hour, for the time to exhaustion"),
+		),
+		mcp.WithString("baseline_path",
+			mcp.Description("Earlier capture of the same process to derive the growth rate from"),
+		),
+		mcp.WithNumber("interval_hours",
+			mcp.Description("Hours between baseline_path and this capture (default: difference of the file modification times)"),
+		),
+	)
+
+	addTool(s, addressSpaceTool, handleGetAddressSpaceHeadroom)
+
+	// Tool 25: Usage-Weighted Page Statistics
+	pageUsageTool := mcp.NewTool("get_page_usage",
+		mcp.WithDescription("Weights committed pages by their Usage to separate touched from merely committed memory, per region type, function, and region"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of functions and regions to list (default: 10)"),
+		),
+	)
+
+	addTool(s, pageUsageTool, handleGetPageUsage)
+
+	// Tool 26: Region Allocation Stacks
+	regionStacksTool := mcp.NewTool("get_region_stacks",
+		mcp.WithDescription("Returns the allocation stacks responsible for a page view region, matched by address or stack ID, with the leaks that share each stack"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("address",
+			mcp.Description("Address inside the region, decimal or 0x-prefixed hex"),
+		),
+		mcp.WithNumber("stack_id",
+			mcp.Description("Stack ID to look up instead of an address"),
+		),
+	)
+
+	addTool(s, regionStacksTool, handleGetRegionStacks)
+
+	// Tool 27: Call Graph Export
+	callGraphTool := mcp.NewTool("export_call_graph",
+		mcp.WithDescription("Exports the call graph parsed from all call stacks and call trees as nodes/edges JSON, weighted by leaked and allocated bytes"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Leave allocator/CRT frames out of the graph (default: false)"),
+		),
+		mcp.WithNumber("max_nodes",
+			mcp.Description("Number of heaviest functions to keep (default: 100)"),
+		),
+		mcp.WithNumber("min_bytes",
+			mcp.Description("Drop functions with fewer leaked and allocated bytes than this"),
+		),
+	)
+
+	addTool(s, callGraphTool, handleExportCallGraph)
+
+	// Tool 28: Call Path Query
+	callPathTool := mcp.NewTool("find_call_path",
+		mcp.WithDescription("Returns the shortest or heaviest call path from entry points (main, thread starts) to a function, showing how leaking code gets reached"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("function",
+			mcp.Description("Target function: exact name or a unique part of it"),
+			mcp.Required(),
+		),
+		mcp.WithString("mode",
+			mcp.Description("shortest (fewest calls, default) or heaviest (most bytes through the weakest call)"),
+		),
+		mcp.WithString("weight",
+			mcp.Description("Edge weight: leaked or allocated (default: leaked when the capture has leaks)"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Leave allocator/CRT frames out of the graph (default: false)"),
+		),
+	)
+
+	addTool(s, callPathTool, handleFindCallPath)
+
+	// Tool 29: Callers Of
+	callersOfTool := mcp.NewTool("callers_of",
+		mcp.WithDescription("Aggregates the direct callers of a function over all call stacks and trees, with the share of its bytes flowing through each"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("function",
+			mcp.Description("Function, e.g. an allocator wrapper: exact name or a unique part of it"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of callers to list (default: 10)"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Leave allocator/CRT frames out of the graph (default: false)"),
+		),
+	)
+
+	addTool(s, callersOfTool, handleCallersOf)
+
+	// Tool 30: Callees Of
+	calleesOfTool := mcp.NewTool("callees_of",
+		mcp.WithDescription("Lists the direct callees of a function weighted by inclusive allocation size, showing where inside a subsystem the memory goes"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("function",
+			mcp.Description("Function: exact name or a unique part of it"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of callees to list (default: 10)"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Leave allocator/CRT frames out of the graph (default: false)"),
+		),
+	)
+
+	addTool(s, calleesOfTool, handleCalleesOf)
+
+	// Tool 31: Top Growth Between Captures
+	topGrowthTool := mcp.NewTool("top_growth_between",
+		mcp.WithDescription("Returns the N functions whose total allocated memory (not just leaks) grew most between two captures, e.g. after a bad nightly"),
+		mcp.WithString("before_path",
+			mcp.Description("Path to the earlier MemPro JSON capture"),
+			mcp.Required(),
+		),
+		mcp.WithString("after_path",
+			mcp.Description("Path to the later MemPro JSON capture (default: json_path or the default capture)"),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file, used when after_path is not given"),
+		),
+		mcp.WithNumber("count",
+			mcp.Description("Number of functions to return (default: 10)"),
+		),
+	)
+
+	addTool(s, topGrowthTool, handleTopGrowthBetween)
+
+	// Tool 32: Issue Counts
+	issueCountsTool := mcp.NewTool("get_issue_counts",
+		mcp.WithDescription("Returns only the number of issues per severity, e.g. {\"critical\": 2, \"high\": 9, ...}. Cheap enough for status badges and frequent polling"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+	)
+
+	addTool(s, issueCountsTool, handleGetIssueCounts)
+
+	// Tool 33: Issues for File
+	issuesForFileTool := mcp.NewTool("get_issues_for_file",
+		mcp.WithDescription("Returns only the issues located in one source file, with line numbers and one-line summaries for inline display in an editor"),
+		mcp.WithString("file",
+			mcp.Description("Source file path as open in the editor; matched against capture paths by file name and the longest run of parent directories, ignoring case and slash direction"),
+			mcp.Required(),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("start_line",
+			mcp.Description("First line of the range to return (default: start of file)"),
+		),
+		mcp.WithNumber("end_line",
+			mcp.Description("Last line of the range to return (default: end of file)"),
+		),
+	)
+
+	addTool(s, issuesForFileTool, handleGetIssuesForFile)
+
+	// Tool 34: Call Tree
+	callTreeTool := mcp.NewTool("get_call_tree",
+		mcp.WithDescription("Returns the capture's allocation call trees with self and inclusive sizes, to trace where allocations originate. Start at the roots, then drill down with the path of a node"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("path",
+			mcp.Description("Node to start at, as the path field of a returned node, e.g. 0/2/1 (default: the roots)"),
+		),
+		mcp.WithNumber("depth",
+			mcp.Description("Levels to return, counting the starting nodes (default: 3)"),
+		),
+		mcp.WithNumber("min_bytes",
+			mcp.Description("Leave out nodes with a smaller inclusive size; they are counted in omitted_children (default: 0)"),
+		),
+	)
+
+	addTool(s, callTreeTool, handleGetCallTree)
+
+	// Tool 35: Symbol Search
+	searchSymbolsTool := mcp.NewTool("search_symbols",
+		mcp.WithDescription("Searches the capture's function, file, and type names through a trigram index, by substring, regex, or fuzzy match"),
+		mcp.WithString("query",
+			mcp.Description("Text, Go regular expression, or approximate name to search for"),
+			mcp.Required(),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("substring (case-insensitive), regex (case-sensitive unless (?i)), or fuzzy (default: substring)"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("function, file, type, or all (default: all)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum matches to return (default: 20)"),
+		),
+	)
+
+	addTool(s, searchSymbolsTool, handleSearchSymbols)
+
+	// Tool 36: Rescore Issues
+	rescoreTool := mcp.NewTool("rescore_issues",
+		mcp.WithDescription("Re-scores the analyzed issues with alternate severity bars and per-type weights, e.g. \"what if Critical started at 1MB\", and reports which severities change. Repeated calls on an unchanged capture reuse the analyzed issues without reloading it"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("critical_bytes",
+			mcp.Description("Issues at or above this weighted size are Critical; Critical issues below it drop to High"),
+		),
+		mcp.WithNumber("high_bytes",
+			mcp.Description("Issues at or above this weighted size are at least High; High issues below it drop to Medium"),
+		),
+		mcp.WithNumber("medium_bytes",
+			mcp.Description("Issues at or above this weighted size are at least Medium; Medium issues below it drop to Low"),
+		),
+		mcp.WithString("weights",
+			mcp.Description("Comma-separated Type=factor size multipliers, e.g. MemoryLeak=2,TinyAllocation=0.5 (default: 1 for every type)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum changed issues to list (default: 50)"),
+		),
+	)
+
+	addTool(s, rescoreTool, handleRescoreIssues)
+
+	// Tool 37: Type Analysis
+	analyzeTypesTool := mcp.NewTool("analyze_types",
+		mcp.WithDescription("Ranks allocation types (e.g. C++ classes) by total size, count, or average size with their most common allocation site, and flags types that dominate the heap or grew since a baseline capture"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("sort_by",
+			mcp.Description("size, count, or average (default: size)"),
+		),
+		mcp.WithNumber("count",
+			mcp.Description("Number of types to return (default: 20)"),
+		),
+		mcp.WithString("baseline_path",
+			mcp.Description("Earlier capture to measure growth against; types that grew beyond the tolerance are flagged growing"),
+		),
+		mcp.WithString("tolerance",
+			mcp.Description("Tolerance profile for growth (default: tolerance_profile from the config, else normal)"),
+		),
+	)
+
+	addTool(s, analyzeTypesTool, handleAnalyzeTypes)
+
+	// Tool 38: Virtual Memory Map
+	analyzePagesTool := mcp.NewTool("analyze_pages",
+		mcp.WithDescription("Summarizes the virtual memory map from the page views: committed, reserved, and free memory per region type and protection, large reserved-but-unused regions, and regions with unusual protection"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("min_reserved_bytes",
+			mcp.Description("Smallest reserved region to list (default: 1 MB)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of reserved and unusual regions to list (default: 10)"),
+		),
+	)
+
+	addTool(s, analyzePagesTool, handleAnalyzePages)
+
+	// Tool 39: Suggest Verification
+	suggestVerificationTool := mcp.NewTool("suggest_verification",
+		mcp.WithDescription("Plans how to confirm a fix for one issue after the next capture: what to re-capture, which tool calls to re-run with which arguments, and the expected metric changes"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file the issue was found in; used as the baseline"),
+		),
+		mcp.WithString("issue_id",
+			mcp.Description("Issue ID as returned by analyze_leaks or get_all_issues, e.g. leak-3f2a9c1b"),
+			mcp.Required(),
+		),
+		mcp.WithString("after_path",
+			mcp.Description("Path the capture after the fix will be written to, filled into the tool calls (default: a placeholder)"),
+		),
+	)
+
+	addTool(s, suggestVerificationTool, handleSuggestVerification)
+
+	// Tool 40: Compare Sessions
+	compareSessionsTool := mcp.NewTool("compare_sessions",
+		mcp.WithDescription("Diffs two MemPro JSON exports of the same program: new, fixed, and grown leaks, the fragmentation delta, and the functions whose total size changed most, with an overall verdict"),
+		mcp.WithString("baseline_path",
+			mcp.Description("Path to the baseline MemPro JSON capture"),
+			mcp.Required(),
+		),
+		mcp.WithString("current_path",
+			mcp.Description("Path to the MemPro JSON capture to compare against the baseline (default: json_path or the default capture)"),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file, used when current_path is not given"),
+		),
+		mcp.WithNumber("count",
+			mcp.Description("Number of changed functions to return (default: 10)"),
+		),
+		mcp.WithString("tolerance",
+			mcp.Description("Tolerance profile for the verdict: strict, normal, lenient, or a configured profile (default: tolerance_profile or normal)"),
+		),
+	)
+
+	addTool(s, compareSessionsTool, handleCompareSessions)
+
+	// Tool 41: Query Leaks
+	queryLeaksTool := mcp.NewTool("query_leaks",
+		mcp.WithDescription("Returns only the leaks matching the given filters (source file glob, function regex, size range, suspect flag, module), so large captures need no client-side filtering"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("file",
+			mcp.Description("Glob over the source path, matched against its trailing components ignoring case and slash direction: * and ? stay within a component, ** spans several, e.g. *.cpp or render/**"),
+		),
+		mcp.WithString("function",
+			mcp.Description("Regular expression (Go syntax) matched against the blamed or the allocating function, e.g. ^Mesh::"),
+		),
+		mcp.WithNumber("min_size",
+			mcp.Description("Minimum leaked bytes"),
+		),
+		mcp.WithNumber("max_size",
+			mcp.Description("Maximum leaked bytes"),
+		),
+		mcp.WithBoolean("suspect_only",
+			mcp.Description("Only leaks MemPro flags as suspect (default: false)"),
+		),
+		mcp.WithString("module",
+			mcp.Description("Module of the first application frame in the call stack, with or without extension, e.g. game.exe or game"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, queryLeaksTool, handleQueryLeaks)
+
+	// Tool 42: Export CSV
+	exportCSVTool := mcp.NewTool("export_csv",
+		mcp.WithDescription("Exports leaks, function statistics, or allocation types as CSV with a header row, for Excel or pandas"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("table",
+			mcp.Description("leaks (most severe first), functions, or types (largest total size first)"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of rows (default: unlimited)"),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Optional path to write the CSV file to; the response then reports the row count instead of the CSV"),
+		),
+	)
+
+	addTool(s, exportCSVTool, handleExportCSV)
+
+	// Tool 43: Export Flamegraph
+	flameGraphTool := mcp.NewTool("export_flamegraph",
+		mcp.WithDescription("Converts the call trees (or leak call stacks) to Brendan Gregg's folded-stack format, or a self-contained SVG flamegraph, weighted by inclusive allocated (or leaked) bytes"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("format",
+			mcp.Description("folded (default), for flamegraph.pl, inferno, or speedscope, or svg"),
+		),
+		mcp.WithString("source",
+			mcp.Description("call_trees (default, weighted by allocated bytes) or leaks (weighted by leaked bytes)"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Fold allocator/CRT frames into their callers (default: false)"),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Optional path to write the flamegraph to; the response then reports the stack count instead of the flamegraph"),
+		),
+	)
+
+	addTool(s, flameGraphTool, handleExportFlameGraph)
+
+	// Tool 44: Save Baseline
+	saveBaselineTool := mcp.NewTool("save_baseline",
+		mcp.WithDescription("Saves a capture's summary (totals, fragmentation, and leaked bytes per function) to a baseline file that check_regression compares later captures against"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("baseline_path",
+			mcp.Description("Path of the baseline file to write"),
+			mcp.Required(),
+		),
+	)
+
+	addTool(s, saveBaselineTool, handleSaveBaseline)
+
+	// Tool 45: Check Regression
+	checkRegressionTool := mcp.NewTool("check_regression",
+		mcp.WithDescription("Compares a capture against a baseline saved by save_baseline and reports totals and per-function leak sizes that grew beyond the tolerance profile, for leak budget enforcement"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("baseline_path",
+			mcp.Description("Path of the baseline file written by save_baseline"),
+			mcp.Required(),
+		),
+		mcp.WithString("tolerance",
+			mcp.Description("Tolerance profile: strict, normal, lenient, or one from the config (default: config tolerance_profile, else normal)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of function regressions to list, largest growth first (default: 20)"),
+		),
+	)
+
+	addTool(s, checkRegressionTool, handleCheckRegression)
+
+	// Tool 46: List Sessions
+	listSessionsTool := mcp.NewTool("list_sessions",
+		mcp.WithDescription("Lists the captures loaded into the workspace with load_session, by name, and which one is active"),
+	)
+
+	addTool(s, listSessionsTool, handleListSessions)
+
+	// Tool 47: Load Session
+	loadSessionTool := mcp.NewTool("load_session",
+		mcp.WithDescription("Loads a capture into the workspace under a short name; other tools then accept the name in place of its path, e.g. json_path \"before\""),
+		mcp.WithString("name",
+			mcp.Description("Session name: letters, digits, '_' and '-', starting with a letter"),
+			mcp.Required(),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file, or a directory to follow its newest capture"),
+		),
+		mcp.WithBoolean("activate",
+			mcp.Description("Make this the active session (default: only when no session is active)"),
+		),
+	)
+
+	addTool(s, loadSessionTool, handleLoadSession)
+
+	// Tool 48: Set Active Session
+	setActiveSessionTool := mcp.NewTool("set_active_session",
+		mcp.WithDescription("Makes a loaded session the active one: tools called without json_path analyze it, and resources render it"),
+		mcp.WithString("name",
+			mcp.Description("Name given to load_session"),
+			mcp.Required(),
+		),
+	)
+
+	addTool(s, setActiveSessionTool, handleSetActiveSession)
+	// Tool 49: Import Heaptrack
+	importHeaptrackTool := mcp.NewTool("import_heaptrack",
+		mcp.WithDescription("Converts a KDE heaptrack capture (heaptrack.*.gz or decompressed text) to a MemPro JSON capture. Heaptrack files can also be passed to any tool's json_path directly"),
+		mcp.WithString("heaptrack_path",
+			mcp.Description("Path to the heaptrack capture; zstd-compressed files must be decompressed first"),
+			mcp.Required(),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Path to write the MemPro JSON capture (default: heaptrack_path with a .json extension)"),
+		),
+	)
+
+	addTool(s, importHeaptrackTool, handleImportHeaptrack)
+
+	// Tool 50: Allocation Size Statistics
+	statisticsTool := mcp.NewTool("get_statistics",
+		mcp.WithDescription("Computes p50/p90/p99 allocation sizes, mean, standard deviation, and per-function coefficient of variation and count vs. byte shares, separating many small allocations from few large ones"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of functions to list (default: 20)"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("Order of the function list: bytes (default), count, or cv"),
+		),
+	)
+
+	addTool(s, statisticsTool, handleGetStatistics)
+
+	// Tool 51: Snapshot Timeline
+	timelineTool := mcp.NewTool("analyze_timeline",
+		mcp.WithDescription("Reports live memory growth between snapshots and flags the heap and functions whose memory grows monotonically, consistent with leaks"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of growing functions to list (default: 10)"),
+		),
+	)
+
+	addTool(s, timelineTool, handleAnalyzeTimeline)
+
+	// Tool 52: Hot Allocation Paths
+	hotPathsTool := mcp.NewTool("get_hot_paths",
+		mcp.WithDescription("Returns the root-to-leaf call tree paths that allocate the most bytes, as readable call chains"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of paths to return (default: 10)"),
+		),
+		mcp.WithNumber("min_bytes",
+			mcp.Description("Leave out paths with a smaller inclusive size (default: 0)"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Leave allocator/CRT frames between the root and the leaf out of the chains (default: false)"),
+		),
+	)
+
+	addTool(s, hotPathsTool, handleGetHotPaths)
+
+	// Tool 53: Self vs Inclusive Attribution
+	attributionTool := mcp.NewTool("attribute_allocations",
+		mcp.WithDescription("Reports self vs inclusive allocation share per call tree node, listing the nodes that allocate the most themselves and flagging pass-through frames whose children account for nearly all their bytes"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of owners and pass-through frames to list (default: 20)"),
+		),
+		mcp.WithNumber("min_bytes",
+			mcp.Description("Skip nodes with a smaller inclusive size (default: 0)"),
+		),
+		mcp.WithNumber("pass_through",
+			mcp.Description("Self size, as a percent of inclusive size, at or below which a frame with children is pass-through (default: 5)"),
+		),
+	)
+
+	addTool(s, attributionTool, handleAttributeAllocations)
+
+	// Tool 54: Module Budget Check
+	checkBudgetsTool := mcp.NewTool("check_budgets",
+		mcp.WithDescription("Sums allocations per module (source path prefixes configured in module_budgets) from the function statistics and reports each module's budget use with a pass/fail status for CI gating"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of each module's largest functions to list (default: 3)"),
+		),
+	)
+
+	addTool(s, checkBudgetsTool, handleCheckBudgets)
+
+	// Tool 55: Allocation Churn
+	churnTool := mcp.NewTool("analyze_churn",
+		mcp.WithDescription("Flags churn hotspots: call sites with very high allocation counts and small average sizes, with a pooling, reserve, or arena recommendation for each"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("min_count",
+			mcp.Description("Minimum allocation count of a hotspot (default: 10000)"),
+		),
+		mcp.WithNumber("max_average_size",
+			mcp.Description("Maximum average allocation size in bytes of a hotspot (default: 256)"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description("Maximum number of issues to return, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size in bytes of the returned issue JSON, most severe first (default: unlimited)"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of issues to skip, most severe first, for paging through large results (default: 0)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of issues in the page; the page block gives the total and the next offset (default: unlimited)"),
+		),
+		mcp.WithBoolean("aggregate_suggestions",
+			mcp.Description("Emit suggestions shared by several issues once, with issue counts, total size, and examples"),
+		),
+		mcp.WithString("format",
+			mcp.Description("json (default) or sarif, a SARIF 2.1.0 log for code scanning and IDE problem panes"),
+		),
+		mcp.WithString("source_root",
+			mcp.Description("Checkout the capture was built from; SARIF locations of files found under it are made relative to it, and embedded source is read from it (default: config source_root)"),
+		),
+		mcp.WithNumber("source_lines",
+			mcp.Description("Source lines embedded around each issue's file and line, 0 to omit (default: config source_lines, else 0)"),
+		),
+		mcp.WithBoolean("debug",
+			mcp.Description("Include diagnostics (phase timings, record counts, skipped records)"),
+		),
+	)
+
+	addTool(s, churnTool, handleAnalyzeChurn)
+
+	// Tool 56: Small-Allocation Overhead
+	overheadTool := mcp.NewTool("estimate_overhead",
+		mcp.WithDescription("Estimates allocator header and alignment overhead of small allocations from the function statistics, and the call sites a slab/pool allocator would save the most on"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("max_size",
+			mcp.Description("Include call sites whose average allocation is at most this many bytes (default: 128)"),
+		),
+		mcp.WithNumber("header_size",
+			mcp.Description("Allocator header bytes per allocation (default: 16)"),
+		),
+		mcp.WithNumber("alignment",
+			mcp.Description("Granularity in bytes the allocator rounds requests up to (default: 16)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of call sites to list (default: 20)"),
+		),
+	)
+
+	addTool(s, overheadTool, handleEstimateOverhead)
+
+	// Tool 57: Type Padding
+	typePaddingTool := mcp.NewTool("analyze_type_padding",
+		mcp.WithDescription("Estimates per-type padding against power-of-two and cache-line size classes, and ranks the types that trimming by field reordering would save the most on"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("min_count",
+			mcp.Description("Skip types with fewer objects (default: 1000)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of types to list in each group (default: 20)"),
+		),
+	)
+
+	addTool(s, typePaddingTool, handleAnalyzeTypePadding)
+
+	// Tool 58: Validate Capture Data
+	validateDataTool := mcp.NewTool("validate_data",
+		mcp.WithDescription("Checks a capture against the MemPro JSON format and reports every malformed, unknown, or missing field and section with its location, e.g. non-numeric sizes, a missing Leaks section, or a truncated file"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+	)
+
+	addTool(s, validateDataTool, handleValidateData)
+
+	// Tool 59: Analyze Directory
+	analyzeDirectoryTool := mcp.NewTool("analyze_directory",
+		mcp.WithDescription("Analyzes every capture in a directory, e.g. nightly runs, and matches issues across them by ID to report how often each recurs (\"present in 7 of 10 runs\"), separating persistent leaks from intermittent, flaky ones"),
+		mcp.WithString("directory",
+			mcp.Description("Directory of captures; runs are ordered oldest first by modification time"),
+			mcp.Required(),
+		),
+		mcp.WithString("type",
+			mcp.Description("Only issues of this type, e.g. MemoryLeak (default: all)"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Only issues with this presence: persistent (every run), intermittent, or once"),
+		),
+		mcp.WithNumber("min_runs",
+			mcp.Description("Only issues found in at least this many runs (default: 1)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of issues to return, most frequent first (default: 20)"),
+		),
+		mcp.WithNumber("concurrency",
+			mcp.Description("Captures analyzed in parallel (default: number of CPUs)"),
+		),
+	)
+
+	addTool(s, analyzeDirectoryTool, handleAnalyzeDirectory)
+
+	// Tool 60: Top Allocators
+	topAllocatorsTool := mcp.NewTool("get_top_allocators",
+		mcp.WithDescription("Returns the top N allocating functions ranked by total size, allocation count, average size, or largest allocation, so churn-heavy call sites are as easy to find as big leakers"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("sort_by",
+			mcp.Description("size, count, average, or max (default: size)"),
+		),
+		mcp.WithNumber("count",
+			mcp.Description("Number of functions to return (default: 10)"),
+		),
+	)
+
+	addTool(s, topAllocatorsTool, handleGetTopAllocators)
+
+	// Tool 61: Thread Analysis
+	analyzeThreadsTool := mcp.NewTool("analyze_threads",
+		mcp.WithDescription("Shows allocated, freed, live, and leaked bytes per thread and flags threads that allocate but never free, such as worker threads that keep their buffers"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithNumber("min_allocations",
+			mcp.Description("Allocations a thread must make without any frees to be flagged never_frees (default: 10)"),
+		),
+	)
+
+	addTool(s, analyzeThreadsTool, handleAnalyzeThreads)
+
+	// Tool 62: Generate Suppression
+	generateSuppressionTool := mcp.NewTool("generate_suppression",
+		mcp.WithDescription("Builds a suppression entry that silences a known or accepted leak or large allocation by issue ID, optionally appending it to a suppressions file"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("issue_id",
+			mcp.Description("ID of the issue to suppress, e.g. leak-3f2a9c1b"),
+			mcp.Required(),
+		),
+		mcp.WithString("scope",
+			mcp.Description("stack (innermost application frames), function, file, or id (default: stack for leaks with a call stack, else function)"),
+		),
+		mcp.WithString("reason",
+			mcp.Description("Why the issue is accepted, recorded in the entry"),
+		),
+		mcp.WithString("append_to",
+			mcp.Description("Suppressions file to append the entry to, created if missing; use the configured suppressions_file to apply it on hot reload"),
+		),
+	)
+
+	addTool(s, generateSuppressionTool, handleGenerateSuppression)
+
+	// Tool 63: Export Issues
+	exportIssuesTool := mcp.NewTool("export_issues",
+		mcp.WithDescription("Turns issues into ready-to-file GitHub issue or Jira payloads with a title, a body with the call stack and suggestion, and labels by severity, optionally creating them through the configured issue tracker"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("target",
+			mcp.Description("github or jira"),
+			mcp.Required(),
+		),
+		mcp.WithString("issue_ids",
+			mcp.Description("Comma-separated issue IDs to export, e.g. leak-3f2a9c1b (default: the most severe issues)"),
+		),
+		mcp.WithString("min_severity",
+			mcp.Description("Without issue_ids, only issues at or above this severity: Critical, High, Medium, or Low (default: all)"),
+		),
+		mcp.WithString("type",
+			mcp.Description("Without issue_ids, only issues of this type, e.g. MemoryLeak (default: all)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Without issue_ids, number of issues to export, most severe first (default: 10)"),
+		),
+		mcp.WithBoolean("post",
+			mcp.Description("Create the issues through issue_tracker in the config instead of only returning the payloads (default: false)"),
+		),
+	)
+
+	addTool(s, exportIssuesTool, handleExportIssues)
+
+	// Tool 64: Generate HTML Report
+	htmlReportTool := mcp.NewTool("generate_html_report",
+		mcp.WithDescription("Writes a single-file HTML report with sortable leak and issue tables, a treemap of allocated bytes by module, and an embedded flamegraph, for reading in a browser without an MCP client"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("output_path",
+			mcp.Description("Path of the HTML file to write"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("rows",
+			mcp.Description("Rows per table, most severe first (default: 200)"),
+		),
+		mcp.WithBoolean("skip_plumbing",
+			mcp.Description("Fold allocator/CRT frames into their callers in the flamegraph (default: false)"),
+		),
+	)
+
+	addTool(s, htmlReportTool, handleGenerateHTMLReport)
+
+	// Tool 65: Find Function
+	findFunctionTool := mcp.NewTool("find_function",
+		mcp.WithDescription("Finds a function by partial or approximate name and returns everything the capture records about it in one response: allocation statistics, leaks it allocated or is blamed for, call tree nodes with callers, and the types it allocates most"),
+		mcp.WithString("name",
+			mcp.Description("Full, partial, or approximate function name, e.g. \"LoadTex\" for TextureCache::LoadTexture"),
+			mcp.Required(),
+		),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("substring (case-insensitive, falls back to fuzzy when nothing matches), regex, or fuzzy (default: substring)"),
+		),
+		mcp.WithNumber("rows",
+			mcp.Description("Maximum leaks and call tree nodes to list, largest first (default: 10)"),
+		),
+	)
+
+	addTool(s, findFunctionTool, handleFindFunction)
+
+	// Tool 66: Leak Call Stack
+	leakCallStackTool := mcp.NewTool("get_leak_callstack",
+		mcp.WithDescription("Returns the complete call stack of one leak, by issue ID or Leaks array index, as structured frames with module, offset, source location, and which frame is plumbing or blamed; other tools summarize stacks to a few frames"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("issue_id",
+			mcp.Description("ID of the leak issue, e.g. leak-3f2a9c1b"),
+		),
+		mcp.WithNumber("index",
+			mcp.Description("0-based index into the capture's Leaks array, instead of issue_id"),
+		),
+	)
+
+	addTool(s, leakCallStackTool, handleGetLeakCallStack)
+
+	// Tool 67: STL Containers
+	stlTool := mcp.NewTool("analyze_stl",
+		mcp.WithDescription("Finds std::vector, std::string, and std::map/unordered_map allocation frames, estimates reallocation churn from their size ranges and node overhead of maps, and suggests reserve(), shrink_to_fit(), or flat containers with the bytes and allocations each would save"),
+		mcp.WithString("json_path",
+			mcp.Description("Path to MemPro JSON analysis file"),
+		),
+		mcp.WithString("container",
+			mcp.Description("Only report one container: vector, string, map (also set), or unordered_map (also unordered_set)"),
+		),
+		mcp.WithNumber("min_allocations",
+			mcp.Description("Skip call sites with fewer allocations (default: 100)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of call sites to list (default: 20)"),
+		),
+	)
+
+	addTool(s, stlTool, handleAnalyzeSTL)
+
+	// Tool 68: Leak Growth Rate
+	leakRateTool := mcp.NewTool("estimate_leak_rate",
+		mcp.WithDescription("Fits the growth rate of each leak site in bytes per hour across two or more captures of the same program taken at known times, and projects the time until the leaks exhaust a memory budget"),
+		mcp.WithString("captures",
+			mcp.Description("Comma-separated time=path pairs, e.g. 2026-01-02T10:00:00Z=run1.json,2026-01-02T14:00:00Z=run2.json; a time is an RFC 3339 timestamp, a duration since the start such as 90m, or a number of hours. Without a time, the file's modification time is used"),
+			mcp.Required(),
+		),
+		mcp.WithString("budget",
+			mcp.Description("Memory budget to project time to OOM against, in bytes or with a unit, e.g. 2GB (default: no projection)"),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Number of growing leak sites to list, fastest first (default: 20)"),
+		),
+	)
+
+	addTool(s, leakRateTool, handleEstimateLeakRate)
+}
+
+// Resource templates for individual records of the current capture
+const (
+	leakResourcePrefix     = "mempro://leaks/"
+	functionResourcePrefix = "mempro://functions/"
+)
+
+// resourceParam returns the unescaped template parameter of uri after prefix
+func resourceParam(uri, prefix string) (string, error) {
+	param, err := url.PathUnescape(strings.TrimPrefix(uri, prefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid resource URI %q: %w", uri, err)
+	}
+	return param, nil
+}
+
+func setupResources(s *server.MCPServer) {
+	// Resource: Quick stats
+	statsResource := mcp.NewResource(
+		"mempro://stats",
+		"Memory Statistics",
+		mcp.WithResourceDescription("Quick memory statistics from the most recent analysis"),
+		mcp.WithMIMEType("application/json"),
+	)
+
+	s.AddResource(statsResource, func(request mcp.ReadResourceRequest) ([]interface{}, error) {
+		analyzer, err := analysis.NewMemoryAnalyzer(currentCapturePath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load analyzer: %w", err)
+		}
+
+		leakPercentage := 0.0
+		if analyzer.Data().TotalSize > 0 {
+			leakPercentage = float64(analyzer.Data().LeakSize) / float64(analyzer.Data().TotalSize) * 100
+		}
+
+		stats := map[string]interface{}{
+			"session":           analyzer.Data().SessionName,
+			"total_allocations": analyzer.Data().TotalAllocations,
+			"total_size":        analyzer.Data().TotalSize,
+			"leak_count":        analyzer.Data().LeakCount,
+			"leak_size":         analyzer.Data().LeakSize,
+			"fragmentation":     analyzer.Data().MemoryFragmentation,
+			"leak_percentage":   leakPercentage,
+		}
+
+		jsonData, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		textContent := mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      "mempro://stats",
+				MIMEType: "application/json",
+			},
+			Text: string(jsonData),
+		}
+
+		return []interface{}{textContent}, nil
+	})
+
+	// Resource: Issue counts
+	countsResource := mcp.NewResource(
+		"mempro://counts",
+		"Issue Counts",
+		mcp.WithResourceDescription("Number of issues per severity in the most recent analysis, for status badges"),
+		mcp.WithMIMEType("application/json"),
+	)
+
+	s.AddResource(countsResource, func(request mcp.ReadResourceRequest) ([]interface{}, error) {
+		analyzer, err := analysis.NewMemoryAnalyzer(currentCapturePath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load analyzer: %w", err)
+		}
+
+		jsonData, err := json.Marshal(analyzer.IssueCounts())
+		if err != nil {
+			return nil, err
+		}
+
+		textContent := mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      "mempro://counts",
+				MIMEType: "application/json",
+			},
+			Text: string(jsonData),
+		}
+
+		return []interface{}{textContent}, nil
+	})
+
+	// Resource: Markdown summary
+	summaryResource := mcp.NewResource(
+		"mempro://summary",
+		"Memory Summary Report",
+		mcp.WithResourceDescription("Readable Markdown report of the most recent analysis: key metrics, critical findings, issue counts, and top leakers"),
+		mcp.WithMIMEType("text/markdown"),
+	)
+
+	s.AddResource(summaryResource, func(request mcp.ReadResourceRequest) ([]interface{}, error) {
+		analyzer, err := analysis.NewMemoryAnalyzer(currentCapturePath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load analyzer: %w", err)
+		}
+
+		textContent := mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      "mempro://summary",
+				MIMEType: "text/markdown",
+			},
+			Text: analyzer.MarkdownSummary(),
+		}
+
+		return []interface{}{textContent}, nil
+	})
+
+	// Resource: Watched sessions
+	watchResource := mcp.NewResource(
+		"mempro://watch",
+		"Watched Sessions",
+		mcp.WithResourceDescription("Captures polled by watch_session, with their latest snapshot and changes not yet reported"),
+		mcp.WithMIMEType("application/json"),
+	)
+
+	s.AddResource(watchResource, func(request mcp.ReadResourceRequest) ([]interface{}, error) {
+		jsonData, err := json.MarshalIndent(analysis.WatchStatuses(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		textContent := mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      "mempro://watch",
+				MIMEType: "application/json",
+			},
+			Text: string(jsonData),
+		}
+
+		return []interface{}{textContent}, nil
+	})
+
+	// Resource template: Single leak record
+	leakTemplate := mcp.NewResourceTemplate(
+		leakResourcePrefix+"{index}",
+		"Leak Record",
+		mcp.WithTemplateDescription("One entry of the current capture's Leaks array by 0-based index, with the issue ID analyze_leaks reports it under"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.AddResourceTemplate(leakTemplate, func(request mcp.ReadResourceRequest) ([]interface{}, error) {
+		index, err := resourceParam(request.Params.URI, leakResourcePrefix)
+		if err != nil {
+			return nil, err
+		}
+		analyzer, err := analysis.NewMemoryAnalyzerSections(currentCapturePath(), analysis.SectionLeaks|analysis.SectionPageViews|analysis.SectionThreads|analysis.SectionHeaps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load analyzer: %w", err)
+		}
+		record, err := analyzer.LeakRecord(index)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonData, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		textContent := mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+			},
+			Text: string(jsonData),
+		}
+
+		return []interface{}{textContent}, nil
+	})
+
+	// Resource template: Function statistics
+	functionTemplate := mcp.NewResourceTemplate(
+		functionResourcePrefix+"{name}",
+		"Function Statistics",
+		mcp.WithTemplateDescription("The current capture's Functions entries for one function; percent-encode the name, e.g. mempro://functions/Foo%3A%3ALoad"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+
+	s.AddResourceTemplate(functionTemplate, func(request mcp.ReadResourceRequest) ([]interface{}, error) {
+		name, err := resourceParam(request.Params.URI, functionResourcePrefix)
+		if err != nil {
+			return nil, err
+		}
+		analyzer, err := analysis.NewMemoryAnalyzerSections(currentCapturePath(), analysis.SectionFunctions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load analyzer: %w", err)
+		}
+		records, err := analyzer.FunctionRecords(name)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonData, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		textContent := mcp.TextResourceContents{
+			ResourceContents: mcp.ResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+			},
+			Text: string(jsonData),
+		}
+
+		return []interface{}{textContent}, nil
+	})
+}
+
+// Tool handlers
+
+func handleAnalyzeLeaks(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeLeaks())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleGetSummary(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	summary := analyzer.GetSummary()
+	return withDiagnostics(mcp.NewToolResultText(summary), args, analyzer), nil
+}
+
+func handleGetTopLeakers(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	count := 10
+	if countArg, ok := args["count"].(float64); ok {
+		count = int(countArg)
+	}
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	topLeakers := analyzer.GetTopLeakers(count, stackFrames(args))
+	return withDiagnostics(mcp.NewToolResultText(topLeakers), args, analyzer), nil
+}
+
+func handleAnalyzeFragmentation(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionPageViews)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeFragmentation())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleFindLargeAllocations(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeLargeAllocations())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleFindDuplicateAllocations(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeDuplicateAllocations())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleFindPoolCandidates(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzePoolCandidates())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleFindAlignmentWaste(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeAlignmentWaste())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleFindTinyAllocations(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeTinyAllocations())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleAnalyzeLifetimes(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if !analyzer.HasLifetimes() {
+		return mcp.NewToolResultError("Failed to analyze lifetimes: the capture has no allocation timing data (Allocations records or FreedCount/AverageLifetime on Functions)"), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeLifetimes())
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleGetAllIssues(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeAll()...)
+
+	allIssues := struct {
+		Summary         string                 `json:"summary"`
+		Leaks           []analysis.MemoryIssue `json:"leaks"`
+		Fragmentation   []analysis.MemoryIssue `json:"fragmentation"`
+		LargeAllocs     []analysis.MemoryIssue `json:"large_allocations"`
+		DuplicateAllocs []analysis.MemoryIssue `json:"duplicate_allocations"`
+		PoolCandidates  []analysis.MemoryIssue `json:"pool_candidates"`
+		AlignmentWaste  []analysis.MemoryIssue `json:"alignment_waste"`
+		TinyAllocs      []analysis.MemoryIssue `json:"tiny_allocations"`
+		Lifetimes       []analysis.MemoryIssue `json:"lifetimes"`
+		Heaps           []analysis.MemoryIssue `json:"heaps"`
+		Tags            []analysis.MemoryIssue `json:"tags"`
+		AddressSpace    []analysis.MemoryIssue `json:"address_space"`
+	}{
+		Summary:         analyzer.GetSummary(),
+		Leaks:           groups[0],
+		Fragmentation:   groups[1],
+		LargeAllocs:     groups[2],
+		DuplicateAllocs: groups[3],
+		PoolCandidates:  groups[4],
+		AlignmentWaste:  groups[5],
+		TinyAllocs:      groups[6],
+		Lifetimes:       groups[7],
+		Heaps:           groups[8],
+		Tags:            groups[9],
+		AddressSpace:    groups[10],
+	}
+
+	return withDiagnostics(formatIssues(args, analyzer, groups, allIssues, extras), args, analyzer), nil
+}
+
+func handleValidateConfig(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	configPath, _ := args["config_path"].(string)
+	if configPath == "" {
+		configPath = analysis.CurrentConfig().Path()
+	}
+	if configPath == "" {
+		return mcp.NewToolResultError("No configuration file in use; pass config_path to validate one"), nil
+	}
+
+	validation := analysis.ValidateConfig(configPath)
+	result, err := json.MarshalIndent(validation, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleDeanonymize(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	text, _ := args["text"].(string)
+
+	mapPath, _ := args["map_path"].(string)
+	if mapPath == "" {
+		mapPath = analysis.CurrentConfig().SymbolMapPath()
+	}
+	if mapPath == "" {
+		mapPath = analysis.UserSymbolMapPath()
+	}
+
+	symbols, err := analysis.LoadSymbolMap(mapPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load symbol map: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(symbols.Reveal(text)), nil
+}
+
+func handleExplainLeak(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	issueID, _ := args["issue_id"].(string)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	cfg := analysis.CurrentConfig()
+	opts := analysis.ExplainOptions{
+		ContextLines: analysis.DefaultContextLines,
+		SourceRoot:   cfg.ResolvePath(cfg.SourceRoot),
+	}
+	if v, ok := args["context_lines"].(float64); ok && v >= 0 {
+		opts.ContextLines = int(v)
+	}
+	if v, ok := args["source_root"].(string); ok && v != "" {
+		opts.SourceRoot = v
+	}
+	if v, ok := args["history"].(string); ok {
+		for _, path := range strings.Split(v, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				opts.History = append(opts.History, path)
+			}
+		}
+	}
+	tolerance, _ := args["tolerance"].(string)
+	if opts.Tolerance, err = cfg.Tolerance(tolerance); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to explain issue: %v", err)), nil
+	}
+
+	explanation, err := analyzer.ExplainIssue(issueID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to explain issue: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(explanation, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleCompareFunction(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	function, _ := args["function"].(string)
+	beforePath, _ := args["before_path"].(string)
+	afterPath, _ := args["after_path"].(string)
+	if afterPath == "" {
+		afterPath = getJSONPath(args)
+	}
+	name, _ := args["tolerance"].(string)
+	tolerance, err := analysis.CurrentConfig().Tolerance(name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare: %v", err)), nil
+	}
+
+	before, err := loadCapture(args, beforePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze before capture: %v", err)), nil
+	}
+	after, err := loadCapture(args, afterPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze after capture: %v", err)), nil
+	}
+
+	comparison, err := analysis.CompareFunction(function, before, after, tolerance)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(comparison, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleWatchSession(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	jsonPath := getJSONPath(args)
+	action, _ := args["action"].(string)
+	if action == "" {
+		action = "changes"
+	}
+
+	interval := analysis.DefaultWatchInterval
+	if v, ok := args["interval_seconds"].(float64); ok && v > 0 {
+		interval = max(time.Duration(v*float64(time.Second)), analysis.MinWatchInterval)
+	}
+
+	var response interface{}
+	switch action {
+	case "start":
+		w, err := analysis.StartWatch(jsonPath, interval, clientNotifier)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to watch: %v", err)), nil
+		}
+		response = w.Status()
+	case "changes":
+		w, ok := analysis.FindWatch(jsonPath)
+		if !ok {
+			w, err := analysis.StartWatch(jsonPath, interval, clientNotifier)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to watch: %v", err)), nil
+			}
+			response = map[string]interface{}{
+				"message": "Started watching; call again to see changes since this baseline",
+				"status":  w.Status(),
+			}
+			break
+		}
+		// Poll now so the report is current regardless of the interval; a failed poll
+		// is visible in last_error and the previous snapshot is reported against
+		w.Poll()
+		response = map[string]interface{}{
+			"changes": w.Report(),
+			"status":  w.Status(),
+		}
+	case "stop":
+		if !analysis.StopWatch(jsonPath) {
+			return mcp.NewToolResultError(fmt.Sprintf("Not watching %s", analysis.CurrentConfig().Redactor().Path(jsonPath))), nil
+		}
+		response = map[string]string{"message": "Stopped watching"}
+	case "status":
+		response = analysis.WatchStatuses()
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown action %q (expected changes, start, stop, or status)", action)), nil
+	}
+
+	result, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeBatch(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	list, _ := args["paths"].(string)
+
+	paths, err := analysis.ExpandBatchPaths(list)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find captures: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+	}
+
+	top, workers := 0, 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+	if v, ok := args["concurrency"].(float64); ok {
+		workers = int(v)
+	}
+
+	batch := analysis.AnalyzeBatch(paths, top, workers)
+	result, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeApplication(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	list, _ := args["captures"].(string)
+	application, _ := args["application"].(string)
+
+	captures, err := analysis.ParseProcessCaptures(list)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse captures: %v", err)), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	aggregate, err := analysis.AnalyzeApplication(application, captures, top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(aggregate, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleMergeSessions(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	firstPath, _ := args["first_path"].(string)
+	secondPath, _ := args["second_path"].(string)
+	outputPath, _ := args["output_path"].(string)
+
+	first, err := loadCapture(args, firstPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze first capture: %v", err)), nil
+	}
+	second, err := loadCapture(args, secondPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze second capture: %v", err)), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	report := analysis.MergeCaptures(first, second, top)
+	if outputPath != "" {
+		if err := report.Save(outputPath); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to save merged capture: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+		}
+		report.Output = analysis.CurrentConfig().Redactor().Path(outputPath)
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// runConfigCheck validates the config for --check and returns the process exit code
+func runConfigCheck(configPath string) int {
+	if configPath == "" {
+		fmt.Fprintln(os.Stderr, "No configuration file given; use --config or MEMPRO_CONFIG")
+		return 2
+	}
+
+	validation := analysis.ValidateConfig(configPath)
+	for _, p := range validation.Problems {
+		location := p.File
+		if p.Line > 0 {
+			location = fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+		}
+		fmt.Printf("%s: %s: %s\n", location, p.Severity, p.Message)
+	}
+
+	if !validation.Valid {
+		return 1
+	}
+	fmt.Printf("Configuration OK (%d files checked)\n", len(validation.Files))
+	return 0
+}
+
+// stackFrames returns how many frames to keep when summarizing call stacks, or 0 for full stacks
+func stackFrames(args map[string]interface{}) int {
+	cfg := analysis.CurrentConfig()
+	summarize := cfg.SummarizeStacks
+	if v, ok := args["summarize_stacks"].(bool); ok {
+		summarize = v
+	}
+	if !summarize {
+		return 0
+	}
+
+	frames := cfg.StackFrames
+	if v, ok := args["stack_frames"].(float64); ok && v > 0 {
+		frames = int(v)
+	}
+	if frames <= 0 {
+		frames = analysis.DefaultStackFrames
+	}
+	return frames
+}
+
+// issueExtras carries the optional blocks that accompany an issue list
+type issueExtras struct {
+	page        *IssuePage
+	omitted     *OmittedSummary
+	suggestions []analysis.SuggestionGroup
+}
+
+func handleGetThreadBreakdown(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if !analyzer.HasThreads() {
+		return mcp.NewToolResultError("Failed to break down by thread: the capture has no ThreadId on its leak or allocation records"), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.ThreadBreakdown(top), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeThreads(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if !analyzer.HasThreads() {
+		return mcp.NewToolResultError("Failed to analyze threads: the capture has no ThreadId on its leak or allocation records and no per-thread totals"), nil
+	}
+
+	minAllocations := 0
+	if v, ok := args["min_allocations"].(float64); ok {
+		minAllocations = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.AnalyzeThreads(minAllocations), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleGenerateSuppression(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	id, _ := args["issue_id"].(string)
+	if id == "" {
+		return mcp.NewToolResultError("Failed to generate suppression: issue_id is required"), nil
+	}
+	scope, _ := args["scope"].(string)
+	reason, _ := args["reason"].(string)
+	appendTo, _ := args["append_to"].(string)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	suppression, err := analyzer.SuppressionFor(id, scope, reason)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to generate suppression: %v", err)), nil
+	}
+	if appendTo != "" {
+		if err := analysis.AppendSuppression(appendTo, *suppression); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to generate suppression: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+		}
+	}
+
+	result, err := json.MarshalIndent(suppression, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleExportIssues(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	var opts analysis.ExportOptions
+	opts.Target, _ = args["target"].(string)
+	if opts.Target == "" {
+		return mcp.NewToolResultError("Failed to export issues: target is required"), nil
+	}
+	if v, ok := args["issue_ids"].(string); ok {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				opts.IDs = append(opts.IDs, id)
+			}
+		}
+	}
+	opts.MinSeverity, _ = args["min_severity"].(string)
+	opts.Type, _ = args["type"].(string)
+	if v, ok := args["top"].(float64); ok {
+		opts.Top = int(v)
+	}
+	opts.Post, _ = args["post"].(bool)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	export, err := analyzer.ExportIssues(opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export issues: %v", err)), nil
+	}
+
+	var result strings.Builder
+	enc := json.NewEncoder(&result)
+	enc.SetEscapeHTML(false) // Keep code fences and wiki markup readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(strings.TrimSpace(result.String())), args, analyzer), nil
+}
+
+func handleGenerateHTMLReport(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	outputPath, _ := args["output_path"].(string)
+	if outputPath == "" {
+		return mcp.NewToolResultError("Failed to generate report: output_path is required"), nil
+	}
+	rows := 0
+	if v, ok := args["rows"].(float64); ok {
+		rows = int(v)
+	}
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	data, report, err := analyzer.HTMLReport(rows, skipPlumbing)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to generate report: %v", err)), nil
+	}
+	if err := analysis.SaveHTMLReport(outputPath, data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to generate report: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+	}
+	report.Output = analysis.CurrentConfig().Redactor().Path(outputPath)
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleFindFunction(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	name, _ := args["name"].(string)
+	mode, _ := args["mode"].(string)
+	rows := 0
+	if v, ok := args["rows"].(float64); ok {
+		rows = int(v)
+	}
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	match, err := analyzer.FindFunction(name, mode, rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find function: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(match, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleGetLeakCallStack(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	ref, _ := args["issue_id"].(string)
+	if v, ok := args["index"].(float64); ok && ref == "" {
+		ref = strconv.Itoa(int(v))
+	}
+	if ref == "" {
+		return mcp.NewToolResultError("Failed to get call stack: issue_id or index is required"), nil
+	}
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	stack, err := analyzer.LeakCallStack(ref)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get call stack: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(stack, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleAnalyzeSTL(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.STLSections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	container, _ := args["container"].(string)
+	minAllocations, top := 0, 0
+	if v, ok := args["min_allocations"].(float64); ok {
+		minAllocations = int(v)
+	}
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	report, err := analyzer.AnalyzeSTL(container, minAllocations, top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze containers: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleEstimateLeakRate(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	list, _ := args["captures"].(string)
+	captures, err := analysis.ParseTimedCaptures(list)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse captures: %v", err)), nil
+	}
+
+	var budget analysis.ByteSize
+	if v, ok := args["budget"].(string); ok && v != "" {
+		if budget, err = analysis.ParseByteSize(v); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse budget: %v", err)), nil
+		}
+	}
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	report, err := analysis.EstimateLeakRate(captures, int64(budget), top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to estimate leak rate: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetHeapBreakdown(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if !analyzer.HasHeaps() {
+		return mcp.NewToolResultError("Failed to break down by heap: the capture has no Heaps table or HeapId on its records"), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.HeapBreakdown(top), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetTagRollup(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if !analyzer.HasTags() {
+		return mcp.NewToolResultError("Failed to roll up tags: the capture has no Tag on its records"), nil
+	}
+
+	depth, top := 0, 0
+	if v, ok := args["depth"].(float64); ok {
+		depth = int(v)
+	}
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.TagRollup(depth, top), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCompareSizeClasses(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.CompareSizeClasses(top), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetAddressSpaceHeadroom(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	jsonPath := getJSONPath(args)
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	space := int64(0)
+	if v, ok := args["address_space"].(float64); ok {
+		space = int64(v)
+	}
+	growth, _ := args["growth_per_hour"].(float64)
+	growthSource := "parameter"
+
+	if baselinePath, _ := args["baseline_path"].(string); baselinePath != "" && growth == 0 {
+		baseline, err := loadCapture(args, baselinePath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load baseline: %v", err)), nil
+		}
+
+		hours, _ := args["interval_hours"].(float64)
+		if hours <= 0 {
+			before, errBefore := os.Stat(analysis.LatestCapture(analysis.SessionPath(baselinePath)))
+			after, errAfter := os.Stat(jsonPath)
+			if errBefore == nil && errAfter == nil {
+				hours = after.ModTime().Sub(before.ModTime()).Hours()
+			}
+		}
+		if hours <= 0 {
+			return mcp.NewToolResultError("Failed to derive growth rate: the baseline is not older than the capture; pass interval_hours"), nil
+		}
+
+		committed, reserved := analyzer.UsedAddressSpace()
+		baseCommitted, baseReserved := baseline.UsedAddressSpace()
+		growth = float64(committed+reserved-baseCommitted-baseReserved) / hours
+		growthSource = "baseline"
+	}
+
+	report := analyzer.AddressSpace(space, growth)
+	if report.GrowthPerHour != 0 {
+		report.GrowthSource = growthSource
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetPageUsage(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if len(analyzer.Data().PageViews) == 0 {
+		return mcp.NewToolResultError("Failed to compute page usage: the capture has no page views"), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.PageUsage(top), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetRegionStacks(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	var address int64
+	hasAddress := false
+	if v, ok := args["address"].(string); ok && v != "" {
+		address, err = strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to parse address: %v", err)), nil
+		}
+		hasAddress = true
+	}
+	stackID := 0
+	if v, ok := args["stack_id"].(float64); ok {
+		stackID = int(v)
+	}
+	if !hasAddress && stackID == 0 {
+		return mcp.NewToolResultError("Failed to find region stacks: address or stack_id is required"), nil
+	}
+
+	stacks, err := analyzer.RegionStacks(address, hasAddress, stackID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find region stacks: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(stacks, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleExportCallGraph(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if len(analyzer.Data().Leaks) == 0 && len(analyzer.Data().CallTrees) == 0 {
+		return mcp.NewToolResultError("Failed to build call graph: the capture has no leaks or call trees"), nil
+	}
+
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+	maxNodes := 0
+	if v, ok := args["max_nodes"].(float64); ok {
+		maxNodes = int(v)
+	}
+	var minBytes int64
+	if v, ok := args["min_bytes"].(float64); ok {
+		minBytes = int64(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.CallGraph(skipPlumbing, maxNodes, minBytes), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleFindCallPath(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	function, _ := args["function"].(string)
+	if function == "" {
+		return mcp.NewToolResultError("Failed to find call path: function is required"), nil
+	}
+	mode, _ := args["mode"].(string)
+	weight, _ := args["weight"].(string)
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	paths, err := analyzer.CallPath(function, mode, weight, skipPlumbing)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find call path: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCallersOf(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	function, _ := args["function"].(string)
+	if function == "" {
+		return mcp.NewToolResultError("Failed to find callers: function is required"), nil
+	}
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	callers, err := analyzer.CallersOf(function, skipPlumbing, top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find callers: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(callers, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCalleesOf(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	function, _ := args["function"].(string)
+	if function == "" {
+		return mcp.NewToolResultError("Failed to find callees: function is required"), nil
+	}
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	callees, err := analyzer.CalleesOf(function, skipPlumbing, top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find callees: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(callees, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleTopGrowthBetween(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	beforePath, _ := args["before_path"].(string)
+	afterPath, _ := args["after_path"].(string)
+	if afterPath == "" {
+		afterPath = getJSONPath(args)
+	}
+	count := 0
+	if v, ok := args["count"].(float64); ok {
+		count = int(v)
+	}
+
+	before, err := loadCaptureSections(args, beforePath, analysis.SectionFunctions)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze before capture: %v", err)), nil
+	}
+	after, err := loadCaptureSections(args, afterPath, analysis.SectionFunctions)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze after capture: %v", err)), nil
+	}
+
+	growth, err := analysis.TopGrowth(before, after, count)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(growth, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetIssueCounts(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	result, err := json.Marshal(analyzer.IssueCounts())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetIssuesForFile(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	file, _ := args["file"].(string)
+	startLine, endLine := 0, 0
+	if v, ok := args["start_line"].(float64); ok {
+		startLine = int(v)
+	}
+	if v, ok := args["end_line"].(float64); ok {
+		endLine = int(v)
+	}
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	issues, err := analyzer.IssuesForFile(file, startLine, endLine)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find issues: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetCallTree(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	path, _ := args["path"].(string)
+	depth := 0
+	if v, ok := args["depth"].(float64); ok {
+		depth = int(v)
+	}
+	var minBytes int64
+	if v, ok := args["min_bytes"].(float64); ok {
+		minBytes = int64(v)
+	}
+
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionCallTrees)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	tree, err := analyzer.CallTree(path, depth, minBytes)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get call tree: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleSearchSymbols(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	query, _ := args["query"].(string)
+	mode, _ := args["mode"].(string)
+	kind, _ := args["kind"].(string)
+	limit := 0
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+
+	// An unchanged capture reuses the index of the previous search without loading it
+	jsonPath := getJSONPath(args)
+	var analyzer *analysis.MemoryAnalyzer
+	index, cached := symbolIndexCache.Get(jsonPath)
+	if cached {
+		recordCacheHit(args)
+		setCurrentCapture(captureSource(args), clientNotifier)
+	} else {
+		var err error
+		analyzer, err = loadAnalyzer(args)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+		}
+		index = analyzer.Symbols()
+		symbolIndexCache.Put(jsonPath, index)
+	}
+
+	matches, err := index.Search(query, mode, kind, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to search symbols: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(matches, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	if analyzer == nil {
+		return mcp.NewToolResultText(string(result)), nil
+	}
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleRescoreIssues(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	var opts analysis.RescoreOptions
+	if v, ok := args["critical_bytes"].(float64); ok {
+		opts.CriticalBytes = int64(v)
+	}
+	if v, ok := args["high_bytes"].(float64); ok {
+		opts.HighBytes = int64(v)
+	}
+	if v, ok := args["medium_bytes"].(float64); ok {
+		opts.MediumBytes = int64(v)
+	}
+	if v, ok := args["weights"].(string); ok && v != "" {
+		weights, err := analysis.ParseWeights(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to rescore issues: %v", err)), nil
+		}
+		opts.Weights = weights
+	}
+	limit := 0
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+
+	// An unchanged capture reuses the issues analyzed by the previous call
+	jsonPath := getJSONPath(args)
+	issues, cached := issueSetCache.Get(jsonPath)
+	if cached {
+		recordCacheHit(args)
+		setCurrentCapture(captureSource(args), clientNotifier)
+	} else {
+		analyzer, err := loadAnalyzer(args)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+		}
+		issues = analyzer.AllIssues()
+		issueSetCache.Put(jsonPath, issues)
+	}
+
+	rescored, err := analysis.Rescore(issues, opts, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to rescore issues: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(rescored, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeTypes(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	sortBy, _ := args["sort_by"].(string)
+	baselinePath, _ := args["baseline_path"].(string)
+	count := 0
+	if v, ok := args["count"].(float64); ok {
+		count = int(v)
+	}
+	name, _ := args["tolerance"].(string)
+	tolerance, err := analysis.CurrentConfig().Tolerance(name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze types: %v", err)), nil
+	}
+
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionTypes)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	var baseline *analysis.MemoryAnalyzer
+	if baselinePath != "" {
+		baseline, err = loadCaptureSections(args, baselinePath, analysis.SectionTypes)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze baseline capture: %v", err)), nil
+		}
+	}
+
+	report, err := analyzer.AnalyzeTypes(sortBy, count, baseline, tolerance)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze types: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzePages(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionPageViews)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+	if len(analyzer.Data().PageViews) == 0 {
+		return mcp.NewToolResultError("Failed to analyze pages: the capture has no page views"), nil
+	}
+
+	var minReserved int64
+	if v, ok := args["min_reserved_bytes"].(float64); ok {
+		minReserved = int64(v)
+	}
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	result, err := json.MarshalIndent(analyzer.PageMap(minReserved, top), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleSuggestVerification(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	id, _ := args["issue_id"].(string)
+	if id == "" {
+		return mcp.NewToolResultError("Failed to plan verification: issue_id is required"), nil
+	}
+	afterPath, _ := args["after_path"].(string)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	red := analysis.CurrentConfig().Redactor()
+	plan, err := analyzer.SuggestVerification(id, red.Path(getJSONPath(args)), red.Path(afterPath))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to plan verification: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCompareSessions(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	baselinePath, _ := args["baseline_path"].(string)
+	currentPath, _ := args["current_path"].(string)
+	if currentPath == "" {
+		currentPath = getJSONPath(args)
+	}
+	count := 0
+	if v, ok := args["count"].(float64); ok {
+		count = int(v)
+	}
+	name, _ := args["tolerance"].(string)
+	tolerance, err := analysis.CurrentConfig().Tolerance(name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare: %v", err)), nil
+	}
+
+	baseline, err := loadCaptureSections(args, baselinePath, analysis.CompareSessionSections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze baseline capture: %v", err)), nil
+	}
+	current, err := loadCaptureSections(args, currentPath, analysis.CompareSessionSections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze current capture: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(analysis.CompareSessions(baseline, current, count, tolerance), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleQueryLeaks(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	var query analysis.LeakQuery
+	query.File, _ = args["file"].(string)
+	query.Function, _ = args["function"].(string)
+	if v, ok := args["min_size"].(float64); ok {
+		query.MinSize = int64(v)
+	}
+	if v, ok := args["max_size"].(float64); ok {
+		query.MaxSize = int64(v)
+	}
+	query.SuspectOnly, _ = args["suspect_only"].(bool)
+	query.Module, _ = args["module"].(string)
+
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	issues, summary, err := analyzer.QueryLeaks(query)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to query leaks: %v", err)), nil
+	}
+
+	groups, extras := prepareIssues(args, issues)
+	response := formatIssues(args, analyzer, groups, groups[0], extras)
+	if !response.IsError {
+		appendJSONContent(response, "query", summary)
+	}
+	return withDiagnostics(response, args, analyzer), nil
+}
+
+func handleExportCSV(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	table, _ := args["table"].(string)
+	sections, ok := analysis.CSVSections[table]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export CSV: unknown table %q (want leaks, functions, or types)", table)), nil
+	}
+	outputPath, _ := args["output_path"].(string)
+	limit := 0
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+
+	analyzer, err := loadAnalyzerSections(args, sections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	data, rows, err := analyzer.ExportCSV(table, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export CSV: %v", err)), nil
+	}
+	if outputPath == "" {
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	if err := analysis.SaveCSV(outputPath, data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export CSV: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+	}
+	result, err := json.MarshalIndent(analysis.CSVExport{Table: table, Rows: rows, Output: analysis.CurrentConfig().Redactor().Path(outputPath)}, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleExportFlameGraph(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = "folded"
+	}
+	if format != "folded" && format != "svg" {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export flamegraph: unknown format %q (want folded or svg)", format)), nil
+	}
+	source, _ := args["source"].(string)
+	if source == "" {
+		source = "call_trees"
+	}
+	sections := map[string]analysis.CaptureSections{"call_trees": analysis.SectionCallTrees, "leaks": analysis.SectionLeaks | analysis.SectionPageViews}[source]
+	if sections == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export flamegraph: unknown source %q (want call_trees or leaks)", source)), nil
+	}
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+	outputPath, _ := args["output_path"].(string)
+
+	analyzer, err := loadAnalyzerSections(args, sections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	var stacks analysis.FoldedStacks
+	title := "Allocated bytes"
+	if source == "leaks" {
+		stacks, err = analyzer.FoldLeaks(skipPlumbing)
+		title = "Leaked bytes"
+	} else {
+		stacks, err = analyzer.FoldCallTrees(skipPlumbing)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export flamegraph: %v", err)), nil
+	}
+
+	var data []byte
+	if format == "svg" {
+		data = stacks.SVG(fmt.Sprintf("%s: %s", title, analyzer.Data().SessionName))
+	} else {
+		data = stacks.Folded()
+	}
+	if outputPath == "" {
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	if err := analysis.SaveFlameGraph(outputPath, data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to export flamegraph: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+	}
+	export := analysis.FlameGraphExport{
+		Format:     format,
+		Source:     source,
+		Stacks:     len(stacks),
+		TotalBytes: stacks.Total(),
+		Output:     analysis.CurrentConfig().Redactor().Path(outputPath),
+	}
+	result, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// prepareIssues applies the per-call output options shared by all issue tools: the
+// requested page, output caps within it, then suggestion aggregation over the issues that remain
+func prepareIssues(args map[string]interface{}, groups ...[]analysis.MemoryIssue) ([][]analysis.MemoryIssue, issueExtras) {
+	var extras issueExtras
+
+	offset, limit, paged := pageArgs(args)
+	if paged {
+		groups, extras.page = pageIssueGroups(groups, offset, limit)
+	}
+
+	// Before the caps, so max_bytes counts the embedded source
+	if context, root := sourceArgs(args); context > 0 {
+		groups = analysis.EmbedSource(groups, context, root)
+	}
+
+	maxItems, maxBytes := outputLimits(args)
+	groups, extras.omitted = capIssueGroups(groups, maxItems, maxBytes)
+
+	if extras.page != nil {
+		for _, issues := range groups {
+			extras.page.Returned += len(issues)
+		}
+		if next := offset + extras.page.Returned; extras.page.Returned > 0 && next < extras.page.Total {
+			extras.page.NextOffset = next
+		}
+	}
+
+	aggregate := analysis.CurrentConfig().AggregateSuggestions
+	if v, ok := args["aggregate_suggestions"].(bool); ok {
+		aggregate = v
+	}
+	if aggregate {
+		extras.suggestions = analysis.AggregateSuggestions(groups, 2)
+	}
+
+	return groups, extras
+}
+
+// sourceArgs returns how many source lines to embed around issues and the checkout to find
+// them in. Source is never embedded while symbol anonymization is on.
+func sourceArgs(args map[string]interface{}) (int, string) {
+	cfg := analysis.CurrentConfig()
+	context, root := cfg.SourceLines, cfg.ResolvePath(cfg.SourceRoot)
+	if v, ok := args["source_lines"].(float64); ok && v >= 0 {
+		context = int(v)
+	}
+	if v, ok := args["source_root"].(string); ok && v != "" {
+		root = v
+	}
+	if cfg.SymbolMapPath() != "" {
+		return 0, root
+	}
+	return context, root
+}
+
+func handleSaveBaseline(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	baselinePath, _ := args["baseline_path"].(string)
+	if baselinePath == "" {
+		return mcp.NewToolResultError("Failed to save baseline: baseline_path is required"), nil
+	}
+
+	analyzer, err := loadAnalyzerSections(args, analysis.BaselineSections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	red := analysis.CurrentConfig().Redactor()
+	baseline := analyzer.TakeBaseline(red.Path(getJSONPath(args)))
+	if err := baseline.Save(baselinePath); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save baseline: %v", red.Error(err))), nil
+	}
+
+	summary := struct {
+		Baseline  string `json:"baseline"`
+		Session   string `json:"session"`
+		LeakSize  int64  `json:"leak_size"`
+		Functions int    `json:"functions"`
+	}{red.Path(baselinePath), baseline.Session, baseline.LeakSize, len(baseline.FunctionLeaks)}
+	result, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCheckRegression(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	baselinePath, _ := args["baseline_path"].(string)
+	if baselinePath == "" {
+		return mcp.NewToolResultError("Failed to check regression: baseline_path is required"), nil
+	}
+	tolerance, _ := args["tolerance"].(string)
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	cfg := analysis.CurrentConfig()
+	tol, err := cfg.Tolerance(tolerance)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to check regression: %v", err)), nil
+	}
+	baseline, err := analysis.LoadBaseline(baselinePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to check regression: %v", cfg.Redactor().Error(err))), nil
+	}
+
+	analyzer, err := loadAnalyzerSections(args, analysis.BaselineSections)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	report := analyzer.CheckRegression(baseline, cfg.Redactor().Path(baselinePath), tol, top)
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleListSessions(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	sessions := analysis.WorkspaceSessions()
+	red := analysis.CurrentConfig().Redactor()
+	for i := range sessions {
+		sessions[i].Path = red.Path(sessions[i].Path)
+	}
+
+	result, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleLoadSession(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	name, _ := args["name"].(string)
+	activate, _ := args["activate"].(bool)
+
+	session, err := analysis.LoadSession(name, captureSource(args), activate, func(path string) (*analysis.MemoryAnalyzer, error) {
+		return loadCapture(args, path)
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load session: %v", err)), nil
+	}
+	if session.Active {
+		setCurrentCapture(session.Path, clientNotifier)
+	}
+
+	return sessionResult(session)
+}
+
+func handleSetActiveSession(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	name, _ := args["name"].(string)
+
+	session, err := analysis.SetActiveSession(name)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to set active session: %v", err)), nil
+	}
+	setCurrentCapture(session.Path, clientNotifier)
+
+	return sessionResult(session)
+}
+
+// sessionResult formats a workspace session for load_session and set_active_session
+func sessionResult(session *analysis.WorkspaceSession) (*mcp.CallToolResult, error) {
+	session.Path = analysis.CurrentConfig().Redactor().Path(session.Path)
+
+	result, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleImportHeaptrack(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	heaptrackPath, _ := args["heaptrack_path"].(string)
+	if heaptrackPath == "" {
+		return mcp.NewToolResultError("Failed to import heaptrack capture: heaptrack_path is required"), nil
+	}
+	outputPath, _ := args["output_path"].(string)
+	if outputPath == "" {
+		outputPath = analysis.HeaptrackOutputPath(heaptrackPath)
+	}
+
+	red := analysis.CurrentConfig().Redactor()
+	imported, err := analysis.ImportHeaptrack(heaptrackPath, outputPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to import heaptrack capture: %v", red.Error(err))), nil
+	}
+	imported.Output = red.Path(imported.Output)
+	imported.Session = red.Session(imported.Session)
+
+	result, err := json.MarshalIndent(imported, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetStatistics(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+	sortBy, _ := args["sort"].(string)
+
+	stats, err := analyzer.Statistics(top, sortBy)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compute statistics: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeTimeline(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionSnapshots|analysis.SectionLeaks|analysis.SectionPageViews)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	timeline, err := analyzer.Timeline(top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze timeline: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(timeline, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleGetHotPaths(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+	var minBytes int64
+	if v, ok := args["min_bytes"].(float64); ok {
+		minBytes = int64(v)
+	}
+	skipPlumbing, _ := args["skip_plumbing"].(bool)
+
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionCallTrees)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	paths, err := analyzer.HotPaths(top, minBytes, skipPlumbing)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get hot paths: %v", err)), nil
+	}
+
+	var result strings.Builder
+	enc := json.NewEncoder(&result)
+	enc.SetEscapeHTML(false) // Keep the chains' arrows and template arguments readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(paths); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(strings.TrimSpace(result.String())), nil
+}
+
+func handleAttributeAllocations(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+	var minBytes int64
+	if v, ok := args["min_bytes"].(float64); ok {
+		minBytes = int64(v)
+	}
+	threshold, _ := args["pass_through"].(float64)
+
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionCallTrees)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	report, err := analyzer.AttributeAllocations(top, minBytes, threshold)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to attribute allocations: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleCheckBudgets(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionFunctions)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	report, err := analyzer.CheckModuleBudgets(top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to check budgets: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeChurn(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzer(args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	minCount := 0
+	if v, ok := args["min_count"].(float64); ok {
+		minCount = int(v)
+	}
+	maxAverage, _ := args["max_average_size"].(float64)
+
+	groups, extras := prepareIssues(args, analyzer.AnalyzeChurn(minCount, maxAverage))
+	return withDiagnostics(formatIssues(args, analyzer, groups, groups[0], extras), args, analyzer), nil
+}
+
+func handleEstimateOverhead(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionFunctions)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	var maxSize, alignment int64
+	header := int64(analysis.AllocatorHeaderBytes)
+	if v, ok := args["max_size"].(float64); ok {
+		maxSize = int64(v)
+	}
+	if v, ok := args["header_size"].(float64); ok {
+		header = int64(v)
+	}
+	if v, ok := args["alignment"].(float64); ok {
+		alignment = int64(v)
+	}
+	top := 0
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	report, err := analyzer.EstimateOverhead(maxSize, header, alignment, top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to estimate overhead: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleAnalyzeTypePadding(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionTypes)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	minCount, top := 0, 0
+	if v, ok := args["min_count"].(float64); ok {
+		minCount = int(v)
+	}
+	if v, ok := args["top"].(float64); ok {
+		top = int(v)
+	}
+
+	report, err := analyzer.TypePadding(minCount, top)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze type padding: %v", err)), nil
+	}
+
+	var result strings.Builder
+	enc := json.NewEncoder(&result)
+	enc.SetEscapeHTML(false) // Keep template arguments such as std::vector<int> readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(strings.TrimSpace(result.String())), nil
+}
+
+func handleValidateData(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	validation := analysis.ValidateData(getJSONPath(args), analysis.CurrentConfig().Redactor())
+	result, err := json.MarshalIndent(validation, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+func handleGetTopAllocators(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	sortBy, _ := args["sort_by"].(string)
+	count := 0
+	if v, ok := args["count"].(float64); ok {
+		count = int(v)
+	}
+
+	analyzer, err := loadAnalyzerSections(args, analysis.SectionFunctions)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze: %v", err)), nil
+	}
+
+	report, err := analyzer.TopAllocators(sortBy, count)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to rank allocators: %v", err)), nil
+	}
+
+	result, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return withDiagnostics(mcp.NewToolResultText(string(result)), args, analyzer), nil
+}
+
+func handleAnalyzeDirectory(args map[string]interface{}) (*mcp.CallToolResult, error) {
+	directory, _ := args["directory"].(string)
+	if directory == "" {
+		return mcp.NewToolResultError("Failed to analyze directory: directory is required"), nil
+	}
+
+	var opts analysis.DirectoryOptions
+	opts.Type, _ = args["type"].(string)
+	opts.Pattern, _ = args["pattern"].(string)
+	if v, ok := args["min_runs"].(float64); ok {
+		opts.MinRuns = int(v)
+	}
+	if v, ok := args["top"].(float64); ok {
+		opts.Top = int(v)
+	}
+	if v, ok := args["concurrency"].(float64); ok {
+		opts.Workers = int(v)
+	}
+
+	recurrence, err := analysis.AnalyzeDirectory(directory, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to analyze directory: %v", analysis.CurrentConfig().Redactor().Error(err))), nil
+	}
+
+	result, err := json.MarshalIndent(recurrence, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// formatIssues renders an issue tool's result in the call's format: value as indented JSON,
+// or all groups as one SARIF log. The extra blocks follow either way.
+func formatIssues(args map[string]interface{}, analyzer *analysis.MemoryAnalyzer, groups [][]analysis.MemoryIssue, value interface{}, extras issueExtras) *mcp.CallToolResult {
+	format, _ := args["format"].(string)
+	switch format {
+	case "", "json":
+	case "sarif":
+		cfg := analysis.CurrentConfig()
+		sourceRoot := cfg.ResolvePath(cfg.SourceRoot)
+		if v, ok := args["source_root"].(string); ok && v != "" {
+			sourceRoot = v
+		}
+		value = analyzer.SARIF(sourceRoot, groups...)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: unknown format %q (want json or sarif)", format))
+	}
+
+	var result strings.Builder
+	enc := json.NewEncoder(&result)
+	enc.SetEscapeHTML(false) // Keep template arguments and source snippet markers readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to format results: %v", err))
+	}
+	return issueResult(strings.TrimSpace(result.String()), extras)
+}
+
+// issueResult builds a tool result from formatted issues plus their extra blocks
+func issueResult(text string, extras issueExtras) *mcp.CallToolResult {
+	result := mcp.NewToolResultText(text)
+	if len(extras.suggestions) > 0 {
+		appendJSONContent(result, "suggestions", extras.suggestions)
+	}
+	if extras.omitted != nil {
+		appendJSONContent(result, "omitted", extras.omitted)
+	}
+	if extras.page != nil {
+		appendJSONContent(result, "page", extras.page)
+	}
+	return result
+}
+
+// appendJSONContent adds {key: value} as an extra text content block
+func appendJSONContent(result *mcp.CallToolResult, key string, value interface{}) {
+	data, err := json.MarshalIndent(map[string]interface{}{key: value}, "", "  ")
+	if err != nil {
+		return
+	}
+	result.Content = append(result.Content, mcp.NewTextContent(string(data)))
+}
+
+// withDiagnostics appends the analyzer's diagnostics as an extra content block when
+// the call passed debug=true or the server runs with --debug
+func withDiagnostics(result *mcp.CallToolResult, args map[string]interface{}, analyzer *analysis.MemoryAnalyzer) *mcp.CallToolResult {
+	debug, _ := args["debug"].(bool)
+	if !debug && !analysis.DebugMode {
+		return result
+	}
+
+	appendJSONContent(result, "diagnostics", analyzer.Diagnostics())
+	return result
+}
+
+// loadAnalyzer loads the capture a tool call is about and makes it the current capture
+// that resources are rendered from
+func loadAnalyzer(args map[string]interface{}) (*analysis.MemoryAnalyzer, error) {
+	return loadAnalyzerSections(args, analysis.AllSections)
+}
+
+// loadAnalyzerSections is loadAnalyzer for tools that need only some record arrays
+func loadAnalyzerSections(args map[string]interface{}, sections analysis.CaptureSections) (*analysis.MemoryAnalyzer, error) {
+	analyzer, err := loadCaptureSections(args, getJSONPath(args), sections)
+	if err == nil {
+		setCurrentCapture(captureSource(args), clientNotifier)
+	}
+	return analyzer, err
+}
+
+// loadCapture loads a capture named by a tool call, attributing its parse time to the call
+// whose arguments are args
+func loadCapture(args map[string]interface{}, jsonPath string) (*analysis.MemoryAnalyzer, error) {
+	return loadCaptureSections(args, jsonPath, analysis.AllSections)
+}
+
+// loadCaptureSections is loadCapture for tools that need only some record arrays
+func loadCaptureSections(args map[string]interface{}, jsonPath string, sections analysis.CaptureSections) (*analysis.MemoryAnalyzer, error) {
+	analyzer, err := analysis.NewMemoryAnalyzerSections(jsonPath, sections)
+	recordCapture(args, analyzer)
+	if err == nil && analyzer.Cached() {
+		recordCacheHit(args)
+	}
+	return analyzer, err
+}
+
+// Helper function to get JSON path from arguments or use default
+func getJSONPath(args map[string]interface{}) string {
+	return analysis.LatestCapture(captureSource(args))
+}
+
+// captureSource returns the capture file or directory named by the arguments, the active
+// session, --json-path, the environment, or the config, else a default location
+func captureSource(args map[string]interface{}) string {
+	if path, ok := args["json_path"].(string); ok && path != "" {
+		return analysis.SessionPath(path)
+	}
+
+	// The active workspace session replaces the defaults
+	if path, ok := analysis.ActiveSessionPath(); ok {
+		return path
+	}
+
+	if jsonPathFlag != "" {
+		return jsonPathFlag
+	}
+
+	// Check if environment variable is set
+	if envPath := os.Getenv("MEMPRO_JSON_PATH"); envPath != "" {
+		return envPath
+	}
+
+	cfg := analysis.CurrentConfig()
+	if cfg.DefaultJSONPath != "" {
+		return cfg.ResolvePath(cfg.DefaultJSONPath)
+	}
+
+	return defaultCapture()
+}
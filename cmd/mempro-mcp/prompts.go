@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mempromcp/pkg/analysis"
+)
+
+// Number of issues and heaps quoted in prompt context
+const promptContextItems = 5
+
+// setupPrompts registers the guided investigations. Each prompt quotes the relevant findings
+// of a capture and lists the tool calls to make, so a client can start one with a click.
+func setupPrompts(s *server.MCPServer) {
+	// Prompt: Investigate the top leak
+	investigateLeakPrompt := mcp.NewPrompt("investigate_top_leak",
+		mcp.WithPromptDescription("Walk through the most severe leak of the capture: explain its call stack, find the owner, and propose and verify a fix"),
+		mcp.WithArgument("json_path",
+			mcp.ArgumentDescription("Path to MemPro JSON analysis file (default: the capture most recently analyzed)"),
+		),
+	)
+
+	s.AddPrompt(investigateLeakPrompt, handleInvestigateLeakPrompt)
+
+	// Prompt: Fragmentation fix plan
+	fragmentationPlanPrompt := mcp.NewPrompt("fragmentation_fix_plan",
+		mcp.WithPromptDescription("Write a prioritized plan to reduce heap fragmentation, based on the capture's fragmentation, heap, page, and size-class findings"),
+		mcp.WithArgument("json_path",
+			mcp.ArgumentDescription("Path to MemPro JSON analysis file (default: the capture most recently analyzed)"),
+		),
+	)
+
+	s.AddPrompt(fragmentationPlanPrompt, handleFragmentationPlanPrompt)
+}
+
+func handleInvestigateLeakPrompt(arguments map[string]string) (*mcp.GetPromptResult, error) {
+	jsonPath, analyzer, err := loadPromptCapture(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Investigate the most severe memory leak in the MemPro capture %q (session %q).\n\n", jsonPath, analyzer.Data().SessionName)
+	fmt.Fprintf(&b, "The capture has %d leaks totaling %s (%.2f%% of %s allocated).\n\n",
+		analyzer.Data().LeakCount, analysis.FormatBytes(analyzer.Data().LeakSize), analyzer.LeakPercentage(), analysis.FormatBytes(analyzer.Data().TotalSize))
+
+	leaks := analyzer.AnalyzeLeaks()
+	if len(leaks) == 0 {
+		b.WriteString("No leaks were reported. Confirm this with ")
+		b.WriteString(promptToolCall("get_all_issues", map[string]interface{}{"json_path": jsonPath}))
+		b.WriteString(" and summarize the remaining issues instead.\n")
+		return promptResult("Investigate the top leak", b.String()), nil
+	}
+
+	top := leaks[0]
+	issue, err := json.MarshalIndent(top, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&b, "The top leak, %s:\n\n```json\n%s\n```\n\n", top.ID, issue)
+
+	b.WriteString("Steps:\n")
+	fmt.Fprintf(&b, "1. Call %s to read the call stack with source context and learn who owns the allocation.\n",
+		promptToolCall("explain_leak", map[string]interface{}{"json_path": jsonPath, "issue_id": top.ID}))
+	fmt.Fprintf(&b, "2. Call %s to see which code paths reach it.\n",
+		promptToolCall("callers_of", map[string]interface{}{"json_path": jsonPath, "function": top.FunctionName}))
+	fmt.Fprintf(&b, "3. Call %s to check whether the same owner leaks elsewhere.\n",
+		promptToolCall("query_leaks", map[string]interface{}{"json_path": jsonPath, "function": regexpLiteral(top.FunctionName)}))
+	fmt.Fprintf(&b, "4. Call %s for how to confirm the fix in the next capture.\n\n",
+		promptToolCall("suggest_verification", map[string]interface{}{"json_path": jsonPath, "issue_id": top.ID}))
+	b.WriteString("Finish with the root cause, the code change that fixes it, and how to verify it. Say so when the stack does not show who should free the memory rather than guessing.\n")
+
+	return promptResult(fmt.Sprintf("Investigate %s in %s", top.ID, analyzer.Data().SessionName), b.String()), nil
+}
+
+func handleFragmentationPlanPrompt(arguments map[string]string) (*mcp.GetPromptResult, error) {
+	jsonPath, analyzer, err := loadPromptCapture(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write a plan to reduce heap fragmentation for the MemPro capture %q (session %q).\n\n", jsonPath, analyzer.Data().SessionName)
+	thresholds := analyzer.Config().EffectiveThresholds()
+	fmt.Fprintf(&b, "Fragmentation is %.2f%% (medium from %g%%, high from %g%%) across %d allocations totaling %s.\n\n",
+		analyzer.Fragmentation(), thresholds.FragmentationMedium, thresholds.FragmentationHigh,
+		analyzer.Data().TotalAllocations, analysis.FormatBytes(analyzer.Data().TotalSize))
+
+	issues := append(analyzer.AnalyzeFragmentation(), analyzer.AnalyzeHeaps()...)
+	if len(issues) == 0 {
+		b.WriteString("No fragmentation findings were reported.\n\n")
+	} else {
+		analysis.SortIssues(issues)
+		b.WriteString("Fragmentation findings:\n")
+		for _, issue := range issues[:min(len(issues), promptContextItems)] {
+			fmt.Fprintf(&b, "- [%s] %s: %s\n", issue.Severity, issue.ID, issue.Description)
+		}
+		b.WriteString("\n")
+	}
+
+	if analyzer.HasHeaps() {
+		heaps, err := json.MarshalIndent(analyzer.HeapBreakdown(promptContextItems), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "Largest heaps:\n\n```json\n%s\n```\n\n", heaps)
+	}
+
+	args := map[string]interface{}{"json_path": jsonPath}
+	b.WriteString("Steps:\n")
+	fmt.Fprintf(&b, "1. Call %s and %s to see where free space is stranded.\n",
+		promptToolCall("analyze_fragmentation", args), promptToolCall("get_page_usage", args))
+	fmt.Fprintf(&b, "2. Call %s to find sizes the allocator rounds up badly.\n", promptToolCall("compare_size_classes", args))
+	fmt.Fprintf(&b, "3. Call %s and %s for churn that a pool or arena would absorb.\n",
+		promptToolCall("find_pool_candidates", args), promptToolCall("find_tiny_allocations", args))
+	fmt.Fprintf(&b, "4. Call %s to separate short-lived allocations from long-lived ones sharing their pages.\n\n",
+		promptToolCall("analyze_lifetimes", args))
+	b.WriteString("Finish with a prioritized list of changes (pools, arenas, size-class or heap separation, allocation reordering), each with the allocations it affects, the expected effect on fragmentation, and its cost. ")
+	fmt.Fprintf(&b, "End with how to verify the plan: take a new capture and call %s.\n",
+		promptToolCall("compare_sessions", map[string]interface{}{"baseline_path": jsonPath, "current_path": "{after_path}"}))
+
+	return promptResult("Fragmentation fix plan for "+analyzer.Data().SessionName, b.String()), nil
+}
+
+// loadPromptCapture loads the capture named by a prompt's json_path argument, or the current
+// capture. Prompts only read it; the current capture stays as it is.
+func loadPromptCapture(arguments map[string]string) (string, *analysis.MemoryAnalyzer, error) {
+	jsonPath := arguments["json_path"]
+	if jsonPath == "" {
+		jsonPath = currentCapturePath()
+	}
+	analyzer, err := analysis.NewMemoryAnalyzer(jsonPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load analyzer: %w", err)
+	}
+	return jsonPath, analyzer, nil
+}
+
+// promptToolCall formats a tool call for a prompt, e.g. `explain_leak` with `{"issue_id":"leak-1"}`
+func promptToolCall(name string, args map[string]interface{}) string {
+	var encoded strings.Builder
+	enc := json.NewEncoder(&encoded)
+	enc.SetEscapeHTML(false) // Keep template arguments such as std::vector<int> readable
+	enc.Encode(args)
+	return fmt.Sprintf("`%s` with `%s`", name, strings.TrimSpace(encoded.String()))
+}
+
+// regexpLiteral returns a regular expression matching s exactly
+func regexpLiteral(s string) string {
+	return "^" + regexp.QuoteMeta(s) + "$"
+}
+
+// promptResult wraps text as a prompt's single user message
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages:    []mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text))},
+	}
+}
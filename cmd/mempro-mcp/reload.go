@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"mempromcp/pkg/analysis"
+)
+
+// watchConfig polls the config file and every file it references, reloading the
+// configuration when any of them change. Failed reloads keep the previous config.
+func watchConfig(path string, interval time.Duration, n *Notifier) {
+	files := analysis.CurrentConfig().WatchedFiles()
+	mtimes := fileModTimes(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		current := fileModTimes(files)
+		if sameModTimes(mtimes, current) {
+			continue
+		}
+		mtimes = current
+
+		cfg, err := analysis.LoadConfig(path)
+		if err != nil {
+			msg := fmt.Sprintf("Config reload failed, keeping previous configuration: %v", err)
+			log.Print(msg)
+			n.Log("error", "config", msg)
+			continue
+		}
+
+		analysis.SetConfig(cfg)
+
+		// The reloaded config may reference different files
+		files = cfg.WatchedFiles()
+		mtimes = fileModTimes(files)
+
+		msg := fmt.Sprintf("Configuration reloaded from %s (%d custom rules)", path, cfg.RuleCount())
+		log.Print(msg)
+		n.Log("info", "config", msg)
+	}
+}
+
+// fileModTimes returns the modification time of each file; missing files map to the zero time
+func fileModTimes(files []string) map[string]time.Time {
+	mtimes := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			mtimes[f] = info.ModTime()
+		} else {
+			mtimes[f] = time.Time{}
+		}
+	}
+	return mtimes
+}
+
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for f, t := range a {
+		if !b[f].Equal(t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolRegistry holds optional tools that become available or go away while the server runs,
+// such as converters, detectors, or tools backed by a live connection. mcp-go's own tool
+// table is neither safe for concurrent use nor able to remove tools, so the transport
+// merges these into tools/list and dispatches their calls itself.
+type toolRegistry struct {
+	mu       sync.RWMutex
+	tools    map[string]mcp.Tool
+	handlers map[string]server.ToolHandlerFunc
+}
+
+var runtimeTools = newToolRegistry()
+
+// startupTools holds the tools registered by setupTools. mcp-go lists them, but the
+// transport calls them itself, so that a call's arguments can carry its timer.
+var startupTools = newToolRegistry()
+
+func newToolRegistry() *toolRegistry {
+	return &toolRegistry{
+		tools:    make(map[string]mcp.Tool),
+		handlers: make(map[string]server.ToolHandlerFunc),
+	}
+}
+
+// addTool registers a tool at startup, with mcp-go for tools/list and in startupTools for calls
+func addTool(s *server.MCPServer, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	s.AddTool(tool, handler)
+	startupTools.mu.Lock()
+	startupTools.tools[tool.Name] = tool
+	startupTools.handlers[tool.Name] = handler
+	startupTools.mu.Unlock()
+}
+
+// register adds or replaces a tool and tells the client its tool list changed
+func (r *toolRegistry) register(tool mcp.Tool, handler server.ToolHandlerFunc, n *Notifier) {
+	r.mu.Lock()
+	r.tools[tool.Name] = tool
+	r.handlers[tool.Name] = handler
+	r.mu.Unlock()
+
+	n.Notify("notifications/tools/list_changed", nil)
+}
+
+// unregister removes a tool, notifying the client if it was registered
+func (r *toolRegistry) unregister(name string, n *Notifier) bool {
+	r.mu.Lock()
+	_, ok := r.tools[name]
+	delete(r.tools, name)
+	delete(r.handlers, name)
+	r.mu.Unlock()
+
+	if ok {
+		n.Notify("notifications/tools/list_changed", nil)
+	}
+	return ok
+}
+
+// handler returns the handler for a runtime tool
+func (r *toolRegistry) handler(name string) (server.ToolHandlerFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[name]
+	return h, ok
+}
+
+// list returns the registered tools ordered by name
+func (r *toolRegistry) list() []mcp.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]mcp.Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mempromcp/pkg/analysis"
+)
+
+// defaultResourcePollInterval is how often the current capture is checked for changes
+// while a client is subscribed to a resource rendered from it (set by --watch-interval)
+const defaultResourcePollInterval = 2 * time.Second
+
+// captureResources are rendered from the current capture and change whenever it does, as
+// do the resources under captureResourcePrefixes
+var (
+	captureResources        = []string{"mempro://stats", "mempro://counts", "mempro://summary"}
+	captureResourcePrefixes = []string{leakResourcePrefix, functionResourcePrefix}
+)
+
+// subscriptionSet records the resource URIs the client subscribed to
+type subscriptionSet struct {
+	mu   sync.Mutex
+	uris map[string]bool
+}
+
+var resourceSubscriptions = &subscriptionSet{uris: make(map[string]bool)}
+
+func (s *subscriptionSet) subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uris[uri] = true
+}
+
+func (s *subscriptionSet) unsubscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uris, uri)
+}
+
+func (s *subscriptionSet) subscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uris[uri]
+}
+
+// notifyUpdated sends notifications/resources/updated for each subscribed URI
+func (s *subscriptionSet) notifyUpdated(n *Notifier, uris ...string) {
+	for _, uri := range uris {
+		if s.subscribed(uri) {
+			n.Notify("notifications/resources/updated", map[string]interface{}{"uri": uri})
+		}
+	}
+}
+
+// notifyCaptureUpdated sends notifications/resources/updated for each subscribed resource
+// rendered from the current capture
+func (s *subscriptionSet) notifyCaptureUpdated(n *Notifier) {
+	s.notifyUpdated(n, captureResources...)
+
+	s.mu.Lock()
+	var records []string
+	for uri := range s.uris {
+		for _, prefix := range captureResourcePrefixes {
+			if strings.HasPrefix(uri, prefix) {
+				records = append(records, uri)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(records)
+	s.notifyUpdated(n, records...)
+}
+
+// The current capture is the one most recently analyzed by a tool; resources render it.
+// capturePath holds the file or directory the tool named, so a directory keeps following
+// its newest capture.
+var (
+	captureMu   sync.Mutex
+	capturePath string
+)
+
+// currentCapture returns the file or directory of the current capture, or of the default
+// capture before any tool ran, and the capture file it resolves to
+func currentCapture() (source, path string) {
+	captureMu.Lock()
+	source = capturePath
+	captureMu.Unlock()
+
+	if source == "" {
+		source = captureSource(nil)
+	}
+	return source, analysis.LatestCapture(source)
+}
+
+// currentCapturePath returns the current capture file
+func currentCapturePath() string {
+	_, path := currentCapture()
+	return path
+}
+
+// setCurrentCapture makes source the current capture, notifying subscribers when it
+// resolves to a different file
+func setCurrentCapture(source string, n *Notifier) {
+	previous := currentCapturePath()
+
+	captureMu.Lock()
+	capturePath = source
+	captureMu.Unlock()
+
+	if currentCapturePath() != previous {
+		resourceSubscriptions.notifyCaptureUpdated(n)
+	}
+}
+
+// watchCaptureResources notifies subscribers when the current capture changes on disk,
+// e.g. because MemPro re-exported it or wrote a newer capture to the watched directory
+func watchCaptureResources(interval time.Duration, n *Notifier) {
+	var lastSource, lastPath string
+	var lastModTime time.Time
+	var lastSize int64
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		source, path := currentCapture()
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		// A change of source is announced by setCurrentCapture; only track it here
+		changed := source == lastSource &&
+			(path != lastPath || !info.ModTime().Equal(lastModTime) || info.Size() != lastSize)
+		lastSource, lastPath, lastModTime, lastSize = source, path, info.ModTime(), info.Size()
+
+		if changed {
+			resourceSubscriptions.notifyCaptureUpdated(n)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mempromcp/pkg/analysis"
+)
+
+// loadPhases are the diagnostics phases of loading a capture, as tracked by parseCapture
+var loadPhases = []string{"parse", "symbolicate", "anonymize"}
+
+// CallTiming breaks down where a tool call spent its time
+type CallTiming struct {
+	ParseMs     float64 `json:"parse_ms"`     // Exporting, decoding, symbolicating, and anonymizing the captures the call names
+	AnalyzeMs   float64 `json:"analyze_ms"`   // Everything else the tool did, including formatting its result
+	SerializeMs float64 `json:"serialize_ms"` // Encoding the JSON-RPC response
+	TotalMs     float64 `json:"total_ms"`
+	CacheHit    bool    `json:"cache_hit"` // The call reused cached data instead of loading a capture
+	Captures    int     `json:"captures"`  // Captures loaded for the call
+}
+
+// callTimer collects the analyzers loaded during one tool call
+type callTimer struct {
+	start     time.Time
+	mu        sync.Mutex // Held while recording; a call may load captures concurrently
+	analyzers []*analysis.MemoryAnalyzer
+	cacheHit  bool
+}
+
+// callTimerArg is the argument under which a timed tool call carries its timer, so that
+// calls handled concurrently each collect only the captures they load themselves. Clients
+// cannot set it, since no JSON value decodes to a *callTimer.
+const callTimerArg = "\x00timer"
+
+// startCallTiming begins timing the tool call whose arguments are args
+func startCallTiming(args map[string]interface{}) *callTimer {
+	t := &callTimer{start: time.Now()}
+	args[callTimerArg] = t
+	return t
+}
+
+// callTimerOf returns the timer of a tool call, or nil when the call is not timed
+func callTimerOf(args map[string]interface{}) *callTimer {
+	t, _ := args[callTimerArg].(*callTimer)
+	return t
+}
+
+// recordCapture attributes a loaded capture to the tool call, if it is timed
+func recordCapture(args map[string]interface{}, ma *analysis.MemoryAnalyzer) {
+	if t := callTimerOf(args); t != nil && ma != nil {
+		t.mu.Lock()
+		t.analyzers = append(t.analyzers, ma)
+		t.mu.Unlock()
+	}
+}
+
+// recordCacheHit notes that the tool call, if it is timed, reused cached data
+func recordCacheHit(args map[string]interface{}) {
+	if t := callTimerOf(args); t != nil {
+		t.mu.Lock()
+		t.cacheHit = true
+		t.mu.Unlock()
+	}
+}
+
+// finish stops timing and adds a timing block to a successful tools/call response
+func (t *callTimer) finish(response mcp.JSONRPCMessage) mcp.JSONRPCMessage {
+	handled := time.Since(t.start)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := response.(mcp.JSONRPCResponse)
+	if !ok {
+		return response
+	}
+	result, ok := r.Result.(*mcp.CallToolResult)
+	if !ok {
+		return response
+	}
+
+	timing := CallTiming{Captures: len(t.analyzers), CacheHit: t.cacheHit}
+	for _, ma := range t.analyzers {
+		timing.ParseMs += ma.Diagnostics().Duration(loadPhases...)
+	}
+	timing.AnalyzeMs = max(float64(handled.Microseconds())/1000-timing.ParseMs, 0)
+
+	start := time.Now()
+	json.Marshal(response)
+	timing.SerializeMs = float64(time.Since(start).Microseconds()) / 1000
+	timing.TotalMs = timing.ParseMs + timing.AnalyzeMs + timing.SerializeMs
+	for _, ms := range []*float64{&timing.ParseMs, &timing.AnalyzeMs, &timing.SerializeMs, &timing.TotalMs} {
+		*ms = math.Round(*ms*1000) / 1000
+	}
+
+	appendJSONContent(result, "timing", timing)
+	return response
+}
+
+// withTimingParameter adds the include_timing argument to a tool's input schema
+func withTimingParameter(tool mcp.Tool) mcp.Tool {
+	properties := make(map[string]interface{}, len(tool.InputSchema.Properties)+1)
+	for name, schema := range tool.InputSchema.Properties {
+		properties[name] = schema
+	}
+	properties["include_timing"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Append a timing block (parse, analyze, and serialize milliseconds) to the response",
+	}
+	tool.InputSchema.Properties = properties
+	return tool
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// logLevels orders MCP logging levels from least to most severe
+var logLevels = map[string]int{
+	"debug": 0, "info": 1, "notice": 2, "warning": 3,
+	"error": 4, "critical": 5, "alert": 6, "emergency": 7,
+}
+
+// Notifier sends server-initiated JSON-RPC notifications to the connected client
+type Notifier struct {
+	mu       sync.Mutex
+	w        io.Writer
+	minLevel string
+}
+
+// clientNotifier is shared by the transport and background tasks such as config reload
+var clientNotifier = &Notifier{minLevel: "info"}
+
+// attach sets the writer notifications are sent to; nil disables sending
+func (n *Notifier) attach(w io.Writer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.w = w
+}
+
+// write serializes one JSON-RPC message, guarding the writer against concurrent use
+func (n *Notifier) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.w == nil {
+		return nil
+	}
+	_, err = fmt.Fprintf(n.w, "%s\n", data)
+	return err
+}
+
+// jsonrpcNotification allows arbitrary params, which mcp.JSONRPCNotification does not
+type jsonrpcNotification struct {
+	JSONRPC string                 `json:"jsonrpc"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// Notify sends a notification with the given method and params
+func (n *Notifier) Notify(method string, params map[string]interface{}) {
+	notification := jsonrpcNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Method:  method,
+		Params:  params,
+	}
+	if err := n.write(notification); err != nil {
+		log.Printf("Failed to send %s notification: %v", method, err)
+	}
+}
+
+// Log sends a notifications/message log entry if it meets the client's requested level
+func (n *Notifier) Log(level, logger string, data interface{}) {
+	n.mu.Lock()
+	minLevel := n.minLevel
+	n.mu.Unlock()
+
+	if logLevels[level] < logLevels[minLevel] {
+		return
+	}
+
+	n.Notify("notifications/message", map[string]interface{}{
+		"level":  level,
+		"logger": logger,
+		"data":   data,
+	})
+}
+
+// setLevel records the minimum level requested via logging/setLevel
+func (n *Notifier) setLevel(level string) error {
+	if _, ok := logLevels[level]; !ok {
+		return fmt.Errorf("unknown log level: %s", level)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.minLevel = level
+	return nil
+}
+
+// serveStdio runs the MCP server over stdin/stdout, sharing stdout with the notifier
+// so that background notifications never interleave with responses.
+func serveStdio(s *server.MCPServer, n *Notifier) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	n.attach(os.Stdout)
+	defer n.attach(nil)
+
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				errs <- err
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case line := <-lines:
+			if response := handleMessage(ctx, s, n, []byte(line)); response != nil {
+				if err := n.write(response); err != nil {
+					return fmt.Errorf("failed to write response: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// handleMessage answers the protocol methods mcp-go does not implement and forwards everything else
+func handleMessage(ctx context.Context, s *server.MCPServer, n *Notifier, message json.RawMessage) mcp.JSONRPCMessage {
+	var base struct {
+		Method string          `json:"method"`
+		ID     interface{}     `json:"id,omitempty"`
+		Params json.RawMessage `json:"params,omitempty"`
+	}
+	if err := json.Unmarshal(message, &base); err != nil || base.ID == nil {
+		return s.HandleMessage(ctx, message)
+	}
+
+	switch base.Method {
+	case "logging/setLevel":
+		var params struct {
+			Level string `json:"level"`
+		}
+		if err := json.Unmarshal(base.Params, &params); err != nil {
+			return errorResponse(base.ID, mcp.INVALID_PARAMS, "Invalid logging/setLevel request")
+		}
+		if err := n.setLevel(params.Level); err != nil {
+			return errorResponse(base.ID, mcp.INVALID_PARAMS, err.Error())
+		}
+		return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: base.ID, Result: mcp.EmptyResult{}}
+	case "resources/subscribe", "resources/unsubscribe":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(base.Params, &params); err != nil || params.URI == "" {
+			return errorResponse(base.ID, mcp.INVALID_PARAMS, "Invalid "+base.Method+" request: uri is required")
+		}
+		if base.Method == "resources/subscribe" {
+			resourceSubscriptions.subscribe(params.URI)
+		} else {
+			resourceSubscriptions.unsubscribe(params.URI)
+		}
+		return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: base.ID, Result: mcp.EmptyResult{}}
+	case "tools/list":
+		response := s.HandleMessage(ctx, message)
+		if r, ok := response.(mcp.JSONRPCResponse); ok {
+			if result, ok := r.Result.(mcp.ListToolsResult); ok {
+				result.Tools = append(result.Tools, runtimeTools.list()...)
+				for i, tool := range result.Tools {
+					result.Tools[i] = withTimingParameter(tool)
+				}
+				r.Result = result
+				return r
+			}
+		}
+		return response
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		json.Unmarshal(base.Params, &params)
+
+		handler, ok := runtimeTools.handler(params.Name)
+		if !ok {
+			handler, ok = startupTools.handler(params.Name)
+		}
+		if !ok {
+			// Unknown tools are answered by mcp-go
+			return s.HandleMessage(ctx, message)
+		}
+		if params.Arguments == nil {
+			params.Arguments = make(map[string]interface{})
+		}
+
+		var timer *callTimer
+		if include, _ := params.Arguments["include_timing"].(bool); include {
+			timer = startCallTiming(params.Arguments)
+		}
+
+		start := time.Now()
+		response := callTool(base.ID, handler, params.Arguments)
+		logToolCall(params.Name, time.Since(start), isFailedToolCall(response))
+		if timer != nil {
+			response = timer.finish(response)
+		}
+		return response
+	}
+
+	return s.HandleMessage(ctx, message)
+}
+
+// callTool runs a tool's handler, answering like mcp-go does for the tools it dispatches
+func callTool(id interface{}, handler server.ToolHandlerFunc, args map[string]interface{}) mcp.JSONRPCMessage {
+	result, err := handler(args)
+	if err != nil {
+		return errorResponse(id, mcp.INTERNAL_ERROR, err.Error())
+	}
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: id, Result: result}
+}
+
+// isFailedToolCall reports whether a tools/call response is a protocol error or an error result
+func isFailedToolCall(response mcp.JSONRPCMessage) bool {
+	switch r := response.(type) {
+	case mcp.JSONRPCError:
+		return true
+	case mcp.JSONRPCResponse:
+		if result, ok := r.Result.(*mcp.CallToolResult); ok {
+			return result.IsError
+		}
+	}
+	return false
+}
+
+func errorResponse(id interface{}, code int, message string) mcp.JSONRPCMessage {
+	response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION, ID: id}
+	response.Error.Code = code
+	response.Error.Message = message
+	return response
+}
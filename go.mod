@@ -4,6 +4,9 @@ go 1.23.2
 
 toolchain go1.24.3
 
-require github.com/mark3labs/mcp-go v0.7.0
+require (
+	github.com/expr-lang/expr v1.17.8
+	github.com/mark3labs/mcp-go v0.7.0
+)
 
 require github.com/google/uuid v1.6.0 // indirect
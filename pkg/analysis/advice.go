@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// SuggestionRule maps leaks matching all of its patterns to a suggestion. Patterns are Go
+// regular expressions; an empty pattern matches anything.
+type SuggestionRule struct {
+	Name         string `json:"name"`
+	Function     string `json:"function"`       // Matched against the blamed or allocating function
+	File         string `json:"file"`           // Matched against the source file
+	CallStack    string `json:"call_stack"`     // Matched against the whole call stack text
+	NotCallStack string `json:"not_call_stack"` // Leaks whose call stack matches are skipped
+	Suggestion   string `json:"suggestion"`
+}
+
+type compiledSuggestionRule struct {
+	SuggestionRule
+	function, file, callStack, notCallStack *regexp.Regexp
+}
+
+// defaultSuggestionRules are the built-in leak suggestions, checked after the configured ones.
+// The last rule matches every leak.
+var defaultSuggestionRules = []SuggestionRule{
+	{
+		Name:       "unknown-function",
+		Function:   `Unknown Function`,
+		Suggestion: "Enable debug symbols and rebuild with full symbol information to identify the exact source of this leak. Check for third-party libraries or dynamically loaded modules, and configure symbolication if the call stack records module offsets.",
+	},
+	{
+		Name:       "thread-stack",
+		CallStack:  `\b(?:CreateThread|CreateRemoteThread(?:Ex)?|_beginthread(?:ex)?|CreateFiber(?:Ex)?|ConvertThreadToFiber(?:Ex)?|pthread_create|RtlCreateUserThread)\b|std::j?thread::(?:thread|jthread|_Start)\b`,
+		Suggestion: "Allocated while creating a thread or fiber: its stack and per-thread state are released only when the thread exits and is joined or closed, or the fiber is deleted. Join or detach every std::thread, CloseHandle every thread handle, pthread_join or pthread_detach every pthread, and DeleteFiber every fiber created. If threads are meant to stay alive, check whether a pool keeps spawning new ones, and reduce their stack size if many exist.",
+	},
+	{
+		Name:       "win32-handle",
+		CallStack:  `\b(?:CreateFile(?:Mapping)?[AW]?|Create(?:Event|Mutex|Semaphore|WaitableTimer)(?:Ex)?[AW]?|CreateNamedPipe[AW]?|OpenProcess|OpenThread|CreateToolhelp32Snapshot|FindFirstFile(?:Ex)?[AW]?|Reg(?:Open|Create)KeyEx[AW]?|MapViewOfFile(?:Ex)?)\b`,
+		Suggestion: "Allocated inside a handle-creating API: the memory belongs to the kernel or registry object and is freed only when the handle is closed. Close every handle on all paths with the matching function (CloseHandle, FindClose for FindFirstFile, RegCloseKey for registry keys, UnmapViewOfFile for mapped views), ideally through an RAII wrapper such as wil::unique_handle.",
+	},
+	{
+		Name:       "com-task-memory",
+		CallStack:  `\b(?:CoTaskMemAlloc|CoTaskMemRealloc|SysAllocString(?:Len|ByteLen)?|SysReAllocString(?:Len)?|StringFromCLSID|StringFromIID|SHGetKnownFolderPath)\b`,
+		Suggestion: "COM task memory: whoever receives it per the interface contract (usually the caller, for out parameters) must free it with CoTaskMemFree, and BSTRs with SysFreeString. Check every out parameter on success and error paths, and hold them in wil::unique_cotaskmem, CComHeapPtr, CComBSTR, or _bstr_t.",
+	},
+	{
+		Name:       "array-new",
+		CallStack:  `operator new\[\]`,
+		Suggestion: "Allocated with new[]: it must be released with delete[], not delete (a mismatch is undefined behavior and skips element destructors, which can leak what the elements own). Prefer std::vector or std::unique_ptr<T[]>, which always pair them correctly.",
+	},
+	{
+		Name:         "c-allocation",
+		CallStack:    `\b(?:malloc|calloc|realloc|_?strn?dup|_?wcsn?dup|_?mbsdup)\b`,
+		NotCallStack: `operator new`,
+		Suggestion:   "Allocated with a C allocator (malloc, calloc, realloc, or strdup, which returns malloc'd memory): it must be released with free, never delete, on every path including early returns and errors. Wrap it in std::unique_ptr<T, decltype(&free)> or a scope guard, or replace the buffer or string with std::vector or std::string.",
+	},
+	{
+		Name:       "stl-container",
+		Function:   `std::_Allocate|std::vector`,
+		Suggestion: "STL container leak detected. Ensure proper cleanup in destructors, check for circular references, and verify that containers are properly cleared before going out of scope.",
+	},
+	{
+		Name:       "main",
+		Function:   `main`,
+		Suggestion: "Leak originated from main function. Review allocation ownership and ensure all allocated resources are freed before program exit. Consider using RAII or smart pointers.",
+	},
+	{
+		Name:       "default",
+		Suggestion: "Review allocation patterns in this function and ensure all allocated memory is properly deallocated. Consider using smart pointers (std::unique_ptr, std::shared_ptr) or RAII patterns.",
+	},
+}
+
+// compileSuggestionRules compiles rules in order; index is only used to name unnamed rules
+func compileSuggestionRules(rules []SuggestionRule) ([]*compiledSuggestionRule, error) {
+	compiled := make([]*compiledSuggestionRule, 0, len(rules))
+	for i, rule := range rules {
+		c, err := compileSuggestionRule(i, rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// compileSuggestionRule validates a single rule and compiles its patterns
+func compileSuggestionRule(index int, rule SuggestionRule) (*compiledSuggestionRule, error) {
+	name := rule.Name
+	if name == "" {
+		name = fmt.Sprintf("#%d", index+1)
+	}
+	if rule.Suggestion == "" {
+		return nil, fmt.Errorf("suggestion rule %s: missing 'suggestion'", name)
+	}
+
+	c := &compiledSuggestionRule{SuggestionRule: rule}
+	for _, p := range []struct {
+		field   string
+		pattern string
+		re      **regexp.Regexp
+	}{
+		{"function", rule.Function, &c.function},
+		{"file", rule.File, &c.file},
+		{"call_stack", rule.CallStack, &c.callStack},
+		{"not_call_stack", rule.NotCallStack, &c.notCallStack},
+	} {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("suggestion rule %s: invalid %s pattern: %w", name, p.field, err)
+		}
+		*p.re = re
+	}
+	return c, nil
+}
+
+// matches reports whether the leak, blamed on function, matches every pattern of the rule
+func (r *compiledSuggestionRule) matches(leak Leak, function string) bool {
+	switch {
+	case r.function != nil && !r.function.MatchString(function) && !r.function.MatchString(leak.FunctionName):
+		return false
+	case r.file != nil && !r.file.MatchString(leak.FileName):
+		return false
+	case r.callStack != nil && !r.callStack.MatchString(leak.CallStack):
+		return false
+	case r.notCallStack != nil && r.notCallStack.MatchString(leak.CallStack):
+		return false
+	}
+	return true
+}
+
+// loadSuggestionRulesFile reads a standalone JSON array of suggestion rules
+func loadSuggestionRulesFile(path string) ([]SuggestionRule, error) {
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suggestion rules file: %w", err)
+	}
+
+	var rules []SuggestionRule
+	if err := json.Unmarshal(fileData, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse suggestion rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,109 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// defaultAllocatorCount is how many functions get_top_allocators returns
+const defaultAllocatorCount = 10
+
+// AllocatorStat is one function's allocation footprint
+type AllocatorStat struct {
+	Function        string  `json:"function"`
+	File            string  `json:"file,omitempty"`
+	Line            int     `json:"line,omitempty"`
+	AllocationCount int     `json:"allocation_count"`
+	TotalSize       int64   `json:"total_size"`
+	AverageSize     float64 `json:"average_size"`
+	MinSize         int64   `json:"min_size"`
+	MaxSize         int64   `json:"max_size"`
+	SizeShare       float64 `json:"size_share"`  // Percent of the bytes allocated by all functions
+	CountShare      float64 `json:"count_share"` // Percent of the allocations made by all functions
+	Tag             string  `json:"tag,omitempty"`
+}
+
+// AllocatorReport ranks the capture's allocating functions
+type AllocatorReport struct {
+	Session          string          `json:"session"`
+	SortBy           string          `json:"sort_by"`
+	FunctionCount    int             `json:"function_count"`
+	TotalSize        int64           `json:"total_size"`        // Bytes allocated by all functions
+	TotalAllocations int             `json:"total_allocations"` // Allocations made by all functions
+	Functions        []AllocatorStat `json:"functions"`
+	Omitted          int             `json:"omitted,omitempty"`
+}
+
+// TopAllocators ranks functions by sortBy ("size", "count", "average", or "max") and keeps
+// the top n. Sorting by count surfaces churn-heavy call sites that allocate little at a time.
+func (ma *MemoryAnalyzer) TopAllocators(sortBy string, n int) (*AllocatorReport, error) {
+	if len(ma.data.Functions) == 0 {
+		return nil, fmt.Errorf("capture has no function statistics")
+	}
+	if sortBy == "" {
+		sortBy = "size"
+	}
+	if sortBy != "size" && sortBy != "count" && sortBy != "average" && sortBy != "max" {
+		return nil, fmt.Errorf("unknown sort %q (expected size, count, average, or max)", sortBy)
+	}
+	if n <= 0 {
+		n = defaultAllocatorCount
+	}
+
+	report := &AllocatorReport{Session: ma.data.SessionName, SortBy: sortBy, FunctionCount: len(ma.data.Functions)}
+	for _, fn := range ma.data.Functions {
+		report.TotalSize += fn.TotalSize
+		report.TotalAllocations += fn.AllocationCount
+	}
+
+	stats := make([]AllocatorStat, 0, len(ma.data.Functions))
+	for _, fn := range ma.data.Functions {
+		s := AllocatorStat{
+			Function:        fn.FunctionName,
+			File:            fn.FileName,
+			Line:            fn.LineNumber,
+			AllocationCount: fn.AllocationCount,
+			TotalSize:       fn.TotalSize,
+			AverageSize:     fn.AverageSize,
+			MinSize:         fn.MinSize,
+			MaxSize:         fn.MaxSize,
+			Tag:             fn.Tag,
+		}
+		if s.AverageSize == 0 && s.AllocationCount > 0 {
+			s.AverageSize = float64(s.TotalSize) / float64(s.AllocationCount)
+		}
+		if report.TotalSize > 0 {
+			s.SizeShare = math.Round(float64(s.TotalSize)/float64(report.TotalSize)*10000) / 100
+		}
+		if report.TotalAllocations > 0 {
+			s.CountShare = math.Round(float64(s.AllocationCount)/float64(report.TotalAllocations)*10000) / 100
+		}
+		stats = append(stats, s)
+	}
+
+	if sortBy == "size" {
+		// The capture's index already ranks functions by size
+		ranked := make([]AllocatorStat, 0, len(stats))
+		for _, i := range ma.index().functionsBySize {
+			ranked = append(ranked, stats[i])
+		}
+		stats = ranked
+	} else {
+		sort.SliceStable(stats, func(i, j int) bool {
+			switch sortBy {
+			case "count":
+				return stats[i].AllocationCount > stats[j].AllocationCount
+			case "average":
+				return stats[i].AverageSize > stats[j].AverageSize
+			}
+			return stats[i].MaxSize > stats[j].MaxSize
+		})
+	}
+	if len(stats) > n {
+		report.Omitted = len(stats) - n
+		stats = stats[:n]
+	}
+	report.Functions = stats
+	return report, nil
+}
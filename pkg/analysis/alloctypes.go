@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Type analysis defaults
+const (
+	defaultTypeCount  = 20
+	dominantTypeShare = 10.0 // Percent of the bytes allocated by all types
+)
+
+// TypeStat is one allocation type's footprint, with its growth against a baseline capture
+type TypeStat struct {
+	Type            string      `json:"type"`
+	AllocationCount int         `json:"allocation_count"`
+	TotalSize       int64       `json:"total_size"`
+	AverageSize     float64     `json:"average_size"`
+	MinSize         int64       `json:"min_size,omitempty"`
+	MaxSize         int64       `json:"max_size,omitempty"`
+	Share           float64     `json:"share"` // Percent of the bytes allocated by all types
+	Function        string      `json:"function,omitempty"`
+	File            string      `json:"file,omitempty"`
+	Line            int         `json:"line,omitempty"`
+	Tag             string      `json:"tag,omitempty"`
+	Growth          *TypeGrowth `json:"growth,omitempty"`
+	Flags           []string    `json:"flags,omitempty"` // dominant, new, growing
+}
+
+// TypeGrowth is a type's change since the baseline capture
+type TypeGrowth struct {
+	BeforeSize  int64    `json:"before_size"`
+	BeforeCount int      `json:"before_count"`
+	SizeDelta   int64    `json:"size_delta"`
+	SizePercent *float64 `json:"size_percent,omitempty"` // Omitted for new types
+	CountDelta  int      `json:"count_delta"`
+}
+
+// TypeReport ranks the capture's allocation types
+type TypeReport struct {
+	Session   string     `json:"session"`
+	SortBy    string     `json:"sort_by"`
+	TotalSize int64      `json:"total_size"` // Bytes allocated by all types
+	TypeCount int        `json:"type_count"`
+	Baseline  string     `json:"baseline,omitempty"`
+	Tolerance string     `json:"tolerance,omitempty"`
+	Types     []TypeStat `json:"types"`
+	Omitted   int        `json:"omitted,omitempty"`
+	Growing   []string   `json:"growing,omitempty"` // Every type flagged growing, including omitted ones
+}
+
+// AnalyzeTypes ranks allocation types by sortBy ("size", "count", or "average") and keeps
+// the top n. With a baseline, types whose size or count grew beyond tol are flagged growing.
+func (ma *MemoryAnalyzer) AnalyzeTypes(sortBy string, n int, baseline *MemoryAnalyzer, tol *ToleranceProfile) (*TypeReport, error) {
+	if len(ma.data.Types) == 0 {
+		return nil, fmt.Errorf("capture has no type statistics")
+	}
+	if sortBy == "" {
+		sortBy = "size"
+	}
+	if sortBy != "size" && sortBy != "count" && sortBy != "average" {
+		return nil, fmt.Errorf("unknown sort %q (expected size, count, or average)", sortBy)
+	}
+	if n <= 0 {
+		n = defaultTypeCount
+	}
+
+	report := &TypeReport{Session: ma.data.SessionName, SortBy: sortBy, TypeCount: len(ma.data.Types)}
+	for _, t := range ma.data.Types {
+		report.TotalSize += t.TotalSize
+	}
+
+	if baseline != nil {
+		report.Baseline = baseline.data.SessionName
+		report.Tolerance = tol.Name
+	}
+
+	stats := make([]TypeStat, 0, len(ma.data.Types))
+	for _, t := range ma.data.Types {
+		s := TypeStat{
+			Type:            t.TypeName,
+			AllocationCount: t.AllocationCount,
+			TotalSize:       t.TotalSize,
+			AverageSize:     t.AverageSize,
+			MinSize:         t.MinSize,
+			MaxSize:         t.MaxSize,
+			Function:        t.MostCommonFunction,
+			File:            t.MostCommonFile,
+			Line:            t.MostCommonLine,
+			Tag:             t.Tag,
+		}
+		if s.AverageSize == 0 && s.AllocationCount > 0 {
+			s.AverageSize = float64(s.TotalSize) / float64(s.AllocationCount)
+		}
+		if report.TotalSize > 0 {
+			s.Share = math.Round(float64(s.TotalSize)/float64(report.TotalSize)*10000) / 100
+		}
+		if s.Share >= dominantTypeShare {
+			s.Flags = append(s.Flags, "dominant")
+		}
+
+		if baseline != nil {
+			b, found := baseline.typeRecord(t.TypeName)
+			s.Growth = &TypeGrowth{
+				BeforeSize:  b.TotalSize,
+				BeforeCount: b.AllocationCount,
+				SizeDelta:   t.TotalSize - b.TotalSize,
+				SizePercent: percentChange(b.TotalSize, t.TotalSize),
+				CountDelta:  t.AllocationCount - b.AllocationCount,
+			}
+			if !found {
+				s.Flags = append(s.Flags, "new")
+			} else if tol.significant("total_size", b.TotalSize, t.TotalSize) > 0 ||
+				tol.significant("allocation_count", int64(b.AllocationCount), int64(t.AllocationCount)) > 0 {
+				s.Flags = append(s.Flags, "growing")
+				report.Growing = append(report.Growing, t.TypeName)
+			}
+		}
+		stats = append(stats, s)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		switch sortBy {
+		case "count":
+			return stats[i].AllocationCount > stats[j].AllocationCount
+		case "average":
+			return stats[i].AverageSize > stats[j].AverageSize
+		}
+		return stats[i].TotalSize > stats[j].TotalSize
+	})
+	if len(stats) > n {
+		report.Omitted = len(stats) - n
+		stats = stats[:n]
+	}
+	report.Types = stats
+	return report, nil
+}
@@ -0,0 +1,725 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mempromcp/pkg/mempro"
+)
+
+// MemoryAnalyzer analyzes MemPro data and detects memory issues
+type MemoryAnalyzer struct {
+	data   *MemProData
+	config *Config
+	diag   *Diagnostics
+	cached bool // Data came from parsedCaptures
+
+	symbolsOnce sync.Once
+	symbolIndex *SymbolIndex // Built by Symbols() on first search
+
+	lookups *captureIndex // Shared with the cached capture; built by index() on first use
+}
+
+// parsedCapture is a decoded capture, the sections it was decoded with, and its lookups
+type parsedCapture struct {
+	data     *MemProData
+	sections CaptureSections
+	index    *captureIndex
+}
+
+// parsedCaptures keeps recently parsed captures, so calls on an unchanged file skip
+// reading and decoding it
+var parsedCaptures = NewCaptureCache[parsedCapture](DefaultCaptureCache)
+
+// SetCaptureCacheSize sets how many parsed captures are kept between analyzers (the
+// server's --capture-cache); zero disables the cache
+func SetCaptureCacheSize(n int) {
+	parsedCaptures.SetLimit(n)
+}
+
+// Data returns the capture records the analyzer works on
+func (ma *MemoryAnalyzer) Data() *MemProData {
+	return ma.data
+}
+
+// Config returns the configuration the analyzer was created with
+func (ma *MemoryAnalyzer) Config() *Config {
+	return ma.config
+}
+
+// Diagnostics returns the timings, record counts, and notes collected while loading and
+// analyzing the capture
+func (ma *MemoryAnalyzer) Diagnostics() *Diagnostics {
+	return ma.diag
+}
+
+// Cached reports whether the capture was reused from an earlier analyzer instead of parsed
+func (ma *MemoryAnalyzer) Cached() bool {
+	return ma.cached
+}
+
+// NewMemoryAnalyzer creates a new analyzer from a JSON file, reusing the parsed data of an
+// unchanged file loaded with the same configuration
+func NewMemoryAnalyzer(jsonPath string) (*MemoryAnalyzer, error) {
+	return NewMemoryAnalyzerSections(jsonPath, AllSections)
+}
+
+// NewMemoryAnalyzerSections creates an analyzer that decodes only the given record arrays,
+// for tools that need a few sections of a large capture. The others are left empty.
+// jsonPath may also name a workspace session or a directory of captures.
+func NewMemoryAnalyzerSections(jsonPath string, sections CaptureSections) (*MemoryAnalyzer, error) {
+	diag := newDiagnostics()
+	config := CurrentConfig()
+	jsonPath = LatestCapture(SessionPath(jsonPath))
+
+	// The key is taken before reading, so a file rewritten meanwhile is not cached as new
+	key, cacheable := captureKeyOf(jsonPath)
+	parsed, cached := parsedCaptures.lookup(key)
+	cached = cached && parsed.sections&sections == sections
+	if cached {
+		diag.note("reused the parsed capture from the cache")
+	} else {
+		data, err := parseCapture(jsonPath, sections, config, diag)
+		if err != nil {
+			return nil, err
+		}
+		parsed = parsedCapture{data: data, sections: sections, index: &captureIndex{}}
+		if cacheable {
+			parsedCaptures.store(key, parsed)
+		}
+	}
+	data := parsed.data
+
+	diag.count("Leaks", len(data.Leaks))
+	diag.count("Functions", len(data.Functions))
+	diag.count("CallTrees", len(data.CallTrees))
+	diag.count("PageViews", len(data.PageViews))
+	diag.count("Types", len(data.Types))
+	if len(data.Allocations) > 0 {
+		diag.count("Allocations", len(data.Allocations))
+	}
+	if len(data.Leaks) == 0 && data.LeakCount > 0 {
+		diag.note("header reports %d leaks but the Leaks array is empty; the export may be partial", data.LeakCount)
+	}
+
+	return &MemoryAnalyzer{data: data, config: config, diag: diag, cached: cached, lookups: parsed.index}, nil
+}
+
+// parseCapture streams a capture's sections from disk, applying the configured
+// symbolication, redaction, and symbol anonymization
+func parseCapture(jsonPath string, sections CaptureSections, config *Config, diag *Diagnostics) (*MemProData, error) {
+	red := config.Redactor()
+
+	file, err := os.Open(jsonPath)
+	if err != nil {
+		return nil, red.Error(fmt.Errorf("failed to read JSON file: %w", err))
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil {
+		diag.count("bytes", int(info.Size()))
+	}
+
+	r, isHeaptrack, err := mempro.Open(file)
+	if err != nil {
+		return nil, red.Error(fmt.Errorf("failed to read capture: %w", err))
+	}
+
+	done := diag.track("parse")
+	var data *MemProData
+	var skipped []string
+	if isHeaptrack {
+		data, err = mempro.DecodeHeaptrack(r, filepath.Base(jsonPath))
+		diag.note("converted from heaptrack data")
+	} else {
+		data, skipped, err = mempro.Decode(r, sections)
+	}
+	done()
+	if err != nil {
+		if isHeaptrack {
+			return nil, red.Error(fmt.Errorf("failed to parse heaptrack data: %w", err))
+		}
+		var formatErr *mempro.FormatError
+		if errors.As(err, &formatErr) {
+			return nil, red.Error(fmt.Errorf("failed to parse JSON: %w (validate_data lists every problem)", err))
+		}
+		return nil, red.Error(fmt.Errorf("failed to parse JSON: %w", err))
+	}
+	if len(skipped) > 0 {
+		diag.note("sections not needed by this call were skipped: %s", strings.Join(skipped, ", "))
+	}
+	if !isHeaptrack && data.Schema != mempro.CurrentSchema {
+		if data.Schema > mempro.LatestSchema {
+			diag.note("export schema version %d is newer than this server knows (%d); section layouts were detected", data.Schema, mempro.LatestSchema)
+		} else {
+			diag.note("converted from export schema version %d", data.Schema)
+		}
+	}
+	if config.Symbolication != nil {
+		done = diag.track("symbolicate")
+		err = symbolicateData(data, config.Symbolication, diag)
+		done()
+		if err != nil {
+			return nil, red.Error(fmt.Errorf("failed to symbolicate capture: %w", err))
+		}
+	}
+	red.data(data)
+
+	if mapPath := config.SymbolMapPath(); mapPath != "" {
+		done = diag.track("anonymize")
+		symbols, err := LoadSymbolMap(mapPath)
+		if err == nil {
+			err = anonymizeData(data, symbols)
+		}
+		done()
+		if err != nil {
+			return nil, red.Error(fmt.Errorf("failed to anonymize symbols: %w", err))
+		}
+	}
+	return data, nil
+}
+
+// LatestCapture resolves a directory to its most recently modified .json file, so the
+// capture path can name the directory MemPro exports to; other paths are returned as is
+func LatestCapture(path string) string {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return path
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return path
+	}
+
+	latest := path
+	var latestTime time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		// Later names win ties, which suits timestamped export names
+		if latest == path || !info.ModTime().Before(latestTime) {
+			latest, latestTime = filepath.Join(path, entry.Name()), info.ModTime()
+		}
+	}
+	return latest
+}
+
+// AnalyzeLeaks detects and prioritizes memory leaks
+func (ma *MemoryAnalyzer) AnalyzeLeaks() []MemoryIssue {
+	var issues []MemoryIssue
+	var records []ruleRecord
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_leaks")()
+
+	groups, skipped := ma.groupLeaks()
+	ma.diag.skip("leaks_with_zero_size_and_count", skipped)
+
+	// Groups are classified independently, so they are sharded across the workers
+	type analyzedLeak struct {
+		issue  MemoryIssue
+		record ruleRecord
+	}
+	analyzed := shard(len(groups), ma.workers(), func(start, end int) []analyzedLeak {
+		var found []analyzedLeak
+		suppressed := 0
+		for _, group := range groups[start:end] {
+			leak := group.Leak
+			severity := ma.calculateLeakSeverity(leak)
+			suggestion := ma.generateLeakSuggestion(leak)
+
+			description := ma.formatLeakDescription(leak)
+			function := ma.blame(leak)
+			if function != leak.FunctionName {
+				description += fmt.Sprintf(" (allocated via %s)", leak.FunctionName)
+			}
+			if group.Occurrences > 1 {
+				description += fmt.Sprintf(" (%d leak records with the same call stack)", group.Occurrences)
+			}
+
+			issue := MemoryIssue{
+				ID:           group.ID,
+				Severity:     severity,
+				Type:         "MemoryLeak",
+				Description:  description,
+				FunctionName: function,
+				FileName:     leak.FileName,
+				LineNumber:   leak.LineNumber,
+				Size:         leak.LeakSize,
+				Count:        leak.LeakCount,
+				Score:        leak.LeakScore,
+				Suggestion:   suggestion,
+				Thread:       ma.threadLabel(leak.ThreadId),
+				Heap:         ma.heapLabel(leak.HeapId),
+				Tag:          leak.Tag,
+				Occurrences:  group.Occurrences,
+			}
+			if ma.suppressed(suppressionSubject{issue: issue, allocator: leak.FunctionName, callStack: leak.CallStack}) != nil {
+				suppressed++
+				continue
+			}
+			record := newRuleRecord(issue)
+			record.CallStack = leak.CallStack
+			record.IsSuspect = leak.IsSuspect
+
+			found = append(found, analyzedLeak{issue: issue, record: record})
+		}
+		ma.diag.skip("suppressed_leaks", suppressed)
+		return found
+	})
+	for _, a := range analyzed {
+		issues = append(issues, a.issue)
+		records = append(records, a.record)
+	}
+
+	issues = ma.applyCustomRules(issues, records)
+	SortIssues(issues)
+
+	return issues
+}
+
+// SortIssues orders issues by severity, then by size descending
+func SortIssues(issues []MemoryIssue) {
+	sort.Slice(issues, func(i, j int) bool {
+		return IssueLess(issues[i], issues[j])
+	})
+}
+
+var severityOrder = map[string]int{"Critical": 0, "High": 1, "Medium": 2, "Low": 3}
+
+// severityRank returns the sort rank of a severity; unknown levels sort as lowest priority
+func severityRank(severity string) int {
+	if rank, ok := severityOrder[severity]; ok {
+		return rank
+	}
+	return 999
+}
+
+// IssueLess reports whether issue a has higher priority than b
+func IssueLess(a, b MemoryIssue) bool {
+	rankA, rankB := severityRank(a.Severity), severityRank(b.Severity)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+	return a.Size > b.Size
+}
+
+// AnalyzeFragmentation detects memory fragmentation issues
+func (ma *MemoryAnalyzer) AnalyzeFragmentation() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_fragmentation")()
+
+	fragmentation, source := ma.data.MemoryFragmentation, ""
+	if score, ok := ma.usageWeightedFragmentation(); ok {
+		fragmentation = score
+		source = fmt.Sprintf(" (usage-weighted across committed pages; the export reports %.2f%%)", ma.data.MemoryFragmentation)
+	}
+
+	thresholds := ma.config.EffectiveThresholds()
+	if fragmentation > thresholds.FragmentationHigh {
+		issues = append(issues, MemoryIssue{
+			ID:          issueID("frag"),
+			Severity:    "High",
+			Type:        "MemoryFragmentation",
+			Description: fmt.Sprintf("Memory fragmentation is at %.2f%%, which indicates severe fragmentation%s", fragmentation, source),
+			Size:        ma.data.TotalSize,
+			Count:       ma.data.TotalAllocations,
+			Score:       fragmentation,
+			Suggestion:  "Consider implementing object pooling or using memory arenas to reduce fragmentation. Review allocation patterns and consolidate small allocations where possible.",
+		})
+	} else if fragmentation > thresholds.FragmentationMedium {
+		issues = append(issues, MemoryIssue{
+			ID:          issueID("frag"),
+			Severity:    "Medium",
+			Type:        "MemoryFragmentation",
+			Description: fmt.Sprintf("Memory fragmentation is at %.2f%%, which may impact performance%s", fragmentation, source),
+			Size:        ma.data.TotalSize,
+			Count:       ma.data.TotalAllocations,
+			Score:       fragmentation,
+			Suggestion:  "Monitor fragmentation levels and consider optimizing allocation patterns if fragmentation increases.",
+		})
+	} else {
+		ma.diag.note("fragmentation %.2f%% is below the reporting threshold", fragmentation)
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// AnalyzeLargeAllocations finds unusually large allocations
+func (ma *MemoryAnalyzer) AnalyzeLargeAllocations() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_large_allocations")()
+
+	thresholds := ma.config.EffectiveThresholds()
+	issues = shard(len(ma.data.Functions), ma.workers(), func(start, end int) []MemoryIssue {
+		var found []MemoryIssue
+		skipped := 0
+		for _, fn := range ma.data.Functions[start:end] {
+			if !(fn.AverageSize > thresholds.LargeAverageBytes || fn.MaxSize > thresholds.LargeMaxBytes) {
+				skipped++
+				continue
+			}
+
+			severity := "Medium"
+			if fn.MaxSize > thresholds.LargeHighBytes {
+				severity = "High"
+			}
+
+			issue := MemoryIssue{
+				ID:           issueID("large", fn.FunctionName, fn.FileName, strconv.Itoa(fn.LineNumber)),
+				Severity:     severity,
+				Type:         "LargeAllocation",
+				Description:  fmt.Sprintf("Large allocation detected: average %.0f bytes, max %d bytes across %d allocations", fn.AverageSize, fn.MaxSize, fn.AllocationCount),
+				FunctionName: fn.FunctionName,
+				FileName:     fn.FileName,
+				LineNumber:   fn.LineNumber,
+				Size:         fn.TotalSize,
+				Count:        fn.AllocationCount,
+				Score:        float64(fn.MaxSize),
+				Suggestion:   "Review if large allocations can be split into smaller chunks or allocated incrementally. Consider using streaming or chunked processing for large data.",
+			}
+			if ma.suppressed(suppressionSubject{issue: issue}) != nil {
+				ma.diag.skip("suppressed_large_allocations", 1)
+				continue
+			}
+			found = append(found, issue)
+		}
+		ma.diag.skip("functions_below_large_allocation_threshold", skipped)
+		return found
+	})
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// AnalyzeAll runs every issue detector, returning one issue list per detector
+func (ma *MemoryAnalyzer) AnalyzeAll() [][]MemoryIssue {
+	return [][]MemoryIssue{
+		ma.AnalyzeLeaks(),
+		ma.AnalyzeFragmentation(),
+		ma.AnalyzeLargeAllocations(),
+		ma.AnalyzeDuplicateAllocations(),
+		ma.AnalyzePoolCandidates(),
+		ma.AnalyzeAlignmentWaste(),
+		ma.AnalyzeTinyAllocations(),
+		ma.AnalyzeLifetimes(),
+		ma.AnalyzeHeaps(),
+		ma.AnalyzeTags(),
+		ma.AnalyzeAddressSpace(),
+	}
+}
+
+// AllIssues flattens AnalyzeAll
+func (ma *MemoryAnalyzer) AllIssues() []MemoryIssue {
+	var issues []MemoryIssue
+	for _, group := range ma.AnalyzeAll() {
+		issues = append(issues, group...)
+	}
+	return issues
+}
+
+// GetSummary provides an overall summary of memory usage
+func (ma *MemoryAnalyzer) GetSummary() string {
+	if ma == nil || ma.data == nil {
+		return "Error: No data available for analysis"
+	}
+
+	weighted := ""
+	if score, ok := ma.usageWeightedFragmentation(); ok {
+		weighted = fmt.Sprintf(" (usage-weighted: %.2f%%)", score)
+	}
+
+	summary := fmt.Sprintf(`Memory Analysis Summary
+======================
+Session: %s
+Total Allocations: %d
+Total Size: %d bytes (%.2f MB)
+Leak Count: %d
+Leak Size: %d bytes (%.2f MB)
+Leak Percentage: %.2f%%
+Memory Fragmentation: %.2f%%%s
+
+Critical Findings:
+`, ma.data.SessionName, ma.data.TotalAllocations, ma.data.TotalSize,
+		float64(ma.data.TotalSize)/1024/1024,
+		ma.data.LeakCount, ma.data.LeakSize,
+		float64(ma.data.LeakSize)/1024/1024,
+		ma.LeakPercentage(), ma.data.MemoryFragmentation, weighted)
+
+	for _, finding := range ma.criticalFindings() {
+		summary += "- " + finding + "\n"
+	}
+
+	return summary
+}
+
+// LeakPercentage returns the share of allocated memory that is leaked
+func (ma *MemoryAnalyzer) LeakPercentage() float64 {
+	if ma.data.TotalSize == 0 {
+		return 0
+	}
+	return float64(ma.data.LeakSize) / float64(ma.data.TotalSize) * 100
+}
+
+// criticalFindings lists the headline problems shown in summaries
+func (ma *MemoryAnalyzer) criticalFindings() []string {
+	var findings []string
+	if ma.LeakPercentage() > 50 {
+		findings = append(findings, "CRITICAL: Over 50% of allocated memory is leaked!")
+	}
+	if ma.Fragmentation() > ma.config.EffectiveThresholds().FragmentationHigh {
+		findings = append(findings, "HIGH: Severe memory fragmentation detected")
+	}
+
+	suspectLeaks := 0
+	for _, leak := range ma.data.Leaks {
+		if leak.IsSuspect {
+			suspectLeaks++
+		}
+	}
+	if suspectLeaks > 0 {
+		findings = append(findings, fmt.Sprintf("%d suspect leak locations identified", suspectLeaks))
+	}
+	return findings
+}
+
+// GetTopLeakers returns the top N functions by leak size. When stackFrames is positive,
+// call stacks are summarized to that many application frames.
+func (ma *MemoryAnalyzer) GetTopLeakers(n int, stackFrames int) string {
+	if ma == nil || ma.data == nil {
+		return "Error: No data available for analysis"
+	}
+
+	bySize := ma.index().leaksBySize
+	if n > len(bySize) {
+		n = len(bySize)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Top %d Memory Leakers:\n", n))
+	result.WriteString("====================\n\n")
+
+	for i := 0; i < n; i++ {
+		leak := ma.data.Leaks[bySize[i]]
+		function := ma.blame(leak)
+		result.WriteString(fmt.Sprintf("%d. %s\n", i+1, function))
+		if function != leak.FunctionName {
+			result.WriteString(fmt.Sprintf("   Allocated Via: %s\n", leak.FunctionName))
+		}
+		result.WriteString(fmt.Sprintf("   Leak Size: %d bytes (%.2f KB)\n", leak.LeakSize, float64(leak.LeakSize)/1024))
+		result.WriteString(fmt.Sprintf("   Leak Count: %d allocations\n", leak.LeakCount))
+		result.WriteString(fmt.Sprintf("   Leak Score: %.2f\n", leak.LeakScore))
+		result.WriteString(fmt.Sprintf("   Suspect: %v\n", leak.IsSuspect))
+		if leak.FileName != "" {
+			result.WriteString(fmt.Sprintf("   Location: %s:%d\n", leak.FileName, leak.LineNumber))
+		}
+		if leak.CallStack != "" {
+			stack := leak.CallStack
+			if stackFrames > 0 {
+				stack = ma.config.plumbing.summarizeStack(stack, stackFrames)
+			}
+			result.WriteString(fmt.Sprintf("   CallStack: %s\n", stack))
+		}
+		result.WriteString("\n")
+	}
+
+	return result.String()
+}
+
+// Helper functions
+
+// FormatBytes renders a byte count with a human-readable unit
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.2f GB", float64(n)/1024/1024/1024)
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(n)/1024/1024)
+	case n >= 1024:
+		return fmt.Sprintf("%.2f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// issueID derives a stable identifier from the fields that identify an issue's source record
+func issueID(kind string, parts ...string) string {
+	return kind + "-" + shortHash(strings.Join(parts, "\x00"))
+}
+
+// leakID identifies a leak by its allocation site and stack signature, or its stack ID when
+// the stack text is unknown. Callers resolve CallStack through StackId first, so records
+// referring to a stack by ID get the same ID as records carrying its text.
+func leakID(leak Leak) string {
+	stack := stackSignature(leak.CallStack)
+	if stack == "" && leak.StackId != 0 {
+		stack = "#" + strconv.Itoa(leak.StackId)
+	}
+	return issueID("leak", leak.FunctionName, leak.FileName, strconv.Itoa(leak.LineNumber), stack)
+}
+
+// leakGroup is the leak records sharing one leak ID, merged into one
+type leakGroup struct {
+	Leak               // Summed over the records; CallStack resolved through StackId when missing
+	ID          string // The records' shared leak ID
+	Occurrences int
+}
+
+// groupLeaks returns the leak records merged by stack signature, and how many records were
+// skipped. The groups are shared with the capture's index and must not be modified.
+func (ma *MemoryAnalyzer) groupLeaks() (groups []leakGroup, skipped int) {
+	idx := ma.index()
+	return idx.leakGroups, idx.skippedLeaks
+}
+
+// buildLeakGroups merges leak records with the same leak ID, in capture order. Sizes
+// and counts are summed, the score is the highest, a group is suspect when any record is, and
+// thread, heap, and tag are kept only when all records agree. Records with neither size nor
+// count are skipped and counted. groupOf gives each record's group, or -1 when skipped.
+func (ma *MemoryAnalyzer) buildLeakGroups(stacks map[int]string) (groups []leakGroup, groupOf []int, skipped int) {
+
+	// Hashing the stacks dominates, so IDs are computed on all cores first
+	ids := shard(len(ma.data.Leaks), ma.workers(), func(start, end int) []string {
+		found := make([]string, 0, end-start)
+		for _, leak := range ma.data.Leaks[start:end] {
+			if leak.CallStack == "" {
+				leak.CallStack = stacks[leak.StackId]
+			}
+			found = append(found, leakID(leak))
+		}
+		return found
+	})
+
+	index := make(map[string]int)
+	groupOf = make([]int, len(ma.data.Leaks))
+	for k, leak := range ma.data.Leaks {
+		if leak.LeakSize == 0 && leak.LeakCount == 0 {
+			groupOf[k] = -1
+			skipped++
+			continue
+		}
+		if leak.CallStack == "" {
+			leak.CallStack = stacks[leak.StackId]
+		}
+
+		i, ok := index[ids[k]]
+		if !ok {
+			index[ids[k]] = len(groups)
+			groupOf[k] = len(groups)
+			groups = append(groups, leakGroup{Leak: leak, ID: ids[k], Occurrences: 1})
+			continue
+		}
+		groupOf[k] = i
+
+		g := &groups[i]
+		g.Occurrences++
+		g.LeakSize += leak.LeakSize
+		g.LeakCount += leak.LeakCount
+		g.LeakScore = max(g.LeakScore, leak.LeakScore)
+		g.IsSuspect = g.IsSuspect || leak.IsSuspect
+		if g.ThreadId != leak.ThreadId {
+			g.ThreadId = 0
+		}
+		if g.HeapId != leak.HeapId {
+			g.HeapId = 0
+		}
+		if g.Tag != leak.Tag {
+			g.Tag = ""
+		}
+	}
+	return groups, groupOf, skipped
+}
+
+// leakGroup returns the merged leak with the given issue ID
+func (ma *MemoryAnalyzer) leakGroup(id string) (leakGroup, bool) {
+	idx := ma.index()
+	i, ok := idx.groupsByID[id]
+	if !ok {
+		return leakGroup{}, false
+	}
+	return idx.leakGroups[i], true
+}
+
+// blame returns the function a leak is attributed to, looking past allocator plumbing
+func (ma *MemoryAnalyzer) blame(leak Leak) string {
+	if ma.config == nil {
+		return leak.FunctionName
+	}
+	return ma.config.plumbing.attribute(leak.FunctionName, leak.CallStack)
+}
+
+// applyCustomRules tags issues with their library, then runs configured rules over them;
+// records may be nil when issues carry no extra context
+func (ma *MemoryAnalyzer) applyCustomRules(issues []MemoryIssue, records []ruleRecord) []MemoryIssue {
+	ma.tagLibraries(issues, records)
+	if ma.config == nil || len(ma.config.rules) == 0 {
+		return issues
+	}
+	if records == nil {
+		records = make([]ruleRecord, len(issues))
+		for i, issue := range issues {
+			records[i] = newRuleRecord(issue)
+		}
+	}
+	before := len(issues)
+	issues = applyRules(ma.config.rules, issues, records, ma.workers())
+	ma.diag.skip("issues_dropped_by_rules", before-len(issues))
+	return issues
+}
+
+// calculateLeakSeverity applies the first matching leak_severity rule, else the threshold bands
+func (ma *MemoryAnalyzer) calculateLeakSeverity(leak Leak) string {
+	if r := ma.config.leakSeverityRule(leak, ma.blame(leak)); r != nil {
+		return r.Severity
+	}
+
+	t := ma.config.EffectiveThresholds()
+	if leak.IsSuspect && leak.LeakSize > t.LeakCriticalBytes {
+		return "Critical"
+	}
+	if leak.IsSuspect || leak.LeakSize > t.LeakHighBytes {
+		return "High"
+	}
+	if leak.LeakSize > t.LeakMediumBytes || leak.LeakCount > t.LeakMediumCount {
+		return "Medium"
+	}
+	return "Low"
+}
+
+func (ma *MemoryAnalyzer) formatLeakDescription(leak Leak) string {
+	if strings.Contains(leak.FunctionName, "Unknown Function") {
+		return fmt.Sprintf("Unknown function leaked %d bytes across %d allocations. This may indicate missing debug symbols or dynamically loaded code.",
+			leak.LeakSize, leak.LeakCount)
+	}
+	return fmt.Sprintf("Function leaked %d bytes across %d allocations", leak.LeakSize, leak.LeakCount)
+}
+
+// generateLeakSuggestion returns the suggestion of the first matching suggestion rule
+func (ma *MemoryAnalyzer) generateLeakSuggestion(leak Leak) string {
+	function := ma.blame(leak)
+	for _, r := range ma.config.suggestions {
+		if r.matches(leak, function) {
+			return r.Suggestion
+		}
+	}
+	return ""
+}
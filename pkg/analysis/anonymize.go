@@ -0,0 +1,255 @@
+package analysis
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultAnonymize turns anonymization on for configs that leave it off, and DefaultSymbolMapFile
+// is the mapping file of configs that name none (the server's --anonymize-symbols and --symbol-map)
+var (
+	DefaultAnonymize     bool
+	DefaultSymbolMapFile string
+)
+
+// Public symbols are runtime and standard library names that reveal nothing about the
+// application and that suggestion rules depend on, so they are never anonymized. They are
+// matched by whole identifier: mainWindow::Init and free::Camera are application names.
+var (
+	publicFunctions = map[string]bool{
+		"malloc": true, "calloc": true, "realloc": true, "free": true, "_malloc": true, "_calloc": true,
+		"_realloc": true, "_free": true, "strdup": true, "_strdup": true, "HeapAlloc": true, "VirtualAlloc": true,
+		"CoTaskMemAlloc": true, "main": true, "wmain": true, "WinMain": true,
+		"__libc_malloc": true, "__libc_calloc": true, "__libc_realloc": true, "__libc_free": true,
+		"__libc_start_main": true, "__scrt_common_main": true, "__scrt_common_main_seh": true,
+		"__tmainCRTStartup": true, "__cxa_allocate_exception": true, "__chkstk": true,
+	}
+	publicNamespaces = map[string]bool{"std": true, "__gnu_cxx": true, "__cxxabiv1": true}
+	publicPhrases    = []string{"operator new", "operator delete", "Unknown Function"}
+)
+
+// isPublicSymbol reports whether a symbol is a public function (followed by its signature, if
+// any, but not by ::), is in a public namespace, or starts with a public phrase such as
+// operator new[]
+func isPublicSymbol(name string) bool {
+	for _, phrase := range publicPhrases {
+		if rest, ok := strings.CutPrefix(name, phrase); ok && (rest == "" || !isIdentifierByte(rest[0])) {
+			return true
+		}
+	}
+	end := 0
+	for end < len(name) && isIdentifierByte(name[end]) {
+		end++
+	}
+	if end == 0 {
+		return false
+	}
+	if strings.HasPrefix(name[end:], "::") {
+		return publicNamespaces[name[:end]]
+	}
+	return publicFunctions[name[:end]]
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+var pseudonymPattern = regexp.MustCompile(`\b(?:fn|type)_[0-9a-f]{10}\b`)
+
+// SymbolMap holds stable pseudonyms for symbol names and persists them so output can be de-anonymized locally
+type SymbolMap struct {
+	mu      sync.Mutex
+	path    string
+	dirty   bool
+	Salt    string            `json:"salt"`
+	Symbols map[string]string `json:"symbols"` // pseudonym -> original name
+}
+
+var (
+	symbolMapsMu sync.Mutex
+	symbolMaps   = make(map[string]*SymbolMap)
+)
+
+// UserSymbolMapPath is the mapping file in the user config directory, used when anonymization
+// is on but no map file is configured
+func UserSymbolMapPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "mempro-mcp", "symbol-map.json")
+}
+
+// LoadSymbolMap returns the shared map for path, creating it with a fresh random salt if missing
+func LoadSymbolMap(path string) (*SymbolMap, error) {
+	symbolMapsMu.Lock()
+	defer symbolMapsMu.Unlock()
+
+	if m, ok := symbolMaps[path]; ok {
+		return m, nil
+	}
+
+	m := &SymbolMap{path: path, Symbols: make(map[string]string)}
+	fileData, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(fileData, m); err != nil {
+			return nil, fmt.Errorf("failed to parse symbol map %s: %w", path, err)
+		}
+		if m.Symbols == nil {
+			m.Symbols = make(map[string]string)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate symbol salt: %w", err)
+		}
+		m.Salt = hex.EncodeToString(salt)
+		m.dirty = true
+	default:
+		return nil, fmt.Errorf("failed to read symbol map: %w", err)
+	}
+
+	symbolMaps[path] = m
+	return m, nil
+}
+
+// pseudonym returns the stable replacement for name; the salt keeps pseudonyms from being
+// reversed by hashing guessed names
+func (m *SymbolMap) pseudonym(prefix, name string) string {
+	sum := sha256.Sum256([]byte(m.Salt + "\x00" + name))
+	p := prefix + "_" + hex.EncodeToString(sum[:5])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Symbols[p]; !ok {
+		m.Symbols[p] = name
+		m.dirty = true
+	}
+	return p
+}
+
+// anonymize replaces a symbol name, keeping public names and the container part of templates
+func (m *SymbolMap) anonymize(prefix, name string) string {
+	if name == "" {
+		return name
+	}
+	if !isPublicSymbol(name) {
+		return m.pseudonym(prefix, name)
+	}
+	// std::vector<Secret::Type> keeps std::vector but hides its arguments
+	open := strings.Index(name, "<")
+	end := strings.LastIndex(name, ">")
+	if open < 0 || end < open {
+		return name
+	}
+	return name[:open+1] + m.pseudonym("type", name[open+1:end]) + name[end:]
+}
+
+// Reveal replaces pseudonyms in text with their original names
+func (m *SymbolMap) Reveal(text string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return pseudonymPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if name, ok := m.Symbols[p]; ok {
+			return name
+		}
+		return p
+	})
+}
+
+// save writes the map if new pseudonyms were added; the file is private since it de-anonymizes output
+func (m *SymbolMap) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create symbol map directory: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write symbol map: %w", err)
+	}
+	m.dirty = false
+	return nil
+}
+
+// anonymizeData replaces function and type names throughout a capture, including inside call stacks
+func anonymizeData(d *MemProData, m *SymbolMap) error {
+	names := make(map[string]string)
+	fn := func(name string) string {
+		if name == "" {
+			return name
+		}
+		p, ok := names[name]
+		if !ok {
+			p = m.anonymize("fn", name)
+			names[name] = p
+		}
+		return p
+	}
+
+	for i := range d.Leaks {
+		d.Leaks[i].FunctionName = fn(d.Leaks[i].FunctionName)
+	}
+	for i := range d.Functions {
+		d.Functions[i].FunctionName = fn(d.Functions[i].FunctionName)
+	}
+	for i := range d.PageViews {
+		d.PageViews[i].FunctionName = fn(d.PageViews[i].FunctionName)
+	}
+	for i := range d.Types {
+		d.Types[i].TypeName = m.anonymize("type", d.Types[i].TypeName)
+		d.Types[i].MostCommonFunction = fn(d.Types[i].MostCommonFunction)
+	}
+	for i := range d.Allocations {
+		d.Allocations[i].FunctionName = fn(d.Allocations[i].FunctionName)
+	}
+	for i := range d.Snapshots {
+		for j := range d.Snapshots[i].Functions {
+			d.Snapshots[i].Functions[j].FunctionName = fn(d.Snapshots[i].Functions[j].FunctionName)
+		}
+	}
+	var walk func(trees []CallTree)
+	walk = func(trees []CallTree) {
+		for i := range trees {
+			trees[i].FunctionName = fn(trees[i].FunctionName)
+			walk(trees[i].Children)
+		}
+	}
+	walk(d.CallTrees)
+
+	// Call stacks are rewritten frame by frame, so a short name inside a longer one is left
+	// alone; a frame's module prefix and offset are kept around its pseudonym
+	frame := func(f string) string {
+		if p, ok := names[f]; ok {
+			return p
+		}
+		module := frameModulePattern.FindString(f)
+		offset := frameOffsetPattern.FindString(f[len(module):])
+		return module + fn(f[len(module):len(f)-len(offset)]) + offset
+	}
+	for i := range d.Leaks {
+		d.Leaks[i].CallStack = rewriteCallStack(d.Leaks[i].CallStack, frame)
+	}
+	for i := range d.PageViews {
+		d.PageViews[i].CallStack = rewriteCallStack(d.PageViews[i].CallStack, frame)
+	}
+
+	return m.save()
+}
@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultApplicationTop is how many combined issues analyze_application returns
+const defaultApplicationTop = 20
+
+// ProcessCapture is one process of a multi-process application
+type ProcessCapture struct {
+	Process string
+	Path    string
+}
+
+// ProcessSummary is one process's share of an application's memory
+type ProcessSummary struct {
+	Process        string         `json:"process"`
+	Path           string         `json:"path"`
+	Session        string         `json:"session"`
+	TotalSize      int64          `json:"total_size"`
+	LeakSize       int64          `json:"leak_size"`
+	LeakCount      int            `json:"leak_count"`
+	LeakPercentage float64        `json:"leak_percentage"`
+	Fragmentation  float64        `json:"fragmentation"`
+	Issues         map[string]int `json:"issues"` // Issue count by severity
+}
+
+// ProcessIssue is an issue attributed to the process it was found in
+type ProcessIssue struct {
+	Process string `json:"process"`
+	MemoryIssue
+}
+
+// SharedLeak is a leak found at the same site in several processes, typically shared engine code
+type SharedLeak struct {
+	ID           string   `json:"id"`
+	FunctionName string   `json:"functionName"`
+	Processes    []string `json:"processes"`
+	TotalSize    int64    `json:"total_size"`
+}
+
+// ApplicationAnalysis aggregates issues across the processes of one application
+type ApplicationAnalysis struct {
+	Application string           `json:"application,omitempty"`
+	Processes   []ProcessSummary `json:"processes"`
+	TotalSize   int64            `json:"total_size"`
+	LeakSize    int64            `json:"leak_size"`
+	LeakCount   int              `json:"leak_count"`
+	Issues      []ProcessIssue   `json:"issues"`
+	SharedLeaks []SharedLeak     `json:"shared_leaks,omitempty"`
+	Omitted     int              `json:"omitted,omitempty"` // Issues beyond top
+}
+
+// ParseProcessCaptures reads "process=path" pairs separated by commas. Without a process
+// name, the file name (without extension) is used.
+func ParseProcessCaptures(list string) ([]ProcessCapture, error) {
+	var captures []ProcessCapture
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var c ProcessCapture
+		// A drive letter such as C:\ is not a process name, so split on "=" only
+		if name, path, ok := strings.Cut(entry, "="); ok {
+			c = ProcessCapture{Process: strings.TrimSpace(name), Path: strings.TrimSpace(path)}
+		} else {
+			c = ProcessCapture{Process: strings.TrimSuffix(filepath.Base(entry), filepath.Ext(entry)), Path: entry}
+		}
+		if c.Process == "" || c.Path == "" {
+			return nil, fmt.Errorf("invalid capture %q (expected process=path)", entry)
+		}
+		if seen[c.Process] {
+			return nil, fmt.Errorf("process %q is listed more than once", c.Process)
+		}
+		seen[c.Process] = true
+		captures = append(captures, c)
+	}
+
+	if len(captures) == 0 {
+		return nil, fmt.Errorf("no captures given")
+	}
+	return captures, nil
+}
+
+// AnalyzeApplication runs the standard analysis on each process capture and combines the results
+func AnalyzeApplication(application string, captures []ProcessCapture, top int) (*ApplicationAnalysis, error) {
+	if top <= 0 {
+		top = defaultApplicationTop
+	}
+
+	red := CurrentConfig().Redactor()
+	result := &ApplicationAnalysis{Application: application}
+	shared := make(map[string]*SharedLeak)
+	var issues []ProcessIssue
+
+	for _, c := range captures {
+		analyzer, err := NewMemoryAnalyzer(c.Path)
+		if err != nil {
+			return nil, fmt.Errorf("process %s: %w", c.Process, err)
+		}
+
+		summary := ProcessSummary{
+			Process:        c.Process,
+			Path:           red.Path(c.Path),
+			Session:        analyzer.data.SessionName,
+			TotalSize:      analyzer.data.TotalSize,
+			LeakSize:       analyzer.data.LeakSize,
+			LeakCount:      analyzer.data.LeakCount,
+			LeakPercentage: analyzer.LeakPercentage(),
+			Fragmentation:  analyzer.data.MemoryFragmentation,
+			Issues:         make(map[string]int),
+		}
+		result.TotalSize += summary.TotalSize
+		result.LeakSize += summary.LeakSize
+		result.LeakCount += summary.LeakCount
+
+		for _, group := range analyzer.AnalyzeAll() {
+			for _, issue := range group {
+				summary.Issues[issue.Severity]++
+				issues = append(issues, ProcessIssue{Process: c.Process, MemoryIssue: issue})
+
+				if issue.Type != "MemoryLeak" {
+					continue
+				}
+				s, ok := shared[issue.ID]
+				if !ok {
+					s = &SharedLeak{ID: issue.ID, FunctionName: issue.FunctionName}
+					shared[issue.ID] = s
+				}
+				s.Processes = append(s.Processes, c.Process)
+				s.TotalSize += issue.Size
+			}
+		}
+		result.Processes = append(result.Processes, summary)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return IssueLess(issues[i].MemoryIssue, issues[j].MemoryIssue) })
+	result.Issues = issues[:min(len(issues), top)]
+	result.Omitted = len(issues) - len(result.Issues)
+
+	for _, s := range shared {
+		if len(s.Processes) > 1 {
+			result.SharedLeaks = append(result.SharedLeaks, *s)
+		}
+	}
+	sort.Slice(result.SharedLeaks, func(i, j int) bool {
+		if result.SharedLeaks[i].TotalSize != result.SharedLeaks[j].TotalSize {
+			return result.SharedLeaks[i].TotalSize > result.SharedLeaks[j].TotalSize
+		}
+		return result.SharedLeaks[i].ID < result.SharedLeaks[j].ID
+	})
+
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Attribution defaults. A node is a pass-through frame when at most passThroughPercent of
+// its inclusive bytes are allocated by the node itself.
+const (
+	defaultAttributionTop = 20
+	passThroughPercent    = 5.0
+)
+
+// NodeAttribution is one call tree node's own and inclusive share of the allocated bytes
+type NodeAttribution struct {
+	Path           string  `json:"path"`
+	Function       string  `json:"function"`
+	File           string  `json:"file,omitempty"`
+	Line           int     `json:"line,omitempty"`
+	Depth          int     `json:"depth"` // 0 for roots
+	SelfSize       int64   `json:"self_size"`
+	InclusiveSize  int64   `json:"inclusive_size"`
+	SelfShare      float64 `json:"self_share"`      // Percent of all call tree bytes
+	InclusiveShare float64 `json:"inclusive_share"` // Percent of all call tree bytes
+	SelfPercent    float64 `json:"self_percent"`    // Self size as a percent of inclusive size
+	PassThrough    bool    `json:"pass_through,omitempty"`
+	DominantChild  string  `json:"dominant_child,omitempty"` // Largest child of a pass-through frame
+}
+
+// AttributionReport is the result of attribute_allocations
+type AttributionReport struct {
+	TotalBytes         int64             `json:"total_bytes"`
+	Nodes              int               `json:"nodes"`
+	PassThroughPercent float64           `json:"pass_through_percent"`
+	Owners             []NodeAttribution `json:"owners"`       // Largest self sizes
+	PassThrough        []NodeAttribution `json:"pass_through"` // Largest pass-through frames
+}
+
+// treeSelf returns a node's self size, derived from its children for captures that do not
+// record it
+func treeSelf(t CallTree) int64 {
+	if t.SelfSize != 0 {
+		return t.SelfSize
+	}
+	self := treeInclusive(t)
+	for _, child := range t.Children {
+		self -= treeInclusive(child)
+	}
+	return max(self, 0)
+}
+
+// AttributeAllocations splits every call tree node's bytes into what it allocates itself
+// and what its callees allocate. Owners are the nodes allocating the most themselves;
+// pass-through frames allocate at most threshold percent of their inclusive bytes, so their
+// children are the real owners. Nodes smaller than minBytes inclusive are skipped.
+func (ma *MemoryAnalyzer) AttributeAllocations(top int, minBytes int64, threshold float64) (*AttributionReport, error) {
+	if len(ma.data.CallTrees) == 0 {
+		return nil, fmt.Errorf("capture has no call trees")
+	}
+	if top <= 0 {
+		top = defaultAttributionTop
+	}
+	if threshold <= 0 {
+		threshold = passThroughPercent
+	}
+	if threshold > 100 {
+		return nil, fmt.Errorf("pass_through must be a percentage between 0 and 100, got %g", threshold)
+	}
+
+	report := &AttributionReport{PassThroughPercent: threshold, Owners: []NodeAttribution{}, PassThrough: []NodeAttribution{}}
+	for _, t := range ma.data.CallTrees {
+		report.TotalBytes += treeInclusive(t)
+	}
+	share := func(n int64) float64 {
+		if report.TotalBytes <= 0 {
+			return 0
+		}
+		return math.Round(float64(n)/float64(report.TotalBytes)*10000) / 100
+	}
+
+	var nodes []NodeAttribution
+	var walk func(t CallTree, path string, depth int)
+	walk = func(t CallTree, path string, depth int) {
+		inclusive := treeInclusive(t)
+		if inclusive < minBytes {
+			return
+		}
+		n := NodeAttribution{
+			Path:           path,
+			Function:       t.FunctionName,
+			File:           t.FileName,
+			Line:           t.LineNumber,
+			Depth:          depth,
+			SelfSize:       treeSelf(t),
+			InclusiveSize:  inclusive,
+			SelfShare:      share(treeSelf(t)),
+			InclusiveShare: share(inclusive),
+		}
+		if inclusive > 0 {
+			n.SelfPercent = math.Round(float64(n.SelfSize)/float64(inclusive)*10000) / 100
+		}
+		if len(t.Children) > 0 && inclusive > 0 && float64(n.SelfSize)/float64(inclusive)*100 <= threshold {
+			n.PassThrough = true
+			dominant := t.Children[0]
+			for _, child := range t.Children[1:] {
+				if treeInclusive(child) > treeInclusive(dominant) {
+					dominant = child
+				}
+			}
+			n.DominantChild = dominant.FunctionName
+		}
+		nodes = append(nodes, n)
+
+		for i, child := range t.Children {
+			walk(child, path+"/"+strconv.Itoa(i), depth+1)
+		}
+	}
+	for i, t := range ma.data.CallTrees {
+		walk(t, strconv.Itoa(i), 0)
+	}
+	report.Nodes = len(nodes)
+
+	for _, n := range nodes {
+		if n.SelfSize > 0 {
+			report.Owners = append(report.Owners, n)
+		}
+		if n.PassThrough {
+			report.PassThrough = append(report.PassThrough, n)
+		}
+	}
+	sort.SliceStable(report.Owners, func(i, j int) bool { return report.Owners[i].SelfSize > report.Owners[j].SelfSize })
+	sort.SliceStable(report.PassThrough, func(i, j int) bool {
+		return report.PassThrough[i].InclusiveSize > report.PassThrough[j].InclusiveSize
+	})
+	report.Owners = report.Owners[:min(len(report.Owners), top)]
+	report.PassThrough = report.PassThrough[:min(len(report.PassThrough), top)]
+	return report, nil
+}
@@ -0,0 +1,184 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Baseline settings
+const (
+	baselineVersion      = 1  // Bumped when the file format changes incompatibly
+	defaultRegressionTop = 20 // Function regressions listed by check_regression
+
+	// Baselines need only the header totals and the leaks; page views resolve stack IDs
+	BaselineSections = SectionLeaks | SectionPageViews
+
+	functionLeakMetric = "function_leak_size"
+)
+
+// Baseline is a capture's summary saved by save_baseline for later regression checks
+type Baseline struct {
+	Version          int              `json:"version"`
+	Saved            time.Time        `json:"saved"`
+	Capture          string           `json:"capture"`
+	Session          string           `json:"session"`
+	TotalAllocations int              `json:"total_allocations"`
+	TotalSize        int64            `json:"total_size"`
+	LeakCount        int              `json:"leak_count"`
+	LeakSize         int64            `json:"leak_size"`
+	Fragmentation    float64          `json:"fragmentation"`
+	FunctionLeaks    map[string]int64 `json:"function_leaks"` // Leaked bytes per blamed function
+}
+
+// Regression is a metric that grew beyond tolerance since the baseline
+type Regression struct {
+	Metric   string  `json:"metric"`
+	Function string  `json:"function,omitempty"` // Set for function_leak_size
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Delta    float64 `json:"delta"`
+}
+
+// RegressionReport is the result of checking a capture against a baseline
+type RegressionReport struct {
+	Baseline         string       `json:"baseline"`
+	BaselineSaved    time.Time    `json:"baseline_saved"`
+	BaselineSession  string       `json:"baseline_session"`
+	Session          string       `json:"session"`
+	Tolerance        string       `json:"tolerance"`
+	Passed           bool         `json:"passed"`
+	Regressions      []Regression `json:"regressions"`  // Totals first, then functions by growth
+	Improvements     []string     `json:"improvements"` // Totals that shrank beyond tolerance
+	FixedFunctions   []string     `json:"fixed_functions,omitempty"`
+	OmittedFunctions int          `json:"omitted_functions,omitempty"` // Function regressions beyond top
+}
+
+// TakeBaseline summarizes the capture for save_baseline
+func (ma *MemoryAnalyzer) TakeBaseline(capture string) *Baseline {
+	return &Baseline{
+		Version:          baselineVersion,
+		Saved:            time.Now().UTC(),
+		Capture:          capture,
+		Session:          ma.data.SessionName,
+		TotalAllocations: ma.data.TotalAllocations,
+		TotalSize:        ma.data.TotalSize,
+		LeakCount:        ma.data.LeakCount,
+		LeakSize:         ma.data.LeakSize,
+		Fragmentation:    ma.data.MemoryFragmentation,
+		FunctionLeaks:    ma.functionLeaks(),
+	}
+}
+
+// functionLeaks sums leaked bytes per blamed function
+func (ma *MemoryAnalyzer) functionLeaks() map[string]int64 {
+	leaks := make(map[string]int64)
+	for _, leak := range ma.data.Leaks {
+		if leak.LeakSize > 0 {
+			leaks[ma.blame(leak)] += leak.LeakSize
+		}
+	}
+	return leaks
+}
+
+// Save writes the baseline as indented JSON
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// LoadBaseline reads a baseline written by save_baseline
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d (want %d); save the baseline again", b.Version, baselineVersion)
+	}
+	return &b, nil
+}
+
+// CheckRegression compares the capture against a baseline. Totals and per-function leak
+// sizes that grew beyond tol are regressions; function leak sizes use the leak_size
+// tolerance, so a function that starts leaking regresses unless its size is within the
+// absolute bound. The top function regressions with the largest growth are listed.
+func (ma *MemoryAnalyzer) CheckRegression(b *Baseline, label string, tol *ToleranceProfile, top int) *RegressionReport {
+	if top <= 0 {
+		top = defaultRegressionTop
+	}
+	r := &RegressionReport{
+		Baseline:        label,
+		BaselineSaved:   b.Saved,
+		BaselineSession: b.Session,
+		Session:         ma.data.SessionName,
+		Tolerance:       tol.Name,
+		Regressions:     []Regression{},
+		Improvements:    []string{},
+	}
+
+	totals := []struct {
+		metric          string
+		baseline, after float64
+	}{
+		{"allocation_count", float64(b.TotalAllocations), float64(ma.data.TotalAllocations)},
+		{"total_size", float64(b.TotalSize), float64(ma.data.TotalSize)},
+		{"leak_count", float64(b.LeakCount), float64(ma.data.LeakCount)},
+		{"leak_size", float64(b.LeakSize), float64(ma.data.LeakSize)},
+		{"fragmentation", b.Fragmentation, ma.data.MemoryFragmentation},
+	}
+	for _, t := range totals {
+		if tol.within(t.metric, t.baseline, t.after) {
+			continue
+		}
+		if t.after > t.baseline {
+			r.Regressions = append(r.Regressions, Regression{Metric: t.metric, Baseline: t.baseline, Current: t.after, Delta: t.after - t.baseline})
+		} else {
+			r.Improvements = append(r.Improvements, t.metric)
+		}
+	}
+
+	var functions []Regression
+	current := ma.functionLeaks()
+	for function, after := range current {
+		before := b.FunctionLeaks[function]
+		if after > before && !tol.within("leak_size", float64(before), float64(after)) {
+			functions = append(functions, Regression{
+				Metric:   functionLeakMetric,
+				Function: function,
+				Baseline: float64(before),
+				Current:  float64(after),
+				Delta:    float64(after - before),
+			})
+		}
+	}
+	for function := range b.FunctionLeaks {
+		if _, ok := current[function]; !ok {
+			r.FixedFunctions = append(r.FixedFunctions, function)
+		}
+	}
+	sort.Strings(r.FixedFunctions)
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].Delta != functions[j].Delta {
+			return functions[i].Delta > functions[j].Delta
+		}
+		return functions[i].Function < functions[j].Function
+	})
+
+	r.Passed = len(r.Regressions) == 0 && len(functions) == 0
+	r.OmittedFunctions = max(len(functions)-top, 0)
+	r.Regressions = append(r.Regressions, functions[:min(len(functions), top)]...)
+	return r
+}
@@ -0,0 +1,188 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Batch defaults and limits
+const (
+	defaultBatchTop = 10
+	maxBatchFiles   = 500
+)
+
+// BatchFileResult is the compact verdict for one capture in a batch
+type BatchFileResult struct {
+	Path           string         `json:"path"`
+	Session        string         `json:"session,omitempty"`
+	Verdict        string         `json:"verdict"` // critical, warning, ok, or error
+	LeakSize       int64          `json:"leak_size"`
+	LeakPercentage float64        `json:"leak_percentage"`
+	Fragmentation  float64        `json:"fragmentation"`
+	Issues         map[string]int `json:"issues,omitempty"` // Issue count by severity
+	TopIssue       string         `json:"top_issue,omitempty"`
+	Error          string         `json:"error,omitempty"`
+}
+
+// BatchIssue is an issue attributed to the capture it was found in
+type BatchIssue struct {
+	File string `json:"file"`
+	MemoryIssue
+}
+
+// BatchResult aggregates the standard analysis over many captures
+type BatchResult struct {
+	Files     []BatchFileResult `json:"files"`
+	Verdicts  map[string]int    `json:"verdicts"`
+	TopIssues []BatchIssue      `json:"top_issues"`
+}
+
+// ExpandBatchPaths resolves a comma-separated list of files, directories, and glob patterns
+// into capture files. Directories contribute their *.json files.
+func ExpandBatchPaths(list string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.ContainsAny(entry, "*?[") {
+			matches, err := filepath.Glob(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", entry, err)
+			}
+			for _, m := range matches {
+				if isRegularFile(m) {
+					add(m)
+				}
+			}
+			continue
+		}
+
+		if info, err := os.Stat(entry); err == nil && info.IsDir() {
+			matches, _ := filepath.Glob(filepath.Join(entry, "*.json"))
+			for _, m := range matches {
+				add(m)
+			}
+			continue
+		}
+
+		// Missing files are kept so they show up as errors in the results
+		add(entry)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no capture files match %q", list)
+	}
+	if len(files) > maxBatchFiles {
+		return nil, fmt.Errorf("%d files match, more than the limit of %d", len(files), maxBatchFiles)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// AnalyzeBatch runs the standard analysis on each capture concurrently
+func AnalyzeBatch(paths []string, top, workers int) *BatchResult {
+	if top <= 0 {
+		top = defaultBatchTop
+	}
+
+	files, issues := analyzeBatchFiles(paths, workers)
+	result := &BatchResult{Files: files, Verdicts: make(map[string]int)}
+	var all []BatchIssue
+	for i, f := range files {
+		result.Verdicts[f.Verdict]++
+		all = append(all, issues[i]...)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return IssueLess(all[i].MemoryIssue, all[j].MemoryIssue) })
+	result.TopIssues = all[:min(len(all), top)]
+	return result
+}
+
+// analyzeBatchFiles analyzes captures concurrently, returning each one's verdict and issues
+// in the order of paths
+func analyzeBatchFiles(paths []string, workers int) ([]BatchFileResult, [][]BatchIssue) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	red := CurrentConfig().Redactor()
+	files := make([]BatchFileResult, len(paths))
+	issues := make([][]BatchIssue, len(paths))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			files[i], issues[i] = analyzeBatchFile(path, red.Path(path))
+		}(i, path)
+	}
+	wg.Wait()
+	return files, issues
+}
+
+// analyzeBatchFile analyzes one capture; label is the path as shown in output
+func analyzeBatchFile(path, label string) (BatchFileResult, []BatchIssue) {
+	result := BatchFileResult{Path: label}
+
+	analyzer, err := NewMemoryAnalyzer(path)
+	if err != nil {
+		result.Verdict = "error"
+		result.Error = err.Error()
+		return result, nil
+	}
+	return summarizeBatchFile(analyzer, label)
+}
+
+// summarizeBatchFile runs the standard analysis on a loaded capture and derives its verdict
+func summarizeBatchFile(analyzer *MemoryAnalyzer, label string) (BatchFileResult, []BatchIssue) {
+	result := BatchFileResult{Path: label}
+	result.Session = analyzer.data.SessionName
+	result.LeakSize = analyzer.data.LeakSize
+	result.LeakPercentage = analyzer.LeakPercentage()
+	result.Fragmentation = analyzer.data.MemoryFragmentation
+	result.Issues = make(map[string]int)
+
+	var found []BatchIssue
+	for _, group := range analyzer.AnalyzeAll() {
+		for _, issue := range group {
+			result.Issues[issue.Severity]++
+			found = append(found, BatchIssue{File: label, MemoryIssue: issue})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return IssueLess(found[i].MemoryIssue, found[j].MemoryIssue) })
+	if len(found) > 0 {
+		result.TopIssue = issueLabel(found[0].MemoryIssue)
+	}
+
+	switch {
+	case result.Issues["Critical"] > 0:
+		result.Verdict = "critical"
+	case result.Issues["High"] > 0:
+		result.Verdict = "warning"
+	default:
+		result.Verdict = "ok"
+	}
+	return result, found
+}
@@ -0,0 +1,218 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultBudgetFunctions is how many of a module's largest functions check_budgets lists
+const defaultBudgetFunctions = 3
+
+// ByteSize is a size in bytes, written in the config as a number of bytes or as a string
+// with a binary unit such as "512MB" or "1.5 GB"
+type ByteSize int64
+
+// byteUnits are the accepted size suffixes, longest first so "MB" is not read as "B"
+var byteUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// ParseByteSize parses a size such as "512MB", "64 KB", or "1048576"
+func ParseByteSize(s string) (ByteSize, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := 1.0
+	for _, u := range byteUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text, multiplier = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512MB)", s)
+	}
+	return ByteSize(math.Round(n * multiplier)), nil
+}
+
+// UnmarshalJSON accepts a number of bytes or a size string. A bad size is reported as a
+// *json.UnmarshalTypeError; findBadSizes locates it, since the decoder does not.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	size, err := decodeByteSize(data)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: string(data), Type: reflect.TypeOf(*b)}
+	}
+	*b = size
+	return nil
+}
+
+// decodeByteSize decodes a JSON number of bytes or size string
+func decodeByteSize(data []byte) (ByteSize, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return ParseByteSize(s)
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return 0, fmt.Errorf("invalid size %s (expected bytes or a string such as \"512MB\")", data)
+	}
+	return ByteSize(n), nil
+}
+
+// ModuleBudget limits the memory allocated by the source files under a module's paths
+type ModuleBudget struct {
+	Name   string   `json:"name"`
+	Paths  []string `json:"paths"` // Path prefixes, e.g. "src/renderer/"
+	Budget ByteSize `json:"budget"`
+}
+
+// normalizeModulePath lower-cases a path and uses forward slashes, so Windows and POSIX
+// spellings of the same prefix match
+func normalizeModulePath(path string) string {
+	return strings.ToLower(strings.ReplaceAll(path, `\`, "/"))
+}
+
+// matchLength returns the length of the longest of the module's prefixes that file starts
+// with, or that follows a directory separator in file; 0 when none does
+func (m ModuleBudget) matchLength(file string) int {
+	return pathPrefixLength(m.Paths, file)
+}
+
+// pathPrefixLength returns the length of the longest of prefixes that the normalized file
+// starts with, or that follows a directory separator in it; 0 when none does
+func pathPrefixLength(prefixes []string, file string) int {
+	best := 0
+	for _, prefix := range prefixes {
+		p := strings.TrimPrefix(normalizeModulePath(prefix), "./")
+		if p == "" || len(p) <= best {
+			continue
+		}
+		if strings.HasPrefix(file, p) || strings.Contains(file, "/"+p) {
+			best = len(p)
+		}
+	}
+	return best
+}
+
+// moduleOf returns the index of the module whose longest prefix matches file, or -1
+func moduleOf(modules []ModuleBudget, file string) int {
+	file = normalizeModulePath(file)
+	module, best := -1, 0
+	for i, m := range modules {
+		if n := m.matchLength(file); n > best {
+			module, best = i, n
+		}
+	}
+	return module
+}
+
+// checkModuleBudgets validates the module budgets
+func (c *Config) checkModuleBudgets() error {
+	seen := make(map[string]bool)
+	for i, m := range c.ModuleBudgets {
+		if m.Name == "" {
+			return fmt.Errorf("module_budgets: entry %d has no name", i)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("module_budgets: module %q is defined twice", m.Name)
+		}
+		seen[m.Name] = true
+		if len(m.Paths) == 0 {
+			return fmt.Errorf("module_budgets: module %q has no paths", m.Name)
+		}
+		if m.Budget <= 0 {
+			return fmt.Errorf("module_budgets: budget for %q must be positive", m.Name)
+		}
+	}
+	return nil
+}
+
+// ModuleUsage is one module's allocations against its budget
+type ModuleUsage struct {
+	Module       string          `json:"module"`
+	Functions    int             `json:"functions"`
+	Allocations  int             `json:"allocations"`
+	TotalSize    int64           `json:"total_size"`
+	Budget       int64           `json:"budget"`
+	BudgetUsed   float64         `json:"budget_used"` // Percentage of the budget
+	Status       string          `json:"status"`      // over, near, or ok
+	Passed       bool            `json:"passed"`
+	TopFunctions []FunctionShare `json:"top_functions,omitempty"`
+}
+
+// FunctionShare is a function's allocated bytes within a module
+type FunctionShare struct {
+	FunctionName string `json:"functionName"`
+	FileName     string `json:"fileName,omitempty"`
+	TotalSize    int64  `json:"total_size"`
+}
+
+// BudgetReport is the result of check_budgets
+type BudgetReport struct {
+	Session         string        `json:"session"`
+	Passed          bool          `json:"passed"`
+	Failed          []string      `json:"failed"` // Modules over budget
+	Modules         []ModuleUsage `json:"modules"`
+	UnassignedSize  int64         `json:"unassigned_size"` // Bytes from files outside every module
+	UnassignedCount int           `json:"unassigned_functions"`
+}
+
+// CheckModuleBudgets sums the Functions statistics per module, assigning each function to
+// the module with the longest path prefix matching its file, and fails the modules over
+// budget. Modules are listed in config order with their top largest functions.
+func (ma *MemoryAnalyzer) CheckModuleBudgets(top int) (*BudgetReport, error) {
+	budgets := ma.config.ModuleBudgets
+	if len(budgets) == 0 {
+		return nil, fmt.Errorf("no module budgets are configured; add module_budgets to the config")
+	}
+	if top <= 0 {
+		top = defaultBudgetFunctions
+	}
+
+	report := &BudgetReport{Session: ma.data.SessionName, Passed: true, Failed: []string{}}
+	usage := make([]ModuleUsage, len(budgets))
+	functions := make([][]FunctionShare, len(budgets))
+	for i, m := range budgets {
+		usage[i] = ModuleUsage{Module: m.Name, Budget: int64(m.Budget)}
+	}
+
+	for _, fn := range ma.data.Functions {
+		module := moduleOf(budgets, fn.FileName)
+		if module < 0 {
+			report.UnassignedSize += fn.TotalSize
+			report.UnassignedCount++
+			continue
+		}
+		u := &usage[module]
+		u.Functions++
+		u.Allocations += fn.AllocationCount
+		u.TotalSize += fn.TotalSize
+		functions[module] = append(functions[module], FunctionShare{FunctionName: fn.FunctionName, FileName: fn.FileName, TotalSize: fn.TotalSize})
+	}
+
+	for i := range usage {
+		u := &usage[i]
+		used, status := budgetStatus(u.TotalSize, u.Budget)
+		u.BudgetUsed = math.Round(used*100) / 100
+		u.Status = status
+		u.Passed = status != "over"
+		if !u.Passed {
+			report.Passed = false
+			report.Failed = append(report.Failed, u.Module)
+		}
+
+		list := functions[i]
+		sort.SliceStable(list, func(a, b int) bool { return list[a].TotalSize > list[b].TotalSize })
+		u.TopFunctions = list[:min(len(list), top)]
+	}
+	report.Modules = usage
+	return report, nil
+}
@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultCaptureCache is how many parsed captures are kept, enough for two-capture comparisons
+const DefaultCaptureCache = 2
+
+// captureKey identifies a capture file as it is on disk and the configuration it was
+// loaded with; redaction, anonymization, and rules all change what is derived from it
+type captureKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+	config  *Config
+}
+
+// captureKeyOf returns the key of the capture at path as it is now
+func captureKeyOf(path string) (captureKey, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return captureKey{}, false
+	}
+	return captureKey{path: path, size: info.Size(), modTime: info.ModTime(), config: CurrentConfig()}, true
+}
+
+// CaptureCache keeps values derived from the most recently used captures, so repeated
+// calls on an unchanged capture skip loading it
+type CaptureCache[T any] struct {
+	mu      sync.Mutex
+	limit   int // Captures kept; zero disables the cache
+	entries []cacheEntry[T]
+}
+
+type cacheEntry[T any] struct {
+	key   captureKey
+	value T
+}
+
+// NewCaptureCache returns a cache of the values of up to limit captures; zero disables it
+func NewCaptureCache[T any](limit int) *CaptureCache[T] {
+	return &CaptureCache[T]{limit: limit}
+}
+
+// SetLimit changes how many captures are kept; zero disables the cache
+func (c *CaptureCache[T]) SetLimit(limit int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limit = limit
+	if len(c.entries) > max(limit, 0) {
+		c.entries = c.entries[:max(limit, 0)]
+	}
+}
+
+// Get returns the cached value if it was derived from the capture at path as it is now
+func (c *CaptureCache[T]) Get(path string) (T, bool) {
+	key, ok := captureKeyOf(path)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return c.lookup(key)
+}
+
+// Put remembers a value derived from the capture at path
+func (c *CaptureCache[T]) Put(path string, value T) {
+	if key, ok := captureKeyOf(path); ok {
+		c.store(key, value)
+	}
+}
+
+// lookup returns the value stored under key and marks it most recently used
+func (c *CaptureCache[T]) lookup(key captureKey) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.entries {
+		if e.key == key {
+			copy(c.entries[1:i+1], c.entries[:i])
+			c.entries[0] = e
+			return e.value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// store remembers value under key, evicting the least recently used entries beyond the
+// limit and any older entry for the same path
+func (c *CaptureCache[T]) store(key captureKey, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limit <= 0 {
+		return
+	}
+	entries := []cacheEntry[T]{{key: key, value: value}}
+	for _, e := range c.entries {
+		if e.key.path != key.path && len(entries) < c.limit {
+			entries = append(entries, e)
+		}
+	}
+	c.entries = entries
+}
@@ -0,0 +1,535 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// defaultGraphNodes is how many of the heaviest functions export_call_graph keeps
+const defaultGraphNodes = 100
+
+// CallGraphNode is a function in the call graph. Inclusive sizes count every byte whose
+// stack passes through the function; self sizes count bytes it allocated directly.
+type CallGraphNode struct {
+	Function           string `json:"function"`
+	LeakedBytes        int64  `json:"leaked_bytes"`
+	SelfLeakedBytes    int64  `json:"self_leaked_bytes"`
+	AllocatedBytes     int64  `json:"allocated_bytes"`
+	SelfAllocatedBytes int64  `json:"self_allocated_bytes"`
+	LeakStacks         int    `json:"leak_stacks"` // Leak records whose stack contains the function
+}
+
+// CallGraphEdge is a caller-to-callee edge weighted by the bytes allocated through it
+type CallGraphEdge struct {
+	Caller         string `json:"caller"`
+	Callee         string `json:"callee"`
+	LeakedBytes    int64  `json:"leaked_bytes"`
+	AllocatedBytes int64  `json:"allocated_bytes"`
+	LeakStacks     int    `json:"leak_stacks"`
+}
+
+// CallGraph is a directed call graph in nodes/edges form
+type CallGraph struct {
+	Sources      []string        `json:"sources"` // Records the graph was built from: leaks, call_trees
+	Nodes        []CallGraphNode `json:"nodes"`
+	Edges        []CallGraphEdge `json:"edges"`
+	Roots        []string        `json:"roots"` // Kept functions with no kept callers
+	OmittedNodes int             `json:"omitted_nodes,omitempty"`
+}
+
+// edgeKey identifies a caller-to-callee edge
+type edgeKey struct {
+	caller, callee string
+}
+
+// callGraph is the full graph built from a capture, before pruning
+type callGraph struct {
+	nodes   map[string]*CallGraphNode
+	edges   map[edgeKey]*CallGraphEdge
+	sources []string
+}
+
+// nodeWeight orders nodes and edges by their heaviest measure
+func nodeWeight(leaked, allocated int64) int64 {
+	return max(leaked, allocated)
+}
+
+func (g *callGraph) node(name string) *CallGraphNode {
+	n, ok := g.nodes[name]
+	if !ok {
+		n = &CallGraphNode{Function: name}
+		g.nodes[name] = n
+	}
+	return n
+}
+
+func (g *callGraph) edge(caller, callee string) *CallGraphEdge {
+	k := edgeKey{caller, callee}
+	e, ok := g.edges[k]
+	if !ok {
+		e = &CallGraphEdge{Caller: caller, Callee: callee}
+		g.edges[k] = e
+	}
+	return e
+}
+
+// graphFrames returns a stack's function names, innermost first, optionally without
+// allocator plumbing (the innermost frame is kept when every frame is plumbing)
+func (ma *MemoryAnalyzer) graphFrames(stack, function string, collapsePlumbing bool) []string {
+	frames := parseCallStack(stack)
+	if len(frames) == 0 && function != "" {
+		frames = []string{function}
+	}
+
+	var names []string
+	for _, f := range frames {
+		if collapsePlumbing && ma.config != nil && ma.config.plumbing.isPlumbing(f) {
+			continue
+		}
+		name := frameSymbol(f)
+		// Adjacent identical frames are direct recursion; one node is enough
+		if len(names) > 0 && names[len(names)-1] == name {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 && len(frames) > 0 {
+		names = []string{frameSymbol(frames[0])}
+	}
+	return names
+}
+
+// buildCallGraph parses every leak call stack and call tree into one graph. Leak stacks
+// weight it by leaked bytes, call trees by allocated bytes.
+func (ma *MemoryAnalyzer) buildCallGraph(collapsePlumbing bool) *callGraph {
+	g := &callGraph{
+		nodes: make(map[string]*CallGraphNode),
+		edges: make(map[edgeKey]*CallGraphEdge),
+	}
+
+	for _, leak := range ma.data.Leaks {
+		names := ma.graphFrames(leak.CallStack, leak.FunctionName, collapsePlumbing)
+		if len(names) == 0 {
+			continue
+		}
+		g.node(names[0]).SelfLeakedBytes += leak.LeakSize
+
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				n := g.node(name)
+				n.LeakedBytes += leak.LeakSize
+				n.LeakStacks++
+			}
+		}
+		seenEdges := make(map[edgeKey]bool)
+		for i := len(names) - 1; i > 0; i-- {
+			k := edgeKey{names[i], names[i-1]}
+			if !seenEdges[k] {
+				seenEdges[k] = true
+				e := g.edge(k.caller, k.callee)
+				e.LeakedBytes += leak.LeakSize
+				e.LeakStacks++
+			}
+		}
+	}
+	if len(ma.data.Leaks) > 0 {
+		g.sources = append(g.sources, "leaks")
+	}
+
+	// Plumbing nodes of a tree are folded into their caller; a function already on the
+	// path (recursion) does not count its bytes again
+	var walk func(t CallTree, parent string, onPath map[string]bool)
+	walk = func(t CallTree, parent string, onPath map[string]bool) {
+		name := frameSymbol(t.FunctionName)
+		if collapsePlumbing && parent != "" && ma.config != nil && ma.config.plumbing.isPlumbing(t.FunctionName) {
+			name = parent
+		}
+		inclusive := t.InclusiveSize
+		if inclusive == 0 {
+			inclusive = t.TotalSize
+		}
+
+		n := g.node(name)
+		n.SelfAllocatedBytes += t.SelfSize
+		entered := !onPath[name]
+		if entered {
+			n.AllocatedBytes += inclusive
+			if parent != "" {
+				g.edge(parent, name).AllocatedBytes += inclusive
+			}
+			onPath[name] = true
+		}
+		for _, child := range t.Children {
+			walk(child, name, onPath)
+		}
+		if entered {
+			delete(onPath, name)
+		}
+	}
+	for _, t := range ma.data.CallTrees {
+		walk(t, "", make(map[string]bool))
+	}
+	if len(ma.data.CallTrees) > 0 {
+		g.sources = append(g.sources, "call_trees")
+	}
+
+	return g
+}
+
+// CallGraph exports the call graph, keeping the maxNodes heaviest functions with at least
+// minBytes leaked or allocated and the edges between them
+func (ma *MemoryAnalyzer) CallGraph(collapsePlumbing bool, maxNodes int, minBytes int64) *CallGraph {
+	if maxNodes <= 0 {
+		maxNodes = defaultGraphNodes
+	}
+
+	g := ma.buildCallGraph(collapsePlumbing)
+	result := &CallGraph{Sources: g.sources, Nodes: []CallGraphNode{}, Edges: []CallGraphEdge{}, Roots: []string{}}
+
+	nodes := make([]CallGraphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		if nodeWeight(n.LeakedBytes, n.AllocatedBytes) >= minBytes {
+			nodes = append(nodes, *n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		wi, wj := nodeWeight(nodes[i].LeakedBytes, nodes[i].AllocatedBytes), nodeWeight(nodes[j].LeakedBytes, nodes[j].AllocatedBytes)
+		if wi != wj {
+			return wi > wj
+		}
+		return nodes[i].Function < nodes[j].Function
+	})
+	result.OmittedNodes = len(g.nodes) - min(len(nodes), maxNodes)
+	result.Nodes = append(result.Nodes, nodes[:min(len(nodes), maxNodes)]...)
+
+	kept := make(map[string]bool, len(result.Nodes))
+	for _, n := range result.Nodes {
+		kept[n.Function] = true
+	}
+	hasCaller := make(map[string]bool)
+	for k, e := range g.edges {
+		if kept[k.caller] && kept[k.callee] {
+			result.Edges = append(result.Edges, *e)
+			if k.caller != k.callee {
+				hasCaller[k.callee] = true
+			}
+		}
+	}
+	sort.Slice(result.Edges, func(i, j int) bool {
+		a, b := result.Edges[i], result.Edges[j]
+		wa, wb := nodeWeight(a.LeakedBytes, a.AllocatedBytes), nodeWeight(b.LeakedBytes, b.AllocatedBytes)
+		if wa != wb {
+			return wa > wb
+		}
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		return a.Callee < b.Callee
+	})
+
+	for _, n := range result.Nodes {
+		if !hasCaller[n.Function] {
+			result.Roots = append(result.Roots, n.Function)
+		}
+	}
+	return result
+}
+
+// entryFrames are functions where execution of a program or thread begins
+var entryFrames = []string{
+	"main", "wmain", "WinMain", "wWinMain", "DllMain",
+	"mainCRTStartup", "wmainCRTStartup", "WinMainCRTStartup", "__libc_start_main", "_start",
+	"BaseThreadInitThunk", "RtlUserThreadStart", "start_thread", "thread_start", "_pthread_start",
+}
+
+// maxPathEntries is how many entry points call_path reports paths from
+const maxPathEntries = 5
+
+// PathStep is one function on a call path; sizes are those of the edge into it
+type PathStep struct {
+	Function       string `json:"function"`
+	LeakedBytes    int64  `json:"leaked_bytes,omitempty"`
+	AllocatedBytes int64  `json:"allocated_bytes,omitempty"`
+}
+
+// CallPath is a path from an entry point to the target function
+type CallPath struct {
+	Entry      string     `json:"entry"`
+	Hops       int        `json:"hops"`
+	Bottleneck int64      `json:"bottleneck_bytes"` // Smallest edge weight on the path
+	Steps      []PathStep `json:"steps"`
+}
+
+// CallPathResult answers how a function is reached from the program's entry points
+type CallPathResult struct {
+	Target string     `json:"target"`
+	Mode   string     `json:"mode"`   // shortest or heaviest
+	Weight string     `json:"weight"` // leaked or allocated
+	Paths  []CallPath `json:"paths"`
+}
+
+// resolveFunction finds a graph node by exact name, else ignoring case, else by a unique
+// case-insensitive substring
+func (g *callGraph) resolveFunction(name string) (string, error) {
+	if _, ok := g.nodes[name]; ok {
+		return name, nil
+	}
+	var matches []string
+	lower := strings.ToLower(name)
+	for n := range g.nodes {
+		if strings.EqualFold(n, name) {
+			return n, nil
+		}
+		if strings.Contains(strings.ToLower(n), lower) {
+			matches = append(matches, n)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("function %q is not in any call stack", name)
+	case 1:
+		return matches[0], nil
+	}
+	if len(matches) > 5 {
+		matches = append(matches[:5], "...")
+	}
+	return "", fmt.Errorf("function %q is ambiguous: %s", name, strings.Join(matches, ", "))
+}
+
+// edgeWeight returns an edge's leaked or allocated bytes
+func edgeWeight(e *CallGraphEdge, weight string) int64 {
+	if weight == "allocated" {
+		return e.AllocatedBytes
+	}
+	return e.LeakedBytes
+}
+
+// entries returns the graph's entry points: known entry functions and functions with no callers
+func (g *callGraph) entries() []string {
+	hasCaller := make(map[string]bool)
+	for k := range g.edges {
+		if k.caller != k.callee {
+			hasCaller[k.callee] = true
+		}
+	}
+	var entries []string
+	for name := range g.nodes {
+		if !hasCaller[name] || slices.Contains(entryFrames, name) {
+			entries = append(entries, name)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// path searches from entry to target over edges carrying weight. The shortest mode
+// minimizes hops; the heaviest mode maximizes the bytes carried by the path's weakest edge,
+// then minimizes hops. It returns nil when target is unreachable.
+func (g *callGraph) path(entry, target, mode, weight string) *CallPath {
+	callees := make(map[string][]*CallGraphEdge)
+	for k, e := range g.edges {
+		if k.caller != k.callee && edgeWeight(e, weight) > 0 {
+			callees[k.caller] = append(callees[k.caller], e)
+		}
+	}
+
+	type state struct {
+		width int64
+		hops  int
+		prev  string
+	}
+	const unbounded = int64(1<<63 - 1)
+	best := map[string]state{entry: {width: unbounded}}
+	done := make(map[string]bool)
+	better := func(a, b state) bool {
+		if mode == "heaviest" && a.width != b.width {
+			return a.width > b.width
+		}
+		return a.hops < b.hops
+	}
+
+	for {
+		current, found := "", false
+		for name, s := range best {
+			if done[name] {
+				continue
+			}
+			if !found || better(s, best[current]) || (!better(best[current], s) && name < current) {
+				current, found = name, true
+			}
+		}
+		if !found || current == target {
+			break
+		}
+		done[current] = true
+		for _, e := range callees[current] {
+			next := state{width: min(best[current].width, edgeWeight(e, weight)), hops: best[current].hops + 1, prev: current}
+			if s, ok := best[e.Callee]; !done[e.Callee] && (!ok || better(next, s)) {
+				best[e.Callee] = next
+			}
+		}
+	}
+
+	s, ok := best[target]
+	if !ok || target == entry {
+		return nil
+	}
+	p := &CallPath{Entry: entry, Hops: s.hops, Bottleneck: s.width}
+	for name := target; name != entry; name = best[name].prev {
+		e := g.edges[edgeKey{best[name].prev, name}]
+		p.Steps = append(p.Steps, PathStep{Function: name, LeakedBytes: e.LeakedBytes, AllocatedBytes: e.AllocatedBytes})
+	}
+	p.Steps = append(p.Steps, PathStep{Function: entry})
+	slices.Reverse(p.Steps)
+	return p
+}
+
+// CallPath returns, for each entry point that reaches target, the shortest or heaviest call
+// path to it. weight is leaked or allocated; empty picks leaked bytes when the capture has leaks.
+func (ma *MemoryAnalyzer) CallPath(target, mode, weight string, collapsePlumbing bool) (*CallPathResult, error) {
+	if mode == "" {
+		mode = "shortest"
+	}
+	if mode != "shortest" && mode != "heaviest" {
+		return nil, fmt.Errorf("unknown mode %q (use shortest or heaviest)", mode)
+	}
+	if weight == "" {
+		weight = "allocated"
+		if len(ma.data.Leaks) > 0 {
+			weight = "leaked"
+		}
+	}
+	if weight != "leaked" && weight != "allocated" {
+		return nil, fmt.Errorf("unknown weight %q (use leaked or allocated)", weight)
+	}
+
+	g := ma.buildCallGraph(collapsePlumbing)
+	name, err := g.resolveFunction(target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CallPathResult{Target: name, Mode: mode, Weight: weight, Paths: []CallPath{}}
+	for _, entry := range g.entries() {
+		if p := g.path(entry, name, mode, weight); p != nil {
+			result.Paths = append(result.Paths, *p)
+		}
+	}
+	sort.SliceStable(result.Paths, func(i, j int) bool {
+		a, b := result.Paths[i], result.Paths[j]
+		if mode == "heaviest" && a.Bottleneck != b.Bottleneck {
+			return a.Bottleneck > b.Bottleneck
+		}
+		if a.Hops != b.Hops {
+			return a.Hops < b.Hops
+		}
+		return a.Bottleneck > b.Bottleneck
+	})
+	result.Paths = result.Paths[:min(len(result.Paths), maxPathEntries)]
+	return result, nil
+}
+
+// defaultNeighborTop is how many callers or callees are listed
+const defaultNeighborTop = 10
+
+// FunctionLink is a direct caller or callee and the bytes flowing through the call
+type FunctionLink struct {
+	Function       string  `json:"function"`
+	LeakedBytes    int64   `json:"leaked_bytes"`
+	LeakShare      float64 `json:"leak_share"` // Percentage of the function's inclusive leaked bytes
+	AllocatedBytes int64   `json:"allocated_bytes"`
+	AllocatedShare float64 `json:"allocated_share"` // Percentage of the function's inclusive allocated bytes
+	LeakStacks     int     `json:"leak_stacks"`
+}
+
+// NeighborReport lists a function's direct callers or callees, heaviest first
+type NeighborReport struct {
+	Function           string         `json:"function"`
+	Direction          string         `json:"direction"` // callers or callees
+	LeakedBytes        int64          `json:"leaked_bytes"`
+	SelfLeakedBytes    int64          `json:"self_leaked_bytes"`
+	AllocatedBytes     int64          `json:"allocated_bytes"`
+	SelfAllocatedBytes int64          `json:"self_allocated_bytes"`
+	Links              []FunctionLink `json:"links"`
+	Omitted            int            `json:"omitted,omitempty"`
+}
+
+// neighbors aggregates the edges into (callers) or out of (callees) target over all stacks
+// and call trees. Recursive calls of a function to itself are left out.
+func (ma *MemoryAnalyzer) neighbors(target string, callers, collapsePlumbing bool, top int) (*NeighborReport, error) {
+	if top <= 0 {
+		top = defaultNeighborTop
+	}
+
+	g := ma.buildCallGraph(collapsePlumbing)
+	name, err := g.resolveFunction(target)
+	if err != nil {
+		return nil, err
+	}
+	n := g.nodes[name]
+	report := &NeighborReport{
+		Function:           name,
+		Direction:          "callees",
+		LeakedBytes:        n.LeakedBytes,
+		SelfLeakedBytes:    n.SelfLeakedBytes,
+		AllocatedBytes:     n.AllocatedBytes,
+		SelfAllocatedBytes: n.SelfAllocatedBytes,
+		Links:              []FunctionLink{},
+	}
+	if callers {
+		report.Direction = "callers"
+	}
+
+	for k, e := range g.edges {
+		if k.caller == k.callee {
+			continue
+		}
+		other := ""
+		switch {
+		case callers && k.callee == name:
+			other = k.caller
+		case !callers && k.caller == name:
+			other = k.callee
+		default:
+			continue
+		}
+		link := FunctionLink{Function: other, LeakedBytes: e.LeakedBytes, AllocatedBytes: e.AllocatedBytes, LeakStacks: e.LeakStacks}
+		if n.LeakedBytes > 0 {
+			link.LeakShare = float64(e.LeakedBytes) / float64(n.LeakedBytes) * 100
+		}
+		if n.AllocatedBytes > 0 {
+			link.AllocatedShare = float64(e.AllocatedBytes) / float64(n.AllocatedBytes) * 100
+		}
+		report.Links = append(report.Links, link)
+	}
+	// Callees are ranked by inclusive allocated bytes, leaked bytes breaking ties
+	sort.Slice(report.Links, func(i, j int) bool {
+		a, b := report.Links[i], report.Links[j]
+		if !callers && a.AllocatedBytes != b.AllocatedBytes {
+			return a.AllocatedBytes > b.AllocatedBytes
+		}
+		wa, wb := nodeWeight(a.LeakedBytes, a.AllocatedBytes), nodeWeight(b.LeakedBytes, b.AllocatedBytes)
+		if wa != wb {
+			return wa > wb
+		}
+		return a.Function < b.Function
+	})
+	report.Omitted = max(len(report.Links)-top, 0)
+	report.Links = report.Links[:min(len(report.Links), top)]
+	return report, nil
+}
+
+// CallersOf returns the direct callers of a function with the share of its bytes each asks for
+func (ma *MemoryAnalyzer) CallersOf(target string, collapsePlumbing bool, top int) (*NeighborReport, error) {
+	return ma.neighbors(target, true, collapsePlumbing, top)
+}
+
+// CalleesOf returns the direct callees of a function weighted by the bytes allocated under each
+func (ma *MemoryAnalyzer) CalleesOf(target string, collapsePlumbing bool, top int) (*NeighborReport, error) {
+	return ma.neighbors(target, false, collapsePlumbing, top)
+}
@@ -0,0 +1,223 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultTreeDepth is how many levels get_call_tree returns, counting the starting nodes
+const defaultTreeDepth = 3
+
+// CallTreeNode is a call tree entry as returned by get_call_tree. Path is the node's
+// position in the capture's tree, usable as the path parameter to drill down from it.
+type CallTreeNode struct {
+	Path             string         `json:"path"`
+	Function         string         `json:"function"`
+	File             string         `json:"file,omitempty"`
+	Line             int            `json:"line,omitempty"`
+	AllocationCount  int            `json:"allocation_count"`
+	SelfSize         int64          `json:"self_size"`
+	InclusiveSize    int64          `json:"inclusive_size"`
+	Children         []CallTreeNode `json:"children,omitempty"`
+	OmittedChildren  int            `json:"omitted_children,omitempty"`   // Below min_bytes or past the depth limit
+	OmittedBytes     int64          `json:"omitted_bytes,omitempty"`      // Inclusive size of the omitted children
+	TruncatedByDepth bool           `json:"truncated_by_depth,omitempty"` // Has children that the depth limit cut off
+}
+
+// CallTreeView is a depth-limited slice of the capture's call trees
+type CallTreeView struct {
+	Path         string         `json:"path,omitempty"` // Node the view starts at; empty for the roots
+	Depth        int            `json:"depth"`
+	MinBytes     int64          `json:"min_bytes,omitempty"`
+	Nodes        []CallTreeNode `json:"nodes"`
+	OmittedRoots int            `json:"omitted_roots,omitempty"`
+}
+
+// treeInclusive returns a node's inclusive size, falling back to TotalSize for captures
+// that do not record it
+func treeInclusive(t CallTree) int64 {
+	if t.InclusiveSize != 0 {
+		return t.InclusiveSize
+	}
+	return t.TotalSize
+}
+
+// parseTreePath parses a slash-separated list of child indexes such as "0/2/1"
+func parseTreePath(path string) ([]int, error) {
+	var indexes []int
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		i, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid path %q: expected child indexes like 0/2/1", path)
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+// joinTreePath formats child indexes as a path
+func joinTreePath(indexes []int) string {
+	parts := make([]string, len(indexes))
+	for i, index := range indexes {
+		parts[i] = strconv.Itoa(index)
+	}
+	return strings.Join(parts, "/")
+}
+
+// CallTree returns depth levels of the call trees, starting at the roots or at the
+// node named by path. Children smaller than minBytes inclusive are counted, not listed.
+func (ma *MemoryAnalyzer) CallTree(path string, depth int, minBytes int64) (*CallTreeView, error) {
+	if len(ma.data.CallTrees) == 0 {
+		return nil, fmt.Errorf("capture has no call trees")
+	}
+	if depth <= 0 {
+		depth = defaultTreeDepth
+	}
+
+	view := &CallTreeView{Depth: depth, MinBytes: minBytes, Nodes: []CallTreeNode{}}
+	if path == "" {
+		for i, t := range ma.data.CallTrees {
+			if treeInclusive(t) < minBytes {
+				view.OmittedRoots++
+				continue
+			}
+			view.Nodes = append(view.Nodes, callTreeNode(t, strconv.Itoa(i), depth-1, minBytes))
+		}
+		sortTreeNodes(view.Nodes)
+		return view, nil
+	}
+
+	indexes, err := parseTreePath(path)
+	if err != nil {
+		return nil, err
+	}
+	level := ma.data.CallTrees
+	var node CallTree
+	for n, i := range indexes {
+		if i >= len(level) {
+			if n == 0 {
+				return nil, fmt.Errorf("path %q: capture has %d root trees", path, len(level))
+			}
+			return nil, fmt.Errorf("path %q: node %s has %d children", path, joinTreePath(indexes[:n]), len(level))
+		}
+		node = level[i]
+		level = node.Children
+	}
+
+	view.Path = joinTreePath(indexes)
+	view.Nodes = append(view.Nodes, callTreeNode(node, view.Path, depth-1, minBytes))
+	return view, nil
+}
+
+// callTreeNode converts t and its children down to depth more levels
+func callTreeNode(t CallTree, path string, depth int, minBytes int64) CallTreeNode {
+	n := CallTreeNode{
+		Path:            path,
+		Function:        t.FunctionName,
+		File:            t.FileName,
+		Line:            t.LineNumber,
+		AllocationCount: t.AllocationCount,
+		SelfSize:        t.SelfSize,
+		InclusiveSize:   treeInclusive(t),
+	}
+	for i, child := range t.Children {
+		if depth <= 0 || treeInclusive(child) < minBytes {
+			n.OmittedChildren++
+			n.OmittedBytes += treeInclusive(child)
+			n.TruncatedByDepth = n.TruncatedByDepth || depth <= 0
+			continue
+		}
+		n.Children = append(n.Children, callTreeNode(child, path+"/"+strconv.Itoa(i), depth-1, minBytes))
+	}
+	sortTreeNodes(n.Children)
+	return n
+}
+
+// sortTreeNodes orders siblings by inclusive size, largest first; paths keep the
+// capture's order so they stay valid
+func sortTreeNodes(nodes []CallTreeNode) {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].InclusiveSize > nodes[j].InclusiveSize })
+}
+
+// defaultHotPaths is how many paths get_hot_paths returns
+const defaultHotPaths = 10
+
+// HotPath is a root-to-leaf call tree path and the bytes allocated through it
+type HotPath struct {
+	Path            string  `json:"path"`  // Position of the leaf, usable with get_call_tree
+	Chain           string  `json:"chain"` // Root first, e.g. main -> LoadLevel -> malloc
+	Function        string  `json:"function"`
+	File            string  `json:"file,omitempty"`
+	Line            int     `json:"line,omitempty"`
+	AllocationCount int     `json:"allocation_count"`
+	InclusiveSize   int64   `json:"inclusive_size"`
+	Share           float64 `json:"share"` // Percent of the bytes in all call trees
+}
+
+// HotPaths is the result of get_hot_paths
+type HotPaths struct {
+	TotalPaths int       `json:"total_paths"`
+	TotalBytes int64     `json:"total_bytes"`
+	Paths      []HotPath `json:"paths"`
+}
+
+// HotPaths returns the top root-to-leaf paths of the call trees by the leaf's inclusive
+// size. Plumbing frames other than the root and the leaf are left out of the chains when
+// asked; paths smaller than minBytes are not counted.
+func (ma *MemoryAnalyzer) HotPaths(top int, minBytes int64, collapsePlumbing bool) (*HotPaths, error) {
+	if len(ma.data.CallTrees) == 0 {
+		return nil, fmt.Errorf("capture has no call trees")
+	}
+	if top <= 0 {
+		top = defaultHotPaths
+	}
+
+	result := &HotPaths{Paths: []HotPath{}}
+	for _, t := range ma.data.CallTrees {
+		result.TotalBytes += treeInclusive(t)
+	}
+
+	var walk func(t CallTree, indexes []int, frames []string)
+	walk = func(t CallTree, indexes []int, frames []string) {
+		frames = frames[:len(frames):len(frames)]
+		plumbing := collapsePlumbing && len(frames) > 0 && len(t.Children) > 0 && ma.config != nil && ma.config.plumbing.isPlumbing(t.FunctionName)
+		if !plumbing {
+			frames = append(frames, t.FunctionName)
+		}
+		if len(t.Children) > 0 {
+			for i, child := range t.Children {
+				walk(child, append(indexes[:len(indexes):len(indexes)], i), frames)
+			}
+			return
+		}
+
+		size := treeInclusive(t)
+		if size < minBytes {
+			return
+		}
+		path := HotPath{
+			Path:            joinTreePath(indexes),
+			Chain:           strings.Join(frames, " -> "),
+			Function:        t.FunctionName,
+			File:            t.FileName,
+			Line:            t.LineNumber,
+			AllocationCount: t.AllocationCount,
+			InclusiveSize:   size,
+		}
+		if result.TotalBytes > 0 {
+			path.Share = math.Round(float64(size)/float64(result.TotalBytes)*10000) / 100
+		}
+		result.Paths = append(result.Paths, path)
+	}
+	for i, t := range ma.data.CallTrees {
+		walk(t, []int{i}, nil)
+	}
+
+	result.TotalPaths = len(result.Paths)
+	sort.SliceStable(result.Paths, func(i, j int) bool { return result.Paths[i].InclusiveSize > result.Paths[j].InclusiveSize })
+	result.Paths = result.Paths[:min(len(result.Paths), top)]
+	return result, nil
+}
@@ -0,0 +1,292 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxFunctionCandidates limits the "did you mean" list when a function is not found
+const maxFunctionCandidates = 5
+
+// FunctionFootprint is one function's allocations and leaks in a single capture
+type FunctionFootprint struct {
+	Session         string `json:"session"`
+	Found           bool   `json:"found"`
+	AllocationCount int    `json:"allocation_count"`
+	TotalSize       int64  `json:"total_size"`
+	LeakCount       int    `json:"leak_count"`
+	LeakSize        int64  `json:"leak_size"`
+}
+
+// FootprintDelta is after minus before; percentages are omitted when the before value is zero
+type FootprintDelta struct {
+	AllocationCount  int      `json:"allocation_count"`
+	TotalSize        int64    `json:"total_size"`
+	TotalSizePercent *float64 `json:"total_size_percent,omitempty"`
+	LeakCount        int      `json:"leak_count"`
+	LeakSize         int64    `json:"leak_size"`
+	LeakSizePercent  *float64 `json:"leak_size_percent,omitempty"`
+}
+
+// FunctionComparison reports how a function's footprint changed between two captures
+type FunctionComparison struct {
+	Function  string            `json:"function"`
+	Before    FunctionFootprint `json:"before"`
+	After     FunctionFootprint `json:"after"`
+	Delta     FootprintDelta    `json:"delta"`
+	Verdict   string            `json:"verdict"`   // improved, regressed, unchanged, or mixed
+	Tolerance string            `json:"tolerance"` // Profile whose tolerated changes count as unchanged
+	Summary   string            `json:"summary"`
+}
+
+// FunctionFootprint totals a function's allocation statistics and the leaks attributed to it.
+// Leaks count when the function allocated them directly or is blamed past allocator plumbing.
+func (ma *MemoryAnalyzer) FunctionFootprint(name string) FunctionFootprint {
+	fp := FunctionFootprint{}
+	if ma == nil || ma.data == nil {
+		return fp
+	}
+	fp.Session = ma.data.SessionName
+
+	idx := ma.index()
+	for _, i := range idx.functionsByName[name] {
+		fn := ma.data.Functions[i]
+		fp.Found = true
+		fp.AllocationCount += fn.AllocationCount
+		fp.TotalSize += fn.TotalSize
+	}
+	for _, i := range idx.leaksByFunction[name] {
+		leak := ma.data.Leaks[i]
+		fp.Found = true
+		fp.LeakCount += leak.LeakCount
+		fp.LeakSize += leak.LeakSize
+	}
+	return fp
+}
+
+// functionCandidates returns known function names containing query, case-insensitively
+func (ma *MemoryAnalyzer) functionCandidates(query string) []string {
+	query = strings.ToLower(query)
+	seen := make(map[string]bool)
+	consider := func(name string) {
+		if name != "" && strings.Contains(strings.ToLower(name), query) {
+			seen[name] = true
+		}
+	}
+	idx := ma.index()
+	for name := range idx.functionsByName {
+		consider(name)
+	}
+	for name := range idx.leaksByFunction {
+		consider(name)
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names[:min(len(names), maxFunctionCandidates)]
+}
+
+// CompareFunction reports a function's footprint in two captures with deltas. The verdict
+// ignores changes within the tolerance profile.
+func CompareFunction(name string, before, after *MemoryAnalyzer, tol *ToleranceProfile) (*FunctionComparison, error) {
+	c := &FunctionComparison{
+		Function:  name,
+		Before:    before.FunctionFootprint(name),
+		After:     after.FunctionFootprint(name),
+		Tolerance: tol.Name,
+	}
+
+	if !c.Before.Found && !c.After.Found {
+		candidates := append(before.functionCandidates(name), after.functionCandidates(name)...)
+		sort.Strings(candidates)
+		candidates = dedupeSorted(candidates)
+		if len(candidates) > 0 {
+			return nil, fmt.Errorf("function %q not found in either capture; similar names: %s", name, strings.Join(candidates, ", "))
+		}
+		return nil, fmt.Errorf("function %q not found in either capture", name)
+	}
+
+	c.Delta = FootprintDelta{
+		AllocationCount:  c.After.AllocationCount - c.Before.AllocationCount,
+		TotalSize:        c.After.TotalSize - c.Before.TotalSize,
+		TotalSizePercent: percentChange(c.Before.TotalSize, c.After.TotalSize),
+		LeakCount:        c.After.LeakCount - c.Before.LeakCount,
+		LeakSize:         c.After.LeakSize - c.Before.LeakSize,
+		LeakSizePercent:  percentChange(c.Before.LeakSize, c.After.LeakSize),
+	}
+
+	leakDelta := tol.significant("leak_size", c.Before.LeakSize, c.After.LeakSize)
+	totalDelta := tol.significant("total_size", c.Before.TotalSize, c.After.TotalSize)
+	switch {
+	case leakDelta == 0 && totalDelta == 0:
+		c.Verdict = "unchanged"
+	case leakDelta <= 0 && totalDelta <= 0:
+		c.Verdict = "improved"
+	case leakDelta >= 0 && totalDelta >= 0:
+		c.Verdict = "regressed"
+	default:
+		c.Verdict = "mixed"
+	}
+
+	c.Summary = fmt.Sprintf("%s: leak size %s -> %s (%s), total size %s -> %s (%s)",
+		name,
+		FormatBytes(c.Before.LeakSize), FormatBytes(c.After.LeakSize), formatDelta(c.Delta.LeakSize, c.Delta.LeakSizePercent),
+		FormatBytes(c.Before.TotalSize), FormatBytes(c.After.TotalSize), formatDelta(c.Delta.TotalSize, c.Delta.TotalSizePercent))
+	if !c.Before.Found {
+		c.Summary += "; not present in the before capture"
+	} else if !c.After.Found {
+		c.Summary += "; not present in the after capture"
+	}
+
+	return c, nil
+}
+
+// defaultGrowthCount is how many functions top_growth_between lists
+const defaultGrowthCount = 10
+
+// FunctionGrowth is the growth of one function's total allocated size between two captures
+type FunctionGrowth struct {
+	Function             string   `json:"function"`
+	BeforeSize           int64    `json:"before_size"`
+	AfterSize            int64    `json:"after_size"`
+	Growth               int64    `json:"growth"`
+	GrowthPercent        *float64 `json:"growth_percent,omitempty"`
+	AllocationCountDelta int      `json:"allocation_count_delta"`
+	New                  bool     `json:"new,omitempty"`     // Absent from the before capture
+	Removed              bool     `json:"removed,omitempty"` // Absent from the after capture
+}
+
+// GrowthReport lists the functions whose total memory grew most between two captures
+type GrowthReport struct {
+	BeforeSession string           `json:"before_session"`
+	AfterSession  string           `json:"after_session"`
+	TotalBefore   int64            `json:"total_before"`
+	TotalAfter    int64            `json:"total_after"`
+	TotalGrowth   int64            `json:"total_growth"`
+	Growing       int              `json:"growing"` // Functions that grew at all
+	Functions     []FunctionGrowth `json:"functions"`
+}
+
+// functionTotals sums total size and allocation count per function name
+func (ma *MemoryAnalyzer) functionTotals() map[string]Function {
+	byName := ma.index().functionsByName
+	totals := make(map[string]Function, len(byName))
+	for name, positions := range byName {
+		var t Function
+		for _, i := range positions {
+			t.TotalSize += ma.data.Functions[i].TotalSize
+			t.AllocationCount += ma.data.Functions[i].AllocationCount
+		}
+		totals[name] = t
+	}
+	return totals
+}
+
+// functionChanges returns every function whose total allocated size differs between two
+// sets of function totals, including functions present in only one of them
+func functionChanges(before, after map[string]Function) []FunctionGrowth {
+	var changes []FunctionGrowth
+	for name, fn := range after {
+		b, found := before[name]
+		if fn.TotalSize == b.TotalSize {
+			continue
+		}
+		changes = append(changes, FunctionGrowth{
+			Function:             name,
+			BeforeSize:           b.TotalSize,
+			AfterSize:            fn.TotalSize,
+			Growth:               fn.TotalSize - b.TotalSize,
+			GrowthPercent:        percentChange(b.TotalSize, fn.TotalSize),
+			AllocationCountDelta: fn.AllocationCount - b.AllocationCount,
+			New:                  !found,
+		})
+	}
+	for name, b := range before {
+		if _, found := after[name]; found || b.TotalSize == 0 {
+			continue
+		}
+		changes = append(changes, FunctionGrowth{
+			Function:             name,
+			BeforeSize:           b.TotalSize,
+			Growth:               -b.TotalSize,
+			GrowthPercent:        percentChange(b.TotalSize, 0),
+			AllocationCountDelta: -b.AllocationCount,
+			Removed:              true,
+		})
+	}
+	return changes
+}
+
+// TopGrowth returns the n functions whose total allocated size (not just leaks) grew most
+func TopGrowth(before, after *MemoryAnalyzer, n int) (*GrowthReport, error) {
+	if n <= 0 {
+		n = defaultGrowthCount
+	}
+	if len(before.data.Functions) == 0 || len(after.data.Functions) == 0 {
+		return nil, fmt.Errorf("both captures need function statistics")
+	}
+
+	beforeTotals, afterTotals := before.functionTotals(), after.functionTotals()
+	report := &GrowthReport{
+		BeforeSession: before.data.SessionName,
+		AfterSession:  after.data.SessionName,
+		Functions:     []FunctionGrowth{},
+	}
+	for _, fn := range beforeTotals {
+		report.TotalBefore += fn.TotalSize
+	}
+	for _, fn := range afterTotals {
+		report.TotalAfter += fn.TotalSize
+	}
+	for _, change := range functionChanges(beforeTotals, afterTotals) {
+		if change.Growth > 0 {
+			report.Functions = append(report.Functions, change)
+		}
+	}
+	report.TotalGrowth = report.TotalAfter - report.TotalBefore
+	report.Growing = len(report.Functions)
+
+	sort.Slice(report.Functions, func(i, j int) bool {
+		if report.Functions[i].Growth != report.Functions[j].Growth {
+			return report.Functions[i].Growth > report.Functions[j].Growth
+		}
+		return report.Functions[i].Function < report.Functions[j].Function
+	})
+	report.Functions = report.Functions[:min(len(report.Functions), n)]
+	return report, nil
+}
+
+func percentChange(before, after int64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	p := float64(after-before) / float64(before) * 100
+	return &p
+}
+
+// formatDelta renders a signed size change, e.g. "-1.50 KB, -25.0%"
+func formatDelta(delta int64, percent *float64) string {
+	sign := "+"
+	if delta < 0 {
+		sign, delta = "-", -delta
+	}
+	s := sign + FormatBytes(delta)
+	if percent != nil {
+		s += fmt.Sprintf(", %+.1f%%", *percent)
+	}
+	return s
+}
+
+func dedupeSorted(values []string) []string {
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,335 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// Config holds user-tunable settings loaded from a JSON configuration file
+type Config struct {
+	Rules     []CustomRule `json:"rules"`
+	RulesFile string       `json:"rules_file"`
+	Redact    string       `json:"redact"` // "", "users", or "hash"
+
+	// Leak suggestion rules, checked before the built-in ones; the first match wins
+	SuggestionRules     []SuggestionRule `json:"suggestion_rules"`
+	SuggestionRulesFile string           `json:"suggestion_rules_file"`
+
+	AnonymizeSymbols bool   `json:"anonymize_symbols"`
+	SymbolMapFile    string `json:"symbol_map_file"`
+
+	// Frames treated as allocator plumbing, in addition to (or instead of) the built-in list
+	PlumbingFrames        []string `json:"plumbing_frames"`
+	IgnoreDefaultPlumbing bool     `json:"ignore_default_plumbing"`
+
+	// Third-party libraries issues are attributed to, in addition to (or instead of) the built-in list
+	Libraries              []LibrarySignature `json:"libraries"`
+	IgnoreDefaultLibraries bool               `json:"ignore_default_libraries"`
+
+	// Summarize call stacks to their top application frames by default
+	SummarizeStacks bool `json:"summarize_stacks"`
+	StackFrames     int  `json:"stack_frames"`
+
+	// Local checkout used to show source snippets for captures recorded elsewhere
+	SourceRoot string `json:"source_root"`
+
+	// Source lines embedded around each issue's file and line; zero omits them
+	SourceLines int `json:"source_lines"`
+
+	// Emit shared suggestions once per response instead of on every issue
+	AggregateSuggestions bool `json:"aggregate_suggestions"`
+
+	// Default output caps for issue lists; zero means unlimited
+	MaxItems int `json:"max_items"`
+	MaxBytes int `json:"max_bytes"`
+
+	// Budget per heap name, in bytes or as a size string, overriding budgets recorded in the capture
+	HeapBudgets map[string]ByteSize `json:"heap_budgets"`
+
+	// Budget per allocation tag, in bytes or as a size string; a tag's budget covers its subtags ("Textures" covers "Textures/UI")
+	TagBudgets map[string]ByteSize `json:"tag_budgets"`
+
+	// Budgets per module, each covering the source files under its path prefixes
+	ModuleBudgets []ModuleBudget `json:"module_budgets"`
+
+	// Tolerance profile for comparisons, and profiles defined or overridden per metric
+	ToleranceProfile  string                          `json:"tolerance_profile"`
+	ToleranceProfiles map[string]map[string]Tolerance `json:"tolerance_profiles"`
+
+	// Severity boundaries of the built-in detectors; unset thresholds keep their defaults
+	Thresholds Thresholds `json:"thresholds"`
+
+	// Leak severity rules, first match wins; leaks matching none are classified by Thresholds
+	LeakSeverity []LeakSeverityRule `json:"leak_severity"`
+
+	// Goroutines the issue detectors shard leaks and functions across; zero uses one per CPU
+	AnalysisWorkers int `json:"analysis_workers"`
+
+	// Capture analyzed when a call gives no json_path and MEMPRO_JSON_PATH is unset
+	DefaultJSONPath string `json:"default_json_path"`
+
+	// Resolution of frames recorded as module and offset; nil leaves them as recorded
+	Symbolication *SymbolicationConfig `json:"symbolication"`
+
+	// Known or accepted leaks and large allocations to leave out of the results
+	Suppressions     []Suppression `json:"suppressions"`
+	SuppressionsFile string        `json:"suppressions_file"`
+
+	// Issue trackers export_issues can post to; nil only builds payloads
+	IssueTracker *IssueTrackerConfig `json:"issue_tracker"`
+
+	// Scheduled unattended analysis; nil disables the daemon
+	Daemon *DaemonConfig `json:"daemon"`
+
+	path         string
+	rules        []*compiledRule
+	plumbing     *frameMatcher
+	libraries    []*libraryMatcher
+	suggestions  []*compiledSuggestionRule // Configured suggestion rules, then the built-in ones
+	suppressions []*compiledSuppression
+}
+
+// activeConfig is the configuration applied to newly created analyzers; it may be swapped by hot reload
+var (
+	configMu     sync.RWMutex
+	activeConfig = defaultConfig()
+)
+
+// CurrentConfig returns the active configuration
+func CurrentConfig() *Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return activeConfig
+}
+
+// SetConfig replaces the active configuration; analyzers already created keep their old config
+func SetConfig(cfg *Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	activeConfig = cfg
+}
+
+// defaultConfig returns the configuration used when no config file is given
+func defaultConfig() *Config {
+	plumbing, _ := newPlumbingMatcher(defaultPlumbingFrames)
+	libraries, _ := compileLibraries(defaultLibrarySignatures)
+	suggestions, _ := compileSuggestionRules(defaultSuggestionRules)
+	return &Config{plumbing: plumbing, libraries: libraries, suggestions: suggestions}
+}
+
+// LoadConfig reads a configuration file and compiles any custom rules it references
+func LoadConfig(path string) (*Config, error) {
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := defaultConfig()
+	if err := json.Unmarshal(fileData, cfg); err != nil {
+		if _, bad := findBadSizes(fileData, reflect.TypeOf(cfg)); len(bad) > 0 {
+			line, column := lineColumn(fileData, bad[0].offset)
+			return nil, fmt.Errorf("failed to parse config file %s: line %d, column %d: %s", path, line, column, bad[0].message)
+		}
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	cfg.path = path
+
+	if err := cfg.check(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	cfg.plumbing, _ = newPlumbingMatcher(cfg.plumbingPatterns())
+	cfg.libraries, _ = compileLibraries(cfg.librarySignatures())
+
+	rules := cfg.Rules
+	if cfg.RulesFile != "" {
+		fileRules, err := loadRulesFile(cfg.ResolvePath(cfg.RulesFile))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	cfg.rules = compiled
+
+	suggestionRules := cfg.SuggestionRules
+	if cfg.SuggestionRulesFile != "" {
+		fileRules, err := loadSuggestionRulesFile(cfg.ResolvePath(cfg.SuggestionRulesFile))
+		if err != nil {
+			return nil, err
+		}
+		suggestionRules = append(suggestionRules, fileRules...)
+	}
+
+	suggestions, err := compileSuggestionRules(suggestionRules)
+	if err != nil {
+		return nil, err
+	}
+	cfg.suggestions = append(suggestions, cfg.suggestions...)
+
+	suppressions := cfg.Suppressions
+	if cfg.SuppressionsFile != "" {
+		fileSuppressions, err := loadSuppressionsFile(cfg.ResolvePath(cfg.SuppressionsFile))
+		if err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, fileSuppressions...)
+	}
+
+	if cfg.suppressions, err = compileSuppressions(suppressions); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// check validates settings that JSON decoding alone cannot
+func (c *Config) check() error {
+	if !ValidRedactMode(c.Redact) {
+		return fmt.Errorf("redact: unknown mode %q (expected \"users\" or \"hash\")", c.Redact)
+	}
+	if _, err := newPlumbingMatcher(c.plumbingPatterns()); err != nil {
+		return fmt.Errorf("plumbing_frames: %w", err)
+	}
+	if _, err := compileLibraries(c.Libraries); err != nil {
+		return fmt.Errorf("libraries: %w", err)
+	}
+	if c.StackFrames < 0 {
+		return fmt.Errorf("stack_frames: must not be negative")
+	}
+	if c.SourceLines < 0 {
+		return fmt.Errorf("source_lines: must not be negative")
+	}
+	if c.AnalysisWorkers < 0 {
+		return fmt.Errorf("analysis_workers: must not be negative")
+	}
+	if c.MaxItems < 0 {
+		return fmt.Errorf("max_items: must not be negative")
+	}
+	if c.MaxBytes < 0 {
+		return fmt.Errorf("max_bytes: must not be negative")
+	}
+	for name, budget := range c.HeapBudgets {
+		if budget <= 0 {
+			return fmt.Errorf("heap_budgets: budget for %q must be positive", name)
+		}
+	}
+	for tag, budget := range c.TagBudgets {
+		if budget <= 0 {
+			return fmt.Errorf("tag_budgets: budget for %q must be positive", tag)
+		}
+	}
+	if err := c.checkModuleBudgets(); err != nil {
+		return err
+	}
+	if err := c.Thresholds.check(); err != nil {
+		return err
+	}
+	if err := c.compileLeakSeverityRules(); err != nil {
+		return err
+	}
+	if err := c.checkTolerances(); err != nil {
+		return err
+	}
+	if c.Symbolication != nil {
+		if err := c.Symbolication.check(c); err != nil {
+			return err
+		}
+	}
+	if c.IssueTracker != nil {
+		if err := c.IssueTracker.check(); err != nil {
+			return err
+		}
+	}
+	if c.Daemon != nil {
+		return c.Daemon.check(c)
+	}
+	return nil
+}
+
+// plumbingPatterns returns the configured plumbing frames combined with the built-in list
+func (c *Config) plumbingPatterns() []string {
+	if c.IgnoreDefaultPlumbing {
+		return c.PlumbingFrames
+	}
+	return append(append([]string{}, defaultPlumbingFrames...), c.PlumbingFrames...)
+}
+
+// Path returns the file the configuration was loaded from, or "" for the defaults
+func (c *Config) Path() string {
+	return c.path
+}
+
+// RuleCount returns the number of custom rules compiled from the config and its rules file
+func (c *Config) RuleCount() int {
+	return len(c.rules)
+}
+
+// Redactor returns the redaction to apply to loaded captures; the config overrides --redact
+func (c *Config) Redactor() Redactor {
+	if c.Redact != "" {
+		return Redactor{mode: c.Redact}
+	}
+	return Redactor{mode: DefaultRedactMode}
+}
+
+// SymbolMapPath returns where pseudonyms are stored, or "" if anonymization is off
+func (c *Config) SymbolMapPath() string {
+	if !c.AnonymizeSymbols && !DefaultAnonymize {
+		return ""
+	}
+	switch {
+	case c.SymbolMapFile != "":
+		return c.ResolvePath(c.SymbolMapFile)
+	case DefaultSymbolMapFile != "":
+		return DefaultSymbolMapFile
+	}
+	return UserSymbolMapPath()
+}
+
+// ResolvePath interprets relative paths as relative to the config file's directory
+func (c *Config) ResolvePath(p string) string {
+	if p == "" || filepath.IsAbs(p) || c.path == "" {
+		return p
+	}
+	return filepath.Join(filepath.Dir(c.path), p)
+}
+
+// WatchedFiles lists every file whose contents affect this configuration
+func (c *Config) WatchedFiles() []string {
+	var files []string
+	if c.path != "" {
+		files = append(files, c.path)
+	}
+	if c.RulesFile != "" {
+		files = append(files, c.ResolvePath(c.RulesFile))
+	}
+	if c.SuggestionRulesFile != "" {
+		files = append(files, c.ResolvePath(c.SuggestionRulesFile))
+	}
+	if c.SuppressionsFile != "" {
+		files = append(files, c.ResolvePath(c.SuppressionsFile))
+	}
+	return files
+}
+
+// loadRulesFile reads a standalone JSON array of custom rules
+func loadRulesFile(path string) ([]CustomRule, error) {
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var rules []CustomRule
+	if err := json.Unmarshal(fileData, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
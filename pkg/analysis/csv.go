@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// CSVSections maps each export_csv table to the record arrays it reads; leaks need page
+// views to resolve stack IDs
+var CSVSections = map[string]CaptureSections{
+	"leaks":     SectionLeaks | SectionPageViews | SectionThreads | SectionHeaps,
+	"functions": SectionFunctions,
+	"types":     SectionTypes,
+}
+
+// CSVExport describes a table written to a file by export_csv
+type CSVExport struct {
+	Table  string `json:"table"`
+	Rows   int    `json:"rows"`
+	Output string `json:"output"`
+}
+
+// ExportCSV renders a table of the capture as CSV with a header row. Leaks are ordered most
+// severe first, functions and types largest total size first; limit > 0 keeps that many rows.
+func (ma *MemoryAnalyzer) ExportCSV(table string, limit int) ([]byte, int, error) {
+	var header []string
+	var rows [][]string
+	switch table {
+	case "leaks":
+		header, rows = ma.leakRows()
+	case "functions":
+		header, rows = functionRows(ma.data.Functions)
+	case "types":
+		header, rows = typeRows(ma.data.Types)
+	default:
+		return nil, 0, fmt.Errorf("unknown table %q (want leaks, functions, or types)", table)
+	}
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), len(rows), nil
+}
+
+// leakRows lists each leak with its issue ID and severity, as reported by analyze_leaks
+func (ma *MemoryAnalyzer) leakRows() ([]string, [][]string) {
+	header := []string{"issue_id", "severity", "function", "allocating_function", "file", "line",
+		"size", "count", "occurrences", "score", "suspect", "thread", "heap", "tag", "call_stack"}
+
+	rows := [][]string{}
+	for _, issue := range ma.AnalyzeLeaks() {
+		leak, _ := ma.leakGroup(issue.ID)
+		rows = append(rows, []string{
+			issue.ID, issue.Severity, issue.FunctionName, leak.FunctionName, leak.FileName, strconv.Itoa(leak.LineNumber),
+			strconv.FormatInt(leak.LeakSize, 10), strconv.Itoa(leak.LeakCount), strconv.Itoa(leak.Occurrences), csvFloat(leak.LeakScore),
+			strconv.FormatBool(leak.IsSuspect), ma.threadLabel(leak.ThreadId), ma.heapLabel(leak.HeapId), leak.Tag, leak.CallStack,
+		})
+	}
+	return header, rows
+}
+
+// functionRows lists per-function statistics, largest total size first
+func functionRows(functions []Function) ([]string, [][]string) {
+	header := []string{"function", "file", "line", "allocation_count", "total_size", "average_size",
+		"min_size", "max_size", "percentage", "freed_count", "average_lifetime_ms", "max_lifetime_ms", "heap_id", "tag"}
+
+	sorted := append([]Function{}, functions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TotalSize > sorted[j].TotalSize })
+
+	rows := [][]string{}
+	for _, f := range sorted {
+		rows = append(rows, []string{
+			f.FunctionName, f.FileName, strconv.Itoa(f.LineNumber), strconv.Itoa(f.AllocationCount),
+			strconv.FormatInt(f.TotalSize, 10), csvFloat(f.AverageSize), strconv.FormatInt(f.MinSize, 10),
+			strconv.FormatInt(f.MaxSize, 10), csvFloat(f.Percentage), strconv.Itoa(f.FreedCount),
+			csvFloat(f.AverageLifetime), csvFloat(f.MaxLifetime), strconv.Itoa(f.HeapId), f.Tag,
+		})
+	}
+	return header, rows
+}
+
+// typeRows lists per-type statistics, largest total size first
+func typeRows(types []AllocType) ([]string, [][]string) {
+	header := []string{"type", "allocation_count", "total_size", "average_size", "min_size", "max_size",
+		"percentage", "most_common_function", "most_common_file", "most_common_line", "tag"}
+
+	sorted := append([]AllocType{}, types...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TotalSize > sorted[j].TotalSize })
+
+	rows := [][]string{}
+	for _, t := range sorted {
+		rows = append(rows, []string{
+			t.TypeName, strconv.Itoa(t.AllocationCount), strconv.FormatInt(t.TotalSize, 10), csvFloat(t.AverageSize),
+			strconv.FormatInt(t.MinSize, 10), strconv.FormatInt(t.MaxSize, 10), csvFloat(t.Percentage),
+			t.MostCommonFunction, t.MostCommonFile, strconv.Itoa(t.MostCommonLine), t.Tag,
+		})
+	}
+	return header, rows
+}
+
+// csvFloat formats a float without exponent or trailing zeros, for spreadsheets
+func csvFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// SaveCSV writes an exported table to path
+func SaveCSV(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
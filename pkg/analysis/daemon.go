@@ -0,0 +1,410 @@
+package analysis
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Daemon defaults
+const (
+	daemonTick          = 10 * time.Second
+	daemonTrendFile     = "trend.jsonl"
+	daemonSummaryFile   = "summary.json"
+	defaultDaemonFailOn = "critical"
+)
+
+// verdictRank orders batch verdicts so a gate can fail at a threshold
+var verdictRank = map[string]int{"ok": 0, "warning": 1, "critical": 2}
+
+// DaemonConfig schedules unattended analysis of captures
+type DaemonConfig struct {
+	Path      string `json:"path"`       // Comma-separated capture files, directories, or glob patterns
+	Schedule  string `json:"schedule"`   // Cron expression ("0 2 * * *"), @hourly, @daily, or "@every 30m"
+	OutputDir string `json:"output_dir"` // Reports and the trend log are written here
+	FailOn    string `json:"fail_on"`    // Verdict that fails the gate: critical (default) or warning
+	Tolerance string `json:"tolerance"`  // Profile for the regression gate (default: tolerance_profile)
+}
+
+// check validates a daemon section
+func (d *DaemonConfig) check(c *Config) error {
+	if d.Path == "" {
+		return fmt.Errorf("daemon: path is required")
+	}
+	if d.OutputDir == "" {
+		return fmt.Errorf("daemon: output_dir is required")
+	}
+	if _, err := parseSchedule(d.Schedule); err != nil {
+		return fmt.Errorf("daemon: schedule: %w", err)
+	}
+	if _, ok := verdictRank[d.failOn()]; !ok || d.failOn() == "ok" {
+		return fmt.Errorf("daemon: fail_on: unknown verdict %q (expected \"critical\" or \"warning\")", d.FailOn)
+	}
+	if _, err := c.Tolerance(d.Tolerance); err != nil {
+		return fmt.Errorf("daemon: tolerance: %w", err)
+	}
+	return nil
+}
+
+func (d *DaemonConfig) failOn() string {
+	if d.FailOn == "" {
+		return defaultDaemonFailOn
+	}
+	return d.FailOn
+}
+
+// schedule decides when the daemon runs
+type schedule interface {
+	// due reports whether a run should start at now, given the previous run (zero if none)
+	due(now, last time.Time) bool
+}
+
+// everySchedule runs at a fixed interval, starting immediately
+type everySchedule time.Duration
+
+func (e everySchedule) due(now, last time.Time) bool {
+	return last.IsZero() || now.Sub(last) >= time.Duration(e)
+}
+
+// cronSchedule runs in minutes matching a five-field cron expression
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func (c *cronSchedule) due(now, last time.Time) bool {
+	now = now.Truncate(time.Minute)
+	if !last.IsZero() && !last.Truncate(time.Minute).Before(now) {
+		return false
+	}
+	if c.minute&(1<<now.Minute()) == 0 || c.hour&(1<<now.Hour()) == 0 || c.month&(1<<int(now.Month())) == 0 {
+		return false
+	}
+	// As in cron, a restricted day of month and day of week match when either does
+	dom, dow := c.dom&(1<<now.Day()) != 0, c.dow&(1<<int(now.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// cronShorthands expand the common @ forms
+var cronShorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseSchedule parses "@every <duration>", an @ shorthand, or a five-field cron expression
+// (minute hour day-of-month month day-of-week) with *, lists, ranges, and steps
+func parseSchedule(spec string) (schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("is required")
+	}
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, err
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("interval %s is shorter than one minute", d)
+		}
+		return everySchedule(d), nil
+	}
+	if expanded, ok := cronShorthands[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q: expected 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+	c := &cronSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	bounds := []struct {
+		field    *uint64
+		name     string
+		min, max int
+	}{
+		{&c.minute, "minute", 0, 59},
+		{&c.hour, "hour", 0, 23},
+		{&c.dom, "day of month", 1, 31},
+		{&c.month, "month", 1, 12},
+		{&c.dow, "day of week", 0, 7},
+	}
+	for i, b := range bounds {
+		bits, err := parseCronField(fields[i], b.min, b.max)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.name, err)
+		}
+		*b.field = bits
+	}
+	// Sunday may be written as 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseCronField turns one cron field into a bit set of the values it matches
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(after)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = before, n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// TrendEntry is one capture's totals in one daemon run, stored as a line of the trend log
+type TrendEntry struct {
+	Time             time.Time `json:"time"`
+	Path             string    `json:"path"`
+	Session          string    `json:"session,omitempty"`
+	Verdict          string    `json:"verdict"`
+	TotalAllocations int       `json:"total_allocations"`
+	TotalSize        int64     `json:"total_size"`
+	LeakCount        int       `json:"leak_count"`
+	LeakSize         int64     `json:"leak_size"`
+	Fragmentation    float64   `json:"fragmentation"`
+	Regressions      []string  `json:"regressions,omitempty"` // Totals that grew beyond tolerance since the previous run
+	GateFailed       bool      `json:"gate_failed,omitempty"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// RunDaemon checks the daemon schedule of the active config until the process exits.
+// Config reloads take effect at the next check.
+func RunDaemon(n Logger) {
+	var last time.Time
+	ticker := time.NewTicker(daemonTick)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		cfg := CurrentConfig()
+		if cfg.Daemon == nil {
+			continue
+		}
+		sched, err := parseSchedule(cfg.Daemon.Schedule)
+		if err != nil || !sched.due(now, last) {
+			continue
+		}
+		last = now
+
+		entries, err := runDaemonOnce(cfg, now, n)
+		if err != nil {
+			log.Printf("Daemon run failed: %v", err)
+			n.Log("error", "daemon", fmt.Sprintf("run failed: %v", err))
+			continue
+		}
+		log.Printf("Daemon analyzed %d captures", len(entries))
+	}
+}
+
+// runDaemonOnce analyzes every capture the daemon is configured for, writes a Markdown report
+// per capture and a summary under a timestamped directory, appends the totals to the trend
+// log, and sends a warning notification for each capture that fails the gate
+func runDaemonOnce(cfg *Config, now time.Time, n Logger) ([]TrendEntry, error) {
+	d := cfg.Daemon
+	tol, err := cfg.Tolerance(d.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(d.Path, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, cfg.ResolvePath(entry))
+		}
+	}
+	paths, err := ExpandBatchPaths(strings.Join(entries, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir := cfg.ResolvePath(d.OutputDir)
+	runDir := filepath.Join(outputDir, now.UTC().Format("20060102-150405"))
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	trendPath := filepath.Join(outputDir, daemonTrendFile)
+	previous, err := readTrend(trendPath)
+	if err != nil {
+		return nil, err
+	}
+
+	red := cfg.Redactor()
+	result := &BatchResult{Verdicts: make(map[string]int), TopIssues: []BatchIssue{}}
+	var trend []TrendEntry
+	reportNames := make(map[string]bool)
+	for _, path := range paths {
+		label := red.Path(path)
+		entry := TrendEntry{Time: now, Path: label}
+
+		analyzer, err := NewMemoryAnalyzer(path)
+		if err != nil {
+			entry.Verdict, entry.Error = "error", err.Error()
+			result.Files = append(result.Files, BatchFileResult{Path: label, Verdict: "error", Error: err.Error()})
+			result.Verdicts["error"]++
+			trend = append(trend, entry)
+			n.Log("warning", "daemon", fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+
+		file, issues := summarizeBatchFile(analyzer, label)
+		result.Files = append(result.Files, file)
+		result.Verdicts[file.Verdict]++
+		result.TopIssues = append(result.TopIssues, issues...)
+
+		entry.Session = file.Session
+		entry.Verdict = file.Verdict
+		entry.TotalAllocations = analyzer.data.TotalAllocations
+		entry.TotalSize = analyzer.data.TotalSize
+		entry.LeakCount = analyzer.data.LeakCount
+		entry.LeakSize = analyzer.data.LeakSize
+		entry.Fragmentation = analyzer.data.MemoryFragmentation
+		if prev, ok := previous[label]; ok && prev.Error == "" {
+			entry.Regressions = trendRegressions(prev, entry, tol)
+		}
+		entry.GateFailed = verdictRank[entry.Verdict] >= verdictRank[d.failOn()] || len(entry.Regressions) > 0
+		trend = append(trend, entry)
+
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for i := 2; reportNames[name]; i++ {
+			name = fmt.Sprintf("%s-%d", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), i)
+		}
+		reportNames[name] = true
+		if err := os.WriteFile(filepath.Join(runDir, name+".md"), []byte(analyzer.MarkdownSummary()), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write report: %w", err)
+		}
+
+		if entry.GateFailed {
+			msg := fmt.Sprintf("%s failed the gate: verdict %s", label, entry.Verdict)
+			if len(entry.Regressions) > 0 {
+				msg += fmt.Sprintf(", regressed beyond %s tolerance: %s", tol.Name, strings.Join(entry.Regressions, ", "))
+			}
+			log.Print(msg)
+			n.Log("warning", "daemon", msg)
+		}
+	}
+
+	sort.SliceStable(result.TopIssues, func(i, j int) bool {
+		return IssueLess(result.TopIssues[i].MemoryIssue, result.TopIssues[j].MemoryIssue)
+	})
+	result.TopIssues = result.TopIssues[:min(len(result.TopIssues), defaultBatchTop)]
+	summary, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(runDir, daemonSummaryFile), summary, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write summary: %w", err)
+	}
+	if err := appendTrend(trendPath, trend); err != nil {
+		return nil, err
+	}
+	return trend, nil
+}
+
+// trendRegressions lists the totals that grew beyond tolerance between two runs
+func trendRegressions(before, after TrendEntry, tol *ToleranceProfile) []string {
+	totals := []struct {
+		metric        string
+		before, after float64
+	}{
+		{"allocation_count", float64(before.TotalAllocations), float64(after.TotalAllocations)},
+		{"total_size", float64(before.TotalSize), float64(after.TotalSize)},
+		{"leak_count", float64(before.LeakCount), float64(after.LeakCount)},
+		{"leak_size", float64(before.LeakSize), float64(after.LeakSize)},
+		{"fragmentation", before.Fragmentation, after.Fragmentation},
+	}
+	var regressions []string
+	for _, t := range totals {
+		if t.after > t.before && !tol.within(t.metric, t.before, t.after) {
+			regressions = append(regressions, t.metric)
+		}
+	}
+	return regressions
+}
+
+// readTrend returns the latest trend entry per capture path; a missing log is empty
+func readTrend(path string) (map[string]TrendEntry, error) {
+	latest := make(map[string]TrendEntry)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return latest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trend log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry TrendEntry
+		// A torn last line from an interrupted write is skipped, not fatal
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			latest[entry.Path] = entry
+		}
+	}
+	return latest, scanner.Err()
+}
+
+// appendTrend adds entries to the trend log, one JSON object per line
+func appendTrend(path string, entries []TrendEntry) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open trend log: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write trend log: %w", err)
+		}
+	}
+	return nil
+}
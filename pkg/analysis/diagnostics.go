@@ -0,0 +1,109 @@
+package analysis
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DebugMode logs the phases and notes of every analyzer to stderr (the server's --debug)
+var DebugMode bool
+
+// Logger receives the messages of background work, such as daemon runs and capture
+// watches, at a level of "debug", "info", "warning", or "error"
+type Logger interface {
+	Log(level, logger string, data interface{})
+}
+
+// PhaseTiming records how long one parse or analysis phase took
+type PhaseTiming struct {
+	Phase      string  `json:"phase"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Diagnostics collects per-phase timings, record counts, and skipped records for one analyzer.
+// All methods are safe to call on a nil receiver.
+type Diagnostics struct {
+	mu      sync.Mutex
+	Phases  []PhaseTiming  `json:"phases"`
+	Records map[string]int `json:"record_counts"`
+	Skipped map[string]int `json:"skipped_records"`
+	Notes   []string       `json:"notes,omitempty"`
+}
+
+func newDiagnostics() *Diagnostics {
+	return &Diagnostics{
+		Phases:  []PhaseTiming{},
+		Records: make(map[string]int),
+		Skipped: make(map[string]int),
+	}
+}
+
+// track starts timing a phase; call the returned function when the phase ends
+func (d *Diagnostics) track(phase string) func() {
+	if d == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		d.mu.Lock()
+		d.Phases = append(d.Phases, PhaseTiming{Phase: phase, DurationMs: float64(elapsed.Microseconds()) / 1000})
+		d.mu.Unlock()
+		if DebugMode {
+			log.Printf("[debug] %s took %v", phase, elapsed)
+		}
+	}
+}
+
+// Duration returns the milliseconds spent in the given phases
+func (d *Diagnostics) Duration(phases ...string) float64 {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var ms float64
+	for _, p := range d.Phases {
+		if slices.Contains(phases, p.Phase) {
+			ms += p.DurationMs
+		}
+	}
+	return ms
+}
+
+// count records the number of records seen in a section
+func (d *Diagnostics) count(section string, n int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Records[section] = n
+}
+
+// skip records records that were ignored and why
+func (d *Diagnostics) skip(reason string, n int) {
+	if d == nil || n == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Skipped[reason] += n
+}
+
+// note records a free-form explanation, e.g. why a result is empty
+func (d *Diagnostics) note(format string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	d.mu.Lock()
+	d.Notes = append(d.Notes, msg)
+	d.mu.Unlock()
+	if DebugMode {
+		log.Printf("[debug] %s", msg)
+	}
+}
@@ -0,0 +1,237 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultDirectoryTop is how many recurring issues analyze_directory returns
+const defaultDirectoryTop = 20
+
+// Presence patterns of an issue across the runs of a directory
+const (
+	presencePersistent   = "persistent"   // In every run
+	presenceIntermittent = "intermittent" // In some runs: a flaky issue
+	presenceOnce         = "once"         // In a single run of several
+)
+
+// DirectoryRun is one capture of a directory, in run order
+type DirectoryRun struct {
+	Run      int    `json:"run"` // 1-based, oldest first
+	Path     string `json:"path"`
+	Session  string `json:"session,omitempty"`
+	Verdict  string `json:"verdict"` // critical, warning, ok, or error
+	LeakSize int64  `json:"leak_size"`
+	Issues   int    `json:"issues"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RecurringIssue is an issue matched by ID across the runs it was found in
+type RecurringIssue struct {
+	ID           string  `json:"id"`
+	Type         string  `json:"type"`
+	Severity     string  `json:"severity"` // The most severe in any run
+	FunctionName string  `json:"functionName"`
+	FileName     string  `json:"fileName,omitempty"`
+	LineNumber   int     `json:"lineNumber,omitempty"`
+	Presence     string  `json:"presence"` // e.g. "present in 7 of 10 runs"
+	Pattern      string  `json:"pattern"`  // persistent, intermittent, or once
+	Runs         []int   `json:"runs"`     // Runs the issue was found in
+	Rate         float64 `json:"rate"`     // Percentage of analyzed runs
+	InLatest     bool    `json:"in_latest"`
+	MinSize      int64   `json:"min_size"`
+	MaxSize      int64   `json:"max_size"`
+	MeanSize     float64 `json:"mean_size"`
+}
+
+// SizeRange summarizes one total across runs
+type SizeRange struct {
+	Min  int64   `json:"min"`
+	Max  int64   `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// DirectoryAnalysis reports how often each issue recurs across the captures of a directory
+type DirectoryAnalysis struct {
+	Directory string           `json:"directory"`
+	Runs      []DirectoryRun   `json:"runs"`
+	Analyzed  int              `json:"analyzed"` // Runs loaded without error
+	Verdicts  map[string]int   `json:"verdicts"`
+	LeakSize  SizeRange        `json:"leak_size"`
+	Patterns  map[string]int   `json:"patterns"` // Issue count by presence pattern
+	Issues    []RecurringIssue `json:"issues"`
+	Omitted   int              `json:"omitted,omitempty"` // Issues beyond top
+}
+
+// DirectoryOptions filters and limits the recurring issues analyze_directory returns
+type DirectoryOptions struct {
+	Type    string // Issue type, e.g. MemoryLeak; empty for all
+	Pattern string // persistent, intermittent, or once; empty for all
+	MinRuns int
+	Top     int
+	Workers int
+}
+
+// sortRunsByTime orders captures oldest first by modification time, then by name, so run
+// numbers follow the order nightly captures were written in
+func sortRunsByTime(paths []string) {
+	modTimes := make(map[string]int64, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime().UnixNano()
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		if modTimes[paths[i]] != modTimes[paths[j]] {
+			return modTimes[paths[i]] < modTimes[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+}
+
+// AnalyzeDirectory runs the standard analysis on every capture of a directory and matches
+// issues by ID across them, so flaky leaks show up as present in some runs only
+func AnalyzeDirectory(directory string, opts DirectoryOptions) (*DirectoryAnalysis, error) {
+	if info, err := os.Stat(directory); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", directory)
+	}
+	switch opts.Pattern {
+	case "", presencePersistent, presenceIntermittent, presenceOnce:
+	default:
+		return nil, fmt.Errorf("unknown pattern %q (expected persistent, intermittent, or once)", opts.Pattern)
+	}
+	if opts.Top <= 0 {
+		opts.Top = defaultDirectoryTop
+	}
+
+	paths, err := ExpandBatchPaths(directory)
+	if err != nil {
+		return nil, err
+	}
+	sortRunsByTime(paths)
+	files, found := analyzeBatchFiles(paths, opts.Workers)
+
+	result := &DirectoryAnalysis{
+		Directory: CurrentConfig().Redactor().Path(directory),
+		Verdicts:  make(map[string]int),
+		Patterns:  make(map[string]int),
+		Issues:    []RecurringIssue{},
+	}
+	recurring := make(map[string]*RecurringIssue)
+	var order []string
+	var leaks []int64
+	latest := 0 // The newest run analyzed without error
+
+	for i, f := range files {
+		run := DirectoryRun{
+			Run:      i + 1,
+			Path:     f.Path,
+			Session:  f.Session,
+			Verdict:  f.Verdict,
+			LeakSize: f.LeakSize,
+			Issues:   len(found[i]),
+			Error:    f.Error,
+		}
+		result.Verdicts[f.Verdict]++
+		if f.Verdict == "error" {
+			result.Runs = append(result.Runs, run)
+			continue
+		}
+		result.Analyzed++
+		latest = run.Run
+		leaks = append(leaks, run.LeakSize)
+		result.Runs = append(result.Runs, run)
+
+		seen := make(map[string]bool)
+		for _, issue := range found[i] {
+			if seen[issue.ID] || opts.Type != "" && !strings.EqualFold(issue.Type, opts.Type) {
+				continue
+			}
+			seen[issue.ID] = true
+
+			r, ok := recurring[issue.ID]
+			if !ok {
+				r = &RecurringIssue{
+					ID:           issue.ID,
+					Type:         issue.Type,
+					Severity:     issue.Severity,
+					FunctionName: issue.FunctionName,
+					FileName:     issue.FileName,
+					LineNumber:   issue.LineNumber,
+					MinSize:      issue.Size,
+				}
+				recurring[issue.ID] = r
+				order = append(order, issue.ID)
+			}
+			if severityRank(issue.Severity) < severityRank(r.Severity) {
+				r.Severity = issue.Severity
+			}
+			r.Runs = append(r.Runs, run.Run)
+			r.MinSize = min(r.MinSize, issue.Size)
+			r.MaxSize = max(r.MaxSize, issue.Size)
+			r.MeanSize += float64(issue.Size) // Summed here, averaged below
+		}
+	}
+	result.LeakSize = sizeRange(leaks)
+
+	var issues []RecurringIssue
+	for _, id := range order {
+		r := recurring[id]
+		r.MeanSize /= float64(len(r.Runs))
+		r.Rate = float64(len(r.Runs)) / float64(result.Analyzed) * 100
+		r.Presence = fmt.Sprintf("present in %d of %d runs", len(r.Runs), result.Analyzed)
+		r.InLatest = r.Runs[len(r.Runs)-1] == latest
+		switch {
+		case len(r.Runs) == result.Analyzed:
+			r.Pattern = presencePersistent
+		case len(r.Runs) == 1:
+			r.Pattern = presenceOnce
+		default:
+			r.Pattern = presenceIntermittent
+		}
+		result.Patterns[r.Pattern]++
+
+		if opts.Pattern != "" && r.Pattern != opts.Pattern || len(r.Runs) < opts.MinRuns {
+			continue
+		}
+		issues = append(issues, *r)
+	}
+
+	// Most frequent first; among equally frequent issues, the most severe and largest
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if len(a.Runs) != len(b.Runs) {
+			return len(a.Runs) > len(b.Runs)
+		}
+		if rankA, rankB := severityRank(a.Severity), severityRank(b.Severity); rankA != rankB {
+			return rankA < rankB
+		}
+		return a.MaxSize > b.MaxSize
+	})
+	if len(issues) > opts.Top {
+		result.Omitted = len(issues) - opts.Top
+		issues = issues[:opts.Top]
+	}
+	if issues != nil {
+		result.Issues = issues
+	}
+	return result, nil
+}
+
+// sizeRange returns the minimum, maximum, and mean of values
+func sizeRange(values []int64) SizeRange {
+	if len(values) == 0 {
+		return SizeRange{}
+	}
+	r := SizeRange{Min: values[0], Max: values[0]}
+	var sum float64
+	for _, v := range values {
+		r.Min = min(r.Min, v)
+		r.Max = max(r.Max, v)
+		sum += float64(v)
+	}
+	r.Mean = sum / float64(len(values))
+	return r
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileIssue is an issue reduced to what an editor shows inline at a line
+type FileIssue struct {
+	ID       string `json:"id"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Type     string `json:"type"`
+	Function string `json:"function,omitempty"`
+	Summary  string `json:"summary"`
+}
+
+// FileIssues lists the issues located in one source file
+type FileIssues struct {
+	File    string      `json:"file"`
+	Matched []string    `json:"matched,omitempty"` // Capture file names the path matched, before the line range
+	Issues  []FileIssue `json:"issues"`
+}
+
+// sourcePathParts splits a path into lower-case components, accepting either slash
+func sourcePathParts(p string) []string {
+	var parts []string
+	for _, part := range strings.Split(strings.ToLower(strings.ReplaceAll(p, `\`, "/")), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// commonSuffixParts counts the trailing path components two paths share. Captures are
+// often recorded on a build machine, so an editor path rarely equals the capture path.
+func commonSuffixParts(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// issueSummary is a one-line description of an issue for inline display
+func issueSummary(issue MemoryIssue) string {
+	s := fmt.Sprintf("%s %s: %s", issue.Severity, issue.Type, FormatBytes(issue.Size))
+	if issue.Count > 0 {
+		s += fmt.Sprintf(" in %d allocations", issue.Count)
+	}
+	if issue.FunctionName != "" {
+		s += " (" + issue.FunctionName + ")"
+	}
+	return s
+}
+
+// IssuesForFile returns the issues whose location is in file, optionally limited to the
+// lines startLine..endLine (zero means unbounded); issues without a line only match
+// without a range. Issues are ordered by line, then severity.
+func (ma *MemoryAnalyzer) IssuesForFile(file string, startLine, endLine int) (*FileIssues, error) {
+	if file == "" {
+		return nil, fmt.Errorf("file is required")
+	}
+	if endLine > 0 && startLine > endLine {
+		return nil, fmt.Errorf("start_line %d is after end_line %d", startLine, endLine)
+	}
+	ranged := startLine > 0 || endLine > 0
+
+	// Keep the capture files sharing the longest trailing run of components with the path;
+	// the file name itself must always match
+	want := sourcePathParts(file)
+	best := 0
+	var candidates []MemoryIssue
+	for _, group := range ma.AnalyzeAll() {
+		for _, issue := range group {
+			n := commonSuffixParts(want, sourcePathParts(issue.FileName))
+			if n == 0 || n < best {
+				continue
+			}
+			if n > best {
+				best, candidates = n, nil
+			}
+			candidates = append(candidates, issue)
+		}
+	}
+
+	result := &FileIssues{File: file, Issues: []FileIssue{}}
+	matched := make(map[string]bool)
+	var found []MemoryIssue
+	for _, issue := range candidates {
+		matched[issue.FileName] = true
+		if ranged && (issue.LineNumber <= 0 || issue.LineNumber < startLine || (endLine > 0 && issue.LineNumber > endLine)) {
+			continue
+		}
+		found = append(found, issue)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].LineNumber != found[j].LineNumber {
+			return found[i].LineNumber < found[j].LineNumber
+		}
+		return IssueLess(found[i], found[j])
+	})
+	for _, issue := range found {
+		result.Issues = append(result.Issues, FileIssue{
+			ID:       issue.ID,
+			Line:     issue.LineNumber,
+			Severity: issue.Severity,
+			Type:     issue.Type,
+			Function: issue.FunctionName,
+			Summary:  issueSummary(issue),
+		})
+	}
+	for name := range matched {
+		result.Matched = append(result.Matched, name)
+	}
+	sort.Strings(result.Matched)
+	return result, nil
+}
@@ -0,0 +1,395 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Limits that keep an explanation small enough for one LLM turn
+const (
+	DefaultContextLines = 3
+	maxSnippetFrames    = 3
+	maxRelatedLeaks     = 5
+)
+
+// LeakExplanation is an in-depth dossier on a single issue
+type LeakExplanation struct {
+	Issue            MemoryIssue      `json:"issue"`
+	AllocatedVia     string           `json:"allocated_via,omitempty"`
+	Suspect          bool             `json:"suspect"`
+	Stack            []ExplainedFrame `json:"stack,omitempty"`
+	RelatedFunctions []Function       `json:"related_functions,omitempty"`
+	RelatedLeaks     []MemoryIssue    `json:"related_leaks,omitempty"`
+	Types            []AllocType      `json:"types,omitempty"`
+	Trend            *LeakTrend       `json:"trend,omitempty"`
+	FixOptions       []string         `json:"fix_options"`
+	Notes            []string         `json:"notes,omitempty"`
+}
+
+// ExplainedFrame is one call stack frame with its source location when known
+type ExplainedFrame struct {
+	Frame    string `json:"frame"`
+	Function string `json:"function"`
+	Plumbing bool   `json:"plumbing,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// LeakTrend tracks an issue across earlier captures, oldest first, ending with the current one
+type LeakTrend struct {
+	Direction string       `json:"direction"` // new, growing, shrinking, or stable
+	Points    []TrendPoint `json:"points"`
+}
+
+// TrendPoint is the issue's size in one capture
+type TrendPoint struct {
+	Capture string `json:"capture"`
+	Session string `json:"session,omitempty"`
+	Present bool   `json:"present"`
+	Size    int64  `json:"size"`
+	Count   int    `json:"count"`
+}
+
+// ExplainOptions controls the optional parts of an explanation
+type ExplainOptions struct {
+	ContextLines int               // Source lines shown around each frame
+	SourceRoot   string            // Directory used to resolve source files recorded on another machine
+	History      []string          // Earlier captures of the same program, oldest first
+	Tolerance    *ToleranceProfile // Size changes within its leak_size tolerance count as stable
+}
+
+// ExplainIssue builds a dossier for the issue with the given ID
+func (ma *MemoryAnalyzer) ExplainIssue(id string, opts ExplainOptions) (*LeakExplanation, error) {
+	if ma == nil || ma.data == nil {
+		return nil, fmt.Errorf("no data available for analysis")
+	}
+	defer ma.diag.track("explain")()
+
+	groups := ma.AnalyzeAll()
+	leaks := groups[0]
+	issue, err := findIssue(groups, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &LeakExplanation{Issue: *issue}
+
+	// Names that may explain the issue: the blamed function plus every application frame
+	names := map[string]bool{issue.FunctionName: issue.FunctionName != ""}
+	var leak *Leak
+	if group, ok := ma.leakGroup(id); ok && issue.Type == "MemoryLeak" {
+		leak = &group.Leak
+	}
+	if leak != nil {
+		ex.Suspect = leak.IsSuspect
+		if leak.FunctionName != issue.FunctionName {
+			ex.AllocatedVia = leak.FunctionName
+		}
+		ex.Stack = ma.explainStack(*leak, opts, &ex.Notes)
+		for _, frame := range ex.Stack {
+			if !frame.Plumbing {
+				names[frame.Function] = true
+			}
+		}
+	}
+
+	var related []int
+	for name, ok := range names {
+		if ok {
+			related = append(related, ma.index().functionsByName[name]...)
+		}
+	}
+	sort.Ints(related)
+	for _, i := range related {
+		ex.RelatedFunctions = append(ex.RelatedFunctions, ma.data.Functions[i])
+	}
+	for _, t := range ma.data.Types {
+		sameSite := issue.FileName != "" && t.MostCommonFile == issue.FileName && t.MostCommonLine == issue.LineNumber
+		if names[t.MostCommonFunction] || sameSite {
+			ex.Types = append(ex.Types, t)
+		}
+	}
+	if issue.Type == "MemoryLeak" {
+		for _, other := range leaks {
+			if len(ex.RelatedLeaks) == maxRelatedLeaks {
+				break
+			}
+			if other.ID == id {
+				continue
+			}
+			if other.FunctionName == issue.FunctionName || (issue.FileName != "" && other.FileName == issue.FileName) {
+				ex.RelatedLeaks = append(ex.RelatedLeaks, other)
+			}
+		}
+	}
+
+	if len(opts.History) > 0 {
+		ex.Trend = issueTrend(id, *issue, ma.data.SessionName, opts.History, opts.Tolerance, &ex.Notes)
+	}
+
+	ex.FixOptions = fixOptions(ex, leak)
+	return ex, nil
+}
+
+// explainStack annotates each frame of a leak's call stack with its location and source
+func (ma *MemoryAnalyzer) explainStack(leak Leak, opts ExplainOptions, notes *[]string) []ExplainedFrame {
+	frames := parseCallStack(leak.CallStack)
+	if len(frames) == 0 {
+		frames = []string{leak.FunctionName}
+	}
+
+	// Source would reveal the names that anonymization hides
+	snippets := opts.ContextLines > 0 && ma.config.SymbolMapPath() == ""
+	if !snippets && opts.ContextLines > 0 {
+		*notes = append(*notes, "source snippets are omitted while symbol anonymization is on")
+	}
+
+	var result []ExplainedFrame
+	missing := 0
+	shown := 0
+	for _, frame := range frames {
+		ef := ExplainedFrame{
+			Frame:    frame,
+			Function: frameSymbol(frame),
+			Plumbing: ma.config.plumbing.isPlumbing(frame),
+		}
+		ef.File, ef.Line = ma.frameLocation(ef.Function, leak)
+
+		if snippets && !ef.Plumbing && ef.File != "" && shown < maxSnippetFrames {
+			if snippet, ok := sourceSnippet(ef.File, ef.Line, opts.ContextLines, opts.SourceRoot); ok {
+				ef.Snippet = snippet
+				shown++
+			} else {
+				missing++
+			}
+		}
+		result = append(result, ef)
+	}
+
+	if missing > 0 {
+		*notes = append(*notes, fmt.Sprintf("source not found for %d frames; set source_root to the checkout the capture was built from", missing))
+	}
+	return result
+}
+
+// frameLocation returns the source location of a leak's frame: the leak's own for its
+// allocating function, else that of the function's record, if any
+func (ma *MemoryAnalyzer) frameLocation(function string, leak Leak) (string, int) {
+	if function == leak.FunctionName && leak.FileName != "" {
+		return leak.FileName, leak.LineNumber
+	}
+	if fn, ok := ma.functionRecord(function); ok {
+		return fn.FileName, fn.LineNumber
+	}
+	return "", 0
+}
+
+// sourceSnippet returns the lines around line in file, marking the line itself
+func sourceSnippet(file string, line, context int, root string) (string, bool) {
+	path := resolveSourceFile(file, root)
+	if path == "" || line <= 0 {
+		return "", false
+	}
+	lines, err := readSourceLines(path)
+	if err != nil {
+		return "", false
+	}
+	return formatSnippet(lines, line, context)
+}
+
+// readSourceLines reads a source file as lines
+func readSourceLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), nil
+}
+
+// formatSnippet renders the lines around line, numbered, with the line itself marked
+func formatSnippet(lines []string, line, context int) (string, bool) {
+	if line <= 0 || line > len(lines) {
+		return "", false
+	}
+	first, last := max(1, line-context), min(len(lines), line+context)
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s%5d | %s\n", marker, n, lines[n-1])
+	}
+	return b.String(), true
+}
+
+// EmbedSource returns copies of the issue groups whose issues carry the source lines around
+// their file and line. Each file is read once; issues whose file is not found are unchanged.
+func EmbedSource(groups [][]MemoryIssue, context int, root string) [][]MemoryIssue {
+	files := make(map[string][]string) // Recorded path -> lines, nil when not found
+	embedded := make([][]MemoryIssue, len(groups))
+	for g, issues := range groups {
+		embedded[g] = append([]MemoryIssue(nil), issues...)
+		for i := range embedded[g] {
+			issue := &embedded[g][i]
+			if issue.FileName == "" || issue.LineNumber <= 0 {
+				continue
+			}
+			lines, ok := files[issue.FileName]
+			if !ok {
+				if path := resolveSourceFile(issue.FileName, root); path != "" {
+					lines, _ = readSourceLines(path)
+				}
+				files[issue.FileName] = lines
+			}
+			issue.Source, _ = formatSnippet(lines, issue.LineNumber, context)
+		}
+	}
+	return embedded
+}
+
+// resolveSourceFile finds a recorded source path on this machine: as recorded, or under root
+// using the longest trailing part of the recorded path that exists there
+func resolveSourceFile(file, root string) string {
+	if isRegularFile(file) {
+		return file
+	}
+	if root == "" {
+		return ""
+	}
+
+	parts := strings.FieldsFunc(file, func(r rune) bool { return r == '/' || r == '\\' })
+	for i := range parts {
+		if strings.HasSuffix(parts[i], ":") {
+			continue // Windows drive letter
+		}
+		candidate := filepath.Join(append([]string{root}, parts[i:]...)...)
+		if isRegularFile(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// issueTrend looks the issue up in earlier captures by ID
+func issueTrend(id string, current MemoryIssue, session string, history []string, tol *ToleranceProfile, notes *[]string) *LeakTrend {
+	trend := &LeakTrend{}
+	for _, path := range history {
+		point := TrendPoint{Capture: CurrentConfig().Redactor().Path(path)}
+		analyzer, err := NewMemoryAnalyzer(path)
+		if err != nil {
+			*notes = append(*notes, fmt.Sprintf("skipped history capture: %v", err))
+			continue
+		}
+		point.Session = analyzer.data.SessionName
+
+		for _, group := range analyzer.AnalyzeAll() {
+			for _, issue := range group {
+				if issue.ID == id {
+					point.Present, point.Size, point.Count = true, issue.Size, issue.Count
+				}
+			}
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	trend.Points = append(trend.Points, TrendPoint{
+		Capture: "current",
+		Session: session,
+		Present: true,
+		Size:    current.Size,
+		Count:   current.Count,
+	})
+
+	// Compare against the oldest capture that has the issue; tolerated changes count as stable
+	trend.Direction = "new"
+	for _, p := range trend.Points[:len(trend.Points)-1] {
+		if p.Present {
+			trend.Direction = sizeDirection(p.Size, current.Size, tol)
+			break
+		}
+	}
+	return trend
+}
+
+func sizeDirection(before, after int64, tol *ToleranceProfile) string {
+	switch delta := tol.significant("leak_size", before, after); {
+	case delta > 0:
+		return "growing"
+	case delta < 0:
+		return "shrinking"
+	}
+	return "stable"
+}
+
+// fixOptions tailors remediation advice to what the explanation revealed
+func fixOptions(ex *LeakExplanation, leak *Leak) []string {
+	var options []string
+	add := func(format string, args ...interface{}) {
+		option := fmt.Sprintf(format, args...)
+		for _, o := range options {
+			if o == option {
+				return
+			}
+		}
+		options = append(options, option)
+	}
+
+	issue := ex.Issue
+	if issue.Suggestion != "" {
+		add("%s", issue.Suggestion)
+	}
+	if leak == nil {
+		return options
+	}
+
+	if ex.AllocatedVia != "" {
+		add("The memory is allocated by %s on behalf of %s; find where %s hands the object off and make that owner responsible for releasing it.",
+			ex.AllocatedVia, issue.FunctionName, issue.FunctionName)
+	}
+	if ex.Suspect {
+		add("MemPro marks this site as a suspect: its allocations keep accumulating and are never freed, so look for a missing release on the normal path rather than only on error paths.")
+	}
+	if ex.Trend != nil {
+		switch ex.Trend.Direction {
+		case "growing":
+			add("The leak grows between captures, which points to an unbounded cache, registry, or event subscription; add eviction, a size limit, or unsubscribe on teardown.")
+		case "stable":
+			add("The leak is stable between captures, which suggests a one-time initialization allocation; free it at shutdown or accept it as a known static cost.")
+		}
+	}
+
+	if issue.Count > 0 {
+		average := issue.Size / int64(issue.Count)
+		switch {
+		case issue.Count > 100 && average < 256:
+			add("There are %d small allocations (about %d bytes each), typical of per-item objects; check that erase/remove paths of the owning collection delete the items, or allocate them from a pool that is released as a whole.",
+				issue.Count, average)
+		case average > 64*1024:
+			add("Each allocation averages %s, typical of buffers; check early returns and error paths that skip the free, and hold the buffer in std::vector or std::unique_ptr<T[]>.",
+				FormatBytes(average))
+		}
+	}
+
+	for _, t := range ex.Types {
+		if strings.HasPrefix(t.TypeName, "std::") {
+			add("Allocations are dominated by %s; containers owned by long-lived objects never shrink, so call clear() and shrink_to_fit() or swap with an empty container when the data is no longer needed.",
+				t.TypeName)
+			break
+		}
+	}
+	if strings.Contains(leak.CallStack, "shared_ptr") || strings.Contains(leak.CallStack, "make_shared") {
+		add("shared_ptr appears in the call stack; check for reference cycles and break them with std::weak_ptr.")
+	}
+
+	return options
+}
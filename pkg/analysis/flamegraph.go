@@ -0,0 +1,225 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SVG flamegraph layout
+const (
+	flameWidth       = 1200
+	flameFrameHeight = 16
+	flameTitleHeight = 32
+	flameMargin      = 10
+	flameCharWidth   = 7   // Approximate width of a 12px monospace character
+	flameMinWidth    = 0.1 // Pixels; narrower frames are left out of the SVG
+)
+
+// FoldedStacks maps a semicolon-separated stack, outermost frame first, to the bytes
+// attributed to exactly that stack. Summed over its prefixes, a frame's width is its
+// inclusive size.
+type FoldedStacks map[string]int64
+
+// FlameGraphExport describes a flamegraph written to a file by export_flamegraph
+type FlameGraphExport struct {
+	Format     string `json:"format"`
+	Source     string `json:"source"`
+	Stacks     int    `json:"stacks"`
+	TotalBytes int64  `json:"total_bytes"`
+	Output     string `json:"output"`
+}
+
+// flameFrame makes a function name safe for a folded line, where ";" separates frames
+func flameFrame(function string) string {
+	name := strings.ReplaceAll(frameSymbol(function), ";", ":")
+	if name == "" {
+		return "[unknown]"
+	}
+	return name
+}
+
+// FoldCallTrees folds the call trees into stacks weighted by allocated bytes. A node's own
+// stack gets its inclusive size minus its children's, so frame widths match the tree's
+// inclusive sizes. Plumbing nodes below a root are folded into their caller when asked.
+func (ma *MemoryAnalyzer) FoldCallTrees(collapsePlumbing bool) (FoldedStacks, error) {
+	if len(ma.data.CallTrees) == 0 {
+		return nil, fmt.Errorf("capture has no call trees")
+	}
+
+	stacks := make(FoldedStacks)
+	var walk func(t CallTree, frames []string)
+	walk = func(t CallTree, frames []string) {
+		if !(collapsePlumbing && len(frames) > 0 && ma.config != nil && ma.config.plumbing.isPlumbing(t.FunctionName)) {
+			frames = append(frames[:len(frames):len(frames)], flameFrame(t.FunctionName))
+		}
+		self := treeInclusive(t)
+		for _, child := range t.Children {
+			self -= treeInclusive(child)
+			walk(child, frames)
+		}
+		if self > 0 {
+			stacks[strings.Join(frames, ";")] += self
+		}
+	}
+	for _, t := range ma.data.CallTrees {
+		walk(t, nil)
+	}
+	return stacks, nil
+}
+
+// FoldLeaks folds the leak call stacks into stacks weighted by leaked bytes
+func (ma *MemoryAnalyzer) FoldLeaks(collapsePlumbing bool) (FoldedStacks, error) {
+	if len(ma.data.Leaks) == 0 {
+		return nil, fmt.Errorf("capture has no leaks")
+	}
+
+	stacks := make(FoldedStacks)
+	for _, leak := range ma.data.Leaks {
+		names := ma.graphFrames(leak.CallStack, leak.FunctionName, collapsePlumbing)
+		if len(names) == 0 || leak.LeakSize <= 0 {
+			continue
+		}
+		frames := make([]string, len(names))
+		for i, name := range names {
+			frames[len(names)-1-i] = flameFrame(name)
+		}
+		stacks[strings.Join(frames, ";")] += leak.LeakSize
+	}
+	return stacks, nil
+}
+
+// Total returns the bytes of all stacks
+func (f FoldedStacks) Total() int64 {
+	var total int64
+	for _, v := range f {
+		total += v
+	}
+	return total
+}
+
+// Folded renders the stacks in Brendan Gregg's folded format, one "frame;frame bytes" line
+// per stack, sorted by stack
+func (f FoldedStacks) Folded() []byte {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s %d\n", k, f[k])
+	}
+	return b.Bytes()
+}
+
+// flameNode is a frame of the merged flamegraph: stacks sharing a prefix share its frames
+type flameNode struct {
+	name     string
+	value    int64
+	children map[string]*flameNode
+}
+
+// child returns the named child, adding it when missing
+func (n *flameNode) child(name string) *flameNode {
+	if n.children == nil {
+		n.children = make(map[string]*flameNode)
+	}
+	c, ok := n.children[name]
+	if !ok {
+		c = &flameNode{name: name}
+		n.children[name] = c
+	}
+	return c
+}
+
+// depth returns the number of frame levels below n
+func (n *flameNode) depth() int {
+	depth := 0
+	for _, c := range n.children {
+		depth = max(depth, c.depth()+1)
+	}
+	return depth
+}
+
+// SVG renders the stacks as a self-contained flamegraph: callers at the bottom, frames as
+// wide as their inclusive bytes, siblings in name order, and a tooltip on every frame
+func (f FoldedStacks) SVG(title string) []byte {
+	root := &flameNode{name: "all"}
+	for stack, v := range f {
+		root.value += v
+		n := root
+		for _, frame := range strings.Split(stack, ";") {
+			n = n.child(frame)
+			n.value += v
+		}
+	}
+
+	levels := root.depth() + 1
+	height := flameTitleHeight + levels*flameFrameHeight + flameMargin
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="12">`+"\n",
+		flameWidth, height, flameWidth, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#f8f8f8"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="20" text-anchor="middle" font-size="16">%s</text>`+"\n", flameWidth/2, html.EscapeString(title))
+
+	if root.value > 0 {
+		scale := float64(flameWidth-2*flameMargin) / float64(root.value)
+		var draw func(n *flameNode, level int, x float64)
+		draw = func(n *flameNode, level int, x float64) {
+			width := float64(n.value) * scale
+			if width < flameMinWidth {
+				return
+			}
+			y := height - flameMargin - (level+1)*flameFrameHeight
+			label := fmt.Sprintf("%s (%s, %.2f%%)", n.name, FormatBytes(n.value), float64(n.value)/float64(root.value)*100)
+			fmt.Fprintf(&b, `<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2"/>`,
+				html.EscapeString(label), x, y, width, flameFrameHeight-1, flameColor(n.name))
+			if chars := int((width - 6) / flameCharWidth); chars >= 3 {
+				text := n.name
+				if r := []rune(text); len(r) > chars {
+					text = string(r[:chars-2]) + ".."
+				}
+				fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`, x+3, y+flameFrameHeight-4, html.EscapeString(text))
+			}
+			b.WriteString("</g>\n")
+
+			names := make([]string, 0, len(n.children))
+			for name := range n.children {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				c := n.children[name]
+				draw(c, level+1, x)
+				x += float64(c.value) * scale
+			}
+		}
+		draw(root, 0, flameMargin)
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// flameColor picks a stable warm color for a frame name, as flamegraph.pl does
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, (v>>8)%230, (v>>16)%55)
+}
+
+// SaveFlameGraph writes an exported flamegraph to path
+func SaveFlameGraph(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write flamegraph: %w", err)
+	}
+	return nil
+}
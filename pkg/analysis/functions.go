@@ -0,0 +1,200 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// defaultFunctionRows is how many leaks and call tree nodes find_function lists
+const defaultFunctionRows = 10
+
+// MatchedLeak is a merged leak that a function allocated or is blamed for
+type MatchedLeak struct {
+	ID           string  `json:"id"`
+	Role         string  `json:"role"` // allocated, or blamed past allocator plumbing
+	AllocatedVia string  `json:"allocated_via,omitempty"`
+	File         string  `json:"file,omitempty"`
+	Line         int     `json:"line,omitempty"`
+	Size         int64   `json:"size"`
+	Count        int     `json:"count"`
+	Score        float64 `json:"score"`
+	Suspect      bool    `json:"suspect"`
+	Occurrences  int     `json:"occurrences"`
+	Stack        string  `json:"stack,omitempty"` // Summarized to its top application frames
+}
+
+// FunctionTreeNode is a call tree node of the function with its children counted but not
+// listed; Path is usable with get_call_tree to drill down from it
+type FunctionTreeNode struct {
+	Caller string `json:"caller,omitempty"` // Parent node's function; empty for roots
+	CallTreeNode
+}
+
+// FunctionMatch is everything a capture records about the function best matching a query
+type FunctionMatch struct {
+	Query           string             `json:"query"`
+	Mode            string             `json:"mode"`
+	Function        string             `json:"function"`
+	Sections        []string           `json:"sections"` // Record arrays that mention the function
+	AllocationCount int                `json:"allocation_count"`
+	TotalSize       int64              `json:"total_size"`
+	LeakCount       int                `json:"leak_count"`
+	LeakSize        int64              `json:"leak_size"`
+	TreeSelfSize    int64              `json:"tree_self_size,omitempty"`
+	TreeInclusive   int64              `json:"tree_inclusive_size,omitempty"` // Outermost nodes only, so recursion is not counted twice
+	Functions       []Function         `json:"functions,omitempty"`
+	Leaks           []MatchedLeak      `json:"leaks,omitempty"`
+	OmittedLeaks    int                `json:"omitted_leaks,omitempty"`
+	TreeNodes       []FunctionTreeNode `json:"tree_nodes,omitempty"`
+	OmittedNodes    int                `json:"omitted_nodes,omitempty"`
+	Types           []AllocType        `json:"types,omitempty"` // Types allocated most often by the function
+	OtherMatches    []Symbol           `json:"other_matches,omitempty"`
+	Notes           []string           `json:"notes,omitempty"`
+}
+
+// FindFunction resolves a partial or approximate function name through the symbol index and
+// gathers the function's Functions records, leaks, call tree nodes, and types. An exact name
+// wins; otherwise the first match in search order (largest, or best scored when fuzzy).
+// A substring query that matches nothing is retried as a fuzzy one.
+func (ma *MemoryAnalyzer) FindFunction(query, mode string, rows int) (*FunctionMatch, error) {
+	if query == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if rows <= 0 {
+		rows = defaultFunctionRows
+	}
+
+	found, err := ma.Symbols().Search(query, mode, "function", maxFunctionCandidates+1)
+	if err != nil {
+		return nil, err
+	}
+	result := &FunctionMatch{Query: query, Mode: found.Mode}
+	if found.Total == 0 && found.Mode == "substring" {
+		found = ma.Symbols().fuzzy(query, "function", maxFunctionCandidates+1)
+		result.Mode = found.Mode
+		result.Notes = append(result.Notes, "no function name contains the query; matched approximately")
+	}
+
+	idx := ma.index()
+	_, isFunction := idx.functionsByName[query]
+	_, isLeak := idx.leaksByFunction[query]
+	switch {
+	case isFunction || isLeak:
+		result.Function = query
+	case len(found.Matches) > 0:
+		result.Function = found.Matches[0].Name
+	default:
+		return nil, fmt.Errorf("no function matches %q", query)
+	}
+	for _, s := range found.Matches {
+		if s.Name != result.Function && len(result.OtherMatches) < maxFunctionCandidates {
+			result.OtherMatches = append(result.OtherMatches, s)
+		}
+	}
+	if found.Total > len(found.Matches) {
+		result.Notes = append(result.Notes, fmt.Sprintf("%d functions match; refine the query to pick another", found.Total))
+	}
+
+	ma.matchRecords(result)
+	ma.matchLeaks(result, rows)
+	ma.matchTreeNodes(result, rows)
+	for _, i := range idx.typesByFunction[result.Function] {
+		result.Types = append(result.Types, ma.data.Types[i])
+	}
+	if len(result.Types) > 0 {
+		result.Sections = append(result.Sections, "Types")
+	}
+	return result, nil
+}
+
+// matchRecords adds the function's Functions records and their totals
+func (ma *MemoryAnalyzer) matchRecords(m *FunctionMatch) {
+	for _, i := range ma.index().functionsByName[m.Function] {
+		fn := ma.data.Functions[i]
+		m.Functions = append(m.Functions, fn)
+		m.AllocationCount += fn.AllocationCount
+		m.TotalSize += fn.TotalSize
+	}
+	if len(m.Functions) > 0 {
+		m.Sections = append(m.Sections, "Functions")
+	}
+}
+
+// matchLeaks adds the merged leaks the function allocated or is blamed for, largest first
+func (ma *MemoryAnalyzer) matchLeaks(m *FunctionMatch, rows int) {
+	idx := ma.index()
+	seen := make(map[int]bool)
+	for _, i := range idx.leaksByFunction[m.Function] {
+		g := idx.groupOf[i]
+		if g < 0 || seen[g] {
+			continue
+		}
+		seen[g] = true
+
+		group := idx.leakGroups[g]
+		leak := MatchedLeak{
+			ID:          group.ID,
+			Role:        "allocated",
+			File:        group.FileName,
+			Line:        group.LineNumber,
+			Size:        group.LeakSize,
+			Count:       group.LeakCount,
+			Score:       group.LeakScore,
+			Suspect:     group.IsSuspect,
+			Occurrences: group.Occurrences,
+			Stack:       ma.config.plumbing.summarizeStack(group.CallStack, 0),
+		}
+		if group.FunctionName != m.Function {
+			leak.Role = "blamed"
+			leak.AllocatedVia = group.FunctionName
+		}
+		m.Leaks = append(m.Leaks, leak)
+		m.LeakCount += leak.Count
+		m.LeakSize += leak.Size
+	}
+	if len(m.Leaks) == 0 {
+		return
+	}
+
+	m.Sections = append(m.Sections, "Leaks")
+	sort.SliceStable(m.Leaks, func(i, j int) bool { return m.Leaks[i].Size > m.Leaks[j].Size })
+	if len(m.Leaks) > rows {
+		m.OmittedLeaks = len(m.Leaks) - rows
+		m.Leaks = m.Leaks[:rows]
+	}
+}
+
+// matchTreeNodes adds the call tree nodes of the function, largest inclusive size first
+func (ma *MemoryAnalyzer) matchTreeNodes(m *FunctionMatch, rows int) {
+	var walk func(trees []CallTree, path, caller string, inside bool)
+	walk = func(trees []CallTree, path, caller string, inside bool) {
+		for i, t := range trees {
+			nodePath := strconv.Itoa(i)
+			if path != "" {
+				nodePath = path + "/" + nodePath
+			}
+			match := t.FunctionName == m.Function
+			if match {
+				node := callTreeNode(t, nodePath, 0, 0)
+				m.TreeNodes = append(m.TreeNodes, FunctionTreeNode{Caller: caller, CallTreeNode: node})
+				m.TreeSelfSize += node.SelfSize
+				if !inside {
+					m.TreeInclusive += node.InclusiveSize
+				}
+			}
+			walk(t.Children, nodePath, t.FunctionName, inside || match)
+		}
+	}
+	walk(ma.data.CallTrees, "", "", false)
+	if len(m.TreeNodes) == 0 {
+		return
+	}
+
+	m.Sections = append(m.Sections, "CallTrees")
+	sort.SliceStable(m.TreeNodes, func(i, j int) bool { return m.TreeNodes[i].InclusiveSize > m.TreeNodes[j].InclusiveSize })
+	if len(m.TreeNodes) > rows {
+		m.OmittedNodes = len(m.TreeNodes) - rows
+		m.TreeNodes = m.TreeNodes[:rows]
+	}
+}
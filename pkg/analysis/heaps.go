@@ -0,0 +1,233 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Heap defaults and thresholds
+const (
+	defaultHeapTop = 3
+)
+
+// Budget thresholds, as percentages of the budget
+const (
+	budgetNear     = 90.0
+	budgetCritical = 125.0
+)
+
+// budgetStatus returns the percentage of a budget used and whether that is over, near, or
+// ok; both are empty without a budget
+func budgetStatus(size, budget int64) (float64, string) {
+	if budget <= 0 {
+		return 0, ""
+	}
+	used := float64(size) / float64(budget) * 100
+	switch {
+	case used > 100:
+		return used, "over"
+	case used >= budgetNear:
+		return used, "near"
+	default:
+		return used, "ok"
+	}
+}
+
+// HeapUsage is one heap's memory, leaks, fragmentation, and budget
+type HeapUsage struct {
+	HeapId           int            `json:"heap_id"` // 0 for records without a heap
+	HeapName         string         `json:"heap_name,omitempty"`
+	TotalSize        int64          `json:"total_size"`
+	AllocationCount  int            `json:"allocation_count"`
+	LeakCount        int            `json:"leak_count"`
+	LeakSize         int64          `json:"leak_size"`
+	Fragmentation    float64        `json:"fragmentation,omitempty"` // Only when the capture records it per heap
+	Budget           int64          `json:"budget,omitempty"`
+	BudgetUsed       float64        `json:"budget_used,omitempty"` // Percentage of the budget
+	Status           string         `json:"status,omitempty"`      // over, near, or ok; only with a budget
+	TopLeakFunctions []FunctionLeak `json:"top_leak_functions,omitempty"`
+}
+
+// HeapBreakdown reports memory per named heap
+type HeapBreakdown struct {
+	Heaps []HeapUsage `json:"heaps"`
+}
+
+// HasHeaps reports whether the capture partitions memory into heaps
+func (ma *MemoryAnalyzer) HasHeaps() bool {
+	if len(ma.data.Heaps) > 0 {
+		return true
+	}
+	for _, leak := range ma.data.Leaks {
+		if leak.HeapId != 0 {
+			return true
+		}
+	}
+	for _, fn := range ma.data.Functions {
+		if fn.HeapId != 0 {
+			return true
+		}
+	}
+	for _, a := range ma.data.Allocations {
+		if a.HeapId != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// heapLabel names a heap for display, e.g. "Textures (3)"; empty when unattributed
+func (ma *MemoryAnalyzer) heapLabel(id int) string {
+	if id == 0 {
+		return ""
+	}
+	for _, h := range ma.data.Heaps {
+		if h.HeapId == id && h.HeapName != "" {
+			return fmt.Sprintf("%s (%d)", h.HeapName, id)
+		}
+	}
+	return strconv.Itoa(id)
+}
+
+// heapBudget returns a heap's budget: from heap_budgets by name or ID, else from the capture
+func (ma *MemoryAnalyzer) heapBudget(h HeapUsage, recorded int64) int64 {
+	if ma.config != nil {
+		if budget, ok := ma.config.HeapBudgets[h.HeapName]; ok && h.HeapName != "" {
+			return int64(budget)
+		}
+		if budget, ok := ma.config.HeapBudgets[strconv.Itoa(h.HeapId)]; ok {
+			return int64(budget)
+		}
+	}
+	return recorded
+}
+
+// HeapBreakdown returns per-heap totals, leaks, fragmentation, and budget status, largest first
+func (ma *MemoryAnalyzer) HeapBreakdown(top int) *HeapBreakdown {
+	if top <= 0 {
+		top = defaultHeapTop
+	}
+
+	byID := make(map[int]*HeapUsage)
+	recorded := make(map[int]Heap)
+	get := func(id int) *HeapUsage {
+		u, ok := byID[id]
+		if !ok {
+			u = &HeapUsage{HeapId: id, HeapName: recorded[id].HeapName}
+			byID[id] = u
+		}
+		return u
+	}
+	for _, h := range ma.data.Heaps {
+		recorded[h.HeapId] = h
+		get(h.HeapId)
+	}
+
+	// Function statistics give the heap totals; allocation records stand in when they are absent
+	for _, fn := range ma.data.Functions {
+		if fn.HeapId != 0 {
+			u := get(fn.HeapId)
+			u.TotalSize += fn.TotalSize
+			u.AllocationCount += fn.AllocationCount
+		}
+	}
+	fromFunctions := make(map[int]bool)
+	for id, u := range byID {
+		fromFunctions[id] = u.AllocationCount > 0
+	}
+	for _, a := range ma.data.Allocations {
+		if a.HeapId != 0 && a.FreeTime == nil && !fromFunctions[a.HeapId] {
+			u := get(a.HeapId)
+			u.TotalSize += a.Size
+			u.AllocationCount++
+		}
+	}
+
+	leaksByHeap := make(map[int][]Leak)
+	for _, leak := range ma.data.Leaks {
+		u := get(leak.HeapId)
+		u.LeakCount += leak.LeakCount
+		u.LeakSize += leak.LeakSize
+		leaksByHeap[leak.HeapId] = append(leaksByHeap[leak.HeapId], leak)
+	}
+
+	result := &HeapBreakdown{}
+	for id, u := range byID {
+		h := recorded[id]
+		if h.TotalSize > 0 {
+			u.TotalSize = h.TotalSize
+		}
+		u.Fragmentation = h.MemoryFragmentation
+		u.TopLeakFunctions = ma.topLeakFunctions(leaksByHeap[id], top)
+
+		u.Budget = ma.heapBudget(*u, h.Budget)
+		u.BudgetUsed, u.Status = budgetStatus(u.TotalSize, u.Budget)
+		result.Heaps = append(result.Heaps, *u)
+	}
+	sort.Slice(result.Heaps, func(i, j int) bool {
+		if result.Heaps[i].TotalSize != result.Heaps[j].TotalSize {
+			return result.Heaps[i].TotalSize > result.Heaps[j].TotalSize
+		}
+		return result.Heaps[i].HeapId < result.Heaps[j].HeapId
+	})
+
+	return result
+}
+
+// AnalyzeHeaps reports heaps that are over budget or badly fragmented. Leaks are reported
+// by AnalyzeLeaks, labeled with their heap.
+func (ma *MemoryAnalyzer) AnalyzeHeaps() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil || !ma.HasHeaps() {
+		return issues
+	}
+	defer ma.diag.track("analyze_heaps")()
+
+	thresholds := ma.config.EffectiveThresholds()
+	for _, h := range ma.HeapBreakdown(0).Heaps {
+		if h.HeapId == 0 {
+			continue
+		}
+		label := ma.heapLabel(h.HeapId)
+
+		if h.Status == "over" {
+			severity := "High"
+			if h.BudgetUsed >= budgetCritical {
+				severity = "Critical"
+			}
+			issues = append(issues, MemoryIssue{
+				ID:          issueID("budget", h.HeapName, strconv.Itoa(h.HeapId)),
+				Severity:    severity,
+				Type:        "HeapOverBudget",
+				Description: fmt.Sprintf("Heap %s uses %s of its %s budget (%.1f%%)", label, FormatBytes(h.TotalSize), FormatBytes(h.Budget), h.BudgetUsed),
+				Size:        h.TotalSize - h.Budget,
+				Count:       h.AllocationCount,
+				Score:       h.BudgetUsed,
+				Suggestion:  fmt.Sprintf("Reduce heap %s by %s to meet its budget: start with its largest allocating functions and leaks, or move data that does not belong in this heap to another one.", label, FormatBytes(h.TotalSize-h.Budget)),
+				Heap:        label,
+			})
+		}
+
+		if h.Fragmentation > thresholds.FragmentationMedium {
+			severity := "Medium"
+			if h.Fragmentation > thresholds.FragmentationHigh {
+				severity = "High"
+			}
+			issues = append(issues, MemoryIssue{
+				ID:          issueID("frag", h.HeapName, strconv.Itoa(h.HeapId)),
+				Severity:    severity,
+				Type:        "MemoryFragmentation",
+				Description: fmt.Sprintf("Heap %s is %.2f%% fragmented", label, h.Fragmentation),
+				Size:        h.TotalSize,
+				Count:       h.AllocationCount,
+				Score:       h.Fragmentation,
+				Suggestion:  fmt.Sprintf("Separate short-lived from long-lived allocations in heap %s, or serve its small fixed-size objects from pools, so freed blocks can be coalesced.", label),
+				Heap:        label,
+			})
+		}
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
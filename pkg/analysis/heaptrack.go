@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mempromcp/pkg/mempro"
+)
+
+// HeaptrackImport describes a heaptrack capture converted by import_heaptrack
+type HeaptrackImport struct {
+	Output           string `json:"output"`
+	Session          string `json:"session"`
+	TotalAllocations int    `json:"total_allocations"`
+	TotalSize        int64  `json:"total_size"`
+	LeakCount        int    `json:"leak_count"`
+	LeakSize         int64  `json:"leak_size"`
+	Functions        int    `json:"functions"`
+}
+
+// ImportHeaptrack converts the heaptrack capture at path to a MemPro JSON file at output
+func ImportHeaptrack(path, output string) (*HeaptrackImport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read heaptrack file: %w", err)
+	}
+	defer file.Close()
+
+	r, isHeaptrack, err := mempro.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	if !isHeaptrack {
+		return nil, fmt.Errorf("%s is not heaptrack data", filepath.Base(path))
+	}
+	data, err := mempro.DecodeHeaptrack(r, filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(output, encoded, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write capture: %w", err)
+	}
+
+	return &HeaptrackImport{
+		Output:           output,
+		Session:          data.SessionName,
+		TotalAllocations: data.TotalAllocations,
+		TotalSize:        data.TotalSize,
+		LeakCount:        data.LeakCount,
+		LeakSize:         data.LeakSize,
+		Functions:        len(data.Functions),
+	}, nil
+}
+
+// HeaptrackOutputPath derives the converted capture's path: heaptrack.app.123.gz becomes
+// heaptrack.app.123.json
+func HeaptrackOutputPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+}
@@ -0,0 +1,350 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// HTML report layout
+const (
+	defaultHTMLReportRows = 200
+	treemapWidth          = 1200
+	treemapHeight         = 600
+	treemapMinLabelWidth  = 60 // Pixels; narrower cells show their name in the tooltip only
+	unknownModule         = "(unknown)"
+)
+
+// HTMLReport describes a report written by generate_html_report
+type HTMLReport struct {
+	Output     string `json:"output"`
+	Bytes      int    `json:"bytes"`
+	Leaks      int    `json:"leaks"`
+	Issues     int    `json:"issues"`
+	Modules    int    `json:"modules"`
+	FlameGraph string `json:"flamegraph"`        // call_trees, leaks, or none
+	Omitted    int    `json:"omitted,omitempty"` // Table rows beyond rows
+}
+
+// ModuleSize is the bytes allocated by one module's functions
+type ModuleSize struct {
+	Module    string
+	Functions int
+	TotalSize int64
+}
+
+// treemapCell is a laid-out treemap rectangle
+type treemapCell struct {
+	ModuleSize
+	X, Y, W, H float64
+	Color      string
+	Label      string // Empty when the cell is too narrow for its name
+	Share      float64
+}
+
+// reportRow is one row of a sortable table; Sort holds the cell values compared when sorting
+type reportRow struct {
+	Cells []string
+	Sort  []string
+}
+
+// htmlReportPage is the data the report template renders
+type htmlReportPage struct {
+	Session          string
+	Totals           []struct{ Name, Value string }
+	Findings         []string
+	LeakHeader       []string
+	Leaks            []reportRow
+	IssueHeader      []string
+	Issues           []reportRow
+	Omitted          int
+	Treemap          []treemapCell
+	TreemapW         int
+	TreemapH         int
+	FlameGraph       template.HTML
+	FlameGraphSource string
+}
+
+// functionModule names the module a function belongs to: the configured module whose path
+// prefix matches its file, else the module recorded on the frame, else the file's directory
+func (ma *MemoryAnalyzer) functionModule(fn Function) string {
+	if i := moduleOf(ma.config.ModuleBudgets, fn.FileName); i >= 0 {
+		return ma.config.ModuleBudgets[i].Name
+	}
+	if module := frameModule(fn.FunctionName); module != "" {
+		return module
+	}
+	if dir := path.Dir(strings.ReplaceAll(fn.FileName, `\`, "/")); fn.FileName != "" && dir != "." && dir != "/" {
+		return path.Base(dir)
+	}
+	return unknownModule
+}
+
+// ModuleSizes sums the Functions statistics per module, largest first
+func (ma *MemoryAnalyzer) ModuleSizes() []ModuleSize {
+	index := make(map[string]int)
+	var sizes []ModuleSize
+	for _, fn := range ma.data.Functions {
+		if fn.TotalSize <= 0 {
+			continue
+		}
+		module := ma.functionModule(fn)
+		i, ok := index[module]
+		if !ok {
+			i = len(sizes)
+			index[module] = i
+			sizes = append(sizes, ModuleSize{Module: module})
+		}
+		sizes[i].Functions++
+		sizes[i].TotalSize += fn.TotalSize
+	}
+	sort.SliceStable(sizes, func(i, j int) bool { return sizes[i].TotalSize > sizes[j].TotalSize })
+	return sizes
+}
+
+// squarify lays sizes (largest first) out in the rectangle with the squarified treemap
+// algorithm, so cells stay close to square
+func squarify(sizes []ModuleSize, x, y, w, h float64) []treemapCell {
+	var total int64
+	for _, s := range sizes {
+		total += s.TotalSize
+	}
+	if total <= 0 {
+		return nil
+	}
+	scale := w * h / float64(total)
+
+	// worst returns the highest aspect ratio of a row of areas laid along a side
+	worst := func(row []float64, side float64) float64 {
+		var sum, lo, hi float64
+		for i, a := range row {
+			sum += a
+			if i == 0 || a < lo {
+				lo = a
+			}
+			hi = max(hi, a)
+		}
+		return max(side*side*hi/(sum*sum), sum*sum/(side*side*lo))
+	}
+
+	var cells []treemapCell
+	var row []float64
+	start := 0
+	flush := func(end int) {
+		var sum float64
+		for _, a := range row {
+			sum += a
+		}
+		if w >= h {
+			width := sum / h
+			cy := y
+			for i, a := range row {
+				cells = append(cells, treemapCell{ModuleSize: sizes[start+i], X: x, Y: cy, W: width, H: a / width})
+				cy += a / width
+			}
+			x, w = x+width, w-width
+		} else {
+			height := sum / w
+			cx := x
+			for i, a := range row {
+				cells = append(cells, treemapCell{ModuleSize: sizes[start+i], X: cx, Y: y, W: a / height, H: height})
+				cx += a / height
+			}
+			y, h = y+height, h-height
+		}
+		row, start = nil, end
+	}
+	for i, s := range sizes {
+		area := float64(s.TotalSize) * scale
+		if len(row) > 0 && worst(append(row[:len(row):len(row)], area), min(w, h)) > worst(row, min(w, h)) {
+			flush(i)
+		}
+		row = append(row, area)
+	}
+	flush(len(sizes))
+
+	for i := range cells {
+		c := &cells[i]
+		c.Color = flameColor(c.Module)
+		c.Share = float64(c.TotalSize) / float64(total) * 100
+		if c.W >= treemapMinLabelWidth && c.H >= flameFrameHeight {
+			c.Label = c.Module
+			if chars := int((c.W - 6) / flameCharWidth); len([]rune(c.Label)) > chars {
+				c.Label = string([]rune(c.Label)[:chars-2]) + ".."
+			}
+		}
+	}
+	return cells
+}
+
+// HTMLReport renders a single-file HTML report: totals and findings, sortable leak and issue
+// tables of up to rows entries, a treemap of allocated bytes by module, and a flamegraph of
+// the call trees (or the leak stacks when the capture has none)
+func (ma *MemoryAnalyzer) HTMLReport(rows int, skipPlumbing bool) ([]byte, *HTMLReport, error) {
+	if rows <= 0 {
+		rows = defaultHTMLReportRows
+	}
+	report := &HTMLReport{}
+	page := htmlReportPage{
+		Session:     ma.data.SessionName,
+		Findings:    ma.criticalFindings(),
+		LeakHeader:  []string{"ID", "Severity", "Function", "Location", "Leak size", "Allocations", "Occurrences", "Score"},
+		IssueHeader: []string{"ID", "Severity", "Type", "Function", "Size", "Count", "Description"},
+		TreemapW:    treemapWidth,
+		TreemapH:    treemapHeight,
+	}
+	page.Totals = []struct{ Name, Value string }{
+		{"Total allocations", fmt.Sprintf("%d", ma.data.TotalAllocations)},
+		{"Total size", FormatBytes(ma.data.TotalSize)},
+		{"Leaks", fmt.Sprintf("%d", ma.data.LeakCount)},
+		{"Leak size", fmt.Sprintf("%s (%.2f%%)", FormatBytes(ma.data.LeakSize), ma.LeakPercentage())},
+		{"Fragmentation", fmt.Sprintf("%.2f%%", ma.Fragmentation())},
+	}
+
+	leaks := ma.AnalyzeLeaks()
+	report.Leaks = len(leaks)
+	for i, issue := range leaks {
+		if i == rows {
+			report.Omitted += len(leaks) - rows
+			break
+		}
+		location := ""
+		if issue.FileName != "" {
+			location = fmt.Sprintf("%s:%d", issue.FileName, issue.LineNumber)
+		}
+		occurrences := max(issue.Occurrences, 1)
+		page.Leaks = append(page.Leaks, reportRow{
+			Cells: []string{issue.ID, issue.Severity, issue.FunctionName, location, FormatBytes(issue.Size), fmt.Sprint(issue.Count), fmt.Sprint(occurrences), fmt.Sprintf("%.2f", issue.Score)},
+			Sort:  []string{issue.ID, fmt.Sprint(severityRank(issue.Severity)), issue.FunctionName, location, fmt.Sprint(issue.Size), fmt.Sprint(issue.Count), fmt.Sprint(occurrences), fmt.Sprint(issue.Score)},
+		})
+	}
+
+	issues := ma.AllIssues()
+	SortIssues(issues)
+	report.Issues = len(issues)
+	for i, issue := range issues {
+		if i == rows {
+			report.Omitted += len(issues) - rows
+			break
+		}
+		page.Issues = append(page.Issues, reportRow{
+			Cells: []string{issue.ID, issue.Severity, issue.Type, issue.FunctionName, FormatBytes(issue.Size), fmt.Sprint(issue.Count), issue.Description},
+			Sort:  []string{issue.ID, fmt.Sprint(severityRank(issue.Severity)), issue.Type, issue.FunctionName, fmt.Sprint(issue.Size), fmt.Sprint(issue.Count), issue.Description},
+		})
+	}
+	page.Omitted = report.Omitted
+
+	modules := ma.ModuleSizes()
+	report.Modules = len(modules)
+	page.Treemap = squarify(modules, 0, 0, treemapWidth, treemapHeight)
+
+	report.FlameGraph = "none"
+	if stacks, err := ma.FoldCallTrees(skipPlumbing); err == nil {
+		page.FlameGraph, report.FlameGraph = embedSVG(stacks.SVG("Allocated bytes")), "call_trees"
+	} else if stacks, err := ma.FoldLeaks(skipPlumbing); err == nil {
+		page.FlameGraph, report.FlameGraph = embedSVG(stacks.SVG("Leaked bytes")), "leaks"
+	}
+	page.FlameGraphSource = report.FlameGraph
+
+	var b bytes.Buffer
+	if err := htmlReportTemplate.Execute(&b, page); err != nil {
+		return nil, nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	report.Bytes = b.Len()
+	return b.Bytes(), report, nil
+}
+
+// embedSVG drops the XML declaration so an SVG document can be inlined in HTML. The SVG
+// escapes every name it contains.
+func embedSVG(svg []byte) template.HTML {
+	if i := bytes.IndexByte(svg, '\n'); bytes.HasPrefix(svg, []byte("<?xml")) && i >= 0 {
+		svg = svg[i+1:]
+	}
+	return template.HTML(svg)
+}
+
+// SaveHTMLReport writes a rendered report to path
+func SaveHTMLReport(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes":  FormatBytes,
+	"format": func(v float64) string { return fmt.Sprintf("%.1f", v) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>MemPro report: {{.Session}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; } h2 { margin-top: 2em; border-bottom: 1px solid #ddd; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .3em 1.5em; }
+dt { font-weight: 600; }
+table { border-collapse: collapse; width: 100%; font-size: .9em; }
+th, td { border: 1px solid #ddd; padding: .3em .5em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; white-space: nowrap; }
+th.asc::after { content: " \25B2"; } th.desc::after { content: " \25BC"; }
+td.num { text-align: right; white-space: nowrap; }
+code { font-size: .95em; }
+.Critical { color: #b00020; font-weight: 600; } .High { color: #d35400; } .Medium { color: #b7950b; }
+.scroll { overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Memory report: {{.Session}}</h1>
+<dl>{{range .Totals}}<dt>{{.Name}}</dt><dd>{{.Value}}</dd>{{end}}</dl>
+{{if .Findings}}<ul>{{range .Findings}}<li>{{.}}</li>{{end}}</ul>{{end}}
+
+<h2>Leaks</h2>
+{{if .Leaks}}<table class="sortable">
+<thead><tr>{{range $i, $h := .LeakHeader}}<th{{if ge $i 4}} data-type="number"{{end}}>{{$h}}</th>{{end}}</tr></thead>
+<tbody>{{range .Leaks}}<tr>{{$sort := .Sort}}{{range $i, $c := .Cells}}<td data-sort="{{index $sort $i}}"{{if ge $i 4}} class="num"{{else if eq $i 1}} class="{{$c}}"{{end}}>{{if or (eq $i 0) (eq $i 2) (eq $i 3)}}<code>{{$c}}</code>{{else}}{{$c}}{{end}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>{{else}}<p>No leaks.</p>{{end}}
+
+<h2>All issues</h2>
+{{if .Issues}}<table class="sortable">
+<thead><tr>{{range $i, $h := .IssueHeader}}<th{{if or (eq $i 4) (eq $i 5)}} data-type="number"{{end}}>{{$h}}</th>{{end}}</tr></thead>
+<tbody>{{range .Issues}}<tr>{{$sort := .Sort}}{{range $i, $c := .Cells}}<td data-sort="{{index $sort $i}}"{{if or (eq $i 4) (eq $i 5)}} class="num"{{else if eq $i 1}} class="{{$c}}"{{end}}>{{if or (eq $i 0) (eq $i 3)}}<code>{{$c}}</code>{{else}}{{$c}}{{end}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>{{else}}<p>No issues.</p>{{end}}
+{{if .Omitted}}<p>{{.Omitted}} more rows are left out; raise <code>rows</code> to include them.</p>{{end}}
+
+<h2>Allocations by module</h2>
+{{if .Treemap}}<div class="scroll"><svg xmlns="http://www.w3.org/2000/svg" width="{{.TreemapW}}" height="{{.TreemapH}}" font-family="monospace" font-size="12">
+{{range .Treemap}}<g><title>{{.Module}}: {{bytes .TotalSize}} ({{format .Share}}%), {{.Functions}} functions</title><rect x="{{format .X}}" y="{{format .Y}}" width="{{format .W}}" height="{{format .H}}" fill="{{.Color}}" stroke="#fff"/>{{if .Label}}<text x="{{format .X}}" y="{{format .Y}}" dx="4" dy="14">{{.Label}}</text>{{end}}</g>
+{{end}}</svg></div>{{else}}<p>The capture has no function statistics.</p>{{end}}
+
+<h2>Flamegraph</h2>
+{{if .FlameGraph}}<p>Weighted by {{if eq .FlameGraphSource "leaks"}}leaked bytes of the leak call stacks{{else}}inclusive allocated bytes of the call trees{{end}}; hover a frame for its size.</p>
+<div class="scroll">{{.FlameGraph}}</div>{{else}}<p>The capture has neither call trees nor leak call stacks.</p>{{end}}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var numeric = th.dataset.type === "number";
+      var body = table.tBodies[0];
+      Array.from(body.rows).sort(function (a, b) {
+        var x = a.cells[col].dataset.sort, y = b.cells[col].dataset.sort;
+        var d = numeric ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+        return asc ? d : -d;
+      }).forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,111 @@
+package analysis
+
+import (
+	"sort"
+	"sync"
+)
+
+// captureIndex holds lookups over a parsed capture's records, built on first use and kept
+// with the capture in parsedCaptures, so repeated queries on an unchanged capture neither
+// rescan nor re-sort the records. Everything in it is read-only once built.
+type captureIndex struct {
+	once sync.Once
+
+	stacks       map[int]string // Call stack text by stack ID
+	leakGroups   []leakGroup    // Leak records merged by stack signature, in capture order
+	groupOf      []int          // Position in leakGroups by leak record, -1 when skipped
+	skippedLeaks int            // Leak records with neither size nor count
+	groupsByID   map[string]int // Position in leakGroups by issue ID
+
+	functionsByName map[string][]int // Functions records by name
+	leaksByFunction map[string][]int // Leaks records by allocating and by blamed function
+	leaksByFile     map[string][]int // Leaks records by file
+	typesByName     map[string][]int // Types records by type name
+	typesByFunction map[string][]int // Types records by most common allocating function
+	functionsBySize []int            // Functions records, largest total size first
+	leaksBySize     []int            // Leaks records, largest leak size first
+}
+
+// index returns the capture's lookups, building them on first use
+func (ma *MemoryAnalyzer) index() *captureIndex {
+	ma.lookups.once.Do(func() {
+		defer ma.diag.track("index_capture")()
+		ma.lookups.build(ma)
+	})
+	return ma.lookups
+}
+
+// build fills the index from ma's records
+func (idx *captureIndex) build(ma *MemoryAnalyzer) {
+	data := ma.data
+	idx.stacks = buildStackIndex(data)
+	idx.leakGroups, idx.groupOf, idx.skippedLeaks = ma.buildLeakGroups(idx.stacks)
+	idx.groupsByID = make(map[string]int, len(idx.leakGroups))
+	for i, g := range idx.leakGroups {
+		idx.groupsByID[g.ID] = i
+	}
+
+	idx.functionsByName = make(map[string][]int)
+	for i, fn := range data.Functions {
+		idx.functionsByName[fn.FunctionName] = append(idx.functionsByName[fn.FunctionName], i)
+	}
+
+	idx.leaksByFunction = make(map[string][]int)
+	idx.leaksByFile = make(map[string][]int)
+	for i, leak := range data.Leaks {
+		idx.leaksByFunction[leak.FunctionName] = append(idx.leaksByFunction[leak.FunctionName], i)
+		if leak.CallStack == "" {
+			leak.CallStack = idx.stacks[leak.StackId]
+		}
+		if blamed := ma.blame(leak); blamed != leak.FunctionName {
+			idx.leaksByFunction[blamed] = append(idx.leaksByFunction[blamed], i)
+		}
+		if leak.FileName != "" {
+			idx.leaksByFile[leak.FileName] = append(idx.leaksByFile[leak.FileName], i)
+		}
+	}
+
+	idx.typesByName = make(map[string][]int)
+	idx.typesByFunction = make(map[string][]int)
+	for i, t := range data.Types {
+		idx.typesByName[t.TypeName] = append(idx.typesByName[t.TypeName], i)
+		if t.MostCommonFunction != "" {
+			idx.typesByFunction[t.MostCommonFunction] = append(idx.typesByFunction[t.MostCommonFunction], i)
+		}
+	}
+
+	idx.functionsBySize = sortedBy(len(data.Functions), func(i, j int) bool {
+		return data.Functions[i].TotalSize > data.Functions[j].TotalSize
+	})
+	idx.leaksBySize = sortedBy(len(data.Leaks), func(i, j int) bool {
+		return data.Leaks[i].LeakSize > data.Leaks[j].LeakSize
+	})
+}
+
+// functionRecord returns the last Functions record named name
+func (ma *MemoryAnalyzer) functionRecord(name string) (Function, bool) {
+	positions := ma.index().functionsByName[name]
+	if len(positions) == 0 {
+		return Function{}, false
+	}
+	return ma.data.Functions[positions[len(positions)-1]], true
+}
+
+// typeRecord returns the last Types record named name
+func (ma *MemoryAnalyzer) typeRecord(name string) (AllocType, bool) {
+	positions := ma.index().typesByName[name]
+	if len(positions) == 0 {
+		return AllocType{}, false
+	}
+	return ma.data.Types[positions[len(positions)-1]], true
+}
+
+// sortedBy returns the positions [0, n) stably sorted by less
+func sortedBy(n int, less func(i, j int) bool) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return less(order[a], order[b]) })
+	return order
+}
@@ -0,0 +1,346 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Leak rate defaults
+const (
+	defaultLeakRateTop = 20
+
+	// Leak rates need the header totals and the leaks; page views resolve stack IDs
+	leakRateSections = SectionLeaks | SectionPageViews
+)
+
+// captureTimeLayouts are the absolute timestamps accepted besides RFC 3339
+var captureTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"}
+
+// TimedCapture is a capture file and when it was taken
+type TimedCapture struct {
+	Path     string
+	Time     time.Time // Absolute time, zero when given as an offset
+	Hours    float64   // Since the first capture once sorted
+	Absolute bool
+}
+
+// ParseTimedCaptures reads "time=path" pairs separated by commas. The time is an RFC 3339
+// timestamp, a duration since the start of the run such as "90m" or "2h30m", or a number of
+// hours. Without a time, the file's modification time is used. Captures are sorted by time.
+func ParseTimedCaptures(list string) ([]TimedCapture, error) {
+	var captures []TimedCapture
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var c TimedCapture
+		if stamp, path, ok := strings.Cut(entry, "="); ok {
+			c.Path = strings.TrimSpace(path)
+			var err error
+			if c.Time, c.Hours, c.Absolute, err = parseCaptureTime(strings.TrimSpace(stamp)); err != nil {
+				return nil, fmt.Errorf("capture %q: %w", entry, err)
+			}
+		} else {
+			info, err := os.Stat(entry)
+			if err != nil {
+				return nil, fmt.Errorf("capture %q has no time and its modification time is unknown: %w", entry, err)
+			}
+			c = TimedCapture{Path: entry, Time: info.ModTime(), Absolute: true}
+		}
+		if c.Path == "" {
+			return nil, fmt.Errorf("invalid capture %q (expected time=path)", entry)
+		}
+		if len(captures) > 0 && captures[0].Absolute != c.Absolute {
+			return nil, fmt.Errorf("capture %q: give every capture an absolute time or every capture an offset, not both", entry)
+		}
+		captures = append(captures, c)
+	}
+	if len(captures) < 2 {
+		return nil, fmt.Errorf("at least two captures are required, got %d", len(captures))
+	}
+
+	if captures[0].Absolute {
+		sort.SliceStable(captures, func(i, j int) bool { return captures[i].Time.Before(captures[j].Time) })
+		for i := range captures {
+			captures[i].Hours = captures[i].Time.Sub(captures[0].Time).Hours()
+		}
+	} else {
+		sort.SliceStable(captures, func(i, j int) bool { return captures[i].Hours < captures[j].Hours })
+		start := captures[0].Hours
+		for i := range captures {
+			captures[i].Hours -= start
+		}
+	}
+	for i := 1; i < len(captures); i++ {
+		if captures[i].Hours == captures[i-1].Hours {
+			return nil, fmt.Errorf("captures %s and %s are taken at the same time", filepath.Base(captures[i-1].Path), filepath.Base(captures[i].Path))
+		}
+	}
+	return captures, nil
+}
+
+// parseCaptureTime parses an absolute timestamp, a duration, or a number of hours
+func parseCaptureTime(s string) (t time.Time, hours float64, absolute bool, err error) {
+	for _, layout := range captureTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, 0, true, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Time{}, d.Hours(), false, nil
+	}
+	if h, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Time{}, h, false, nil
+	}
+	return time.Time{}, 0, false, fmt.Errorf("invalid time %q (expected e.g. 2026-01-02T15:04:05Z, 90m, or 1.5 for hours)", s)
+}
+
+// linearFit fits y = intercept + slope*x by least squares; r2 is the fraction of the variance
+// of y the line explains, 1 when y does not vary
+func linearFit(x, y []float64) (slope, intercept, r2 float64) {
+	n := float64(len(x))
+	var sx, sy float64
+	for i := range x {
+		sx += x[i]
+		sy += y[i]
+	}
+	mx, my := sx/n, sy/n
+	var sxx, sxy, syy float64
+	for i := range x {
+		sxx += (x[i] - mx) * (x[i] - mx)
+		sxy += (x[i] - mx) * (y[i] - my)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	if sxx == 0 {
+		return 0, my, 1
+	}
+	slope = sxy / sxx
+	intercept = my - slope*mx
+	r2 = 1.0
+	if syy > 0 {
+		r2 = sxy * sxy / (sxx * syy)
+	}
+	return slope, intercept, r2
+}
+
+// LeakRatePoint is one capture of the series
+type LeakRatePoint struct {
+	Capture   string  `json:"capture"`
+	Session   string  `json:"session,omitempty"`
+	Time      string  `json:"time,omitempty"` // RFC 3339, for absolute times
+	Hours     float64 `json:"hours"`          // Since the first capture
+	TotalSize int64   `json:"total_size"`     // Memory in use according to the capture header
+	LeakSize  int64   `json:"leak_size"`
+	LeakSites int     `json:"leak_sites"`
+}
+
+// LeakRateSite is the fitted growth of one merged leak across the captures
+type LeakRateSite struct {
+	ID            string   `json:"id"`
+	FunctionName  string   `json:"functionName"` // Blamed past allocator plumbing
+	AllocatedVia  string   `json:"allocated_via,omitempty"`
+	File          string   `json:"file,omitempty"`
+	Line          int      `json:"line,omitempty"`
+	Sizes         []int64  `json:"sizes"` // Leaked bytes per capture, 0 where the leak is absent
+	BytesPerHour  float64  `json:"bytes_per_hour"`
+	CountPerHour  float64  `json:"count_per_hour"`
+	Fit           float64  `json:"fit"`                     // R² of the line, 0 to 1; low values mean bursty growth
+	HoursToOOM    *float64 `json:"hours_to_oom,omitempty"`  // Until this leak alone exhausts the budget's headroom; 0 once exhausted
+	ProjectedOOM  string   `json:"projected_oom,omitempty"` // RFC 3339, for absolute times
+	Stack         string   `json:"stack,omitempty"`         // Summarized to its top application frames
+	ShareOfGrowth float64  `json:"share_of_growth"`         // Percentage of the growing leaks' combined rate
+}
+
+// LeakRateReport is the result of estimate_leak_rate
+type LeakRateReport struct {
+	Captures         []LeakRatePoint `json:"captures"`
+	Hours            float64         `json:"hours"` // Between the first and last capture
+	Budget           int64           `json:"budget,omitempty"`
+	InUse            int64           `json:"in_use"`                 // Memory in use at the last capture
+	Headroom         *int64          `json:"headroom,omitempty"`     // Budget left at the last capture
+	LeakBytesPerHour float64         `json:"leak_bytes_per_hour"`    // All leak sites together
+	HeapBytesPerHour float64         `json:"heap_bytes_per_hour"`    // Fitted to the header totals
+	HoursToOOM       *float64        `json:"hours_to_oom,omitempty"` // 0 when the headroom is already exhausted
+	ProjectedOOM     string          `json:"projected_oom,omitempty"`
+	HeapHoursToOOM   *float64        `json:"heap_hours_to_oom,omitempty"`
+	GrowingSites     int             `json:"growing_sites"`
+	ShrinkingSites   int             `json:"shrinking_sites"`
+	StableSites      int             `json:"stable_sites"`
+	Sites            []LeakRateSite  `json:"sites"` // Growing sites, fastest first
+	Omitted          int             `json:"omitted,omitempty"`
+	Notes            []string        `json:"notes,omitempty"`
+}
+
+// EstimateLeakRate follows each merged leak across captures of one program taken at known
+// times, fits its leaked bytes per hour by least squares, and projects when the leaks, and
+// the heap as a whole, exhaust budget bytes (no projection when budget is 0). Leaks are
+// matched by issue ID, which depends on the allocation site and call stack only.
+func EstimateLeakRate(captures []TimedCapture, budget int64, top int) (*LeakRateReport, error) {
+	if top <= 0 {
+		top = defaultLeakRateTop
+	}
+
+	red := CurrentConfig().Redactor()
+	report := &LeakRateReport{Budget: budget, Sites: []LeakRateSite{}}
+	type series struct {
+		site   LeakRateSite
+		counts []float64
+	}
+	sites := make(map[string]*series)
+	var order []string // Site IDs in order of first appearance
+	hours := make([]float64, len(captures))
+
+	for i, c := range captures {
+		analyzer, err := NewMemoryAnalyzerSections(c.Path, leakRateSections)
+		if err != nil {
+			return nil, fmt.Errorf("capture %s: %w", red.Path(c.Path), err)
+		}
+		hours[i] = c.Hours
+		point := LeakRatePoint{
+			Capture:   red.Path(c.Path),
+			Session:   analyzer.data.SessionName,
+			Hours:     math.Round(c.Hours*1000) / 1000,
+			TotalSize: analyzer.data.TotalSize,
+		}
+		if c.Absolute {
+			point.Time = c.Time.Format(time.RFC3339)
+		}
+
+		groups, _ := analyzer.groupLeaks()
+		for _, g := range groups {
+			s, ok := sites[g.ID]
+			if !ok {
+				s = &series{
+					site: LeakRateSite{
+						ID:           g.ID,
+						FunctionName: analyzer.blame(g.Leak),
+						File:         g.FileName,
+						Line:         g.LineNumber,
+						Sizes:        make([]int64, len(captures)),
+						Stack:        analyzer.config.plumbing.summarizeStack(g.CallStack, 0),
+					},
+					counts: make([]float64, len(captures)),
+				}
+				if s.site.FunctionName != g.FunctionName {
+					s.site.AllocatedVia = g.FunctionName
+				}
+				sites[g.ID] = s
+				order = append(order, g.ID)
+			}
+			s.site.Sizes[i] += g.LeakSize
+			s.counts[i] += float64(g.LeakCount)
+			point.LeakSize += g.LeakSize
+			point.LeakSites++
+		}
+		report.Captures = append(report.Captures, point)
+	}
+	report.Hours = report.Captures[len(report.Captures)-1].Hours
+
+	leakSizes := make([]float64, len(captures))
+	heapSizes := make([]float64, len(captures))
+	for i, p := range report.Captures {
+		leakSizes[i], heapSizes[i] = float64(p.LeakSize), float64(p.TotalSize)
+	}
+	leakRate, _, _ := linearFit(hours, leakSizes)
+	heapRate, _, _ := linearFit(hours, heapSizes)
+	report.LeakBytesPerHour = math.Round(leakRate)
+	report.HeapBytesPerHour = math.Round(heapRate)
+	report.InUse = report.Captures[len(report.Captures)-1].TotalSize
+
+	last := captures[len(captures)-1]
+	projected := func(hoursToOOM *float64) string {
+		if !last.Absolute || hoursToOOM == nil {
+			return ""
+		}
+		return last.Time.Add(time.Duration(*hoursToOOM * float64(time.Hour))).Format(time.RFC3339)
+	}
+
+	// hoursToOOM is how long growing by rate takes to use up the headroom: zero once it is
+	// exhausted, and nil when memory is not growing
+	var headroom int64
+	hoursToOOM := func(rate float64) *float64 {
+		var hours float64
+		switch {
+		case headroom == 0:
+		case rate > 0:
+			hours = roundHours(float64(headroom) / rate)
+		default:
+			return nil
+		}
+		return &hours
+	}
+	if budget > 0 {
+		headroom = max(budget-report.InUse, 0)
+		report.Headroom = &headroom
+		if headroom == 0 {
+			report.Notes = append(report.Notes, "memory in use at the last capture already exceeds the budget")
+		}
+		if report.InUse == 0 {
+			report.Notes = append(report.Notes, "the last capture records no total size, so the whole budget is taken as headroom")
+		}
+		report.HoursToOOM = hoursToOOM(leakRate)
+		report.ProjectedOOM = projected(report.HoursToOOM)
+		report.HeapHoursToOOM = hoursToOOM(heapRate)
+	} else {
+		report.Notes = append(report.Notes, "no budget given, so no time to OOM is projected")
+	}
+
+	var growing []LeakRateSite
+	var combined float64
+	for _, id := range order {
+		s := sites[id]
+		sizes := make([]float64, len(captures))
+		for i, size := range s.site.Sizes {
+			sizes[i] = float64(size)
+		}
+		rate, _, fit := linearFit(hours, sizes)
+		countRate, _, _ := linearFit(hours, s.counts)
+		switch {
+		case math.Round(rate) > 0:
+			report.GrowingSites++
+		case math.Round(rate) < 0:
+			report.ShrinkingSites++
+			continue
+		default:
+			report.StableSites++
+			continue
+		}
+
+		s.site.BytesPerHour = math.Round(rate)
+		s.site.CountPerHour = math.Round(countRate*100) / 100
+		s.site.Fit = math.Round(fit*1000) / 1000
+		if budget > 0 {
+			s.site.HoursToOOM = hoursToOOM(rate)
+			s.site.ProjectedOOM = projected(s.site.HoursToOOM)
+		}
+		combined += rate
+		growing = append(growing, s.site)
+	}
+
+	sort.SliceStable(growing, func(i, j int) bool { return growing[i].BytesPerHour > growing[j].BytesPerHour })
+	for i := range growing {
+		growing[i].ShareOfGrowth = math.Round(growing[i].BytesPerHour/combined*1000) / 10
+	}
+	if len(growing) > top {
+		report.Omitted = len(growing) - top
+		growing = growing[:top]
+	}
+	report.Sites = append(report.Sites, growing...)
+	if len(captures) == 2 {
+		report.Notes = append(report.Notes, "two captures fit any line exactly; add captures to tell steady leaks from one-off growth")
+	}
+	return report, nil
+}
+
+// roundHours rounds a duration in hours to two decimals
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
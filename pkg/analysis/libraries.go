@@ -0,0 +1,129 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LibrarySignature identifies a third-party library by its function names, binary modules,
+// or source paths; matching any one attributes a frame to the library
+type LibrarySignature struct {
+	Name    string   `json:"name"`
+	Frames  []string `json:"frames"`  // Function name prefixes, or regular expressions as "re:<pattern>"
+	Modules []string `json:"modules"` // Module name prefixes, case-insensitive, e.g. "libcrypto"
+	Paths   []string `json:"paths"`   // Source path prefixes, matched like module budget paths
+}
+
+// defaultLibrarySignatures are common game and engine dependencies
+var defaultLibrarySignatures = []LibrarySignature{
+	{Name: "zlib", Frames: []string{"deflate", "inflate", "zcalloc", "zcfree", "gzopen", "gzdopen"}, Modules: []string{"zlib", "libz"}, Paths: []string{"zlib/"}},
+	{Name: "OpenSSL", Frames: []string{"CRYPTO_", "OPENSSL_", "SSL_", "EVP_", "BN_", "ASN1_", "X509_", "BIO_", "EC_", "RSA_"}, Modules: []string{"libssl", "libcrypto", "libeay32", "ssleay32"}, Paths: []string{"openssl/"}},
+	{Name: "Lua", Frames: []string{"lua_", "luaL_", "luaopen_", "lj_", "re:^lua[A-Z]_"}, Modules: []string{"lua"}, Paths: []string{"lua/", "luajit/"}},
+	{Name: "PhysX", Frames: []string{"physx::", "re:^Px[A-Z]"}, Modules: []string{"physx"}, Paths: []string{"physx/"}},
+	{Name: "FMOD", Frames: []string{"FMOD::", "FMOD_"}, Modules: []string{"fmod"}, Paths: []string{"fmod/"}},
+	{Name: "Wwise", Frames: []string{"AK::", "re:^Ak[A-Z]\\w*::"}, Modules: []string{"aksoundengine"}, Paths: []string{"wwise/"}},
+	{Name: "Havok", Frames: []string{"re:^hk[a-z]{0,3}[A-Z]"}, Modules: []string{"havok"}, Paths: []string{"havok/"}},
+	{Name: "Bullet", Frames: []string{"btAligned", "re:^bt[A-Z]\\w*::"}, Modules: []string{"bullet"}, Paths: []string{"bullet/", "bullet3/"}},
+	{Name: "SDL", Frames: []string{"SDL_"}, Modules: []string{"sdl"}, Paths: []string{"sdl/", "sdl2/", "sdl3/"}},
+	{Name: "libpng", Frames: []string{"png_"}, Modules: []string{"libpng"}, Paths: []string{"libpng/"}},
+	{Name: "libjpeg", Frames: []string{"jpeg_", "jinit_"}, Modules: []string{"jpeg", "libjpeg", "turbojpeg"}, Paths: []string{"libjpeg/", "libjpeg-turbo/"}},
+	{Name: "FreeType", Frames: []string{"FT_", "ft_mem_"}, Modules: []string{"freetype"}, Paths: []string{"freetype/"}},
+	{Name: "protobuf", Frames: []string{"google::protobuf::"}, Modules: []string{"libprotobuf"}, Paths: []string{"protobuf/", "google/protobuf/"}},
+	{Name: "libcurl", Frames: []string{"curl_", "Curl_"}, Modules: []string{"libcurl"}, Paths: []string{"curl/"}},
+	{Name: "Boost", Frames: []string{"boost::"}, Paths: []string{"boost/"}},
+	{Name: "RapidJSON", Frames: []string{"rapidjson::"}, Paths: []string{"rapidjson/"}},
+	{Name: "Dear ImGui", Frames: []string{"ImGui", "ImDraw", "ImFont"}, Paths: []string{"imgui/"}},
+	{Name: "Steamworks", Frames: []string{"SteamAPI_", "SteamInternal_", "SteamGameServer_"}, Modules: []string{"steam_api"}, Paths: []string{"steamworks/"}},
+}
+
+// libraryMatcher is a compiled library signature
+type libraryMatcher struct {
+	name    string
+	frames  *frameMatcher
+	modules []string // Lower-cased
+	paths   []string
+}
+
+// compileLibraries compiles library signatures, in order
+func compileLibraries(signatures []LibrarySignature) ([]*libraryMatcher, error) {
+	compiled := make([]*libraryMatcher, 0, len(signatures))
+	for i, sig := range signatures {
+		if sig.Name == "" {
+			return nil, fmt.Errorf("entry %d has no name", i)
+		}
+		if len(sig.Frames) == 0 && len(sig.Modules) == 0 && len(sig.Paths) == 0 {
+			return nil, fmt.Errorf("library %q has no frames, modules, or paths", sig.Name)
+		}
+		frames, err := newFrameMatcher(sig.Frames)
+		if err != nil {
+			return nil, fmt.Errorf("library %q: %w", sig.Name, err)
+		}
+		m := &libraryMatcher{name: sig.Name, frames: frames, paths: sig.Paths}
+		for _, module := range sig.Modules {
+			m.modules = append(m.modules, strings.ToLower(module))
+		}
+		compiled = append(compiled, m)
+	}
+	return compiled, nil
+}
+
+// librarySignatures returns the configured libraries followed by the built-in ones, so a
+// configured signature is checked first
+func (c *Config) librarySignatures() []LibrarySignature {
+	if c.IgnoreDefaultLibraries {
+		return c.Libraries
+	}
+	return append(append([]LibrarySignature{}, c.Libraries...), defaultLibrarySignatures...)
+}
+
+// matchesFrame reports whether a frame's function name or module belongs to the library
+func (m *libraryMatcher) matchesFrame(frame string) bool {
+	if m.frames.matches(frame) {
+		return true
+	}
+	if module := strings.ToLower(frameModule(frame)); module != "" {
+		for _, prefix := range m.modules {
+			if strings.HasPrefix(module, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// library returns the library an issue belongs to, or "" for application code. The issue
+// is attributed by its first application frame (the frame blamed for a leak, else the
+// issue's function) and by its source file.
+func (c *Config) library(function, file, stack string) string {
+	frame := function
+	for _, f := range parseCallStack(stack) {
+		if !c.plumbing.isPlumbing(f) {
+			frame = f
+			break
+		}
+	}
+	normalized := normalizeModulePath(file)
+	for _, m := range c.libraries {
+		if (frame != "" && m.matchesFrame(frame)) || (file != "" && pathPrefixLength(m.paths, normalized) > 0) {
+			return m.name
+		}
+	}
+	return ""
+}
+
+// tagLibraries sets the library of each issue and of its rule record, if any
+func (ma *MemoryAnalyzer) tagLibraries(issues []MemoryIssue, records []ruleRecord) {
+	if ma.config == nil || len(ma.config.libraries) == 0 {
+		return
+	}
+	for i := range issues {
+		stack := ""
+		if records != nil {
+			stack = records[i].CallStack
+		}
+		issues[i].Library = ma.config.library(issues[i].FunctionName, issues[i].FileName, stack)
+		if records != nil {
+			records[i].Library = issues[i].Library
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Lifetime thresholds. Churn is many allocations freed within shortLivedMs; residents are
+// allocations alive for at least residentFraction of the session.
+const (
+	shortLivedMs       = 1.0
+	churnMinFreed      = 1000
+	churnMediumFreed   = 10000
+	churnHighFreed     = 100000
+	residentFraction   = 0.5
+	residentMinBytes   = 1024 * 1024      // 1 MB
+	residentMediumSize = 16 * 1024 * 1024 // 16 MB
+)
+
+// LifetimeStats summarizes how long one function's allocations live
+type LifetimeStats struct {
+	FunctionName    string  `json:"functionName"`
+	FileName        string  `json:"fileName,omitempty"`
+	LineNumber      int     `json:"lineNumber,omitempty"`
+	Allocations     int     `json:"allocations"`
+	Bytes           int64   `json:"bytes"`
+	Freed           int     `json:"freed"`
+	AverageLifetime float64 `json:"average_lifetime_ms"` // Over freed allocations
+	MaxLifetime     float64 `json:"max_lifetime_ms"`
+	Residents       int     `json:"residents"` // Alive for at least half the session
+	ResidentBytes   int64   `json:"resident_bytes"`
+}
+
+// HasLifetimes reports whether the capture carries allocation timing data
+func (ma *MemoryAnalyzer) HasLifetimes() bool {
+	if len(ma.data.Allocations) > 0 {
+		return true
+	}
+	for _, fn := range ma.data.Functions {
+		if fn.FreedCount > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionDuration returns the session length, falling back to the latest recorded timestamp
+func (ma *MemoryAnalyzer) sessionDuration() float64 {
+	duration := ma.data.SessionDuration
+	for _, a := range ma.data.Allocations {
+		duration = max(duration, a.AllocTime)
+		if a.FreeTime != nil {
+			duration = max(duration, *a.FreeTime)
+		}
+	}
+	return duration
+}
+
+// LifetimeStats returns lifetime statistics per function, largest first. Per-allocation
+// records are preferred; otherwise the per-function lifetime fields are used.
+func (ma *MemoryAnalyzer) LifetimeStats() []LifetimeStats {
+	duration := ma.sessionDuration()
+	residentAge := duration * residentFraction
+
+	var stats []LifetimeStats
+	if len(ma.data.Allocations) > 0 {
+		index := make(map[string]int)
+		var lifetimeSums []float64
+		for _, a := range ma.data.Allocations {
+			i, ok := index[a.FunctionName]
+			if !ok {
+				i = len(stats)
+				index[a.FunctionName] = i
+				stats = append(stats, LifetimeStats{FunctionName: a.FunctionName, FileName: a.FileName, LineNumber: a.LineNumber})
+				lifetimeSums = append(lifetimeSums, 0)
+			}
+			s := &stats[i]
+			s.Allocations++
+			s.Bytes += a.Size
+
+			// Live allocations have lived until the end of the session so far
+			age := duration - a.AllocTime
+			if a.FreeTime != nil {
+				age = *a.FreeTime - a.AllocTime
+				s.Freed++
+				lifetimeSums[i] += age
+			}
+			s.MaxLifetime = max(s.MaxLifetime, age)
+			if duration > 0 && age >= residentAge {
+				s.Residents++
+				s.ResidentBytes += a.Size
+			}
+		}
+		for i := range stats {
+			if stats[i].Freed > 0 {
+				stats[i].AverageLifetime = lifetimeSums[i] / float64(stats[i].Freed)
+			}
+		}
+	} else {
+		for _, fn := range ma.data.Functions {
+			if fn.FreedCount == 0 {
+				continue
+			}
+			s := LifetimeStats{
+				FunctionName:    fn.FunctionName,
+				FileName:        fn.FileName,
+				LineNumber:      fn.LineNumber,
+				Allocations:     fn.AllocationCount,
+				Bytes:           fn.TotalSize,
+				Freed:           fn.FreedCount,
+				AverageLifetime: fn.AverageLifetime,
+				MaxLifetime:     fn.MaxLifetime,
+			}
+			// Without per-allocation times, a function counts as resident as a whole
+			if duration > 0 && fn.AverageLifetime >= residentAge {
+				s.Residents = fn.FreedCount
+				s.ResidentBytes = fn.TotalSize
+			}
+			stats = append(stats, s)
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Bytes > stats[j].Bytes })
+	return stats
+}
+
+// AnalyzeLifetimes classifies allocation sites by lifetime: short-lived churn, which a scratch
+// buffer or frame arena would absorb, and long-lived residents, which belong in a dedicated
+// heap where they don't pin pages between short-lived allocations
+func (ma *MemoryAnalyzer) AnalyzeLifetimes() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil || !ma.HasLifetimes() {
+		return issues
+	}
+	defer ma.diag.track("analyze_lifetimes")()
+
+	duration := ma.sessionDuration()
+	for _, s := range ma.LifetimeStats() {
+		site := []string{s.FunctionName, s.FileName, strconv.Itoa(s.LineNumber)}
+
+		if s.Freed >= churnMinFreed && s.AverageLifetime < shortLivedMs {
+			severity := "Low"
+			switch {
+			case s.Freed >= churnHighFreed:
+				severity = "High"
+			case s.Freed >= churnMediumFreed:
+				severity = "Medium"
+			}
+
+			issues = append(issues, MemoryIssue{
+				ID:           issueID("churn", site...),
+				Severity:     severity,
+				Type:         "ShortLivedChurn",
+				Description:  fmt.Sprintf("%d allocations are freed after %.3f ms on average (longest %.3f ms)", s.Freed, s.AverageLifetime, s.MaxLifetime),
+				FunctionName: s.FunctionName,
+				FileName:     s.FileName,
+				LineNumber:   s.LineNumber,
+				Size:         s.Bytes,
+				Count:        s.Freed,
+				Score:        float64(s.Freed),
+				Suggestion:   fmt.Sprintf("These objects die almost immediately. Keep them on the stack, reuse a scratch buffer, or allocate them from a per-frame linear arena that is reset in one step, saving about %d allocator round trips.", s.Freed),
+				Savings: &Savings{
+					Allocations: s.Freed,
+				},
+			})
+		} else {
+			ma.diag.skip("functions_without_churn", 1)
+		}
+
+		if s.ResidentBytes >= residentMinBytes {
+			severity := "Low"
+			if s.ResidentBytes >= residentMediumSize {
+				severity = "Medium"
+			}
+
+			issues = append(issues, MemoryIssue{
+				ID:           issueID("resident", site...),
+				Severity:     severity,
+				Type:         "LongLivedResident",
+				Description:  fmt.Sprintf("%d allocations (%s) live for at least half of the %.0f ms session", s.Residents, FormatBytes(s.ResidentBytes), duration),
+				FunctionName: s.FunctionName,
+				FileName:     s.FileName,
+				LineNumber:   s.LineNumber,
+				Size:         s.ResidentBytes,
+				Count:        s.Residents,
+				Score:        s.MaxLifetime / max(duration, 1) * 100,
+				Suggestion:   "These allocations stay resident for most of the session. Allocate them up front from a dedicated long-lived heap or arena so they don't pin pages between short-lived allocations and fragment the general heap. If they are expected to be freed, check them against the leak analysis.",
+			})
+		} else {
+			ma.diag.skip("functions_without_residents", 1)
+		}
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
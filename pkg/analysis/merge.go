@@ -0,0 +1,235 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// defaultMergeTop is how many functions and run-specific leaks a merge report lists
+const defaultMergeTop = 20
+
+// DeterministicLeak is a leak fingerprint present in both captures
+type DeterministicLeak struct {
+	ID           string  `json:"id"`
+	FunctionName string  `json:"functionName"`
+	FileName     string  `json:"fileName,omitempty"`
+	LineNumber   int     `json:"lineNumber,omitempty"`
+	Sizes        []int64 `json:"sizes"`  // Per capture, in input order
+	Counts       []int   `json:"counts"` // Per capture, in input order
+	TotalSize    int64   `json:"total_size"`
+}
+
+// RunSpecificLeak is a leak fingerprint found in only one capture
+type RunSpecificLeak struct {
+	ID           string `json:"id"`
+	FunctionName string `json:"functionName"`
+	Capture      int    `json:"capture"` // Index of the capture, in input order
+	Size         int64  `json:"size"`
+	Count        int    `json:"count"`
+}
+
+// MergeReport describes two captures merged into one
+type MergeReport struct {
+	Sessions           []string            `json:"sessions"`
+	TotalAllocations   int                 `json:"total_allocations"`
+	TotalSize          int64               `json:"total_size"`
+	DeterministicSize  int64               `json:"deterministic_leak_size"`
+	RunSpecificSize    int64               `json:"run_specific_leak_size"`
+	DeterministicLeaks []DeterministicLeak `json:"deterministic_leaks"`
+	RunSpecificLeaks   []RunSpecificLeak   `json:"run_specific_leaks"`
+	TopFunctions       []Function          `json:"top_functions"`
+	Omitted            int                 `json:"omitted,omitempty"` // Run-specific leaks beyond top
+	Output             string              `json:"output,omitempty"`
+
+	merged *MemProData
+}
+
+// MergeCaptures merges two captures of the same binary. Function and type statistics are
+// summed; leaks are intersected by fingerprint, so only leaks that reproduce in both runs
+// remain in the merged capture. Call trees, page views, allocation records, and snapshots
+// are not merged.
+func MergeCaptures(a, b *MemoryAnalyzer, top int) *MergeReport {
+	if top <= 0 {
+		top = defaultMergeTop
+	}
+	inputs := []*MemProData{a.data, b.data}
+
+	merged := &MemProData{
+		SessionName:         a.data.SessionName + " + " + b.data.SessionName,
+		TotalSnapshots:      a.data.TotalSnapshots + b.data.TotalSnapshots,
+		TotalAllocations:    a.data.TotalAllocations + b.data.TotalAllocations,
+		TotalSize:           a.data.TotalSize + b.data.TotalSize,
+		MemoryFragmentation: (a.data.MemoryFragmentation + b.data.MemoryFragmentation) / 2,
+		SessionDuration:     max(a.data.SessionDuration, b.data.SessionDuration),
+		Functions:           mergeFunctions(inputs, a.data.TotalSize+b.data.TotalSize),
+		Types:               mergeTypes(inputs, a.data.TotalSize+b.data.TotalSize),
+	}
+
+	report := &MergeReport{
+		Sessions:         []string{a.data.SessionName, b.data.SessionName},
+		TotalAllocations: merged.TotalAllocations,
+		TotalSize:        merged.TotalSize,
+		merged:           merged,
+	}
+
+	// Leaks are keyed by fingerprint: allocation site plus stack signature
+	byID := make([]map[string]Leak, len(inputs))
+	for i, ma := range []*MemoryAnalyzer{a, b} {
+		byID[i] = make(map[string]Leak)
+		stacks := ma.stackIndex()
+		for _, leak := range ma.data.Leaks {
+			if leak.CallStack == "" {
+				leak.CallStack = stacks[leak.StackId]
+			}
+			id := leakID(leak)
+			if existing, ok := byID[i][id]; ok {
+				existing.LeakSize += leak.LeakSize
+				existing.LeakCount += leak.LeakCount
+				leak = existing
+			}
+			byID[i][id] = leak
+		}
+	}
+
+	var runSpecific []RunSpecificLeak
+	for id, first := range byID[0] {
+		second, ok := byID[1][id]
+		if !ok {
+			runSpecific = append(runSpecific, RunSpecificLeak{ID: id, FunctionName: a.blame(first), Capture: 0, Size: first.LeakSize, Count: first.LeakCount})
+			continue
+		}
+
+		leak := first
+		leak.LeakSize += second.LeakSize
+		leak.LeakCount += second.LeakCount
+		leak.LeakScore = max(first.LeakScore, second.LeakScore)
+		leak.IsSuspect = first.IsSuspect || second.IsSuspect
+		merged.Leaks = append(merged.Leaks, leak)
+		merged.LeakSize += leak.LeakSize
+		merged.LeakCount += leak.LeakCount
+
+		report.DeterministicLeaks = append(report.DeterministicLeaks, DeterministicLeak{
+			ID:           id,
+			FunctionName: a.blame(first),
+			FileName:     first.FileName,
+			LineNumber:   first.LineNumber,
+			Sizes:        []int64{first.LeakSize, second.LeakSize},
+			Counts:       []int{first.LeakCount, second.LeakCount},
+			TotalSize:    leak.LeakSize,
+		})
+		report.DeterministicSize += leak.LeakSize
+	}
+	for id, second := range byID[1] {
+		if _, ok := byID[0][id]; !ok {
+			runSpecific = append(runSpecific, RunSpecificLeak{ID: id, FunctionName: b.blame(second), Capture: 1, Size: second.LeakSize, Count: second.LeakCount})
+		}
+	}
+	for _, leak := range runSpecific {
+		report.RunSpecificSize += leak.Size
+	}
+
+	sort.Slice(merged.Leaks, func(i, j int) bool { return merged.Leaks[i].LeakSize > merged.Leaks[j].LeakSize })
+	sort.Slice(report.DeterministicLeaks, func(i, j int) bool {
+		return report.DeterministicLeaks[i].TotalSize > report.DeterministicLeaks[j].TotalSize
+	})
+	sort.Slice(runSpecific, func(i, j int) bool {
+		if runSpecific[i].Size != runSpecific[j].Size {
+			return runSpecific[i].Size > runSpecific[j].Size
+		}
+		return runSpecific[i].ID < runSpecific[j].ID
+	})
+	report.RunSpecificLeaks = runSpecific[:min(len(runSpecific), top)]
+	report.Omitted = len(runSpecific) - len(report.RunSpecificLeaks)
+	report.TopFunctions = merged.Functions[:min(len(merged.Functions), top)]
+
+	return report
+}
+
+// Save writes the merged capture in MemPro JSON format so other tools can analyze it
+func (r *MergeReport) Save(path string) error {
+	data, err := json.MarshalIndent(r.merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write merged capture: %w", err)
+	}
+	return nil
+}
+
+// mergeFunctions sums function statistics by name, largest total size first
+func mergeFunctions(inputs []*MemProData, totalSize int64) []Function {
+	byName := make(map[string]*Function)
+	var order []string
+	for _, d := range inputs {
+		for _, fn := range d.Functions {
+			m, ok := byName[fn.FunctionName]
+			if !ok {
+				copied := fn
+				byName[fn.FunctionName] = &copied
+				order = append(order, fn.FunctionName)
+				continue
+			}
+			m.AllocationCount += fn.AllocationCount
+			m.TotalSize += fn.TotalSize
+			m.MinSize = min(m.MinSize, fn.MinSize)
+			m.MaxSize = max(m.MaxSize, fn.MaxSize)
+			if freed := m.FreedCount + fn.FreedCount; freed > 0 {
+				m.AverageLifetime = (m.AverageLifetime*float64(m.FreedCount) + fn.AverageLifetime*float64(fn.FreedCount)) / float64(freed)
+				m.FreedCount = freed
+			}
+			m.MaxLifetime = max(m.MaxLifetime, fn.MaxLifetime)
+		}
+	}
+
+	functions := make([]Function, 0, len(order))
+	for _, name := range order {
+		fn := byName[name]
+		if fn.AllocationCount > 0 {
+			fn.AverageSize = float64(fn.TotalSize) / float64(fn.AllocationCount)
+		}
+		if totalSize > 0 {
+			fn.Percentage = float64(fn.TotalSize) / float64(totalSize) * 100
+		}
+		functions = append(functions, *fn)
+	}
+	sort.SliceStable(functions, func(i, j int) bool { return functions[i].TotalSize > functions[j].TotalSize })
+	return functions
+}
+
+// mergeTypes sums allocation type statistics by type name, largest total size first
+func mergeTypes(inputs []*MemProData, totalSize int64) []AllocType {
+	byName := make(map[string]*AllocType)
+	var order []string
+	for _, d := range inputs {
+		for _, t := range d.Types {
+			m, ok := byName[t.TypeName]
+			if !ok {
+				copied := t
+				byName[t.TypeName] = &copied
+				order = append(order, t.TypeName)
+				continue
+			}
+			m.AllocationCount += t.AllocationCount
+			m.TotalSize += t.TotalSize
+			m.MinSize = min(m.MinSize, t.MinSize)
+			m.MaxSize = max(m.MaxSize, t.MaxSize)
+		}
+	}
+
+	types := make([]AllocType, 0, len(order))
+	for _, name := range order {
+		t := byName[name]
+		if t.AllocationCount > 0 {
+			t.AverageSize = float64(t.TotalSize) / float64(t.AllocationCount)
+		}
+		if totalSize > 0 {
+			t.Percentage = float64(t.TotalSize) / float64(totalSize) * 100
+		}
+		types = append(types, *t)
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].TotalSize > types[j].TotalSize })
+	return types
+}
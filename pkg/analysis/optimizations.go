@@ -0,0 +1,466 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Duplicate-allocation thresholds: a call site qualifies when every allocation has the same
+// size and it allocates at least duplicateMinCount times
+const (
+	duplicateMinCount    = 1000
+	duplicateMediumCount = 10000
+	duplicateHighCount   = 100000
+)
+
+// AnalyzeDuplicateAllocations flags call sites that repeatedly allocate identical sizes, which
+// are candidates for caching or reusing a single buffer
+func (ma *MemoryAnalyzer) AnalyzeDuplicateAllocations() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_duplicate_allocations")()
+
+	issues = shard(len(ma.data.Functions), ma.workers(), func(start, end int) []MemoryIssue {
+		var found []MemoryIssue
+		skipped := 0
+		for _, fn := range ma.data.Functions[start:end] {
+			if fn.AllocationCount < duplicateMinCount || fn.MinSize <= 0 || fn.MinSize != fn.MaxSize {
+				skipped++
+				continue
+			}
+
+			severity := "Low"
+			switch {
+			case fn.AllocationCount >= duplicateHighCount:
+				severity = "High"
+			case fn.AllocationCount >= duplicateMediumCount:
+				severity = "Medium"
+			}
+
+			// Reusing one buffer replaces every allocation but the first
+			savings := &Savings{
+				Bytes:       fn.TotalSize - fn.MaxSize,
+				Allocations: fn.AllocationCount - 1,
+			}
+
+			found = append(found, MemoryIssue{
+				ID:           issueID("dup", fn.FunctionName, fn.FileName, strconv.Itoa(fn.LineNumber)),
+				Severity:     severity,
+				Type:         "DuplicateAllocation",
+				Description:  fmt.Sprintf("Allocates %d bytes %d times (%s in total); every allocation has the same size", fn.MaxSize, fn.AllocationCount, FormatBytes(fn.TotalSize)),
+				FunctionName: fn.FunctionName,
+				FileName:     fn.FileName,
+				LineNumber:   fn.LineNumber,
+				Size:         fn.TotalSize,
+				Count:        fn.AllocationCount,
+				Score:        float64(fn.AllocationCount),
+				Suggestion: fmt.Sprintf("Cache or reuse a single %d-byte buffer (e.g. a member or thread_local scratch buffer, or clear() and refill instead of reallocating), saving about %d allocation calls and %s of allocation traffic.",
+					fn.MaxSize, savings.Allocations, FormatBytes(savings.Bytes)),
+				Savings: savings,
+			})
+		}
+		ma.diag.skip("functions_without_duplicate_allocations", skipped)
+		return found
+	})
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// Pool-candidate thresholds and the pool layout used for estimates
+const (
+	poolMinCount         = 10000
+	poolHighCount        = 100000
+	poolMinCallSites     = 2
+	poolSlabSize         = 64 * 1024
+	poolAlignment        = 8
+	AllocatorHeaderBytes = 16 // Typical per-allocation overhead of a general-purpose heap
+	poolListedCallSites  = 5
+)
+
+// AnalyzePoolCandidates finds fixed-size objects allocated at high counts from several call
+// sites, which a dedicated pool or arena would serve with less overhead and fragmentation.
+// Allocation counts in a snapshot are live objects, so they estimate the pool occupancy.
+func (ma *MemoryAnalyzer) AnalyzePoolCandidates() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_pool_candidates")()
+
+	type cluster struct {
+		size      int64
+		count     int
+		callSites []Function
+		types     []string
+		typeCount int
+	}
+	clusters := make(map[int64]*cluster)
+	get := func(size int64) *cluster {
+		c, ok := clusters[size]
+		if !ok {
+			c = &cluster{size: size}
+			clusters[size] = c
+		}
+		return c
+	}
+
+	for _, fn := range ma.data.Functions {
+		if fn.MinSize > 0 && fn.MinSize == fn.MaxSize {
+			c := get(fn.MinSize)
+			c.count += fn.AllocationCount
+			c.callSites = append(c.callSites, fn)
+		}
+	}
+	for _, t := range ma.data.Types {
+		if t.MinSize > 0 && t.MinSize == t.MaxSize {
+			c := get(t.MinSize)
+			c.types = append(c.types, t.TypeName)
+			c.typeCount += t.AllocationCount
+		}
+	}
+
+	sizes := make([]int64, 0, len(clusters))
+	for size := range clusters {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	for _, size := range sizes {
+		c := clusters[size]
+		count := max(c.count, c.typeCount)
+		// A single call site is a reuse opportunity rather than a pool; type statistics
+		// already aggregate every call site, so a known type counts as spread
+		if count < poolMinCount || (len(c.callSites) < poolMinCallSites && len(c.types) == 0) {
+			ma.diag.skip("size_classes_below_pool_threshold", 1)
+			continue
+		}
+
+		sort.Slice(c.callSites, func(i, j int) bool { return c.callSites[i].AllocationCount > c.callSites[j].AllocationCount })
+		var sites []string
+		for _, fn := range c.callSites[:min(len(c.callSites), poolListedCallSites)] {
+			sites = append(sites, fn.FunctionName)
+		}
+		if extra := len(c.callSites) - len(sites); extra > 0 {
+			sites = append(sites, fmt.Sprintf("%d more", extra))
+		}
+
+		slotSize := (size + poolAlignment - 1) / poolAlignment * poolAlignment
+		perSlab := max(int(poolSlabSize/slotSize), 1)
+		slabs := (count + perSlab - 1) / perSlab
+		occupancy := float64(count) / float64(slabs*perSlab) * 100
+
+		severity := "Medium"
+		if count >= poolHighCount {
+			severity = "High"
+		}
+
+		description := fmt.Sprintf("%d live %d-byte objects", count, size)
+		if len(c.types) > 0 {
+			description += fmt.Sprintf(" of type %s", strings.Join(c.types, ", "))
+		}
+		if len(sites) > 0 {
+			description += fmt.Sprintf(" allocated from %d call sites (%s)", len(c.callSites), strings.Join(sites, ", "))
+		}
+
+		// Blame the busiest call site; type-only clusters have none
+		var top Function
+		if len(c.callSites) > 0 {
+			top = c.callSites[0]
+		}
+
+		issues = append(issues, MemoryIssue{
+			ID:           issueID("pool", strconv.FormatInt(size, 10)),
+			Severity:     severity,
+			Type:         "PoolCandidate",
+			Description:  description,
+			FunctionName: top.FunctionName,
+			FileName:     top.FileName,
+			LineNumber:   top.LineNumber,
+			Size:         size * int64(count),
+			Count:        count,
+			Score:        occupancy,
+			Suggestion: fmt.Sprintf("Serve these objects from a dedicated fixed-size pool: %d-byte slots in %s slabs hold %d objects each, so the current %d objects fit in %d slabs at %.1f%% occupancy. This removes per-allocation heap overhead and keeps the objects out of the general heap.",
+				slotSize, FormatBytes(poolSlabSize), perSlab, count, slabs, occupancy),
+			Savings: &Savings{
+				Bytes:       int64(count) * AllocatorHeaderBytes,
+				Allocations: count - slabs,
+			},
+		})
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// Alignment-waste thresholds. Allocators round small sizes up to a power of two and larger
+// sizes up to a multiple of the cache line, so a type a few bytes over a boundary pays for
+// almost a whole extra size class.
+const (
+	cacheLineSize       = 64
+	alignmentMinCount   = 1000
+	alignmentMediumCost = 1024 * 1024      // 1 MB of padding
+	alignmentHighCost   = 10 * 1024 * 1024 // 10 MB of padding
+)
+
+// sizeClassBounds returns the largest allocator size class below size and the class the
+// size is rounded up to
+func sizeClassBounds(size int64) (below, rounded int64) {
+	if size <= cacheLineSize {
+		below = 1
+		for below*2 < size {
+			below *= 2
+		}
+		return below, below * 2
+	}
+	below = (size - 1) / cacheLineSize * cacheLineSize
+	return below, below + cacheLineSize
+}
+
+// AnalyzeAlignmentWaste estimates the padding lost by fixed-size types that are just over a
+// power-of-two or cache-line boundary, e.g. 65-byte objects that occupy 128-byte slots
+func (ma *MemoryAnalyzer) AnalyzeAlignmentWaste() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_alignment_waste")()
+
+	for _, t := range ma.data.Types {
+		size := t.MinSize
+		if t.AllocationCount < alignmentMinCount || size <= 8 || size != t.MaxSize {
+			ma.diag.skip("types_without_alignment_waste", 1)
+			continue
+		}
+
+		// "Just over" means past the boundary by at most an eighth of it (at least 8 bytes)
+		below, rounded := sizeClassBounds(size)
+		overshoot := size - below
+		if !reorderableTrim(overshoot, below) || rounded == size {
+			ma.diag.skip("types_without_alignment_waste", 1)
+			continue
+		}
+
+		count := int64(t.AllocationCount)
+		padding := (rounded - size) * count
+		severity := "Low"
+		switch {
+		case padding >= alignmentHighCost:
+			severity = "High"
+		case padding >= alignmentMediumCost:
+			severity = "Medium"
+		}
+
+		issues = append(issues, MemoryIssue{
+			ID:           issueID("align", t.TypeName, strconv.FormatInt(size, 10)),
+			Severity:     severity,
+			Type:         "AlignmentWaste",
+			Description:  fmt.Sprintf("%s is %d bytes, %d over the %d-byte boundary, so each of its %d objects occupies a %d-byte slot (%s of padding)", t.TypeName, size, overshoot, below, t.AllocationCount, rounded, FormatBytes(padding)),
+			FunctionName: t.MostCommonFunction,
+			FileName:     t.MostCommonFile,
+			LineNumber:   t.MostCommonLine,
+			Size:         padding,
+			Count:        t.AllocationCount,
+			Score:        float64(rounded-size) / float64(rounded) * 100,
+			Suggestion: fmt.Sprintf("Trim %s by %d bytes to fit %d-byte slots: reorder members largest first to remove internal padding, narrow field types, use bitfields for flags, or move rarely used fields out of line. This saves %d bytes per object.",
+				t.TypeName, overshoot, below, rounded-below),
+			Savings: &Savings{
+				Bytes: (rounded - below) * count,
+			},
+		})
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// Tiny-allocation thresholds: a call site qualifies when none of its allocations exceed
+// tinyMaxSize bytes, or when any of them is zero bytes
+const (
+	tinyMaxSize     = 8
+	tinyMinCount    = 1000
+	tinyMediumCount = 10000
+	tinyHighCount   = 100000
+)
+
+// AnalyzeTinyAllocations flags call sites making zero-byte or 1-8 byte heap allocations at
+// scale. Zero-byte requests are almost always bugs; tiny ones cost more in allocator
+// overhead than they store.
+func (ma *MemoryAnalyzer) AnalyzeTinyAllocations() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_tiny_allocations")()
+
+	issues = shard(len(ma.data.Functions), ma.workers(), func(start, end int) []MemoryIssue {
+		var found []MemoryIssue
+		skipped := 0
+		for _, fn := range ma.data.Functions[start:end] {
+			// Captures without size ranges report zero for both bounds
+			zero := fn.MinSize == 0 && (fn.MaxSize > 0 || fn.TotalSize == 0)
+			if fn.AllocationCount < tinyMinCount || (!zero && (fn.MinSize <= 0 || fn.MaxSize > tinyMaxSize)) {
+				skipped++
+				continue
+			}
+
+			severity := "Low"
+			switch {
+			case fn.AllocationCount >= tinyHighCount:
+				severity = "High"
+			case fn.AllocationCount >= tinyMediumCount:
+				severity = "Medium"
+			}
+
+			var description, suggestion string
+			switch {
+			case zero && fn.MaxSize == 0:
+				description = fmt.Sprintf("Makes %d zero-byte allocations", fn.AllocationCount)
+			case zero:
+				description = fmt.Sprintf("Makes %d allocations of 0 to %d bytes, some of them zero-byte", fn.AllocationCount, fn.MaxSize)
+			case fn.MinSize == fn.MaxSize:
+				description = fmt.Sprintf("Makes %d allocations of %d bytes", fn.AllocationCount, fn.MaxSize)
+			default:
+				description = fmt.Sprintf("Makes %d allocations of %d to %d bytes", fn.AllocationCount, fn.MinSize, fn.MaxSize)
+			}
+			if zero {
+				// A zero-byte request is a bug, so rate it one level higher
+				switch severity {
+				case "High":
+					severity = "Critical"
+				case "Medium":
+					severity = "High"
+				default:
+					severity = "Medium"
+				}
+				suggestion = "Zero-byte allocations usually come from empty containers or arrays that still allocate (new T[0], malloc(0), reserving zero capacity). Skip the allocation when the requested size is zero and use a null or shared empty sentinel instead."
+			} else {
+				suggestion = "Each allocation stores at most 8 bytes but pays the allocator's per-allocation overhead. Store the value inline (by value, std::optional, small-buffer optimization), pack it into the owning object, or refer to it by index into a shared array."
+			}
+
+			savings := &Savings{
+				Bytes:       int64(fn.AllocationCount) * AllocatorHeaderBytes,
+				Allocations: fn.AllocationCount,
+			}
+
+			found = append(found, MemoryIssue{
+				ID:           issueID("tiny", fn.FunctionName, fn.FileName, strconv.Itoa(fn.LineNumber)),
+				Severity:     severity,
+				Type:         "TinyAllocation",
+				Description:  description,
+				FunctionName: fn.FunctionName,
+				FileName:     fn.FileName,
+				LineNumber:   fn.LineNumber,
+				Size:         fn.TotalSize,
+				Count:        fn.AllocationCount,
+				Score:        float64(fn.AllocationCount),
+				Suggestion:   fmt.Sprintf("%s Removing them saves about %d allocation calls and %s of allocator overhead.", suggestion, savings.Allocations, FormatBytes(savings.Bytes)),
+				Savings:      savings,
+			})
+		}
+		ma.diag.skip("functions_without_tiny_allocations", skipped)
+		return found
+	})
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// Churn thresholds: a call site is a churn hotspot when it allocates at least churnMinCount
+// times at an average of at most churnMaxAverage bytes
+const (
+	churnMinCount     = 10000
+	churnMediumCount  = 100000
+	churnHighCount    = 1000000
+	churnMaxAverage   = 256.0
+	churnGrowthSpread = 4 // Max/min size ratio from which sizes look like container growth
+)
+
+// churnContainerPattern matches functions that grow containers
+var churnContainerPattern = regexp.MustCompile(`vector|string|push_back|emplace_back|append|insert|resize|Add|Append`)
+
+// AnalyzeChurn flags call sites allocating very many small blocks, judged from each
+// function's AllocationCount against its AverageSize, and recommends a strategy by the
+// shape of the sizes: a pool for fixed sizes, reserve() for growing containers, and an
+// arena otherwise. Zero or negative limits use the defaults.
+func (ma *MemoryAnalyzer) AnalyzeChurn(minCount int, maxAverage float64) []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil {
+		return issues
+	}
+	defer ma.diag.track("analyze_churn")()
+	if minCount <= 0 {
+		minCount = churnMinCount
+	}
+	if maxAverage <= 0 {
+		maxAverage = churnMaxAverage
+	}
+
+	issues = shard(len(ma.data.Functions), ma.workers(), func(start, end int) []MemoryIssue {
+		var found []MemoryIssue
+		skipped := 0
+		for _, fn := range ma.data.Functions[start:end] {
+			average := fn.AverageSize
+			if average <= 0 && fn.AllocationCount > 0 {
+				average = float64(fn.TotalSize) / float64(fn.AllocationCount)
+			}
+			if fn.AllocationCount < minCount || average <= 0 || average > maxAverage {
+				skipped++
+				continue
+			}
+
+			severity := "Low"
+			switch {
+			case fn.AllocationCount >= churnHighCount:
+				severity = "High"
+			case fn.AllocationCount >= churnMediumCount:
+				severity = "Medium"
+			}
+
+			slotSize := (int64(math.Ceil(average)) + poolAlignment - 1) / poolAlignment * poolAlignment
+			var strategy string
+			switch {
+			case fn.MinSize > 0 && fn.MinSize == fn.MaxSize:
+				slotSize = (fn.MinSize + poolAlignment - 1) / poolAlignment * poolAlignment
+				strategy = fmt.Sprintf("Every allocation is %d bytes, so serve them from a fixed-size pool or free list of %d-byte slots and recycle objects instead of freeing them.", fn.MinSize, slotSize)
+			case fn.MinSize > 0 && fn.MaxSize >= fn.MinSize*churnGrowthSpread && churnContainerPattern.MatchString(fn.FunctionName):
+				strategy = fmt.Sprintf("Sizes range from %d to %d bytes in a container-growing function, which points at repeated reallocation: reserve() the expected capacity up front, or reuse the container across calls instead of rebuilding it.", fn.MinSize, fn.MaxSize)
+			default:
+				strategy = "Sizes vary, so allocate these short-lived blocks from a per-frame or per-task arena (bump allocator) that is reset in one step, or keep them in inline or small-buffer storage."
+			}
+
+			perSlab := max(int(poolSlabSize/slotSize), 1)
+			slabs := (fn.AllocationCount + perSlab - 1) / perSlab
+			savings := &Savings{
+				Bytes:       int64(fn.AllocationCount) * AllocatorHeaderBytes,
+				Allocations: fn.AllocationCount - slabs,
+			}
+
+			found = append(found, MemoryIssue{
+				ID:           issueID("churn", fn.FunctionName, fn.FileName, strconv.Itoa(fn.LineNumber)),
+				Severity:     severity,
+				Type:         "AllocationChurn",
+				Description:  fmt.Sprintf("Makes %d allocations averaging %.0f bytes (%s in total)", fn.AllocationCount, average, FormatBytes(fn.TotalSize)),
+				FunctionName: fn.FunctionName,
+				FileName:     fn.FileName,
+				LineNumber:   fn.LineNumber,
+				Size:         fn.TotalSize,
+				Count:        fn.AllocationCount,
+				Score:        math.Round(float64(fn.AllocationCount)/average*100) / 100,
+				Suggestion:   fmt.Sprintf("%s That saves about %d allocation calls and %s of allocator overhead.", strategy, savings.Allocations, FormatBytes(savings.Bytes)),
+				Savings:      savings,
+			})
+		}
+		ma.diag.skip("functions_below_churn_threshold", skipped)
+		return found
+	})
+
+	return ma.applyCustomRules(issues, nil)
+}
@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Overhead estimate defaults: a general-purpose heap's per-allocation header and the
+// granularity it rounds requests up to
+const (
+	defaultOverheadMaxSize   = 128
+	defaultOverheadAlignment = 16
+	defaultOverheadTop       = 20
+	overheadPoolMinCount     = 1000
+)
+
+// OverheadSite is one call site's estimated allocator overhead
+type OverheadSite struct {
+	FunctionName    string  `json:"functionName"`
+	FileName        string  `json:"fileName,omitempty"`
+	LineNumber      int     `json:"lineNumber,omitempty"`
+	Allocations     int     `json:"allocations"`
+	Size            float64 `json:"size"`  // Average request size
+	Exact           bool    `json:"exact"` // Every request has this size
+	RequestedBytes  int64   `json:"requested_bytes"`
+	HeaderBytes     int64   `json:"header_bytes"`
+	AlignmentBytes  int64   `json:"alignment_bytes"`
+	Overhead        int64   `json:"overhead"`
+	OverheadPercent float64 `json:"overhead_percent"` // Of the requested bytes
+	PoolCandidate   bool    `json:"pool_candidate"`
+	PoolSlotSize    int64   `json:"pool_slot_size,omitempty"`
+	PoolSavings     int64   `json:"pool_savings,omitempty"` // Overhead minus the pool's slot padding
+}
+
+// OverheadReport is the result of estimate_overhead
+type OverheadReport struct {
+	MaxSize         int64          `json:"max_size"`
+	HeaderSize      int64          `json:"header_size"`
+	Alignment       int64          `json:"alignment"`
+	Sites           int            `json:"sites"`
+	Allocations     int            `json:"allocations"`
+	RequestedBytes  int64          `json:"requested_bytes"`
+	HeaderBytes     int64          `json:"header_bytes"`
+	AlignmentBytes  int64          `json:"alignment_bytes"`
+	Overhead        int64          `json:"overhead"`
+	OverheadPercent float64        `json:"overhead_percent"`
+	PoolSavings     int64          `json:"pool_savings"` // Over all pool candidates
+	Functions       []OverheadSite `json:"functions"`    // Largest overhead first
+}
+
+// EstimateOverhead estimates the allocator header and alignment bytes spent on call sites
+// whose average request is at most maxSize bytes, from each function's AverageSize and
+// MinSize/MaxSize. Sites allocating often at a narrow range of sizes (the largest at most
+// twice the smallest) are pool candidates: fixed slots carved from slabs need no header.
+func (ma *MemoryAnalyzer) EstimateOverhead(maxSize, header, alignment int64, top int) (*OverheadReport, error) {
+	if maxSize <= 0 {
+		maxSize = defaultOverheadMaxSize
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("header_size must not be negative")
+	}
+	if alignment <= 0 {
+		alignment = defaultOverheadAlignment
+	}
+	if top <= 0 {
+		top = defaultOverheadTop
+	}
+
+	report := &OverheadReport{MaxSize: maxSize, HeaderSize: header, Alignment: alignment, Functions: []OverheadSite{}}
+	var sites []OverheadSite
+	for _, fn := range ma.data.Functions {
+		if fn.AllocationCount <= 0 || fn.TotalSize <= 0 {
+			continue
+		}
+		average := fn.AverageSize
+		if average <= 0 {
+			average = float64(fn.TotalSize) / float64(fn.AllocationCount)
+		}
+		if average > float64(maxSize) {
+			continue
+		}
+
+		count := int64(fn.AllocationCount)
+		site := OverheadSite{
+			FunctionName:   fn.FunctionName,
+			FileName:       fn.FileName,
+			LineNumber:     fn.LineNumber,
+			Allocations:    fn.AllocationCount,
+			Size:           math.Round(average*100) / 100,
+			Exact:          fn.MinSize > 0 && fn.MinSize == fn.MaxSize,
+			RequestedBytes: fn.TotalSize,
+			HeaderBytes:    count * header,
+		}
+		if site.Exact {
+			site.AlignmentBytes = count * (roundUp(fn.MinSize, alignment) - fn.MinSize)
+		} else {
+			size := int64(math.Ceil(average))
+			site.AlignmentBytes = count * (roundUp(size, alignment) - size)
+		}
+		site.Overhead = site.HeaderBytes + site.AlignmentBytes
+		site.OverheadPercent = math.Round(float64(site.Overhead)/float64(fn.TotalSize)*10000) / 100
+
+		if fn.AllocationCount >= overheadPoolMinCount && fn.MinSize > 0 && fn.MaxSize <= 2*fn.MinSize {
+			slot := roundUp(fn.MaxSize, poolAlignment)
+			if padding := slot*count - fn.TotalSize; padding < site.Overhead {
+				site.PoolCandidate = true
+				site.PoolSlotSize = slot
+				site.PoolSavings = site.Overhead - padding
+			}
+		}
+		sites = append(sites, site)
+
+		report.Sites++
+		report.Allocations += site.Allocations
+		report.RequestedBytes += site.RequestedBytes
+		report.HeaderBytes += site.HeaderBytes
+		report.AlignmentBytes += site.AlignmentBytes
+		report.Overhead += site.Overhead
+		report.PoolSavings += site.PoolSavings
+	}
+	if report.RequestedBytes > 0 {
+		report.OverheadPercent = math.Round(float64(report.Overhead)/float64(report.RequestedBytes)*10000) / 100
+	}
+
+	sort.SliceStable(sites, func(i, j int) bool {
+		if sites[i].Overhead != sites[j].Overhead {
+			return sites[i].Overhead > sites[j].Overhead
+		}
+		return sites[i].FunctionName < sites[j].FunctionName
+	})
+	report.Functions = append(report.Functions, sites[:min(len(sites), top)]...)
+	return report, nil
+}
@@ -0,0 +1,122 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// defaultPaddingTop is how many types analyze_type_padding lists
+const defaultPaddingTop = 20
+
+// TypePadding is the estimated padding cost of one allocated type
+type TypePadding struct {
+	TypeName           string  `json:"typeName"`
+	Size               int64   `json:"size"`  // Exact size, or the rounded average when sizes vary
+	Exact              bool    `json:"exact"` // Every object of the type has this size
+	Count              int     `json:"count"`
+	SlotSize           int64   `json:"slot_size"`       // Power-of-two or cache-line class the size rounds up to
+	Padding            int64   `json:"padding"`         // Bytes per object between the size and its slot
+	TotalPadding       int64   `json:"total_padding"`   // Over all objects
+	PaddingPercent     float64 `json:"padding_percent"` // Of the slot
+	Boundary           int64   `json:"boundary"`        // Next class down
+	TrimBytes          int64   `json:"trim_bytes"`      // Bytes to remove to fit the boundary
+	TrimSavings        int64   `json:"trim_savings"`    // Bytes saved over all objects by fitting it
+	CacheLines         int64   `json:"cache_lines"`     // Cache lines one object spans
+	Reorderable        bool    `json:"reorderable"`     // Trim is small enough for field reordering to plausibly reach
+	MostCommonFunction string  `json:"mostCommonFunction,omitempty"`
+	MostCommonFile     string  `json:"mostCommonFile,omitempty"`
+	MostCommonLine     int     `json:"mostCommonLine,omitempty"`
+}
+
+// TypePaddingReport is the result of analyze_type_padding
+type TypePaddingReport struct {
+	Types        int           `json:"types"` // Types analyzed
+	TotalPadding int64         `json:"total_padding"`
+	TrimSavings  int64         `json:"trim_savings"` // Over the reorderable types
+	Candidates   []TypePadding `json:"candidates"`   // Reorderable types, largest trim savings first
+	Others       []TypePadding `json:"others"`       // Other types with padding, most padding first
+}
+
+// reorderableTrim reports whether removing trim bytes from a type just over boundary is
+// within what reordering fields, narrowing types, or packing flags usually recovers: an
+// eighth of the boundary, at least 8 bytes, as for AlignmentWaste issues
+func reorderableTrim(trim, boundary int64) bool {
+	return trim <= max(boundary/8, 8)
+}
+
+// TypePadding estimates, per type with at least minCount objects, the padding between its
+// size and the power-of-two or cache-line class it rounds up to, and what trimming it to the
+// class below would save. Types with sizes varying around their AverageSize are estimated
+// from the average.
+func (ma *MemoryAnalyzer) TypePadding(minCount, top int) (*TypePaddingReport, error) {
+	if len(ma.data.Types) == 0 {
+		return nil, fmt.Errorf("capture has no type statistics")
+	}
+	if minCount <= 0 {
+		minCount = alignmentMinCount
+	}
+	if top <= 0 {
+		top = defaultPaddingTop
+	}
+
+	report := &TypePaddingReport{Candidates: []TypePadding{}, Others: []TypePadding{}}
+	for _, t := range ma.data.Types {
+		exact := t.MinSize > 0 && t.MinSize == t.MaxSize
+		size := t.MinSize
+		if !exact {
+			size = int64(math.Round(t.AverageSize))
+			if size <= 0 && t.AllocationCount > 0 {
+				size = int64(math.Round(float64(t.TotalSize) / float64(t.AllocationCount)))
+			}
+		}
+		if t.AllocationCount < minCount || size <= 8 {
+			continue
+		}
+
+		below, rounded := sizeClassBounds(size)
+		if rounded == size {
+			below = size
+		}
+		count := int64(t.AllocationCount)
+		p := TypePadding{
+			TypeName:           t.TypeName,
+			Size:               size,
+			Exact:              exact,
+			Count:              t.AllocationCount,
+			SlotSize:           rounded,
+			Padding:            rounded - size,
+			TotalPadding:       (rounded - size) * count,
+			PaddingPercent:     math.Round(float64(rounded-size)/float64(rounded)*10000) / 100,
+			Boundary:           below,
+			TrimBytes:          size - below,
+			CacheLines:         (size + cacheLineSize - 1) / cacheLineSize,
+			MostCommonFunction: t.MostCommonFunction,
+			MostCommonFile:     t.MostCommonFile,
+			MostCommonLine:     t.MostCommonLine,
+		}
+		if p.TrimBytes > 0 {
+			p.TrimSavings = (rounded - below) * count
+			p.Reorderable = reorderableTrim(p.TrimBytes, below)
+		}
+
+		report.Types++
+		report.TotalPadding += p.TotalPadding
+		if p.Reorderable {
+			report.TrimSavings += p.TrimSavings
+			report.Candidates = append(report.Candidates, p)
+		} else if p.Padding > 0 {
+			report.Others = append(report.Others, p)
+		}
+	}
+
+	sort.SliceStable(report.Candidates, func(i, j int) bool {
+		return report.Candidates[i].TrimSavings > report.Candidates[j].TrimSavings
+	})
+	sort.SliceStable(report.Others, func(i, j int) bool {
+		return report.Others[i].TotalPadding > report.Others[j].TotalPadding
+	})
+	report.Candidates = report.Candidates[:min(len(report.Candidates), top)]
+	report.Others = report.Others[:min(len(report.Others), top)]
+	return report, nil
+}
@@ -0,0 +1,746 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Address-space layout and headroom thresholds
+const (
+	defaultPageSize   = 4096
+	minUserAddress    = 0x10000 // The first 64 KB are never mapped
+	addressSpace32    = 2 << 30 // Default 32-bit user space
+	addressSpace32LAA = 4 << 30 // Large-address-aware 32-bit process on a 64-bit OS
+	addressSpace64    = 128 << 40
+
+	addressSpaceHigh     = 75.0 // Percent of the address space in use
+	addressSpaceCritical = 90.0
+	minFreeBlock32       = 64 << 20 // Below this, large allocations in a 32-bit process start failing
+)
+
+// pageSize returns the size of a page view region
+func pageSize(pv PageView) int64 {
+	if pv.Size > 0 {
+		return pv.Size
+	}
+	return defaultPageSize
+}
+
+// pageState normalizes a page state such as MEM_COMMIT or "Reserved" to committed,
+// reserved, or free; unknown states return ""
+func pageState(pv PageView) string {
+	state := strings.ToLower(pv.State)
+	switch {
+	case strings.Contains(state, "commit"):
+		return "committed"
+	case strings.Contains(state, "reserve"):
+		return "reserved"
+	case strings.Contains(state, "free"):
+		return "free"
+	}
+	return ""
+}
+
+// AddressSpaceReport describes how much of the process's address space is in use
+type AddressSpaceReport struct {
+	AddressSpace       int64    `json:"address_space"`
+	AddressSpaceSource string   `json:"address_space_source"` // parameter, capture, or inferred
+	Committed          int64    `json:"committed"`
+	Reserved           int64    `json:"reserved"` // Reserved but not committed
+	Used               int64    `json:"used"`
+	UsedPercent        float64  `json:"used_percent"`
+	Headroom           int64    `json:"headroom"`
+	LargestFreeBlock   int64    `json:"largest_free_block"`
+	GrowthPerHour      float64  `json:"growth_per_hour,omitempty"` // Bytes of address space per hour
+	GrowthSource       string   `json:"growth_source,omitempty"`
+	HoursToExhaustion  float64  `json:"hours_to_exhaustion,omitempty"`
+	Notes              []string `json:"notes,omitempty"`
+}
+
+// UsedAddressSpace returns committed and reserved bytes from the page views
+func (ma *MemoryAnalyzer) UsedAddressSpace() (committed, reserved int64) {
+	for _, pv := range ma.data.PageViews {
+		switch pageState(pv) {
+		case "committed":
+			committed += pageSize(pv)
+		case "reserved":
+			reserved += pageSize(pv)
+		}
+	}
+	return committed, reserved
+}
+
+// AddressSpace computes address-space headroom from the page views. space overrides the
+// address-space size (0 uses the capture's, else infers it from the highest address);
+// growthPerHour, when positive, projects the time until the address space is exhausted.
+func (ma *MemoryAnalyzer) AddressSpace(space int64, growthPerHour float64) *AddressSpaceReport {
+	report := &AddressSpaceReport{}
+	report.Committed, report.Reserved = ma.UsedAddressSpace()
+	report.Used = report.Committed + report.Reserved
+
+	type region struct{ start, end int64 }
+	var regions []region
+	var highest int64
+	for _, pv := range ma.data.PageViews {
+		if state := pageState(pv); state == "committed" || state == "reserved" {
+			r := region{pv.Address, pv.Address + pageSize(pv)}
+			regions = append(regions, r)
+			highest = max(highest, r.end)
+		}
+	}
+
+	switch {
+	case space > 0:
+		report.AddressSpace, report.AddressSpaceSource = space, "parameter"
+	case ma.data.AddressSpaceSize > 0:
+		report.AddressSpace, report.AddressSpaceSource = ma.data.AddressSpaceSize, "capture"
+	default:
+		report.AddressSpaceSource = "inferred"
+		switch {
+		case highest <= addressSpace32:
+			report.AddressSpace = addressSpace32
+			report.Notes = append(report.Notes, "Assumed a 32-bit process with a 2 GB address space; pass address_space for large-address-aware (4 GB) or /3GB processes")
+		case highest <= addressSpace32LAA:
+			report.AddressSpace = addressSpace32LAA
+			report.Notes = append(report.Notes, "Assumed a large-address-aware 32-bit process with a 4 GB address space")
+		default:
+			report.AddressSpace = addressSpace64
+		}
+	}
+
+	report.Headroom = max(report.AddressSpace-report.Used, 0)
+	if report.AddressSpace > 0 {
+		report.UsedPercent = float64(report.Used) / float64(report.AddressSpace) * 100
+	}
+
+	// The largest gap between used regions bounds the largest allocation that can succeed
+	sort.Slice(regions, func(i, j int) bool { return regions[i].start < regions[j].start })
+	end := int64(minUserAddress)
+	for _, r := range regions {
+		report.LargestFreeBlock = max(report.LargestFreeBlock, r.start-end)
+		end = max(end, r.end)
+	}
+	report.LargestFreeBlock = max(report.LargestFreeBlock, report.AddressSpace-end)
+
+	if growthPerHour != 0 {
+		report.GrowthPerHour = growthPerHour
+	}
+	if growthPerHour > 0 {
+		report.HoursToExhaustion = float64(report.Headroom) / growthPerHour
+	}
+	if len(ma.data.PageViews) == 0 {
+		report.Notes = append(report.Notes, "The capture has no page views, so no address space is accounted as used")
+	}
+	return report
+}
+
+// AnalyzeAddressSpace reports processes running out of address space, or (in 32-bit
+// processes) out of contiguous blocks for large allocations
+func (ma *MemoryAnalyzer) AnalyzeAddressSpace() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil || len(ma.data.PageViews) == 0 {
+		return issues
+	}
+	defer ma.diag.track("analyze_address_space")()
+
+	report := ma.AddressSpace(0, 0)
+	if report.UsedPercent >= addressSpaceHigh {
+		severity := "High"
+		if report.UsedPercent >= addressSpaceCritical {
+			severity = "Critical"
+		}
+		issues = append(issues, MemoryIssue{
+			ID:          issueID("vas"),
+			Severity:    severity,
+			Type:        "AddressSpaceExhaustion",
+			Description: fmt.Sprintf("%.1f%% of the %s address space is in use (%s committed, %s reserved), leaving %s", report.UsedPercent, FormatBytes(report.AddressSpace), FormatBytes(report.Committed), FormatBytes(report.Reserved), FormatBytes(report.Headroom)),
+			Size:        report.Used,
+			Score:       report.UsedPercent,
+			Suggestion:  "Release reservations that are never committed, unmap views that are no longer needed, and fix leaks that hold address space. For 32-bit processes, consider enabling large-address-awareness or moving to 64-bit.",
+		})
+	} else if report.AddressSpace <= addressSpace32LAA && report.LargestFreeBlock < minFreeBlock32 {
+		issues = append(issues, MemoryIssue{
+			ID:          issueID("vas", "block"),
+			Severity:    "Medium",
+			Type:        "AddressSpaceExhaustion",
+			Description: fmt.Sprintf("The largest free block in the %s address space is %s, so larger allocations will fail although %s is free in total", FormatBytes(report.AddressSpace), FormatBytes(report.LargestFreeBlock), FormatBytes(report.Headroom)),
+			Size:        report.LargestFreeBlock,
+			Score:       report.UsedPercent,
+			Suggestion:  "The address space is fragmented. Reserve large buffers early in one block, avoid interleaving long-lived mappings with short-lived ones, and load DLLs at contiguous base addresses.",
+		})
+	} else {
+		ma.diag.note("address space use %.2f%% is below the reporting threshold", report.UsedPercent)
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
+
+// defaultPageUsageTop is how many functions and regions get_page_usage lists
+const defaultPageUsageTop = 10
+
+// pageTouched returns the committed bytes of a page view that are in use, reading Usage as
+// the percentage of the region touched
+func pageTouched(pv PageView) int64 {
+	usage := min(max(pv.Usage, 0), 100)
+	return pageSize(pv) * int64(usage) / 100
+}
+
+// PageUsageGroup is committed memory of one region type or function, weighted by usage
+type PageUsageGroup struct {
+	Name       string  `json:"name"`
+	Committed  int64   `json:"committed"`
+	Touched    int64   `json:"touched"`
+	Untouched  int64   `json:"untouched"`
+	Efficiency float64 `json:"efficiency"` // Percentage of committed bytes touched
+	Regions    int     `json:"regions"`
+}
+
+// PageRegionUsage is one committed region and how much of it is touched
+type PageRegionUsage struct {
+	Address      string `json:"address"`
+	Size         int64  `json:"size"`
+	Usage        int    `json:"usage"` // Percent
+	Type         string `json:"type,omitempty"`
+	FunctionName string `json:"functionName,omitempty"`
+}
+
+// PageUsageReport approximates working-set efficiency: touched versus merely committed memory
+type PageUsageReport struct {
+	Committed       int64             `json:"committed"`
+	Touched         int64             `json:"touched"`
+	Untouched       int64             `json:"untouched"`
+	Efficiency      float64           `json:"efficiency"`
+	ByType          []PageUsageGroup  `json:"by_type"`
+	ByFunction      []PageUsageGroup  `json:"by_function"`
+	EmptiestRegions []PageRegionUsage `json:"emptiest_regions"` // Most untouched bytes first
+	Omitted         int               `json:"omitted_functions,omitempty"`
+}
+
+// PageUsage weights committed page views by their Usage, grouped by region type and by the
+// function that committed them
+func (ma *MemoryAnalyzer) PageUsage(top int) *PageUsageReport {
+	if top <= 0 {
+		top = defaultPageUsageTop
+	}
+
+	report := &PageUsageReport{}
+	byType := make(map[string]*PageUsageGroup)
+	byFunction := make(map[string]*PageUsageGroup)
+	add := func(groups map[string]*PageUsageGroup, name string, size, touched int64) {
+		g, ok := groups[name]
+		if !ok {
+			g = &PageUsageGroup{Name: name}
+			groups[name] = g
+		}
+		g.Committed += size
+		g.Touched += touched
+		g.Regions++
+	}
+
+	var regions []PageView
+	for _, pv := range ma.data.PageViews {
+		if pageState(pv) != "committed" {
+			continue
+		}
+		size, touched := pageSize(pv), pageTouched(pv)
+		report.Committed += size
+		report.Touched += touched
+
+		typ := pv.Type
+		if typ == "" {
+			typ = "(unknown)"
+		}
+		function := pv.FunctionName
+		if function == "" {
+			function = "(unattributed)"
+		}
+		add(byType, typ, size, touched)
+		add(byFunction, function, size, touched)
+		regions = append(regions, pv)
+	}
+	report.Untouched = report.Committed - report.Touched
+	if report.Committed > 0 {
+		report.Efficiency = float64(report.Touched) / float64(report.Committed) * 100
+	}
+
+	report.ByType = usageGroups(byType)
+	functions := usageGroups(byFunction)
+	report.ByFunction = functions[:min(len(functions), top)]
+	report.Omitted = len(functions) - len(report.ByFunction)
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		return pageSize(regions[i])-pageTouched(regions[i]) > pageSize(regions[j])-pageTouched(regions[j])
+	})
+	for _, pv := range regions[:min(len(regions), top)] {
+		report.EmptiestRegions = append(report.EmptiestRegions, PageRegionUsage{
+			Address:      fmt.Sprintf("0x%x", pv.Address),
+			Size:         pageSize(pv),
+			Usage:        min(max(pv.Usage, 0), 100),
+			Type:         pv.Type,
+			FunctionName: pv.FunctionName,
+		})
+	}
+
+	return report
+}
+
+// usageGroups finalizes page usage groups, most untouched bytes first
+func usageGroups(groups map[string]*PageUsageGroup) []PageUsageGroup {
+	result := make([]PageUsageGroup, 0, len(groups))
+	for _, g := range groups {
+		g.Untouched = g.Committed - g.Touched
+		if g.Committed > 0 {
+			g.Efficiency = float64(g.Touched) / float64(g.Committed) * 100
+		}
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Untouched != result[j].Untouched {
+			return result[i].Untouched > result[j].Untouched
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// usageWeightedFragmentation scores fragmentation over committed pages, weighting each
+// page's unused share by the bytes touched in it. Nearly untouched regions are sparse
+// reservations rather than fragmented heap, so they barely count, unlike a raw page count
+// where every mostly-empty page scores as fragmented. ok is false when no page records Usage.
+func (ma *MemoryAnalyzer) usageWeightedFragmentation() (score float64, ok bool) {
+	var weighted float64
+	var touched int64
+	for _, pv := range ma.data.PageViews {
+		if pageState(pv) != "committed" || pv.Usage <= 0 {
+			continue
+		}
+		t := pageTouched(pv)
+		weighted += float64(t) * float64(100-min(pv.Usage, 100))
+		touched += t
+	}
+	if touched == 0 {
+		return 0, false
+	}
+	return weighted / float64(touched), true
+}
+
+// Fragmentation returns the fragmentation score used for findings: usage-weighted when the
+// capture's page views record Usage, else the exported MemoryFragmentation
+func (ma *MemoryAnalyzer) Fragmentation() float64 {
+	if score, ok := ma.usageWeightedFragmentation(); ok {
+		return score
+	}
+	return ma.data.MemoryFragmentation
+}
+
+// Page map defaults
+const (
+	defaultMinReservation = 1 << 20 // Reserved regions listed by analyze_pages
+	defaultPageMapTop     = 10
+)
+
+// Windows page protection constants
+const (
+	pageNoAccess         = 0x01
+	pageReadOnly         = 0x02
+	pageReadWrite        = 0x04
+	pageWriteCopy        = 0x08
+	pageExecute          = 0x10
+	pageExecuteRead      = 0x20
+	pageExecuteReadWrite = 0x40
+	pageExecuteWriteCopy = 0x80
+	pageGuard            = 0x100
+	pageNoCache          = 0x200
+	pageWriteCombine     = 0x400
+)
+
+var protectionNames = map[int]string{
+	pageNoAccess:         "PAGE_NOACCESS",
+	pageReadOnly:         "PAGE_READONLY",
+	pageReadWrite:        "PAGE_READWRITE",
+	pageWriteCopy:        "PAGE_WRITECOPY",
+	pageExecute:          "PAGE_EXECUTE",
+	pageExecuteRead:      "PAGE_EXECUTE_READ",
+	pageExecuteReadWrite: "PAGE_EXECUTE_READWRITE",
+	pageExecuteWriteCopy: "PAGE_EXECUTE_WRITECOPY",
+}
+
+// protectionName renders a protection value such as PAGE_READWRITE|PAGE_GUARD; zero (not
+// recorded) returns ""
+func protectionName(p int) string {
+	if p == 0 {
+		return ""
+	}
+	name, ok := protectionNames[p&0xff]
+	if !ok {
+		name = fmt.Sprintf("0x%x", p&0xff)
+	}
+	for _, m := range []struct {
+		flag int
+		name string
+	}{{pageGuard, "PAGE_GUARD"}, {pageNoCache, "PAGE_NOCACHE"}, {pageWriteCombine, "PAGE_WRITECOMBINE"}} {
+		if p&m.flag != 0 {
+			name += "|" + m.name
+		}
+	}
+	return name
+}
+
+// unusualProtection explains why a committed region's protection stands out, or returns ""
+func unusualProtection(pv PageView) string {
+	p := pv.Protection
+	base := p & 0xff
+	image := strings.Contains(strings.ToLower(pv.Type), "image")
+	switch {
+	case p == 0:
+		return ""
+	case base == pageExecuteReadWrite:
+		return "writable and executable"
+	case base == pageExecuteWriteCopy && !image:
+		return "writable and executable outside a module image"
+	case (base == pageExecute || base == pageExecuteRead) && !image:
+		return "executable memory outside a module image (JIT or injected code)"
+	case base == pageNoAccess:
+		return "committed but inaccessible"
+	case p&pageNoCache != 0:
+		return "uncached (usually a driver or device mapping)"
+	case p&pageWriteCombine != 0:
+		return "write-combined (usually a GPU or driver mapping)"
+	case p&pageGuard != 0:
+		return "guard page (normal below a thread stack, unusual elsewhere)"
+	case protectionNames[base] == "":
+		return "unrecognized protection value"
+	}
+	return ""
+}
+
+// PageStateTotals sums regions in one state
+type PageStateTotals struct {
+	Bytes   int64 `json:"bytes"`
+	Regions int   `json:"regions"`
+}
+
+// PageTypeTotals splits one region type (MEM_PRIVATE, MEM_MAPPED, ...) by state
+type PageTypeTotals struct {
+	Type      string `json:"type"`
+	Committed int64  `json:"committed"`
+	Reserved  int64  `json:"reserved"`
+	Regions   int    `json:"regions"`
+}
+
+// ProtectionTotals sums committed regions with one protection
+type ProtectionTotals struct {
+	Protection string `json:"protection"`
+	Bytes      int64  `json:"bytes"`
+	Regions    int    `json:"regions"`
+}
+
+// MapRegion is a page view region listed by analyze_pages
+type MapRegion struct {
+	Address      string `json:"address"`
+	Size         int64  `json:"size"`
+	State        string `json:"state"`
+	Type         string `json:"type,omitempty"`
+	Protection   string `json:"protection,omitempty"`
+	FunctionName string `json:"functionName,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// PageMapReport summarizes the virtual memory map recorded in the page views
+type PageMapReport struct {
+	Committed           PageStateTotals    `json:"committed"`
+	Reserved            PageStateTotals    `json:"reserved"` // Reserved but not committed
+	Free                PageStateTotals    `json:"free"`
+	Unknown             *PageStateTotals   `json:"unknown,omitempty"` // Regions whose state is not recognized
+	ByType              []PageTypeTotals   `json:"by_type"`
+	ByProtection        []ProtectionTotals `json:"by_protection,omitempty"` // Committed regions
+	LargeReservations   []MapRegion        `json:"large_reservations"`      // Largest first
+	OmittedReservations int                `json:"omitted_reservations,omitempty"`
+	UnusualProtection   []MapRegion        `json:"unusual_protection"` // Largest first
+	OmittedUnusual      int                `json:"omitted_unusual,omitempty"`
+}
+
+// PageMap summarizes committed, reserved, and free memory, lists reserved regions of at
+// least minReserved bytes, and surfaces committed regions with unusual protection
+func (ma *MemoryAnalyzer) PageMap(minReserved int64, top int) *PageMapReport {
+	if minReserved <= 0 {
+		minReserved = defaultMinReservation
+	}
+	if top <= 0 {
+		top = defaultPageMapTop
+	}
+
+	report := &PageMapReport{LargeReservations: []MapRegion{}, UnusualProtection: []MapRegion{}}
+	byType := make(map[string]*PageTypeTotals)
+	byProtection := make(map[string]*ProtectionTotals)
+	region := func(pv PageView, state, reason string) MapRegion {
+		return MapRegion{
+			Address:      fmt.Sprintf("0x%x", pv.Address),
+			Size:         pageSize(pv),
+			State:        state,
+			Type:         pv.Type,
+			Protection:   protectionName(pv.Protection),
+			FunctionName: pv.FunctionName,
+			Reason:       reason,
+		}
+	}
+
+	var unknown PageStateTotals
+	for _, pv := range ma.data.PageViews {
+		size := pageSize(pv)
+		state := pageState(pv)
+		totals := &unknown
+		switch state {
+		case "committed":
+			totals = &report.Committed
+		case "reserved":
+			totals = &report.Reserved
+		case "free":
+			totals = &report.Free
+		}
+		totals.Bytes += size
+		totals.Regions++
+
+		if state == "committed" || state == "reserved" {
+			typ := pv.Type
+			if typ == "" {
+				typ = "(unknown)"
+			}
+			t, ok := byType[typ]
+			if !ok {
+				t = &PageTypeTotals{Type: typ}
+				byType[typ] = t
+			}
+			t.Regions++
+			if state == "committed" {
+				t.Committed += size
+			} else {
+				t.Reserved += size
+			}
+		}
+
+		switch state {
+		case "reserved":
+			if size >= minReserved {
+				report.LargeReservations = append(report.LargeReservations, region(pv, state, ""))
+			}
+		case "committed":
+			if name := protectionName(pv.Protection); name != "" {
+				p, ok := byProtection[name]
+				if !ok {
+					p = &ProtectionTotals{Protection: name}
+					byProtection[name] = p
+				}
+				p.Bytes += size
+				p.Regions++
+			}
+			if reason := unusualProtection(pv); reason != "" {
+				report.UnusualProtection = append(report.UnusualProtection, region(pv, state, reason))
+			}
+		}
+	}
+
+	if unknown.Regions > 0 {
+		report.Unknown = &unknown
+	}
+	for _, t := range byType {
+		report.ByType = append(report.ByType, *t)
+	}
+	sort.Slice(report.ByType, func(i, j int) bool {
+		a, b := report.ByType[i], report.ByType[j]
+		if a.Committed+a.Reserved != b.Committed+b.Reserved {
+			return a.Committed+a.Reserved > b.Committed+b.Reserved
+		}
+		return a.Type < b.Type
+	})
+	for _, p := range byProtection {
+		report.ByProtection = append(report.ByProtection, *p)
+	}
+	sort.Slice(report.ByProtection, func(i, j int) bool {
+		a, b := report.ByProtection[i], report.ByProtection[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Protection < b.Protection
+	})
+
+	bySize := func(regions []MapRegion) {
+		sort.SliceStable(regions, func(i, j int) bool { return regions[i].Size > regions[j].Size })
+	}
+	bySize(report.LargeReservations)
+	bySize(report.UnusualProtection)
+	if n := len(report.LargeReservations); n > top {
+		report.OmittedReservations = n - top
+		report.LargeReservations = report.LargeReservations[:top]
+	}
+	if n := len(report.UnusualProtection); n > top {
+		report.OmittedUnusual = n - top
+		report.UnusualProtection = report.UnusualProtection[:top]
+	}
+	return report
+}
+
+// PageRegion is one page view region
+type PageRegion struct {
+	Address      string `json:"address"`
+	Size         int64  `json:"size"`
+	State        string `json:"state"`
+	Type         string `json:"type,omitempty"`
+	Protection   int    `json:"protection"`
+	Usage        int    `json:"usage"`
+	StackId      int    `json:"stack_id,omitempty"`
+	FunctionName string `json:"functionName,omitempty"`
+}
+
+// StackLeak is a leak recorded with a given allocation stack
+type StackLeak struct {
+	ID           string `json:"id"`
+	FunctionName string `json:"functionName"`
+	Size         int64  `json:"size"`
+	Count        int    `json:"count"`
+}
+
+// StackRecord is an allocation stack with the regions and leaks that share it
+type StackRecord struct {
+	StackId   int         `json:"stack_id"`
+	Frames    []string    `json:"frames,omitempty"`
+	Regions   int         `json:"regions"`
+	Committed int64       `json:"committed"`
+	Leaks     []StackLeak `json:"leaks,omitempty"`
+}
+
+// RegionStacks answers which allocation stacks are responsible for a region
+type RegionStacks struct {
+	Regions []PageRegion  `json:"regions"`
+	Stacks  []StackRecord `json:"stacks"`
+	Notes   []string      `json:"notes,omitempty"`
+}
+
+// stackIndex maps stack IDs to call stacks; the map is shared with the capture's index
+func (ma *MemoryAnalyzer) stackIndex() map[int]string {
+	return ma.index().stacks
+}
+
+// buildStackIndex maps stack IDs to call stacks, from page views and leaks that record both
+func buildStackIndex(data *MemProData) map[int]string {
+	index := make(map[int]string)
+	for _, pv := range data.PageViews {
+		if pv.StackId != 0 && pv.CallStack != "" {
+			index[pv.StackId] = pv.CallStack
+		}
+	}
+	for _, leak := range data.Leaks {
+		if leak.StackId != 0 && leak.CallStack != "" {
+			if _, ok := index[leak.StackId]; !ok {
+				index[leak.StackId] = leak.CallStack
+			}
+		}
+	}
+	return index
+}
+
+// RegionStacks returns the page view regions containing address (when hasAddress) or
+// allocated from stackID, with their allocation stacks and the leaks sharing each stack.
+// Leaks are linked by StackId, or by identical call stack text when they carry none.
+func (ma *MemoryAnalyzer) RegionStacks(address int64, hasAddress bool, stackID int) (*RegionStacks, error) {
+	result := &RegionStacks{Stacks: []StackRecord{}}
+	ids := make(map[int]bool)
+	for _, pv := range ma.data.PageViews {
+		var match bool
+		if hasAddress {
+			match = address >= pv.Address && address < pv.Address+pageSize(pv)
+		} else {
+			match = pv.StackId == stackID
+		}
+		if !match {
+			continue
+		}
+		result.Regions = append(result.Regions, PageRegion{
+			Address:      fmt.Sprintf("0x%x", pv.Address),
+			Size:         pageSize(pv),
+			State:        pv.State,
+			Type:         pv.Type,
+			Protection:   pv.Protection,
+			Usage:        pv.Usage,
+			StackId:      pv.StackId,
+			FunctionName: pv.FunctionName,
+		})
+		if pv.StackId != 0 {
+			ids[pv.StackId] = true
+		}
+	}
+	if !hasAddress {
+		ids[stackID] = true
+	}
+
+	index := ma.stackIndex()
+	if len(result.Regions) == 0 && (hasAddress || index[stackID] == "") {
+		if hasAddress {
+			return nil, fmt.Errorf("no page view contains address 0x%x", address)
+		}
+		return nil, fmt.Errorf("no page view or leak has stack ID %d", stackID)
+	}
+
+	byText := make(map[string]int)
+	for id, stack := range index {
+		if ids[id] {
+			byText[stack] = id
+		}
+	}
+
+	stacks := make(map[int]*StackRecord)
+	get := func(id int) *StackRecord {
+		s, ok := stacks[id]
+		if !ok {
+			s = &StackRecord{StackId: id, Frames: parseCallStack(index[id])}
+			stacks[id] = s
+		}
+		return s
+	}
+	for id := range ids {
+		get(id)
+	}
+	for _, pv := range ma.data.PageViews {
+		if ids[pv.StackId] {
+			s := get(pv.StackId)
+			s.Regions++
+			if pageState(pv) == "committed" {
+				s.Committed += pageSize(pv)
+			}
+		}
+	}
+	for _, leak := range ma.data.Leaks {
+		id := leak.StackId
+		if id == 0 {
+			id = byText[leak.CallStack]
+		}
+		if id != 0 && ids[id] {
+			if leak.CallStack == "" {
+				leak.CallStack = index[id]
+			}
+			s := get(id)
+			s.Leaks = append(s.Leaks, StackLeak{ID: leakID(leak), FunctionName: ma.blame(leak), Size: leak.LeakSize, Count: leak.LeakCount})
+		}
+	}
+
+	for _, s := range stacks {
+		if len(s.Frames) == 0 {
+			result.Notes = append(result.Notes, fmt.Sprintf("Stack %d has no recorded call stack", s.StackId))
+		}
+		sort.Slice(s.Leaks, func(i, j int) bool { return s.Leaks[i].Size > s.Leaks[j].Size })
+		result.Stacks = append(result.Stacks, *s)
+	}
+	sort.Slice(result.Stacks, func(i, j int) bool {
+		if result.Stacks[i].Committed != result.Stacks[j].Committed {
+			return result.Stacks[i].Committed > result.Stacks[j].Committed
+		}
+		return result.Stacks[i].StackId < result.Stacks[j].StackId
+	})
+	sort.Strings(result.Notes)
+	if hasAddress && len(ids) == 0 {
+		result.Notes = append(result.Notes, "The region has no stack ID, so its allocation stack is unknown")
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minShardSize is the fewest records worth a goroutine; smaller inputs are analyzed inline
+const minShardSize = 2048
+
+// workers returns how many goroutines detectors shard records across: analysis_workers
+// from the config, else one per CPU
+func (ma *MemoryAnalyzer) workers() int {
+	if ma.config != nil && ma.config.AnalysisWorkers > 0 {
+		return ma.config.AnalysisWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// shard splits the records [0, n) into contiguous ranges, calls fn on each range from its own
+// goroutine, and concatenates the results in range order, so the output matches a sequential
+// loop over all records. fn must only read shared state; Diagnostics may be updated.
+func shard[T any](n, workers int, fn func(start, end int) []T) []T {
+	shards := min(workers, n/minShardSize)
+	if shards <= 1 {
+		return fn(0, n)
+	}
+
+	results := make([][]T, shards)
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = fn(i*n/shards, (i+1)*n/shards)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, r := range results {
+		total += len(r)
+	}
+	merged := make([]T, 0, total)
+	for _, r := range results {
+		merged = append(merged, r...)
+	}
+	return merged
+}
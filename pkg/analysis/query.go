@@ -0,0 +1,134 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// LeakQuery filters leak issues. Empty fields do not filter.
+type LeakQuery struct {
+	File        string `json:"file,omitempty"`     // Glob over the source path, e.g. "render/**/*.cpp"
+	Function    string `json:"function,omitempty"` // Regular expression over the blamed or allocating function
+	MinSize     int64  `json:"min_size,omitempty"`
+	MaxSize     int64  `json:"max_size,omitempty"`
+	SuspectOnly bool   `json:"suspect_only,omitempty"`
+	Module      string `json:"module,omitempty"` // e.g. "game.exe" or "game"
+
+	file     *regexp.Regexp
+	function *regexp.Regexp
+}
+
+// LeakQueryResult summarizes what a query matched
+type LeakQueryResult struct {
+	Query       LeakQuery `json:"query"`
+	Matched     int       `json:"matched"`
+	MatchedSize int64     `json:"matched_size"`
+	Total       int       `json:"total"` // Leak issues before filtering
+}
+
+// globPattern converts a path glob to a regular expression matching a trailing run of path
+// components: "*" and "?" stay within one component, "**" spans several
+func globPattern(glob string) (*regexp.Regexp, error) {
+	glob = strings.ReplaceAll(glob, `\`, "/")
+	if _, err := path.Match(strings.ReplaceAll(glob, "**", "*"), ""); err != nil {
+		return nil, fmt.Errorf("invalid file glob %q: %w", glob, err)
+	}
+
+	var b strings.Builder
+	b.WriteString(`(?i)(^|/)`)
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(`.*`)
+				i++
+			} else {
+				b.WriteString(`[^/]*`)
+			}
+		case '?':
+			b.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid file glob %q: unclosed [", glob)
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.Compile(b.String())
+}
+
+// compile checks the query and prepares its patterns
+func (q *LeakQuery) compile() error {
+	if q.MinSize < 0 || q.MaxSize < 0 {
+		return fmt.Errorf("sizes must not be negative")
+	}
+	if q.MaxSize > 0 && q.MinSize > q.MaxSize {
+		return fmt.Errorf("min_size (%d) must not exceed max_size (%d)", q.MinSize, q.MaxSize)
+	}
+	var err error
+	if q.File != "" {
+		if q.file, err = globPattern(q.File); err != nil {
+			return err
+		}
+	}
+	if q.Function != "" {
+		if q.function, err = regexp.Compile(q.Function); err != nil {
+			return fmt.Errorf("invalid function regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// matchesModule reports whether a frame module is the queried one, with or without its extension
+func (q *LeakQuery) matchesModule(module string) bool {
+	return strings.EqualFold(module, q.Module) ||
+		strings.EqualFold(strings.TrimSuffix(module, path.Ext(module)), q.Module)
+}
+
+// QueryLeaks returns the leak issues matching q, most severe first
+func (ma *MemoryAnalyzer) QueryLeaks(q LeakQuery) ([]MemoryIssue, *LeakQueryResult, error) {
+	if err := q.compile(); err != nil {
+		return nil, nil, err
+	}
+
+	// Captures have far fewer files than leaks, so the file pattern is matched once per file
+	var files map[string]bool
+	if q.file != nil {
+		files = make(map[string]bool)
+		for file := range ma.index().leaksByFile {
+			files[file] = q.file.MatchString(strings.ReplaceAll(file, `\`, "/"))
+		}
+		files[""] = q.file.MatchString("")
+	}
+
+	issues := ma.AnalyzeLeaks()
+	result := &LeakQueryResult{Query: q, Total: len(issues)}
+	matched := []MemoryIssue{}
+	for _, issue := range issues {
+		leak, _ := ma.leakGroup(issue.ID)
+		switch {
+		case q.MinSize > 0 && issue.Size < q.MinSize,
+			q.MaxSize > 0 && issue.Size > q.MaxSize,
+			q.SuspectOnly && !leak.IsSuspect,
+			q.file != nil && !files[issue.FileName],
+			q.function != nil && !q.function.MatchString(issue.FunctionName) && !q.function.MatchString(leak.FunctionName),
+			q.Module != "" && !q.matchesModule(ma.config.plumbing.module(leak.FunctionName, leak.CallStack)):
+			continue
+		}
+		matched = append(matched, issue)
+		result.MatchedSize += issue.Size
+	}
+	result.Matched = len(matched)
+	return matched, result, nil
+}
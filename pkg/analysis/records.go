@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LeakRecord is one entry of the capture's Leaks array, served as mempro://leaks/{index}
+type LeakRecord struct {
+	Index          int    `json:"index"`
+	IssueID        string `json:"issue_id,omitempty"` // The analyze_leaks issue it is reported in; empty for empty records
+	BlamedFunction string `json:"blamed_function"`
+	Thread         string `json:"thread,omitempty"`
+	Heap           string `json:"heap,omitempty"`
+	Leak
+}
+
+// FunctionRecords are the capture's Functions entries for one name, served as
+// mempro://functions/{name}; a function can have entries for several heaps or call sites
+type FunctionRecords struct {
+	FunctionName string     `json:"functionName"`
+	Records      []Function `json:"records"`
+}
+
+// CallStackFrame is one frame of a leak's call stack
+type CallStackFrame struct {
+	Depth    int    `json:"depth"` // 0 is the allocating frame
+	Frame    string `json:"frame"` // As recorded in the capture
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Offset   string `json:"offset,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Plumbing bool   `json:"plumbing,omitempty"`
+	Blamed   bool   `json:"blamed,omitempty"` // The frame the leak is attributed to
+}
+
+// LeakCallStack is the complete call stack of one leak, innermost frame first
+type LeakCallStack struct {
+	IssueID           string           `json:"issue_id,omitempty"`
+	Index             *int             `json:"index,omitempty"` // Leaks array entry, when looked up by index
+	Function          string           `json:"function"`        // Blamed past allocator plumbing
+	AllocatedVia      string           `json:"allocated_via,omitempty"`
+	Size              int64            `json:"size"`
+	Count             int              `json:"count"`
+	Occurrences       int              `json:"occurrences,omitempty"` // Records merged into the issue
+	StackID           int              `json:"stack_id,omitempty"`
+	Depth             int              `json:"depth"`
+	ApplicationFrames int              `json:"application_frames"`
+	Frames            []CallStackFrame `json:"frames"`
+	Notes             []string         `json:"notes,omitempty"`
+}
+
+// LeakRecord returns the leak at index in the capture's Leaks array, its call stack resolved
+// by StackId if needed, with the issue ID under which analyze_leaks reports it after merging
+// records with the same call stack
+func (ma *MemoryAnalyzer) LeakRecord(index string) (*LeakRecord, error) {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || i >= len(ma.data.Leaks) {
+		return nil, fmt.Errorf("no leak at index %q (the capture has %d)", index, len(ma.data.Leaks))
+	}
+	leak := ma.data.Leaks[i]
+	if leak.CallStack == "" {
+		leak.CallStack = ma.stackIndex()[leak.StackId]
+	}
+
+	record := &LeakRecord{
+		Index:          i,
+		BlamedFunction: ma.blame(leak),
+		Thread:         ma.threadLabel(leak.ThreadId),
+		Heap:           ma.heapLabel(leak.HeapId),
+		Leak:           leak,
+	}
+	if g := ma.index().groupOf[i]; g >= 0 {
+		record.IssueID = ma.index().leakGroups[g].ID
+	}
+	return record, nil
+}
+
+// FunctionRecords returns the Functions entries named name
+func (ma *MemoryAnalyzer) FunctionRecords(name string) (*FunctionRecords, error) {
+	records := &FunctionRecords{FunctionName: name, Records: []Function{}}
+	for _, i := range ma.index().functionsByName[name] {
+		records.Records = append(records.Records, ma.data.Functions[i])
+	}
+	if len(records.Records) == 0 {
+		return nil, fmt.Errorf("no function named %q in the capture", name)
+	}
+	return records, nil
+}
+
+// LeakCallStack returns the full call stack of a leak, given the issue ID analyze_leaks
+// reports it under or its index in the capture's Leaks array
+func (ma *MemoryAnalyzer) LeakCallStack(ref string) (*LeakCallStack, error) {
+	var leak Leak
+	result := &LeakCallStack{}
+	if group, ok := ma.leakGroup(ref); ok {
+		leak = group.Leak
+		result.IssueID = group.ID
+		result.Occurrences = group.Occurrences
+	} else if _, err := strconv.Atoi(ref); err == nil {
+		record, err := ma.LeakRecord(ref)
+		if err != nil {
+			return nil, err
+		}
+		leak = record.Leak
+		result.IssueID = record.IssueID
+		result.Index = &record.Index
+	} else {
+		return nil, fmt.Errorf("no leak with issue ID %q; leak IDs are listed by analyze_leaks", ref)
+	}
+
+	result.Function = ma.blame(leak)
+	if result.Function != leak.FunctionName {
+		result.AllocatedVia = leak.FunctionName
+	}
+	result.Size, result.Count, result.StackID = leak.LeakSize, leak.LeakCount, leak.StackId
+
+	frames := parseCallStack(leak.CallStack)
+	if len(frames) == 0 {
+		frames = []string{leak.FunctionName}
+		result.Notes = append(result.Notes, "the capture records no call stack for this leak; only the allocating function is known")
+	}
+	blamed := false
+	for depth, frame := range frames {
+		f := CallStackFrame{
+			Depth:    depth,
+			Frame:    frame,
+			Function: frameSymbol(frame),
+			Module:   frameModule(frame),
+			Offset:   strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(frameOffsetPattern.FindString(frame)), "+")),
+			Plumbing: ma.config.plumbing.isPlumbing(frame),
+		}
+		f.File, f.Line = ma.frameLocation(f.Function, leak)
+		if !blamed && f.Function == result.Function {
+			f.Blamed, blamed = true, true
+		}
+		if !f.Plumbing {
+			result.ApplicationFrames++
+		}
+		result.Frames = append(result.Frames, f)
+	}
+	result.Depth = len(result.Frames)
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redaction modes
+const (
+	RedactNone  = ""
+	RedactUsers = "users" // Replace user names in home directory paths
+	RedactHash  = "hash"  // Replace directories of absolute paths with a stable hash, keeping the file name
+)
+
+// DefaultRedactMode applies to configs that set no redact mode (the server's --redact)
+var DefaultRedactMode string
+
+var (
+	// An absolute path embedded in text, preceded by start of text or a delimiter
+	embeddedPathPattern = regexp.MustCompile(`(^|[\s("'=,;<\[])((?:[A-Za-z]:[\\/]|\\\\|/)[^\s:;,"'<>|()\[\]]+)`)
+	userDirPattern      = regexp.MustCompile(`(?i)((?:[A-Za-z]:)?[\\/](?:Users|home|Documents and Settings)[\\/])([^\\/]+)`)
+)
+
+func ValidRedactMode(mode string) bool {
+	return mode == RedactNone || mode == RedactUsers || mode == RedactHash
+}
+
+// Redactor rewrites paths and session names so captures can be shared without exposing local details
+type Redactor struct {
+	mode string
+}
+
+// Path redacts a single path; relative paths carry no directory structure and are kept
+func (r Redactor) Path(p string) string {
+	switch r.mode {
+	case RedactUsers:
+		return userDirPattern.ReplaceAllString(p, "${1}<user>")
+	case RedactHash:
+		if !isAbsPath(p) {
+			return p
+		}
+		last := strings.LastIndexAny(p, `\/`)
+		return fmt.Sprintf("<path:%s>/%s", shortHash(p[:last]), p[last+1:])
+	}
+	return p
+}
+
+// isAbsPath recognizes Windows drive, UNC, and Unix absolute paths regardless of the host OS
+func isAbsPath(p string) bool {
+	if len(p) >= 3 && p[1] == ':' && (p[2] == '\\' || p[2] == '/') {
+		c := p[0] | 0x20
+		return c >= 'a' && c <= 'z'
+	}
+	return strings.HasPrefix(p, "/") || strings.HasPrefix(p, `\\`)
+}
+
+// text redacts every absolute path embedded in free text such as call stacks or error messages
+func (r Redactor) text(s string) string {
+	switch r.mode {
+	case RedactUsers:
+		return userDirPattern.ReplaceAllString(s, "${1}<user>")
+	case RedactHash:
+		return embeddedPathPattern.ReplaceAllStringFunc(s, func(m string) string {
+			sub := embeddedPathPattern.FindStringSubmatch(m)
+			return sub[1] + r.Path(sub[2])
+		})
+	}
+	return s
+}
+
+// Session replaces the session name with a stable pseudonym in hash mode
+func (r Redactor) Session(name string) string {
+	switch r.mode {
+	case RedactHash:
+		if name == "" {
+			return name
+		}
+		return "session-" + shortHash(name)
+	case RedactUsers:
+		return r.text(name)
+	}
+	return name
+}
+
+// Error redacts paths in an error message, e.g. file-not-found errors that echo the capture path
+func (r Redactor) Error(err error) error {
+	if err == nil || r.mode == RedactNone {
+		return err
+	}
+	return errors.New(r.text(err.Error()))
+}
+
+// data redacts all path-bearing fields of a parsed capture in place
+func (r Redactor) data(d *MemProData) {
+	if r.mode == RedactNone {
+		return
+	}
+
+	d.SessionName = r.Session(d.SessionName)
+	for i := range d.Leaks {
+		d.Leaks[i].FileName = r.Path(d.Leaks[i].FileName)
+		d.Leaks[i].CallStack = r.text(d.Leaks[i].CallStack)
+	}
+	for i := range d.Functions {
+		d.Functions[i].FileName = r.Path(d.Functions[i].FileName)
+	}
+	for i := range d.PageViews {
+		d.PageViews[i].CallStack = r.text(d.PageViews[i].CallStack)
+	}
+	for i := range d.Types {
+		d.Types[i].MostCommonFile = r.Path(d.Types[i].MostCommonFile)
+	}
+	for i := range d.Allocations {
+		d.Allocations[i].FileName = r.Path(d.Allocations[i].FileName)
+	}
+	r.callTrees(d.CallTrees)
+}
+
+func (r Redactor) callTrees(trees []CallTree) {
+	for i := range trees {
+		trees[i].FileName = r.Path(trees[i].FileName)
+		r.callTrees(trees[i].Children)
+	}
+}
+
+// shortHash returns a stable 8-character identifier for a value; case-insensitive since
+// Windows paths differ in case between tools
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(s)))
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,115 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// summaryTopLeakers is how many leaks the Markdown summary lists
+const summaryTopLeakers = 5
+
+// MarkdownSummary renders the summary as Markdown for clients that display resources inline
+func (ma *MemoryAnalyzer) MarkdownSummary() string {
+	if ma == nil || ma.data == nil {
+		return "**Error:** No data available for analysis\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Memory Analysis: %s\n\n", mdText(ma.data.SessionName))
+
+	b.WriteString("| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Total allocations | %d |\n", ma.data.TotalAllocations)
+	fmt.Fprintf(&b, "| Total size | %s |\n", FormatBytes(ma.data.TotalSize))
+	fmt.Fprintf(&b, "| Leak count | %d |\n", ma.data.LeakCount)
+	fmt.Fprintf(&b, "| Leak size | %s (%.2f%%) |\n", FormatBytes(ma.data.LeakSize), ma.LeakPercentage())
+	fmt.Fprintf(&b, "| Fragmentation | %.2f%% |\n\n", ma.data.MemoryFragmentation)
+
+	b.WriteString("## Critical Findings\n\n")
+	findings := ma.criticalFindings()
+	if len(findings) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "- %s\n", finding)
+	}
+
+	leaks := ma.AnalyzeLeaks()
+	large := ma.AnalyzeLargeAllocations()
+	fragmentation := ma.AnalyzeFragmentation()
+
+	b.WriteString("\n## Issues by Severity\n\n")
+	b.WriteString("| Severity | Leaks | Large allocations | Fragmentation |\n|---|---|---|---|\n")
+	for _, severity := range []string{"Critical", "High", "Medium", "Low"} {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", severity,
+			countSeverity(leaks, severity), countSeverity(large, severity), countSeverity(fragmentation, severity))
+	}
+
+	if len(leaks) > 0 {
+		top := append([]MemoryIssue(nil), leaks...)
+		sort.SliceStable(top, func(i, j int) bool { return top[i].Size > top[j].Size })
+		top = top[:min(len(top), summaryTopLeakers)]
+
+		b.WriteString("\n## Top Leakers\n\n")
+		b.WriteString("| # | ID | Function | Leak size | Allocations | Severity | Location |\n|---|---|---|---|---|---|---|\n")
+		for i, issue := range top {
+			location := ""
+			if issue.FileName != "" {
+				location = fmt.Sprintf("`%s:%d`", mdCode(issue.FileName), issue.LineNumber)
+			}
+			fmt.Fprintf(&b, "| %d | `%s` | `%s` | %s | %d | %s | %s |\n",
+				i+1, issue.ID, mdCode(issue.FunctionName), FormatBytes(issue.Size), issue.Count, issue.Severity, location)
+		}
+	}
+
+	return b.String()
+}
+
+func countSeverity(issues []MemoryIssue, severity string) int {
+	n := 0
+	for _, issue := range issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// IssueCounts is the number of issues at each severity across all analyses
+type IssueCounts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Total    int `json:"total"`
+}
+
+// IssueCounts tallies AnalyzeAll by severity without formatting any issue
+func (ma *MemoryAnalyzer) IssueCounts() IssueCounts {
+	return countIssues(ma.AllIssues())
+}
+
+// countIssues tallies issues by severity
+func countIssues(issues []MemoryIssue) IssueCounts {
+	var counts IssueCounts
+	for _, issue := range issues {
+		switch issue.Severity {
+		case "Critical":
+			counts.Critical++
+		case "High":
+			counts.High++
+		case "Medium":
+			counts.Medium++
+		case "Low":
+			counts.Low++
+		}
+		counts.Total++
+	}
+	return counts
+}
+
+// mdText escapes characters that would change Markdown structure in running text or table cells
+var mdText = strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "\n", " ").Replace
+
+// mdCode makes a value safe inside a code span in a table cell
+var mdCode = strings.NewReplacer("|", `\|`, "`", "'", "\n", " ").Replace
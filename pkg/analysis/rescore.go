@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultRescoreChanges is how many changed issues rescore_issues lists
+const defaultRescoreChanges = 50
+
+// RescoreOptions are the severity bars and type weights to apply. A bar of zero keeps the
+// detectors' own boundary for that severity.
+type RescoreOptions struct {
+	CriticalBytes int64              `json:"critical_bytes,omitempty"`
+	HighBytes     int64              `json:"high_bytes,omitempty"`
+	MediumBytes   int64              `json:"medium_bytes,omitempty"`
+	Weights       map[string]float64 `json:"weights,omitempty"` // Size multiplier by issue type, applied before comparing with the bars
+}
+
+// RescoredIssue is an issue whose severity the override changed
+type RescoredIssue struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	FunctionName string `json:"functionName,omitempty"`
+	Size         int64  `json:"size"`
+	WeightedSize int64  `json:"weighted_size"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+}
+
+// RescoreResult compares severities before and after the override
+type RescoreResult struct {
+	Options RescoreOptions  `json:"options"`
+	Before  IssueCounts     `json:"before"`
+	After   IssueCounts     `json:"after"`
+	Changed []RescoredIssue `json:"changed"`
+	Omitted int             `json:"omitted,omitempty"` // Changed issues beyond the limit
+}
+
+// ParseWeights parses "Type=factor" pairs separated by commas, e.g. "MemoryLeak=2,TinyAllocation=0.5"
+func ParseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		typ, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q: expected Type=factor", pair)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q: factor must be a non-negative number", pair)
+		}
+		weights[strings.TrimSpace(typ)] = w
+	}
+	return weights, nil
+}
+
+// check requires at least one bar and rejects bars that are negative or out of order
+func (o RescoreOptions) check() error {
+	bars := []int64{o.CriticalBytes, o.HighBytes, o.MediumBytes}
+	names := []string{"critical_bytes", "high_bytes", "medium_bytes"}
+	if o.CriticalBytes < 0 || o.HighBytes < 0 || o.MediumBytes < 0 {
+		return fmt.Errorf("bars must not be negative")
+	}
+	if o.CriticalBytes == 0 && o.HighBytes == 0 && o.MediumBytes == 0 {
+		return fmt.Errorf("at least one of %s is required", strings.Join(names, ", "))
+	}
+	for i := range bars {
+		for j := i + 1; j < len(bars); j++ {
+			if bars[i] > 0 && bars[j] > bars[i] {
+				return fmt.Errorf("%s (%d) must not exceed %s (%d)", names[j], bars[j], names[i], bars[i])
+			}
+		}
+	}
+	return nil
+}
+
+// severity re-scores one issue. Each given bar promotes issues at or above it to its
+// severity and demotes issues of that severity or worse below it to the next one down;
+// severities without a bar, and issues without a size, keep the detector's verdict.
+func (o RescoreOptions) severity(issue MemoryIssue) (string, int64) {
+	weight := 1.0
+	if w, ok := o.Weights[issue.Type]; ok {
+		weight = w
+	}
+	size := int64(math.Round(float64(issue.Size) * weight))
+
+	names := []string{"Critical", "High", "Medium", "Low"}
+	rank, ok := severityOrder[issue.Severity]
+	if !ok || issue.Size <= 0 {
+		return issue.Severity, size
+	}
+	for r, bar := range []int64{o.CriticalBytes, o.HighBytes, o.MediumBytes} {
+		switch {
+		case bar == 0:
+		case size >= bar && rank > r:
+			rank = r
+		case size < bar && rank <= r:
+			rank = r + 1
+		}
+	}
+	return names[rank], size
+}
+
+// Rescore applies opts to already-analyzed issues and reports the severities that change,
+// largest weighted size first, keeping at most limit of them
+func Rescore(issues []MemoryIssue, opts RescoreOptions, limit int) (*RescoreResult, error) {
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultRescoreChanges
+	}
+
+	result := &RescoreResult{Options: opts, Before: countIssues(issues), Changed: []RescoredIssue{}}
+	rescored := make([]MemoryIssue, len(issues))
+	for i, issue := range issues {
+		severity, weighted := opts.severity(issue)
+		if severity != issue.Severity {
+			result.Changed = append(result.Changed, RescoredIssue{
+				ID:           issue.ID,
+				Type:         issue.Type,
+				FunctionName: issue.FunctionName,
+				Size:         issue.Size,
+				WeightedSize: weighted,
+				From:         issue.Severity,
+				To:           severity,
+			})
+		}
+		issue.Severity = severity
+		rescored[i] = issue
+	}
+	result.After = countIssues(rescored)
+
+	sort.SliceStable(result.Changed, func(i, j int) bool { return result.Changed[i].WeightedSize > result.Changed[j].WeightedSize })
+	if len(result.Changed) > limit {
+		result.Omitted = len(result.Changed) - limit
+		result.Changed = result.Changed[:limit]
+	}
+	return result, nil
+}
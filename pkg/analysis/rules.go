@@ -0,0 +1,156 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// CustomRule is a user-defined expression evaluated against every detected issue
+type CustomRule struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"` // Optional issue type filter, e.g. MemoryLeak
+	When       string `json:"when"` // expr-lang expression that must evaluate to a bool
+	Severity   string `json:"severity"`
+	Suggestion string `json:"suggestion"`
+	Drop       bool   `json:"drop"`
+}
+
+// ruleRecord is the view of an issue exposed to rule expressions
+type ruleRecord struct {
+	Type         string
+	Severity     string
+	FunctionName string
+	FileName     string
+	LineNumber   int
+	Size         int64
+	Count        int
+	Score        float64
+	CallStack    string
+	IsSuspect    bool
+	Library      string
+}
+
+// ruleEnv is the expression environment; the record is reachable as both issue and leak
+type ruleEnv struct {
+	Issue ruleRecord `expr:"issue"`
+	Leak  ruleRecord `expr:"leak"`
+}
+
+type compiledRule struct {
+	CustomRule
+	program *vm.Program
+}
+
+var validSeverities = map[string]bool{"Critical": true, "High": true, "Medium": true, "Low": true}
+
+// includesFunction is the function form of the contains operator, includes(s, substr).
+// expr reserves contains for its infix operator (s contains substr), so the function cannot
+// share its name.
+var includesFunction = expr.Function("includes", func(params ...any) (any, error) {
+	return strings.Contains(params[0].(string), params[1].(string)), nil
+}, new(func(string, string) bool))
+
+// containsCallPattern spots contains written as a function call, to point failing rules at includes
+var containsCallPattern = regexp.MustCompile(`\bcontains\s*\(`)
+
+// compileRules validates and compiles rule expressions once at config load time
+func compileRules(rules []CustomRule) ([]*compiledRule, error) {
+	compiled := make([]*compiledRule, 0, len(rules))
+
+	for i, rule := range rules {
+		c, err := compileRule(i, rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+
+	return compiled, nil
+}
+
+// compileRule validates a single rule; index is only used to name unnamed rules in errors
+func compileRule(index int, rule CustomRule) (*compiledRule, error) {
+	name := rule.Name
+	if name == "" {
+		name = fmt.Sprintf("#%d", index+1)
+	}
+	if rule.When == "" {
+		return nil, fmt.Errorf("rule %s: missing 'when' expression", name)
+	}
+	if rule.Severity != "" && !validSeverities[rule.Severity] {
+		return nil, fmt.Errorf("rule %s: unknown severity %q", name, rule.Severity)
+	}
+
+	program, err := expr.Compile(rule.When, expr.Env(ruleEnv{}), expr.AsBool(), includesFunction)
+	if err != nil {
+		if containsCallPattern.MatchString(rule.When) {
+			return nil, fmt.Errorf("rule %s: contains is an operator, call includes(s, substr) instead: %w", name, err)
+		}
+		return nil, fmt.Errorf("rule %s: %w", name, err)
+	}
+
+	return &compiledRule{CustomRule: rule, program: program}, nil
+}
+
+// newRuleRecord builds the rule view of an issue; callers fill in record-specific fields
+func newRuleRecord(issue MemoryIssue) ruleRecord {
+	return ruleRecord{
+		Type:         issue.Type,
+		Severity:     issue.Severity,
+		FunctionName: issue.FunctionName,
+		FileName:     issue.FileName,
+		LineNumber:   issue.LineNumber,
+		Size:         issue.Size,
+		Count:        issue.Count,
+		Score:        issue.Score,
+		Library:      issue.Library,
+	}
+}
+
+// applyRules evaluates custom rules in order against each issue; later matches override earlier ones.
+// records[i] must describe issues[i]. Issues are evaluated on up to workers goroutines.
+func applyRules(rules []*compiledRule, issues []MemoryIssue, records []ruleRecord, workers int) []MemoryIssue {
+	if len(rules) == 0 || len(issues) == 0 {
+		return issues
+	}
+
+	return shard(len(issues), workers, func(start, end int) []MemoryIssue {
+		kept := make([]MemoryIssue, 0, end-start)
+		for i, issue := range issues[start:end] {
+			record := records[start+i]
+
+			dropped := false
+			for _, rule := range rules {
+				if rule.Type != "" && rule.Type != issue.Type {
+					continue
+				}
+
+				out, err := expr.Run(rule.program, ruleEnv{Issue: record, Leak: record})
+				if err != nil || out != true {
+					continue
+				}
+
+				if rule.Drop {
+					dropped = true
+					break
+				}
+				if rule.Severity != "" {
+					issue.Severity = rule.Severity
+					record.Severity = rule.Severity
+				}
+				if rule.Suggestion != "" {
+					issue.Suggestion = rule.Suggestion
+				}
+			}
+
+			if !dropped {
+				kept = append(kept, issue)
+			}
+		}
+		return kept
+	})
+}
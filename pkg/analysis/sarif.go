@@ -0,0 +1,200 @@
+package analysis
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SARIF 2.1.0 identifiers
+const (
+	sarifVersion    = "2.1.0"
+	sarifSchema     = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifSourceRoot = "SRCROOT"
+)
+
+// sarifRuleDescriptions names the issue types; other types are described by their name
+var sarifRuleDescriptions = map[string]string{
+	"MemoryLeak":             "Memory leaked by a call site",
+	"MemoryFragmentation":    "Heap fragmentation",
+	"LargeAllocation":        "Large allocations",
+	"DuplicateAllocation":    "Repeated same-size allocations",
+	"PoolCandidate":          "Allocation churn suited to a pool",
+	"AlignmentWaste":         "Bytes lost to allocation alignment",
+	"TinyAllocation":         "Allocations smaller than their overhead",
+	"ShortLivedChurn":        "Short-lived allocation churn",
+	"LongLivedResident":      "Long-lived resident allocations",
+	"HeapOverBudget":         "Heap over budget",
+	"TagOverBudget":          "Allocation tag over budget",
+	"AddressSpaceExhaustion": "Address space running out",
+	"MonotonicGrowth":        "Live memory growing across snapshots",
+	"AllocationChurn":        "Very many small allocations",
+}
+
+// SARIFLog is a SARIF 2.1.0 log with one run, for code scanning and IDE problem panes
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is the analysis of one capture
+type SARIFRun struct {
+	Tool               SARIFTool                    `json:"tool"`
+	OriginalURIBaseIDs map[string]SARIFArtifactLink `json:"originalUriBaseIds,omitempty"`
+	Results            []SARIFResult                `json:"results"`
+	Properties         map[string]interface{}       `json:"properties,omitempty"`
+}
+
+// The remaining SARIF types mirror the SARIF objects of the same name
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is one issue
+type SARIFResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             SARIFMessage      `json:"message"`
+	Locations           []SARIFLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          MemoryIssue       `json:"properties"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLink `json:"artifactLocation"`
+	Region           *SARIFRegion      `json:"region,omitempty"`
+}
+
+type SARIFArtifactLink struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type SARIFRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type SARIFLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevel maps an issue severity to a SARIF level
+func sarifLevel(severity string) string {
+	switch severity {
+	case "Critical", "High":
+		return "error"
+	case "Medium":
+		return "warning"
+	}
+	return "note"
+}
+
+// sarifURI converts a recorded source path to a SARIF artifact location. Paths that resolve
+// to a file under sourceRoot become relative to it, which code scanning needs to place
+// results in the repository; others are kept as recorded, absolute ones as file URIs.
+func sarifURI(file, sourceRoot string) SARIFArtifactLink {
+	if sourceRoot != "" {
+		if local := resolveSourceFile(file, sourceRoot); local != "" {
+			if rel, err := filepath.Rel(sourceRoot, local); err == nil && !strings.HasPrefix(rel, "..") {
+				return SARIFArtifactLink{URI: filepath.ToSlash(rel), URIBaseID: sarifSourceRoot}
+			}
+		}
+	}
+
+	path := strings.ReplaceAll(file, `\`, "/")
+	if strings.HasPrefix(path, "/") || filepath.VolumeName(file) != "" || (len(path) > 1 && path[1] == ':') {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return SARIFArtifactLink{URI: (&url.URL{Scheme: "file", Path: path}).String()}
+	}
+	return SARIFArtifactLink{URI: (&url.URL{Path: path}).String()}
+}
+
+// SARIF converts issue groups to a SARIF log. Each issue type is a rule, the issue ID is
+// the fingerprint that keeps results matched across captures, and the whole issue is kept
+// in the result's properties.
+func (ma *MemoryAnalyzer) SARIF(sourceRoot string, groups ...[]MemoryIssue) *SARIFLog {
+	run := SARIFRun{
+		Tool:       SARIFTool{Driver: SARIFDriver{Name: "MemPro Memory Analyzer", Version: "1.0.0", Rules: []SARIFRule{}}},
+		Results:    []SARIFResult{},
+		Properties: map[string]interface{}{"session": ma.data.SessionName},
+	}
+	if sourceRoot != "" {
+		if abs, err := filepath.Abs(sourceRoot); err == nil {
+			root := filepath.ToSlash(abs)
+			if !strings.HasPrefix(root, "/") {
+				root = "/" + root
+			}
+			uri := (&url.URL{Scheme: "file", Path: strings.TrimSuffix(root, "/") + "/"}).String()
+			run.OriginalURIBaseIDs = map[string]SARIFArtifactLink{sarifSourceRoot: {URI: uri}}
+		}
+	}
+
+	rules := make(map[string]bool)
+	for _, group := range groups {
+		for _, issue := range group {
+			if !rules[issue.Type] {
+				rules[issue.Type] = true
+				description, ok := sarifRuleDescriptions[issue.Type]
+				if !ok {
+					description = issue.Type
+				}
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{ID: issue.Type, ShortDescription: SARIFMessage{Text: description}})
+			}
+
+			message := issue.Description
+			if issue.Suggestion != "" {
+				message = strings.TrimSuffix(message, ".") + ". " + issue.Suggestion
+			}
+			result := SARIFResult{
+				RuleID:              issue.Type,
+				Level:               sarifLevel(issue.Severity),
+				Message:             SARIFMessage{Text: message},
+				PartialFingerprints: map[string]string{"memproIssueId/v1": issue.ID},
+				Properties:          issue,
+			}
+
+			var location SARIFLocation
+			if issue.FileName != "" {
+				location.PhysicalLocation = &SARIFPhysicalLocation{ArtifactLocation: sarifURI(issue.FileName, sourceRoot)}
+				if issue.LineNumber > 0 {
+					location.PhysicalLocation.Region = &SARIFRegion{StartLine: issue.LineNumber}
+				}
+			}
+			if issue.FunctionName != "" {
+				location.LogicalLocations = []SARIFLogicalLocation{{FullyQualifiedName: issue.FunctionName, Kind: "function"}}
+			}
+			if location.PhysicalLocation != nil || location.LogicalLocations != nil {
+				result.Locations = []SARIFLocation{location}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool { return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID })
+
+	return &SARIFLog{Schema: sarifSchema, Version: sarifVersion, Runs: []SARIFRun{run}}
+}
@@ -0,0 +1,117 @@
+package analysis
+
+import "sort"
+
+// CompareSessionSections are the record arrays compare_sessions reads: leaks with the page
+// views that resolve their stack IDs and the threads and heaps that label them, and function statistics
+const CompareSessionSections = SectionLeaks | SectionPageViews | SectionThreads | SectionHeaps | SectionFunctions
+
+// SessionTotals are a capture's headline numbers
+type SessionTotals struct {
+	Session          string  `json:"session"`
+	TotalAllocations int     `json:"total_allocations"`
+	TotalSize        int64   `json:"total_size"`
+	LeakCount        int     `json:"leak_count"`
+	LeakSize         int64   `json:"leak_size"`
+	Fragmentation    float64 `json:"fragmentation"`
+}
+
+// SessionComparison diffs two captures of the same program: totals, leaks matched by
+// issue ID, and per-function size changes
+type SessionComparison struct {
+	Baseline      SessionTotals    `json:"baseline"`
+	Current       SessionTotals    `json:"current"`
+	Verdict       string           `json:"verdict"` // improved, regressed, unchanged, or mixed
+	Tolerance     string           `json:"tolerance"`
+	Regressions   []string         `json:"regressions,omitempty"`  // Totals that grew beyond tolerance
+	Improvements  []string         `json:"improvements,omitempty"` // Totals that shrank beyond tolerance
+	SizeDelta     int64            `json:"total_size_delta"`
+	LeakSizeDelta int64            `json:"leak_size_delta"`
+	Fragmentation float64          `json:"fragmentation_delta"`
+	NewLeaks      []MemoryIssue    `json:"new_leaks"`
+	FixedLeaks    []MemoryIssue    `json:"fixed_leaks"`
+	GrownLeaks    []LeakChange     `json:"grown_leaks"`
+	ShrunkLeaks   []LeakChange     `json:"shrunk_leaks"`
+	OmittedLeaks  int              `json:"omitted_leaks,omitempty"` // Leak entries beyond the per-list limit
+	Functions     []FunctionGrowth `json:"functions,omitempty"`     // Largest size changes first; omitted without function statistics
+	Grown         int              `json:"functions_grown"`
+	Shrunk        int              `json:"functions_shrunk"`
+}
+
+// sessionTotals copies a snapshot's totals
+func sessionTotals(s *SessionSnapshot) SessionTotals {
+	return SessionTotals{
+		Session:          s.Session,
+		TotalAllocations: s.TotalAllocations,
+		TotalSize:        s.TotalSize,
+		LeakCount:        s.LeakCount,
+		LeakSize:         s.LeakSize,
+		Fragmentation:    s.Fragmentation,
+	}
+}
+
+// CompareSessions diffs the current capture against a baseline, listing the n functions
+// whose total allocated size changed most in either direction. The verdict ignores totals
+// that changed within the tolerance profile; any new or fixed leak counts.
+func CompareSessions(baseline, current *MemoryAnalyzer, n int, tol *ToleranceProfile) *SessionComparison {
+	if n <= 0 {
+		n = defaultGrowthCount
+	}
+
+	before, after := takeSnapshot(baseline), takeSnapshot(current)
+	diff := diffSnapshots(before, after, tol)
+	c := &SessionComparison{
+		Baseline:      sessionTotals(before),
+		Current:       sessionTotals(after),
+		Tolerance:     diff.Tolerance,
+		Regressions:   diff.Regressions,
+		Improvements:  diff.Improvements,
+		SizeDelta:     diff.TotalSize,
+		LeakSizeDelta: diff.LeakSize,
+		Fragmentation: diff.Fragmentation,
+		NewLeaks:      append([]MemoryIssue{}, diff.NewLeaks...),
+		FixedLeaks:    append([]MemoryIssue{}, diff.ResolvedLeaks...),
+		GrownLeaks:    []LeakChange{},
+		ShrunkLeaks:   []LeakChange{},
+		OmittedLeaks:  diff.Omitted,
+	}
+	for _, change := range diff.ChangedLeaks {
+		if change.Delta > 0 {
+			c.GrownLeaks = append(c.GrownLeaks, change)
+		} else {
+			c.ShrunkLeaks = append(c.ShrunkLeaks, change)
+		}
+	}
+
+	worse := len(c.Regressions) > 0 || len(c.NewLeaks) > 0
+	better := len(c.Improvements) > 0 || len(c.FixedLeaks) > 0
+	switch {
+	case worse && better:
+		c.Verdict = "mixed"
+	case worse:
+		c.Verdict = "regressed"
+	case better:
+		c.Verdict = "improved"
+	default:
+		c.Verdict = "unchanged"
+	}
+
+	if len(baseline.data.Functions) > 0 && len(current.data.Functions) > 0 {
+		changes := functionChanges(baseline.functionTotals(), current.functionTotals())
+		for _, change := range changes {
+			if change.Growth > 0 {
+				c.Grown++
+			} else {
+				c.Shrunk++
+			}
+		}
+		sort.Slice(changes, func(i, j int) bool {
+			if a, b := abs64(changes[i].Growth), abs64(changes[j].Growth); a != b {
+				return a > b
+			}
+			return changes[i].Function < changes[j].Function
+		})
+		c.Functions = changes[:min(len(changes), n)]
+	}
+	return c
+}
@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// LeakSeverityRule assigns a severity to the leaks matching all of its conditions; unset
+// conditions match any leak. Sizes are leaked bytes, bounds are inclusive, and a zero
+// maximum means no upper bound.
+type LeakSeverityRule struct {
+	Name     string `json:"name"`
+	MinSize  int64  `json:"min_size"`
+	MaxSize  int64  `json:"max_size"`
+	MinCount int    `json:"min_count"`
+	MaxCount int    `json:"max_count"`
+	Suspect  *bool  `json:"suspect"`
+	Function string `json:"function"` // Regular expression matched against the blamed or allocating function
+	Severity string `json:"severity"`
+
+	function *regexp.Regexp
+}
+
+// label names the rule in errors
+func (r *LeakSeverityRule) label(index int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("leak_severity[%d] (%s)", index, r.Name)
+	}
+	return fmt.Sprintf("leak_severity[%d]", index)
+}
+
+// compileLeakSeverityRules validates the rules and compiles their function patterns
+func (c *Config) compileLeakSeverityRules() error {
+	for i := range c.LeakSeverity {
+		r := &c.LeakSeverity[i]
+		if !validSeverities[r.Severity] {
+			return fmt.Errorf("%s: invalid severity %q (expected Critical, High, Medium, or Low)", r.label(i), r.Severity)
+		}
+		if r.MinSize < 0 || r.MaxSize < 0 || r.MinCount < 0 || r.MaxCount < 0 {
+			return fmt.Errorf("%s: sizes and counts must not be negative", r.label(i))
+		}
+		if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+			return fmt.Errorf("%s: min_size (%d) exceeds max_size (%d)", r.label(i), r.MinSize, r.MaxSize)
+		}
+		if r.MaxCount > 0 && r.MinCount > r.MaxCount {
+			return fmt.Errorf("%s: min_count (%d) exceeds max_count (%d)", r.label(i), r.MinCount, r.MaxCount)
+		}
+		if r.Function != "" {
+			re, err := regexp.Compile(r.Function)
+			if err != nil {
+				return fmt.Errorf("%s: invalid function pattern: %w", r.label(i), err)
+			}
+			r.function = re
+		}
+	}
+	return nil
+}
+
+// matches reports whether the leak, blamed on function, meets every condition of the rule
+func (r *LeakSeverityRule) matches(leak Leak, function string) bool {
+	switch {
+	case leak.LeakSize < r.MinSize || (r.MaxSize > 0 && leak.LeakSize > r.MaxSize):
+		return false
+	case leak.LeakCount < r.MinCount || (r.MaxCount > 0 && leak.LeakCount > r.MaxCount):
+		return false
+	case r.Suspect != nil && leak.IsSuspect != *r.Suspect:
+		return false
+	case r.function != nil && !r.function.MatchString(function) && !r.function.MatchString(leak.FunctionName):
+		return false
+	}
+	return true
+}
+
+// leakSeverityRule returns the first configured rule matching the leak, if any
+func (c *Config) leakSeverityRule(leak Leak, function string) *LeakSeverityRule {
+	for i := range c.LeakSeverity {
+		if r := &c.LeakSeverity[i]; r.matches(leak, function) {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// defaultSizeClassTop is how many call sites with the most slack are listed per allocator
+const defaultSizeClassTop = 5
+
+// sizeAllocator rounds a request up to the block an allocator actually hands out
+type sizeAllocator struct {
+	name  string
+	round func(size int64) int64
+}
+
+// roundUp rounds size up to a multiple of step
+func roundUp(size, step int64) int64 {
+	return (size + step - 1) / step * step
+}
+
+// quarterStep is the spacing of size classes with four classes per doubling, as used by
+// jemalloc and mimalloc above their small sizes: 80, 96, 112, 128, 160, 192, ...
+func quarterStep(size int64) int64 {
+	lg := bits.Len64(uint64(size-1)) - 1
+	return int64(1) << max(lg-2, 0)
+}
+
+// sizeAllocators approximate the size classes of default 64-bit builds
+var sizeAllocators = []sizeAllocator{
+	{
+		// 8, then multiples of 16 up to 128, then four classes per doubling
+		name: "jemalloc",
+		round: func(size int64) int64 {
+			switch {
+			case size <= 8:
+				return 8
+			case size <= 128:
+				return roundUp(size, 16)
+			}
+			return roundUp(size, quarterStep(size))
+		},
+	},
+	{
+		// Word multiples up to 64 bytes, then four bins per doubling; huge objects are
+		// page-aligned
+		name: "mimalloc",
+		round: func(size int64) int64 {
+			switch {
+			case size <= 64:
+				return roundUp(size, 8)
+			case size > 16*1024*1024:
+				return roundUp(size, 4096)
+			}
+			return roundUp(size, quarterStep(size))
+		},
+	},
+	{
+		// Alignment of an eighth of the size's power of two (at least 16) up to 256 KB,
+		// then whole 8 KB pages
+		name: "tcmalloc",
+		round: func(size int64) int64 {
+			switch {
+			case size <= 8:
+				return 8
+			case size < 128:
+				return roundUp(size, 16)
+			case size > 256*1024:
+				return roundUp(size, 8192)
+			}
+			lg := bits.Len64(uint64(size)) - 1
+			return roundUp(size, min(int64(1)<<(lg-3), 8192))
+		},
+	},
+}
+
+// SlackSite is a call site's share of an allocator's slack
+type SlackSite struct {
+	FunctionName string `json:"functionName"`
+	Size         int64  `json:"size"`       // Requested size (average when sizes vary)
+	BlockSize    int64  `json:"block_size"` // Size class the request lands in
+	Slack        int64  `json:"slack"`      // Total bytes lost over all allocations
+}
+
+// AllocatorFit is how well one allocator's size classes fit the observed sizes
+type AllocatorFit struct {
+	Allocator      string      `json:"allocator"`
+	AllocatedBytes int64       `json:"allocated_bytes"`
+	SlackBytes     int64       `json:"slack_bytes"`
+	SlackPercent   float64     `json:"slack_percent"` // Of allocated bytes
+	WorstSites     []SlackSite `json:"worst_sites,omitempty"`
+}
+
+// SizeClassFit compares size-class slack across allocators
+type SizeClassFit struct {
+	Source         string         `json:"source"`      // allocations or functions
+	Approximate    bool           `json:"approximate"` // Sizes of functions with varying sizes are averages
+	Allocations    int            `json:"allocations"`
+	RequestedBytes int64          `json:"requested_bytes"`
+	Allocators     []AllocatorFit `json:"allocators"`
+	Best           string         `json:"best"`
+}
+
+// sizeSample is a number of allocations of one size from one call site
+type sizeSample struct {
+	function string
+	size     int64
+	count    int
+}
+
+// sizeSamples returns the observed size distribution: exact sizes from allocation records,
+// else per-function sizes (the average where sizes vary)
+func (ma *MemoryAnalyzer) sizeSamples() ([]sizeSample, string, bool) {
+	if len(ma.data.Allocations) > 0 {
+		type key struct {
+			function string
+			size     int64
+		}
+		counts := make(map[key]int)
+		for _, a := range ma.data.Allocations {
+			if a.Size > 0 {
+				counts[key{a.FunctionName, a.Size}]++
+			}
+		}
+		samples := make([]sizeSample, 0, len(counts))
+		for k, n := range counts {
+			samples = append(samples, sizeSample{function: k.function, size: k.size, count: n})
+		}
+		return samples, "allocations", false
+	}
+
+	var samples []sizeSample
+	approximate := false
+	for _, fn := range ma.data.Functions {
+		if fn.AllocationCount <= 0 || fn.TotalSize <= 0 {
+			continue
+		}
+		size := fn.MaxSize
+		if fn.MinSize != fn.MaxSize || size <= 0 {
+			size = (fn.TotalSize + int64(fn.AllocationCount) - 1) / int64(fn.AllocationCount)
+			approximate = true
+		}
+		samples = append(samples, sizeSample{function: fn.FunctionName, size: size, count: fn.AllocationCount})
+	}
+	return samples, "functions", approximate
+}
+
+// CompareSizeClasses maps the observed allocation sizes onto each allocator's size classes
+// and reports the slack (bytes handed out beyond the request) each would waste
+func (ma *MemoryAnalyzer) CompareSizeClasses(top int) *SizeClassFit {
+	if top <= 0 {
+		top = defaultSizeClassTop
+	}
+
+	samples, source, approximate := ma.sizeSamples()
+	result := &SizeClassFit{Source: source, Approximate: approximate}
+	for _, s := range samples {
+		result.Allocations += s.count
+		result.RequestedBytes += s.size * int64(s.count)
+	}
+
+	for _, a := range sizeAllocators {
+		fit := AllocatorFit{Allocator: a.name}
+		slackBySite := make(map[string]*SlackSite)
+		for _, s := range samples {
+			block := a.round(s.size)
+			slack := (block - s.size) * int64(s.count)
+			fit.AllocatedBytes += block * int64(s.count)
+			fit.SlackBytes += slack
+
+			site, ok := slackBySite[s.function]
+			if !ok {
+				site = &SlackSite{FunctionName: s.function, Size: s.size, BlockSize: block}
+				slackBySite[s.function] = site
+			}
+			site.Slack += slack
+		}
+		if fit.AllocatedBytes > 0 {
+			fit.SlackPercent = float64(fit.SlackBytes) / float64(fit.AllocatedBytes) * 100
+		}
+
+		sites := make([]SlackSite, 0, len(slackBySite))
+		for _, site := range slackBySite {
+			if site.Slack > 0 {
+				sites = append(sites, *site)
+			}
+		}
+		sort.Slice(sites, func(i, j int) bool {
+			if sites[i].Slack != sites[j].Slack {
+				return sites[i].Slack > sites[j].Slack
+			}
+			return sites[i].FunctionName < sites[j].FunctionName
+		})
+		fit.WorstSites = sites[:min(len(sites), top)]
+
+		result.Allocators = append(result.Allocators, fit)
+	}
+
+	sort.SliceStable(result.Allocators, func(i, j int) bool {
+		return result.Allocators[i].SlackBytes < result.Allocators[j].SlackBytes
+	})
+	if len(result.Allocators) > 0 && result.Allocations > 0 {
+		result.Best = result.Allocators[0].Allocator
+	}
+	return result
+}
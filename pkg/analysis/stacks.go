@@ -0,0 +1,244 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultStackFrames is how many application frames a summarized stack keeps
+const DefaultStackFrames = 3
+
+// defaultPlumbingFrames are allocator and CRT frames that never explain who owns an allocation
+var defaultPlumbingFrames = []string{
+	"operator new", "operator delete", "malloc", "calloc", "realloc", "free",
+	"_malloc_base", "_calloc_base", "_realloc_base", "_free_base", "_aligned_malloc", "_aligned_realloc",
+	"_malloc_dbg", "_calloc_dbg", "_realloc_dbg", "_nh_malloc", "_heap_alloc",
+	"std::_Allocate", "std::_Default_allocate_traits", "std::allocator", "std::allocator_traits", "std::_Allocate_manually_vector_aligned",
+	"HeapAlloc", "HeapReAlloc", "RtlAllocateHeap", "RtlReAllocateHeap", "VirtualAlloc",
+	"__libc_malloc", "__libc_calloc", "__libc_realloc", "je_malloc", "mi_malloc", "tc_malloc",
+	"invoke_main", "__scrt_common_main", "mainCRTStartup", "wmainCRTStartup", "WinMainCRTStartup",
+	"BaseThreadInitThunk", "RtlUserThreadStart", "__libc_start_main", "_start",
+}
+
+var (
+	frameModulePattern = regexp.MustCompile(`^[\w.\-]+!`)              // game.exe!Foo::Bar
+	frameOffsetPattern = regexp.MustCompile(`\s*\+\s*0x[0-9a-fA-F]+$`) // Foo::Bar + 0x1a
+)
+
+// parseCallStack splits a MemPro call stack string into frames, innermost (allocating) frame first
+func parseCallStack(stack string) []string {
+	stack = strings.TrimSpace(stack)
+	if stack == "" {
+		return nil
+	}
+
+	var parts []string
+	reverse := false
+	switch {
+	case strings.Contains(stack, "\n"):
+		parts = strings.Split(stack, "\n")
+	case strings.Contains(stack, "<-"):
+		parts = strings.Split(stack, "<-")
+	case strings.Contains(stack, "->"):
+		// Caller -> callee order lists the outermost frame first
+		parts = strings.Split(stack, "->")
+		reverse = true
+	case strings.Contains(stack, ";"):
+		parts = strings.Split(stack, ";")
+	case strings.Contains(stack, "|"):
+		parts = strings.Split(stack, "|")
+	default:
+		parts = []string{stack}
+	}
+
+	frames := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			frames = append(frames, p)
+		}
+	}
+	if reverse {
+		for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+			frames[i], frames[j] = frames[j], frames[i]
+		}
+	}
+	return frames
+}
+
+// rewriteCallStack replaces each frame of a call stack with fn(frame), keeping the
+// stack's separator and frame order
+func rewriteCallStack(stack string, fn func(frame string) string) string {
+	sep := ""
+	for _, s := range []string{"\n", "<-", "->", ";", "|"} {
+		if strings.Contains(stack, s) {
+			sep = s
+			break
+		}
+	}
+	parts := []string{stack}
+	if sep != "" {
+		parts = strings.Split(stack, sep)
+	}
+	for i, p := range parts {
+		frame := strings.TrimSpace(p)
+		if frame == "" {
+			continue
+		}
+		start := strings.Index(p, frame)
+		parts[i] = p[:start] + fn(frame) + p[start+len(frame):]
+	}
+	return strings.Join(parts, sep)
+}
+
+// frameSymbol strips module prefixes and offsets so a frame can be matched by name
+func frameSymbol(frame string) string {
+	frame = frameModulePattern.ReplaceAllString(frame, "")
+	return frameOffsetPattern.ReplaceAllString(frame, "")
+}
+
+// stackSignature normalizes a call stack for issue IDs: frame symbols innermost first, so
+// relinked builds that move offsets and exports using another separator keep the same IDs
+func stackSignature(stack string) string {
+	frames := parseCallStack(stack)
+	for i, frame := range frames {
+		frames[i] = frameSymbol(frame)
+	}
+	return strings.Join(frames, "\n")
+}
+
+// frameModule returns a frame's module prefix without the "!", or "" when it has none
+func frameModule(frame string) string {
+	return strings.TrimSuffix(frameModulePattern.FindString(frame), "!")
+}
+
+// frameMatcher decides which frames are plumbing, or belong to a library; patterns are names,
+// or regular expressions when written as "re:<pattern>"
+type frameMatcher struct {
+	prefixes []string // Library frames: matched as plain prefixes, so "CRYPTO_" covers "CRYPTO_malloc"
+	names    []string // Plumbing frames: matched as whole identifiers or scopes, see matchesName
+	patterns []*regexp.Regexp
+}
+
+// newFrameMatcher compiles library frame patterns, whose names are plain prefixes
+func newFrameMatcher(patterns []string) (*frameMatcher, error) {
+	return compileFrameMatcher(patterns, false)
+}
+
+// newPlumbingMatcher compiles plumbing patterns, whose names must match whole identifiers so
+// that application functions such as reallocateTextureCache are not taken for realloc
+func newPlumbingMatcher(patterns []string) (*frameMatcher, error) {
+	return compileFrameMatcher(patterns, true)
+}
+
+func compileFrameMatcher(patterns []string, wholeNames bool) (*frameMatcher, error) {
+	m := &frameMatcher{}
+	for _, p := range patterns {
+		if expr, ok := strings.CutPrefix(p, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+			m.patterns = append(m.patterns, re)
+			continue
+		}
+		if wholeNames {
+			m.names = append(m.names, p)
+		} else {
+			m.prefixes = append(m.prefixes, p)
+		}
+	}
+	return m, nil
+}
+
+// matchesName reports whether symbol is name, or a scope or specialization of it: the match
+// must end at an identifier boundary, so "malloc" covers "malloc<int>" and "Engine::Memory"
+// covers "Engine::Memory::Alloc", but "free" does not cover "freelistPop"
+func matchesName(symbol, name string) bool {
+	rest, ok := strings.CutPrefix(symbol, name)
+	if !ok || name == "" {
+		return false
+	}
+	return rest == "" || !isIdentifierByte(name[len(name)-1]) || !isIdentifierByte(rest[0])
+}
+
+// isPlumbing reports whether a frame belongs to the allocator, C runtime, or a configured wrapper
+func (m *frameMatcher) isPlumbing(frame string) bool {
+	return m.matches(frame)
+}
+
+// matches reports whether a frame's symbol matches any of the patterns
+func (m *frameMatcher) matches(frame string) bool {
+	if m == nil {
+		return false
+	}
+	symbol := frameSymbol(frame)
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(symbol, prefix) {
+			return true
+		}
+	}
+	for _, name := range m.names {
+		if matchesName(symbol, name) {
+			return true
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// attribute returns the function that should be blamed for an allocation: the function
+// itself unless it is plumbing, in which case the first application frame of its stack.
+func (m *frameMatcher) attribute(function, stack string) string {
+	if !m.isPlumbing(function) {
+		return function
+	}
+	for _, f := range parseCallStack(stack) {
+		if !m.isPlumbing(f) {
+			return frameSymbol(f)
+		}
+	}
+	return function
+}
+
+// module returns the module of the first application frame that names one, starting
+// with the allocating function, or "" when no frame records a module
+func (m *frameMatcher) module(function, stack string) string {
+	for _, f := range append([]string{function}, parseCallStack(stack)...) {
+		if mod := frameModule(f); mod != "" && !m.isPlumbing(f) {
+			return mod
+		}
+	}
+	return ""
+}
+
+// summarizeStack reduces a call stack to its top application frames, skipping allocator
+// and CRT plumbing. When every frame is plumbing the innermost frame is kept.
+func (m *frameMatcher) summarizeStack(stack string, maxFrames int) string {
+	frames := parseCallStack(stack)
+	if len(frames) == 0 {
+		return ""
+	}
+	if maxFrames <= 0 {
+		maxFrames = DefaultStackFrames
+	}
+
+	var app []string
+	for _, f := range frames {
+		if !m.isPlumbing(f) {
+			app = append(app, frameSymbol(f))
+		}
+	}
+	if len(app) == 0 {
+		app = []string{frameSymbol(frames[0])}
+	}
+
+	if len(app) <= maxFrames {
+		return strings.Join(app, " <- ")
+	}
+	return fmt.Sprintf("%s <- ... (+%d more)", strings.Join(app[:maxFrames], " <- "), len(app)-maxFrames)
+}
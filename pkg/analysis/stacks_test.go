@@ -0,0 +1,70 @@
+package analysis
+
+import "testing"
+
+func TestPlumbingMatchesWholeIdentifiers(t *testing.T) {
+	plumbing, err := newPlumbingMatcher(append(defaultPlumbingFrames, "Engine::Memory", "re:^TArray<.*>::ResizeGrow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		frame string
+		want  bool
+	}{
+		{"malloc", true},
+		{"realloc", true},
+		{"free", true},
+		{"_start", true},
+		{"operator new", true},
+		{"operator new[]", true},
+		{"operator delete(void*)", true},
+		{"game.exe!malloc + 0x1a", true},
+		{"std::allocator<char>::allocate", true},
+		{"std::_Allocate<16,std::_Default_allocate_traits,0>", true},
+		{"Engine::Memory::Alloc", true},
+		{"TArray<int>::ResizeGrow", true},
+
+		{"reallocateTextureCache", false},
+		{"freelistPop", false},
+		{"_startServer", false},
+		{"mallocTracked", false},
+		{"operator newline", false},
+		{"HeapAllocator::Grow", false},
+		{"Engine::MemoryPool::Alloc", false},
+		{"game.exe!reallocateTextureCache + 0x10", false},
+	}
+	for _, tt := range tests {
+		if got := plumbing.isPlumbing(tt.frame); got != tt.want {
+			t.Errorf("isPlumbing(%q) = %v, want %v", tt.frame, got, tt.want)
+		}
+	}
+}
+
+func TestAttributeKeepsNearMissFunctions(t *testing.T) {
+	plumbing, err := newPlumbingMatcher(defaultPlumbingFrames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stack := "reallocateTextureCache <- Renderer::frame <- main"
+	if got := plumbing.attribute("reallocateTextureCache", stack); got != "reallocateTextureCache" {
+		t.Errorf("attribute = %q, want reallocateTextureCache", got)
+	}
+	stack = "realloc <- reallocateTextureCache <- Renderer::frame"
+	if got := plumbing.attribute("realloc", stack); got != "reallocateTextureCache" {
+		t.Errorf("attribute = %q, want reallocateTextureCache", got)
+	}
+}
+
+func TestLibraryFramesMatchPrefixes(t *testing.T) {
+	frames, err := newFrameMatcher([]string{"CRYPTO_", "btAligned"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range []string{"CRYPTO_malloc", "btAlignedAllocInternal"} {
+		if !frames.matches(frame) {
+			t.Errorf("matches(%q) = false, want true", frame)
+		}
+	}
+}
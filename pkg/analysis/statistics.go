@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// defaultStatisticsTop is how many functions get_statistics lists
+const defaultStatisticsTop = 20
+
+// SizeDistribution summarizes a set of allocation sizes in bytes
+type SizeDistribution struct {
+	Allocations int     `json:"allocations"`
+	Bytes       int64   `json:"bytes"`
+	Min         int64   `json:"min"`
+	Max         int64   `json:"max"`
+	Mean        float64 `json:"mean"`
+	StdDev      float64 `json:"stddev"`
+	CV          float64 `json:"cv"` // Standard deviation over mean
+	P50         int64   `json:"p50"`
+	P90         int64   `json:"p90"`
+	P99         int64   `json:"p99"`
+}
+
+// FunctionDistribution is one function's share of the allocations and the spread of its sizes.
+// A count share well above the byte share marks many small allocations; the reverse marks
+// few large ones.
+type FunctionDistribution struct {
+	FunctionName string  `json:"functionName"`
+	CountShare   float64 `json:"count_share"` // Percent of all allocations
+	ByteShare    float64 `json:"byte_share"`  // Percent of all bytes
+	SizeDistribution
+}
+
+// SizeStatistics is the result of get_statistics
+type SizeStatistics struct {
+	Source      string                 `json:"source"`      // allocations or functions
+	Approximate bool                   `json:"approximate"` // Derived from per-function minimum, maximum, and average
+	Overall     SizeDistribution       `json:"overall"`
+	Functions   []FunctionDistribution `json:"functions"`
+}
+
+// sizeMoments accumulates the count, sum, and sum of squares of allocation sizes
+type sizeMoments struct {
+	count      int
+	sum        float64
+	sumSquares float64
+	min, max   int64
+	sizes      map[int64]int // Allocations per size, for percentiles
+}
+
+// add records count allocations with the given mean and variance, sized between lo and hi
+func (m *sizeMoments) add(count int, mean, variance float64, lo, hi int64) {
+	if m.count == 0 || lo < m.min {
+		m.min = lo
+	}
+	if m.count == 0 || hi > m.max {
+		m.max = hi
+	}
+	m.count += count
+	m.sum += mean * float64(count)
+	m.sumSquares += (variance + mean*mean) * float64(count)
+	if m.sizes == nil {
+		m.sizes = make(map[int64]int)
+	}
+	m.sizes[int64(math.Round(mean))] += count
+}
+
+// percentile returns the nearest-rank size below which p percent of the allocations fall
+func (m *sizeMoments) percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(m.count)))
+	seen := 0
+	for _, size := range sorted {
+		seen += m.sizes[size]
+		if seen >= rank {
+			return size
+		}
+	}
+	return m.max
+}
+
+// distribution summarizes the accumulated sizes
+func (m *sizeMoments) distribution() SizeDistribution {
+	if m.count == 0 {
+		return SizeDistribution{}
+	}
+	mean := m.sum / float64(m.count)
+	stddev := math.Sqrt(max(m.sumSquares/float64(m.count)-mean*mean, 0))
+	d := SizeDistribution{
+		Allocations: m.count,
+		Bytes:       int64(math.Round(m.sum)),
+		Min:         m.min,
+		Max:         m.max,
+		Mean:        math.Round(mean*100) / 100,
+		StdDev:      math.Round(stddev*100) / 100,
+	}
+	if mean > 0 {
+		d.CV = math.Round(stddev/mean*1000) / 1000
+	}
+
+	sorted := make([]int64, 0, len(m.sizes))
+	for size := range m.sizes {
+		sorted = append(sorted, size)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	d.P50 = m.percentile(sorted, 50)
+	d.P90 = m.percentile(sorted, 90)
+	d.P99 = m.percentile(sorted, 99)
+	return d
+}
+
+// Statistics computes the allocation size distribution overall and per function, listing the
+// top functions by sortBy (bytes, count, or cv). Allocation records give exact figures;
+// otherwise each function's allocations are taken at its average size for percentiles, and
+// its spread is the largest its minimum, maximum, and average allow, so standard deviations
+// are upper bounds.
+func (ma *MemoryAnalyzer) Statistics(top int, sortBy string) (*SizeStatistics, error) {
+	if top <= 0 {
+		top = defaultStatisticsTop
+	}
+	var less func(a, b FunctionDistribution) bool
+	switch sortBy {
+	case "", "bytes":
+		less = func(a, b FunctionDistribution) bool { return a.Bytes > b.Bytes }
+	case "count":
+		less = func(a, b FunctionDistribution) bool { return a.Allocations > b.Allocations }
+	case "cv":
+		less = func(a, b FunctionDistribution) bool { return a.CV > b.CV }
+	default:
+		return nil, fmt.Errorf("unknown sort %q (expected bytes, count, or cv)", sortBy)
+	}
+
+	result := &SizeStatistics{Source: "allocations", Functions: []FunctionDistribution{}}
+	var overall sizeMoments
+	functions := make(map[string]*sizeMoments)
+	var order []string
+	record := func(function string, count int, mean, variance float64, lo, hi int64) {
+		m, ok := functions[function]
+		if !ok {
+			m = &sizeMoments{}
+			functions[function] = m
+			order = append(order, function)
+		}
+		m.add(count, mean, variance, lo, hi)
+		overall.add(count, mean, variance, lo, hi)
+	}
+
+	if len(ma.data.Allocations) > 0 {
+		for _, a := range ma.data.Allocations {
+			if a.Size > 0 {
+				record(a.FunctionName, 1, float64(a.Size), 0, a.Size, a.Size)
+			}
+		}
+	} else {
+		result.Source = "functions"
+		for _, fn := range ma.data.Functions {
+			if fn.AllocationCount <= 0 || fn.TotalSize <= 0 {
+				continue
+			}
+			mean := float64(fn.TotalSize) / float64(fn.AllocationCount)
+			lo, hi := fn.MinSize, fn.MaxSize
+			if lo <= 0 || hi < lo {
+				lo, hi = int64(mean), int64(math.Ceil(mean))
+			}
+			// Bhatia-Davis bound on the variance of values in [lo, hi] with this mean
+			variance := max((float64(hi)-mean)*(mean-float64(lo)), 0)
+			if variance > 0 {
+				result.Approximate = true
+			}
+			record(fn.FunctionName, fn.AllocationCount, mean, variance, lo, hi)
+		}
+	}
+
+	result.Overall = overall.distribution()
+	for _, name := range order {
+		d := FunctionDistribution{FunctionName: name, SizeDistribution: functions[name].distribution()}
+		if overall.count > 0 {
+			d.CountShare = math.Round(float64(d.Allocations)/float64(overall.count)*10000) / 100
+		}
+		if overall.sum > 0 {
+			d.ByteShare = math.Round(float64(d.Bytes)/overall.sum*10000) / 100
+		}
+		result.Functions = append(result.Functions, d)
+	}
+	sort.SliceStable(result.Functions, func(i, j int) bool {
+		a, b := result.Functions[i], result.Functions[j]
+		if less(a, b) != less(b, a) {
+			return less(a, b)
+		}
+		return a.FunctionName < b.FunctionName
+	})
+	result.Functions = result.Functions[:min(len(result.Functions), top)]
+	return result, nil
+}
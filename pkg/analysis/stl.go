@@ -0,0 +1,415 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// STL analysis defaults and the container layout used for estimates (64-bit standard libraries)
+const (
+	defaultSTLTop            = 20
+	defaultSTLMinAllocations = 100
+	stlMaxCallers            = 3
+	msvcGrowthFactor         = 1.5  // MSVC grows vector and string capacity by half
+	gnuGrowthFactor          = 2.0  // libstdc++ and libc++ double it
+	treeNodeLinkBytes        = 32   // Parent, left, and right pointers plus color of a map node
+	hashNodeLinkBytes        = 16   // Next pointer and cached hash of an unordered_map node
+	stlSmallString           = 64   // Average string buffer size below which string_view or interning pays off
+	stlMinSlack              = 4096 // Unused capacity below which shrink_to_fit() is not suggested
+)
+
+// STLSections are the capture sections analyze_stl reads; page views resolve leak stack IDs
+const STLSections = SectionFunctions | SectionLeaks | SectionPageViews | SectionCallTrees
+
+// stlContainers classifies allocating frames by container, in match order
+var stlContainers = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"unordered_map", regexp.MustCompile(`^std::(unordered_(multi)?(map|set)<|_Hash<|_Hashtable|__detail::_Hashtable|__hash_table<)`)},
+	{"map", regexp.MustCompile(`^std::((multi)?(map|set)<|_Tree<|_Tree_|_Rb_tree)`)},
+	{"string", regexp.MustCompile(`^std::(basic_string<|string::|wstring::|_String_val)`)},
+	{"vector", regexp.MustCompile(`^std::(vector<|_Vector)`)},
+}
+
+// Frames that reveal the standard library implementation, and with it the growth factor
+var (
+	msvcSTLPattern = regexp.MustCompile(`_Emplace_reallocate|_Resize_reallocate|_Reallocate_grow_by|_Reallocate_for|_Calculate_growth|_Buynode|_Tree_val|_String_val`)
+	gnuSTLPattern  = regexp.MustCompile(`_M_realloc_insert|_M_realloc_append|_M_create|_M_default_append|_M_get_node|_M_allocate|__push_back_slow_path|__emplace_back_slow_path|__append|__grow_by|__recommend`)
+)
+
+// MSVC traits classes of trees and hash tables, and the templates whose second argument is a mapped value
+var (
+	msvcTraitsPattern  = regexp.MustCompile(`^std::_T(map|set)_traits<|^std::_U(map|set)_traits<`)
+	mapTemplatePattern = regexp.MustCompile(`map<|_Tmap_traits<|_Umap_traits<`)
+)
+
+// stlSymbol strips a frame to its symbol and drops the libstdc++ and libc++ inline namespaces,
+// so every implementation spells containers as std::vector<...>
+func stlSymbol(frame string) string {
+	symbol := frameSymbol(frame)
+	symbol = strings.ReplaceAll(symbol, "std::__1::", "std::")
+	symbol = strings.ReplaceAll(symbol, "std::__cxx11::", "std::")
+	return symbol
+}
+
+// stlContainer returns the container a frame allocates for, or "" when it is not a
+// standard container's frame
+func stlContainer(frame string) string {
+	symbol := stlSymbol(frame)
+	for _, c := range stlContainers {
+		if c.pattern.MatchString(symbol) {
+			return c.name
+		}
+	}
+	return ""
+}
+
+// templateArguments returns the top-level template arguments of the first template in symbol
+func templateArguments(symbol string) []string {
+	start := strings.IndexByte(symbol, '<')
+	if start < 0 {
+		return nil
+	}
+	var args []string
+	depth, from := 0, start+1
+	for i := start; i < len(symbol); i++ {
+		switch symbol[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				return append(args, strings.TrimSpace(symbol[from:i]))
+			}
+		case ',':
+			if depth == 1 {
+				args = append(args, strings.TrimSpace(symbol[from:i]))
+				from = i + 1
+			}
+		}
+	}
+	return args
+}
+
+// stlElementType names what a container holds: the element type, the character type of a
+// string, or "key -> value" of a map
+func stlElementType(container, symbol string) string {
+	args := templateArguments(symbol)
+	if len(args) > 0 && msvcTraitsPattern.MatchString(args[0]) {
+		// MSVC trees and hash tables take their key and value through a traits class
+		args = templateArguments(args[0])
+	}
+	switch {
+	case len(args) == 0:
+		return ""
+	case (container == "map" || container == "unordered_map") && len(args) > 1 && mapTemplatePattern.MatchString(symbol):
+		return args[0] + " -> " + args[1]
+	}
+	return args[0]
+}
+
+// STLSite is one standard container allocation site with its estimates
+type STLSite struct {
+	Container           string   `json:"container"` // vector, string, map (also set), or unordered_map (also unordered_set)
+	Function            string   `json:"function"`
+	File                string   `json:"file,omitempty"`
+	Line                int      `json:"line,omitempty"`
+	ElementType         string   `json:"element_type,omitempty"`
+	Callers             []string `json:"callers,omitempty"` // Application functions growing the container, most bytes first, from the call trees
+	Allocations         int      `json:"allocations"`
+	TotalSize           int64    `json:"total_size"`
+	AverageSize         float64  `json:"average_size"`
+	MinSize             int64    `json:"min_size"`
+	MaxSize             int64    `json:"max_size"`
+	GrowthFactor        float64  `json:"growth_factor,omitempty"`
+	GrowthSteps         int      `json:"growth_steps,omitempty"`         // Buffers one container allocates growing from min_size to max_size
+	EstimatedContainers int      `json:"estimated_containers,omitempty"` // Containers grown to max_size that explain the allocations
+	Reallocations       int      `json:"reallocations,omitempty"`        // Allocations reserve() would avoid
+	ReallocatedBytes    int64    `json:"reallocated_bytes,omitempty"`    // Bytes of the intermediate buffers
+	LiveBytes           int64    `json:"live_bytes,omitempty"`           // Still allocated at the end of the capture, from the leaks
+	SlackBytes          int64    `json:"slack_bytes,omitempty"`          // Expected unused capacity of the live buffers
+	NodeOverhead        int64    `json:"node_overhead,omitempty"`        // Allocator headers and links of map nodes
+	Suggestions         []string `json:"suggestions"`
+	Savings             *Savings `json:"savings,omitempty"`
+}
+
+// STLContainerTotals sums the sites of one container kind
+type STLContainerTotals struct {
+	Sites       int   `json:"sites"`
+	Allocations int   `json:"allocations"`
+	TotalSize   int64 `json:"total_size"`
+	Savings     int64 `json:"savings"` // Bytes
+}
+
+// STLReport is the result of analyze_stl
+type STLReport struct {
+	Session     string                        `json:"session"`
+	Library     string                        `json:"standard_library"` // msvc (growth factor 1.5), gnu (2, libstdc++ and libc++), or assumed (1.5)
+	Sites       int                           `json:"sites"`
+	Allocations int                           `json:"allocations"`
+	TotalSize   int64                         `json:"total_size"`
+	Containers  map[string]STLContainerTotals `json:"containers"`
+	SavedBytes  int64                         `json:"saved_bytes"`
+	SavedAllocs int                           `json:"saved_allocations"`
+	Functions   []STLSite                     `json:"functions"` // Largest savings first
+	Omitted     int                           `json:"omitted,omitempty"`
+	Notes       []string                      `json:"notes,omitempty"`
+}
+
+// AnalyzeSTL finds the Functions records of standard container frames, estimates how much
+// of their allocation is reallocation during growth from the spread of their sizes, and
+// suggests reserve(), shrink_to_fit(), or flat containers with the bytes and allocations each
+// would save. container limits the report to one kind; sites below minAllocations are skipped.
+func (ma *MemoryAnalyzer) AnalyzeSTL(container string, minAllocations, top int) (*STLReport, error) {
+	if container != "" && stlContainerIndex(container) < 0 {
+		return nil, fmt.Errorf("unknown container %q (expected vector, string, map, or unordered_map)", container)
+	}
+	if minAllocations <= 0 {
+		minAllocations = defaultSTLMinAllocations
+	}
+	if top <= 0 {
+		top = defaultSTLTop
+	}
+
+	report := &STLReport{Session: ma.data.SessionName, Containers: map[string]STLContainerTotals{}, Functions: []STLSite{}}
+	factor, library := ma.stlGrowthFactor()
+	report.Library = library
+
+	var sites []STLSite
+	positions := make(map[string]int) // Site by function name
+	for _, fn := range ma.data.Functions {
+		kind := stlContainer(fn.FunctionName)
+		if kind == "" || (container != "" && kind != container) || fn.AllocationCount < minAllocations {
+			continue
+		}
+		if i, ok := positions[fn.FunctionName]; ok {
+			// A function with several records (heaps, lines) is one site
+			s := &sites[i]
+			s.Allocations += fn.AllocationCount
+			s.TotalSize += fn.TotalSize
+			s.MinSize = min(s.MinSize, fn.MinSize)
+			s.MaxSize = max(s.MaxSize, fn.MaxSize)
+			continue
+		}
+		positions[fn.FunctionName] = len(sites)
+		sites = append(sites, STLSite{
+			Container:   kind,
+			Function:    fn.FunctionName,
+			File:        fn.FileName,
+			Line:        fn.LineNumber,
+			ElementType: stlElementType(kind, stlSymbol(fn.FunctionName)),
+			Allocations: fn.AllocationCount,
+			TotalSize:   fn.TotalSize,
+			MinSize:     fn.MinSize,
+			MaxSize:     fn.MaxSize,
+		})
+	}
+	if len(sites) == 0 {
+		report.Notes = append(report.Notes, "no standard container frames allocate in the function statistics; exports that attribute allocations to operator new or malloc hide them")
+		return report, nil
+	}
+
+	ma.stlCallers(sites, positions)
+	ma.stlLiveBytes(sites, positions)
+	for i := range sites {
+		s := &sites[i]
+		s.AverageSize = math.Round(float64(s.TotalSize)/float64(s.Allocations)*100) / 100
+		if s.Container == "vector" || s.Container == "string" {
+			s.estimateGrowth(factor)
+		} else {
+			s.estimateNodes()
+		}
+		if s.Suggestions == nil {
+			s.Suggestions = []string{}
+		}
+
+		totals := report.Containers[s.Container]
+		totals.Sites++
+		totals.Allocations += s.Allocations
+		totals.TotalSize += s.TotalSize
+		report.Sites++
+		report.Allocations += s.Allocations
+		report.TotalSize += s.TotalSize
+		if s.Savings != nil {
+			totals.Savings += s.Savings.Bytes
+			report.SavedBytes += s.Savings.Bytes
+			report.SavedAllocs += s.Savings.Allocations
+		}
+		report.Containers[s.Container] = totals
+	}
+
+	sort.SliceStable(sites, func(i, j int) bool {
+		a, b := sites[i].Savings, sites[j].Savings
+		switch {
+		case a == nil || b == nil:
+			return a != nil && b == nil
+		case a.Bytes != b.Bytes:
+			return a.Bytes > b.Bytes
+		}
+		return sites[i].TotalSize > sites[j].TotalSize
+	})
+	if len(sites) > top {
+		report.Omitted = len(sites) - top
+		sites = sites[:top]
+	}
+	report.Functions = sites
+	return report, nil
+}
+
+// stlContainerIndex returns the position of a container name in stlContainers, or -1
+func stlContainerIndex(name string) int {
+	for i, c := range stlContainers {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// stlGrowthFactor picks the capacity growth factor of the standard library the capture was
+// built with, recognized by its internal frame names
+func (ma *MemoryAnalyzer) stlGrowthFactor() (float64, string) {
+	for _, fn := range ma.data.Functions {
+		switch {
+		case msvcSTLPattern.MatchString(fn.FunctionName):
+			return msvcGrowthFactor, "msvc"
+		case gnuSTLPattern.MatchString(fn.FunctionName):
+			return gnuGrowthFactor, "gnu"
+		}
+	}
+	return msvcGrowthFactor, "assumed"
+}
+
+// stlCallers fills in each site's callers: the nearest application function above the
+// site's call tree nodes, weighted by the nodes' inclusive size
+func (ma *MemoryAnalyzer) stlCallers(sites []STLSite, positions map[string]int) {
+	weights := make([]map[string]int64, len(sites))
+	var walk func(trees []CallTree, caller string)
+	walk = func(trees []CallTree, caller string) {
+		for _, t := range trees {
+			if i, ok := positions[t.FunctionName]; ok && caller != "" {
+				if weights[i] == nil {
+					weights[i] = make(map[string]int64)
+				}
+				weights[i][caller] += treeInclusive(t)
+			}
+			next := caller
+			if stlContainer(t.FunctionName) == "" && !ma.config.plumbing.isPlumbing(t.FunctionName) {
+				next = frameSymbol(t.FunctionName)
+			}
+			walk(t.Children, next)
+		}
+	}
+	walk(ma.data.CallTrees, "")
+
+	for i, w := range weights {
+		callers := make([]string, 0, len(w))
+		for name := range w {
+			callers = append(callers, name)
+		}
+		sort.Slice(callers, func(a, b int) bool {
+			if w[callers[a]] != w[callers[b]] {
+				return w[callers[a]] > w[callers[b]]
+			}
+			return callers[a] < callers[b]
+		})
+		if len(callers) > 0 {
+			sites[i].Callers = callers[:min(len(callers), stlMaxCallers)]
+		}
+	}
+}
+
+// stlLiveBytes adds each merged leak to the site of its innermost container frame
+func (ma *MemoryAnalyzer) stlLiveBytes(sites []STLSite, positions map[string]int) {
+	groups, _ := ma.groupLeaks()
+	for _, g := range groups {
+		for _, frame := range append([]string{g.FunctionName}, parseCallStack(g.CallStack)...) {
+			if stlContainer(frame) == "" {
+				continue
+			}
+			if i, ok := positions[frame]; ok {
+				sites[i].LiveBytes += g.LeakSize
+			} else if i, ok := positions[frameSymbol(frame)]; ok {
+				sites[i].LiveBytes += g.LeakSize
+			}
+			break
+		}
+	}
+}
+
+// estimateGrowth estimates a vector's or string's reallocations. A container growing by
+// factor from MinSize to MaxSize allocates one buffer per step, so the allocations are
+// explained by AllocationCount / steps containers, and all but each container's last
+// buffer are reallocations that reserve() avoids. Live buffers are on average
+// (factor-1)/(2*factor) unused capacity, which shrink_to_fit() returns.
+func (s *STLSite) estimateGrowth(factor float64) {
+	s.GrowthFactor = factor
+	if s.MinSize > 0 && s.MaxSize > s.MinSize {
+		s.GrowthSteps = int(math.Ceil(math.Log(float64(s.MaxSize)/float64(s.MinSize))/math.Log(factor))) + 1
+	} else {
+		s.GrowthSteps = 1
+	}
+	s.EstimatedContainers = max((s.Allocations+s.GrowthSteps-1)/s.GrowthSteps, 1)
+	s.Reallocations = s.Allocations - s.EstimatedContainers
+
+	if s.Reallocations > 0 {
+		// Intermediate buffers of one container: MaxSize/factor + MaxSize/factor^2 + ...
+		intermediate := 0.0
+		for k := 1; k < s.GrowthSteps; k++ {
+			intermediate += math.Pow(factor, -float64(k))
+		}
+		s.ReallocatedBytes = int64(float64(s.TotalSize) * intermediate / (1 + intermediate))
+		s.Savings = &Savings{Bytes: s.ReallocatedBytes, Allocations: s.Reallocations}
+
+		capacity := fmt.Sprintf("%d bytes", s.MaxSize)
+		if s.ElementType != "" && s.Container == "vector" {
+			capacity = fmt.Sprintf("%d / sizeof(%s) elements", s.MaxSize, s.ElementType)
+		} else if s.Container == "string" {
+			capacity = fmt.Sprintf("%d characters", s.MaxSize)
+		}
+		s.Suggestions = append(s.Suggestions, fmt.Sprintf("Buffers grow from %d to %d bytes in about %d steps, so an estimated %d containers reallocate %d times. reserve(%s) before filling them, or reuse one container across calls, to save about %d allocation calls and %s of copied buffers.",
+			s.MinSize, s.MaxSize, s.GrowthSteps, s.EstimatedContainers, s.Reallocations, capacity, s.Reallocations, FormatBytes(s.ReallocatedBytes)))
+	}
+
+	if s.LiveBytes > 0 {
+		s.SlackBytes = int64(float64(s.LiveBytes) * (factor - 1) / (2 * factor))
+		if s.SlackBytes >= stlMinSlack {
+			s.Suggestions = append(s.Suggestions, fmt.Sprintf("%s of these buffers are still allocated; after growth about %.0f%% of a buffer is unused capacity, so shrink_to_fit() on long-lived containers once filled would return about %s.",
+				FormatBytes(s.LiveBytes), (factor-1)/(2*factor)*100, FormatBytes(s.SlackBytes)))
+			if s.Savings == nil {
+				s.Savings = &Savings{}
+			}
+			s.Savings.Bytes += s.SlackBytes
+		}
+	}
+
+	if s.Container == "string" && s.AverageSize <= stlSmallString && s.Allocations >= duplicateMinCount {
+		s.Suggestions = append(s.Suggestions, fmt.Sprintf("Strings average %.0f bytes over %d allocations: pass std::string_view for read-only parameters, or intern repeated strings into IDs, so short copies stop allocating.",
+			s.AverageSize, s.Allocations))
+	}
+}
+
+// estimateNodes estimates the per-node overhead of a map: every element is its own
+// allocation with an allocator header and tree or hash links, which a flat container
+// storing the elements contiguously does without
+func (s *STLSite) estimateNodes() {
+	links := int64(treeNodeLinkBytes)
+	flat := "a sorted-vector map (std::flat_map in C++23, boost::container::flat_map) keeps ordered iteration, and suits maps that are looked up far more often than modified"
+	if s.Container == "unordered_map" {
+		links = hashNodeLinkBytes
+		flat = "an open-addressing table (absl::flat_hash_map, ankerl::unordered_dense, tsl::robin_map) keeps O(1) lookups; reserve() the expected size to avoid rehashing the bucket array"
+	}
+	s.NodeOverhead = int64(s.Allocations) * (AllocatorHeaderBytes + links)
+	s.Savings = &Savings{Bytes: s.NodeOverhead, Allocations: s.Allocations}
+
+	node := ""
+	if s.MinSize > 0 && s.MinSize == s.MaxSize {
+		node = fmt.Sprintf(" of %d bytes", s.MinSize)
+	}
+	s.Suggestions = append(s.Suggestions, fmt.Sprintf("Each of the %d allocations%s is one node carrying about %d bytes of allocator header and links (%s in total). Storing the elements contiguously avoids nearly all of these allocations: %s.",
+		s.Allocations, node, AllocatorHeaderBytes+links, FormatBytes(s.NodeOverhead), flat))
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// suggestionExamples is how many representative issues are listed per aggregated suggestion
+const suggestionExamples = 3
+
+// SuggestionGroup is one suggestion shared by several issues
+type SuggestionGroup struct {
+	ID         string   `json:"id"`
+	Suggestion string   `json:"suggestion"`
+	IssueCount int      `json:"issue_count"`
+	TotalSize  int64    `json:"total_size"`
+	Examples   []string `json:"examples"`
+	Summary    string   `json:"summary"`
+}
+
+// AggregateSuggestions replaces suggestion text shared by at least minIssues issues with a
+// reference to a single SuggestionGroup, modifying the issues in place. Groups are ordered by
+// the total size of the issues they cover.
+func AggregateSuggestions(groups [][]MemoryIssue, minIssues int) []SuggestionGroup {
+	if minIssues < 2 {
+		minIssues = 2
+	}
+
+	type member struct{ group, index int }
+	members := make(map[string][]member)
+	for g, issues := range groups {
+		for i, issue := range issues {
+			if issue.Suggestion != "" {
+				members[issue.Suggestion] = append(members[issue.Suggestion], member{g, i})
+			}
+		}
+	}
+
+	var result []SuggestionGroup
+	for text, ms := range members {
+		if len(ms) < minIssues {
+			continue
+		}
+
+		sg := SuggestionGroup{Suggestion: text, IssueCount: len(ms)}
+		covered := make([]MemoryIssue, 0, len(ms))
+		for _, m := range ms {
+			issue := groups[m.group][m.index]
+			sg.TotalSize += issue.Size
+			covered = append(covered, issue)
+		}
+
+		sort.Slice(covered, func(i, j int) bool { return covered[i].Size > covered[j].Size })
+		for _, issue := range covered[:min(len(covered), suggestionExamples)] {
+			sg.Examples = append(sg.Examples, issueLabel(issue))
+		}
+		sg.Summary = fmt.Sprintf("This suggestion applies to %d issues totaling %s; representative examples: %s",
+			sg.IssueCount, FormatBytes(sg.TotalSize), strings.Join(sg.Examples, ", "))
+
+		result = append(result, sg)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalSize != result[j].TotalSize {
+			return result[i].TotalSize > result[j].TotalSize
+		}
+		return result[i].Suggestion < result[j].Suggestion
+	})
+
+	ids := make(map[string]string, len(result))
+	for i := range result {
+		result[i].ID = fmt.Sprintf("S%d", i+1)
+		ids[result[i].Suggestion] = result[i].ID
+	}
+	for g := range groups {
+		for i := range groups[g] {
+			if id, ok := ids[groups[g][i].Suggestion]; ok {
+				groups[g][i].Suggestion = "See suggestion " + id
+			}
+		}
+	}
+
+	return result
+}
+
+// issueLabel is a short human-readable name for an issue
+func issueLabel(issue MemoryIssue) string {
+	label := issue.FunctionName
+	if label == "" {
+		label = issue.Type
+	}
+	if issue.FileName != "" {
+		label += fmt.Sprintf(" (%s:%d)", issue.FileName, issue.LineNumber)
+	}
+	return fmt.Sprintf("%s [%s]", label, FormatBytes(issue.Size))
+}
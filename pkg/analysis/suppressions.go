@@ -0,0 +1,279 @@
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultSuppressionFrames is how many application frames a generated stack suppression lists
+const defaultSuppressionFrames = 4
+
+// anyFrames stands for any number of frames in a suppression's frame list
+const anyFrames = "..."
+
+// suppressibleTypes are the issue types suppressions apply to
+var suppressibleTypes = []string{"MemoryLeak", "LargeAllocation"}
+
+// Suppression silences a known or accepted issue, like a valgrind suppression. Every field
+// given must match; an entry with none is rejected.
+type Suppression struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type,omitempty"`       // MemoryLeak or LargeAllocation; empty for both
+	ID        string   `json:"id,omitempty"`         // Exact issue ID
+	Function  string   `json:"function,omitempty"`   // Matched against the blamed or allocating function
+	File      string   `json:"file,omitempty"`       // Matched against the source file
+	CallStack string   `json:"call_stack,omitempty"` // Matched against the whole call stack text
+	Frames    []string `json:"frames,omitempty"`     // Application frames, innermost first; * globs, ... skips frames
+	Reason    string   `json:"reason,omitempty"`
+}
+
+type compiledSuppression struct {
+	Suppression
+	function, file, callStack *regexp.Regexp
+	frames                    []*regexp.Regexp // nil entries stand for "..."
+}
+
+// compileSuppressions validates suppressions and compiles their patterns
+func compileSuppressions(suppressions []Suppression) ([]*compiledSuppression, error) {
+	compiled := make([]*compiledSuppression, 0, len(suppressions))
+	for i, s := range suppressions {
+		c, err := compileSuppression(i, s)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// compileSuppression validates a single suppression; index is only used to name unnamed ones in errors
+func compileSuppression(index int, s Suppression) (*compiledSuppression, error) {
+	name := s.Name
+	if name == "" {
+		name = fmt.Sprintf("#%d", index+1)
+	}
+	if s.ID == "" && s.Function == "" && s.File == "" && s.CallStack == "" && len(s.Frames) == 0 {
+		return nil, fmt.Errorf("suppression %s: needs at least one of id, function, file, call_stack, or frames", name)
+	}
+	if s.Type != "" && !slices.Contains(suppressibleTypes, s.Type) {
+		return nil, fmt.Errorf("suppression %s: unknown type %q (expected %s)", name, s.Type, strings.Join(suppressibleTypes, " or "))
+	}
+
+	c := &compiledSuppression{Suppression: s}
+	for _, p := range []struct {
+		field   string
+		pattern string
+		re      **regexp.Regexp
+	}{
+		{"function", s.Function, &c.function},
+		{"file", s.File, &c.file},
+		{"call_stack", s.CallStack, &c.callStack},
+	} {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("suppression %s: invalid %s pattern: %w", name, p.field, err)
+		}
+		*p.re = re
+	}
+	for _, frame := range s.Frames {
+		if frame == anyFrames {
+			c.frames = append(c.frames, nil)
+			continue
+		}
+		c.frames = append(c.frames, regexp.MustCompile(frameGlob(frame)))
+	}
+	return c, nil
+}
+
+// frameGlob turns a frame glob into an anchored regular expression: * matches any run of
+// characters, and \* a literal star, as in operator\*
+func frameGlob(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case glob[i] == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case glob[i] == '*':
+			b.WriteString(".*")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// escapeGlob quotes the characters frameGlob treats specially
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`).Replace(s)
+}
+
+// suppressionSubject is what suppressions are matched against
+type suppressionSubject struct {
+	issue     MemoryIssue
+	allocator string // Allocating function, when plumbing was blamed past it
+	callStack string
+}
+
+// matches reports whether the suppression silences the subject; frames are the call
+// stack's application frames, innermost first
+func (s *compiledSuppression) matches(subject suppressionSubject, frames []string) bool {
+	issue := subject.issue
+	if s.Type != "" && s.Type != issue.Type {
+		return false
+	}
+	if s.ID != "" && s.ID != issue.ID {
+		return false
+	}
+	if s.function != nil && !s.function.MatchString(issue.FunctionName) && !s.function.MatchString(subject.allocator) {
+		return false
+	}
+	if s.file != nil && !s.file.MatchString(issue.FileName) {
+		return false
+	}
+	if s.callStack != nil && !s.callStack.MatchString(subject.callStack) {
+		return false
+	}
+	return s.frames == nil || matchFrames(s.frames, frames)
+}
+
+// matchFrames matches patterns against the innermost frames; a nil pattern skips any number
+// of frames, and the stack may continue past the last pattern
+func matchFrames(patterns []*regexp.Regexp, frames []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	if patterns[0] == nil {
+		for i := 0; i <= len(frames); i++ {
+			if matchFrames(patterns[1:], frames[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(frames) > 0 && patterns[0].MatchString(frames[0]) && matchFrames(patterns[1:], frames[1:])
+}
+
+// suppressed returns the first configured suppression that silences the subject, or nil
+func (ma *MemoryAnalyzer) suppressed(subject suppressionSubject) *compiledSuppression {
+	if len(ma.config.suppressions) == 0 {
+		return nil
+	}
+	frames := ma.applicationFrames(subject.callStack)
+	for _, s := range ma.config.suppressions {
+		if s.matches(subject, frames) {
+			return s
+		}
+	}
+	return nil
+}
+
+// applicationFrames returns a call stack's frame symbols without allocator plumbing,
+// innermost first
+func (ma *MemoryAnalyzer) applicationFrames(stack string) []string {
+	var frames []string
+	for _, frame := range parseCallStack(stack) {
+		if !ma.config.plumbing.isPlumbing(frame) {
+			frames = append(frames, frameSymbol(frame))
+		}
+	}
+	return frames
+}
+
+// SuppressionFor builds a suppression entry for an issue. Scope is "stack" (the innermost
+// application frames; the default for leaks with a call stack), "function", "file", or "id".
+func (ma *MemoryAnalyzer) SuppressionFor(id, scope, reason string) (*Suppression, error) {
+	issue, err := findIssue([][]MemoryIssue{ma.AnalyzeLeaks(), ma.AnalyzeLargeAllocations()}, id)
+	if err != nil {
+		if _, err := findIssue(ma.AnalyzeAll(), id); err == nil {
+			return nil, fmt.Errorf("issue %s cannot be suppressed: suppressions apply to %s issues", id, strings.Join(suppressibleTypes, " and "))
+		}
+		return nil, err
+	}
+
+	var stack string
+	if group, ok := ma.leakGroup(id); ok && issue.Type == "MemoryLeak" {
+		stack = group.Leak.CallStack
+	}
+	frames := ma.applicationFrames(stack)
+	if len(frames) > defaultSuppressionFrames {
+		frames = frames[:defaultSuppressionFrames]
+	}
+	if scope == "" {
+		scope = "function"
+		if len(frames) > 0 {
+			scope = "stack"
+		}
+	}
+
+	s := &Suppression{
+		Name:   fmt.Sprintf("%s-%s", strings.ToLower(issue.Type), issue.FunctionName),
+		Type:   issue.Type,
+		Reason: reason,
+	}
+	switch scope {
+	case "stack":
+		if len(frames) == 0 {
+			return nil, errors.New("the issue has no call stack; use scope function, file, or id")
+		}
+		for _, frame := range frames {
+			s.Frames = append(s.Frames, escapeGlob(frame))
+		}
+	case "function":
+		s.Function = "^" + regexp.QuoteMeta(issue.FunctionName) + "$"
+	case "file":
+		if issue.FileName == "" {
+			return nil, errors.New("the issue has no source file; use scope stack, function, or id")
+		}
+		s.File = regexp.QuoteMeta(issue.FileName) + "$"
+	case "id":
+		s.ID = issue.ID
+	default:
+		return nil, fmt.Errorf("unknown scope %q (expected stack, function, file, or id)", scope)
+	}
+	return s, nil
+}
+
+// loadSuppressionsFile reads a standalone JSON array of suppressions
+func loadSuppressionsFile(path string) ([]Suppression, error) {
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppressions file: %w", err)
+	}
+
+	var suppressions []Suppression
+	if err := json.Unmarshal(fileData, &suppressions); err != nil {
+		return nil, fmt.Errorf("failed to parse suppressions file %s: %w", path, err)
+	}
+	return suppressions, nil
+}
+
+// AppendSuppression adds a suppression to a suppressions file, creating it if missing
+func AppendSuppression(path string, s Suppression) error {
+	var suppressions []Suppression
+	if _, err := os.Stat(path); err == nil {
+		if suppressions, err = loadSuppressionsFile(path); err != nil {
+			return err
+		}
+	}
+	suppressions = append(suppressions, s)
+
+	fileData, err := json.MarshalIndent(suppressions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(fileData, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write suppressions file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Symbolication defaults; addresses are passed to the backend in batches to stay under
+// command line limits
+const (
+	defaultSymbolTimeout = 60 // Seconds per module
+	symbolBatchSize      = 256
+)
+
+// addressFramePattern matches unsymbolized frames: module!0xoffset (as heaptrack imports
+// name them) or module.ext+0xoffset (as debuggers print them)
+var addressFramePattern = regexp.MustCompile(`^(?:([\w.\-]+)!0x([0-9a-fA-F]+)|([\w\-]+\.[\w.\-]+)\s*\+\s*0x([0-9a-fA-F]+))$`)
+
+// SymbolicationConfig resolves frames recorded as a module and an offset to function, file,
+// and line when a capture is loaded
+type SymbolicationConfig struct {
+	Backend     string                   `json:"backend"`      // "addr2line" (default) or "pdb" (Windows, through dbghelp)
+	Tool        string                   `json:"tool"`         // addr2line executable, e.g. "llvm-addr2line"; default "addr2line"
+	SymbolPath  string                   `json:"symbol_path"`  // dbghelp search path, e.g. "srv*C:\\symbols*https://msdl.microsoft.com/download/symbols"; default _NT_SYMBOL_PATH
+	Modules     map[string]ModuleSymbols `json:"modules"`      // Per module name as it appears in frames
+	SearchPaths []string                 `json:"search_paths"` // Directories searched for binaries not listed in modules
+	Timeout     int                      `json:"timeout"`      // Seconds per module; default 60
+
+	bases map[string]uint64 // Parsed ModuleSymbols.Base, by lower-cased module name
+}
+
+// ModuleSymbols locates one module's binary
+type ModuleSymbols struct {
+	Path string `json:"path"` // Binary with debug information (or its PDB alongside)
+	Base string `json:"base"` // Load address subtracted from recorded addresses, e.g. "0x7f3a2c000000"; omit for offsets
+}
+
+// sourceFrame is a resolved address; Function is empty when the backend found no symbol
+type sourceFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// symbolBackend resolves offsets within one module's binary
+type symbolBackend interface {
+	resolve(module, binary string, offsets []uint64) ([]sourceFrame, error)
+	close()
+}
+
+// check validates the symbolication settings and parses the module bases
+func (s *SymbolicationConfig) check(c *Config) error {
+	switch s.Backend {
+	case "", "addr2line", "pdb":
+	default:
+		return fmt.Errorf("symbolication: unknown backend %q (expected \"addr2line\" or \"pdb\")", s.Backend)
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("symbolication: timeout must not be negative")
+	}
+	s.bases = make(map[string]uint64)
+	for name, m := range s.Modules {
+		if m.Base == "" {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(m.Base), "0x"), 16, 64)
+		if err != nil {
+			return fmt.Errorf("symbolication: modules: %s: invalid base %q (expected a hex address)", name, m.Base)
+		}
+		s.bases[strings.ToLower(name)] = base
+	}
+	for name, m := range s.Modules {
+		m.Path = c.ResolvePath(m.Path)
+		s.Modules[name] = m
+	}
+	for i, dir := range s.SearchPaths {
+		s.SearchPaths[i] = c.ResolvePath(dir)
+	}
+	return nil
+}
+
+// module returns the settings of a module, matching its name case-insensitively
+func (s *SymbolicationConfig) module(name string) (ModuleSymbols, uint64, bool) {
+	if m, ok := s.Modules[name]; ok {
+		return m, s.bases[strings.ToLower(name)], true
+	}
+	for key, m := range s.Modules {
+		if strings.EqualFold(key, name) {
+			return m, s.bases[strings.ToLower(key)], true
+		}
+	}
+	return ModuleSymbols{}, 0, false
+}
+
+// binary returns the file to symbolize a module with and its load base, or "" when no
+// binary is configured or found in the search paths
+func (s *SymbolicationConfig) binary(name string) (string, uint64) {
+	m, base, ok := s.module(name)
+	if ok && m.Path != "" {
+		return m.Path, base
+	}
+	for _, dir := range s.SearchPaths {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, base
+		}
+	}
+	return "", base
+}
+
+// timeout returns how long one module may take to resolve
+func (s *SymbolicationConfig) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return time.Duration(s.Timeout) * time.Second
+	}
+	return defaultSymbolTimeout * time.Second
+}
+
+// newSymbolBackend returns the configured backend
+func newSymbolBackend(s *SymbolicationConfig) (symbolBackend, error) {
+	if s.Backend == "pdb" {
+		symbolPath := s.SymbolPath
+		if symbolPath == "" {
+			symbolPath = os.Getenv("_NT_SYMBOL_PATH")
+		}
+		return newPDBBackend(symbolPath)
+	}
+	tool := s.Tool
+	if tool == "" {
+		tool = "addr2line"
+	}
+	return &addr2lineBackend{tool: tool, timeout: s.timeout()}, nil
+}
+
+// parseAddressFrame splits an unsymbolized frame into its module and address
+func parseAddressFrame(frame string) (string, uint64, bool) {
+	m := addressFramePattern.FindStringSubmatch(strings.TrimSpace(frame))
+	if m == nil {
+		return "", 0, false
+	}
+	module, address := m[1], m[2]
+	if module == "" {
+		module, address = m[3], m[4]
+	}
+	offset, err := strconv.ParseUint(address, 16, 64)
+	return module, offset, err == nil
+}
+
+// symbolicateData resolves the capture's address frames and rewrites them in place as
+// module!function. Leaks recorded as "Unknown Function" take the first frame of their call
+// stack that resolved. Modules without a binary, and addresses no symbol covers, are left
+// as recorded.
+func symbolicateData(data *MemProData, s *SymbolicationConfig, diag *Diagnostics) error {
+	addresses := make(map[string]map[uint64]bool) // Module -> recorded addresses
+	collect := func(frame string) {
+		if module, address, ok := parseAddressFrame(frame); ok {
+			if addresses[module] == nil {
+				addresses[module] = make(map[uint64]bool)
+			}
+			addresses[module][address] = true
+		}
+	}
+	for _, l := range data.Leaks {
+		collect(l.FunctionName)
+		for _, f := range parseCallStack(l.CallStack) {
+			collect(f)
+		}
+	}
+	for _, f := range data.Functions {
+		collect(f.FunctionName)
+	}
+	walkCallTrees(data.CallTrees, func(t *CallTree) { collect(t.FunctionName) })
+	for _, p := range data.PageViews {
+		collect(p.FunctionName)
+		for _, f := range parseCallStack(p.CallStack) {
+			collect(f)
+		}
+	}
+	for _, a := range data.Allocations {
+		collect(a.FunctionName)
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	backend, err := newSymbolBackend(s)
+	if err != nil {
+		return err
+	}
+	defer backend.close()
+
+	modules := make([]string, 0, len(addresses))
+	for module := range addresses {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	resolved := make(map[string]map[uint64]sourceFrame)
+	total, found := 0, 0
+	for _, module := range modules {
+		recorded := make([]uint64, 0, len(addresses[module]))
+		for address := range addresses[module] {
+			recorded = append(recorded, address)
+		}
+		sort.Slice(recorded, func(i, j int) bool { return recorded[i] < recorded[j] })
+		total += len(recorded)
+
+		binary, base := s.binary(module)
+		if binary == "" && s.Backend != "pdb" {
+			diag.note("no binary found for module %s; add it to symbolication.modules or search_paths", module)
+			continue
+		}
+		// Addresses below the base were recorded as offsets already
+		offsets := make([]uint64, len(recorded))
+		for i, address := range recorded {
+			offsets[i] = address
+			if address >= base {
+				offsets[i] = address - base
+			}
+		}
+		frames, err := backend.resolve(module, binary, offsets)
+		if err != nil {
+			diag.note("could not symbolicate module %s: %v", module, err)
+			continue
+		}
+		resolved[module] = make(map[uint64]sourceFrame)
+		for i, f := range frames {
+			if f.Function != "" {
+				resolved[module][recorded[i]] = f
+				found++
+			}
+		}
+	}
+	diag.note("symbolicated %d of %d addresses in %d modules", found, total, len(modules))
+	if found == 0 {
+		return nil
+	}
+
+	lookup := func(frame string) (sourceFrame, bool) {
+		module, address, ok := parseAddressFrame(frame)
+		if !ok {
+			return sourceFrame{}, false
+		}
+		f, ok := resolved[module][address]
+		if ok {
+			f.Function = module + "!" + f.Function
+		}
+		return f, ok
+	}
+	rename := func(frame string) string {
+		if f, ok := lookup(frame); ok {
+			return f.Function
+		}
+		return frame
+	}
+	locate := func(function, file *string, line *int) {
+		f, ok := lookup(*function)
+		if !ok {
+			return
+		}
+		*function = f.Function
+		if *file == "" {
+			*file, *line = f.File, f.Line
+		}
+	}
+
+	for i := range data.Leaks {
+		l := &data.Leaks[i]
+		locate(&l.FunctionName, &l.FileName, &l.LineNumber)
+		if l.FunctionName == "" || strings.Contains(l.FunctionName, "Unknown Function") {
+			for _, frame := range parseCallStack(l.CallStack) {
+				if f, ok := lookup(frame); ok {
+					l.FunctionName = f.Function
+					if l.FileName == "" {
+						l.FileName, l.LineNumber = f.File, f.Line
+					}
+					break
+				}
+			}
+		}
+		l.CallStack = rewriteCallStack(l.CallStack, rename)
+	}
+	for i := range data.Functions {
+		f := &data.Functions[i]
+		locate(&f.FunctionName, &f.FileName, &f.LineNumber)
+	}
+	walkCallTrees(data.CallTrees, func(t *CallTree) { locate(&t.FunctionName, &t.FileName, &t.LineNumber) })
+	for i := range data.PageViews {
+		p := &data.PageViews[i]
+		p.FunctionName = rename(p.FunctionName)
+		p.CallStack = rewriteCallStack(p.CallStack, rename)
+	}
+	for i := range data.Allocations {
+		a := &data.Allocations[i]
+		locate(&a.FunctionName, &a.FileName, &a.LineNumber)
+	}
+	return nil
+}
+
+// walkCallTrees calls fn on every node of the trees, parents before children
+func walkCallTrees(trees []CallTree, fn func(t *CallTree)) {
+	for i := range trees {
+		fn(&trees[i])
+		walkCallTrees(trees[i].Children, fn)
+	}
+}
+
+// addr2lineBackend runs addr2line (or a compatible tool such as llvm-addr2line) per module
+type addr2lineBackend struct {
+	tool    string
+	timeout time.Duration
+}
+
+func (b *addr2lineBackend) resolve(module, binary string, offsets []uint64) ([]sourceFrame, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	frames := make([]sourceFrame, 0, len(offsets))
+	for start := 0; start < len(offsets); start += symbolBatchSize {
+		batch := offsets[start:min(start+symbolBatchSize, len(offsets))]
+		args := []string{"-f", "-C", "-e", binary}
+		for _, offset := range batch {
+			args = append(args, fmt.Sprintf("0x%x", offset))
+		}
+
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, b.tool, args...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%s: %w: %s", b.tool, err, msg)
+			}
+			return nil, fmt.Errorf("%s: %w", b.tool, err)
+		}
+
+		// Two lines per address: the function, then file:line
+		var lines []string
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if len(lines) < 2*len(batch) {
+			return nil, fmt.Errorf("%s returned %d lines for %d addresses", b.tool, len(lines), len(batch))
+		}
+		for i := range batch {
+			frames = append(frames, parseAddr2line(lines[2*i], lines[2*i+1]))
+		}
+	}
+	return frames, nil
+}
+
+func (b *addr2lineBackend) close() {}
+
+// parseAddr2line reads one address's output; "??" marks what addr2line could not resolve
+func parseAddr2line(function, location string) sourceFrame {
+	f := sourceFrame{}
+	if function = strings.TrimSpace(function); function != "??" {
+		f.Function = function
+	}
+	// "file:line", possibly followed by " (discriminator N)"
+	if i := strings.Index(location, " ("); i >= 0 {
+		location = location[:i]
+	}
+	if i := strings.LastIndex(location, ":"); i > 0 {
+		file, line := location[:i], location[i+1:]
+		if file != "??" {
+			f.File = file
+			f.Line, _ = strconv.Atoi(line)
+		}
+	}
+	return f
+}
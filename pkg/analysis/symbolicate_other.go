@@ -0,0 +1,11 @@
+//go:build !(windows && (amd64 || arm64))
+
+package analysis
+
+import "fmt"
+
+// newPDBBackend reports that PDB symbolication needs dbghelp, which only 64-bit Windows
+// builds load
+func newPDBBackend(symbolPath string) (symbolBackend, error) {
+	return nil, fmt.Errorf("the pdb symbolication backend requires a 64-bit Windows build; use addr2line (or llvm-addr2line) elsewhere")
+}
@@ -0,0 +1,150 @@
+//go:build windows && (amd64 || arm64)
+
+package analysis
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// dbghelp options: undecorated names, line numbers, symbols loaded on first use, and no
+// dialogs or prompts from the symbol server
+const (
+	symoptUndname            = 0x00000002
+	symoptDeferredLoads      = 0x00000004
+	symoptLoadLines          = 0x00000010
+	symoptFailCriticalErrors = 0x00000200
+	symoptNoPrompts          = 0x00080000
+
+	symbolInfoSize   = 88         // sizeof(SYMBOL_INFOW), which counts one name character
+	maxSymbolName    = 2000       // MAX_SYM_NAME
+	pdbModuleAddress = 0x10000000 // Where each module is loaded while resolving it
+)
+
+var (
+	dbghelp                   = syscall.NewLazyDLL("dbghelp.dll")
+	procSymSetOptions         = dbghelp.NewProc("SymSetOptions")
+	procSymInitializeW        = dbghelp.NewProc("SymInitializeW")
+	procSymCleanup            = dbghelp.NewProc("SymCleanup")
+	procSymLoadModuleExW      = dbghelp.NewProc("SymLoadModuleExW")
+	procSymUnloadModule64     = dbghelp.NewProc("SymUnloadModule64")
+	procSymFromAddrW          = dbghelp.NewProc("SymFromAddrW")
+	procSymGetLineFromAddrW64 = dbghelp.NewProc("SymGetLineFromAddrW64")
+
+	// dbghelp is single-threaded, so one backend resolves at a time
+	dbghelpMu sync.Mutex
+)
+
+// symbolInfoW is SYMBOL_INFOW with room for the name
+type symbolInfoW struct {
+	SizeOfStruct uint32
+	TypeIndex    uint32
+	Reserved     [2]uint64
+	Index        uint32
+	Size         uint32
+	ModBase      uint64
+	Flags        uint32
+	_            uint32
+	Value        uint64
+	Address      uint64
+	Register     uint32
+	Scope        uint32
+	Tag          uint32
+	NameLen      uint32
+	MaxNameLen   uint32
+	Name         [maxSymbolName]uint16
+}
+
+// imagehlpLineW64 is IMAGEHLP_LINEW64
+type imagehlpLineW64 struct {
+	SizeOfStruct uint32
+	Key          uintptr
+	LineNumber   uint32
+	FileName     *uint16
+	Address      uint64
+}
+
+// pdbBackend resolves offsets through dbghelp, which finds each module's PDB next to the
+// binary or on the symbol path, including symbol servers
+type pdbBackend struct {
+	process syscall.Handle
+}
+
+func newPDBBackend(symbolPath string) (symbolBackend, error) {
+	if err := dbghelp.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load dbghelp.dll: %w", err)
+	}
+	dbghelpMu.Lock()
+
+	var searchPath *uint16
+	if symbolPath != "" {
+		p, err := syscall.UTF16PtrFromString(symbolPath)
+		if err != nil {
+			dbghelpMu.Unlock()
+			return nil, fmt.Errorf("invalid symbol path: %w", err)
+		}
+		searchPath = p
+	}
+	process, _ := syscall.GetCurrentProcess()
+	procSymSetOptions.Call(symoptUndname | symoptDeferredLoads | symoptLoadLines | symoptFailCriticalErrors | symoptNoPrompts)
+	if ok, _, err := procSymInitializeW.Call(uintptr(process), uintptr(unsafe.Pointer(searchPath)), 0); ok == 0 {
+		dbghelpMu.Unlock()
+		return nil, fmt.Errorf("SymInitialize failed: %w", err)
+	}
+	return &pdbBackend{process: process}, nil
+}
+
+func (b *pdbBackend) resolve(module, binary string, offsets []uint64) ([]sourceFrame, error) {
+	image := binary
+	if image == "" {
+		image = module // dbghelp looks for it on the symbol path
+	}
+	imageName, err := syscall.UTF16PtrFromString(image)
+	if err != nil {
+		return nil, err
+	}
+	base, _, err := procSymLoadModuleExW.Call(uintptr(b.process), 0, uintptr(unsafe.Pointer(imageName)), 0, pdbModuleAddress, 0, 0, 0)
+	if base == 0 {
+		return nil, fmt.Errorf("SymLoadModuleEx failed: %w", err)
+	}
+	defer procSymUnloadModule64.Call(uintptr(b.process), base)
+
+	frames := make([]sourceFrame, len(offsets))
+	symbol := &symbolInfoW{SizeOfStruct: symbolInfoSize, MaxNameLen: maxSymbolName}
+	for i, offset := range offsets {
+		address := uint64(base) + offset
+		var displacement uint64
+		if ok, _, _ := procSymFromAddrW.Call(uintptr(b.process), uintptr(address), uintptr(unsafe.Pointer(&displacement)), uintptr(unsafe.Pointer(symbol))); ok == 0 {
+			continue
+		}
+		frames[i].Function = syscall.UTF16ToString(symbol.Name[:min(int(symbol.NameLen), maxSymbolName)])
+
+		line := &imagehlpLineW64{}
+		line.SizeOfStruct = uint32(unsafe.Sizeof(*line))
+		var lineDisplacement uint32
+		if ok, _, _ := procSymGetLineFromAddrW64.Call(uintptr(b.process), uintptr(address), uintptr(unsafe.Pointer(&lineDisplacement)), uintptr(unsafe.Pointer(line))); ok != 0 {
+			frames[i].File = utf16PtrToString(line.FileName)
+			frames[i].Line = int(line.LineNumber)
+		}
+	}
+	return frames, nil
+}
+
+func (b *pdbBackend) close() {
+	procSymCleanup.Call(uintptr(b.process))
+	dbghelpMu.Unlock()
+}
+
+// utf16PtrToString reads a NUL-terminated UTF-16 string owned by dbghelp
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	var s []uint16
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; ptr = unsafe.Add(ptr, 2) {
+		s = append(s, *(*uint16)(ptr))
+	}
+	return syscall.UTF16ToString(s)
+}
@@ -0,0 +1,319 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Symbol search defaults
+const (
+	defaultSymbolLimit = 20
+	minFuzzyScore      = 0.3 // Dice coefficient of shared trigrams
+)
+
+// symbolKinds are the kinds of names the symbol index covers
+var symbolKinds = []string{"function", "file", "type"}
+
+// Symbol is a distinct function, file, or type name in a capture. Bytes is the allocated
+// size its Functions or Types records report; names seen only in leaks or call trees have 0.
+type Symbol struct {
+	Name  string  `json:"name"`
+	Kind  string  `json:"kind"`
+	Bytes int64   `json:"bytes"`
+	Score float64 `json:"score,omitempty"` // Fuzzy mode only
+}
+
+// SymbolSearch is the result of a symbol search
+type SymbolSearch struct {
+	Query   string   `json:"query"`
+	Mode    string   `json:"mode"`
+	Matches []Symbol `json:"matches"`
+	Total   int      `json:"total"`   // Matches before the limit
+	Checked int      `json:"checked"` // Candidates the index narrowed the search to
+	Indexed int      `json:"indexed"` // Symbols in the index
+}
+
+// SymbolIndex is a trigram index over lower-cased symbol names. Each posting list holds
+// the ascending positions of the symbols containing the trigram.
+type SymbolIndex struct {
+	symbols  []Symbol
+	lower    []string
+	trigrams map[uint32][]int32
+	counts   []int // Distinct trigrams per symbol
+}
+
+// Symbols returns the capture's symbol index, building it on first use
+func (ma *MemoryAnalyzer) Symbols() *SymbolIndex {
+	ma.symbolsOnce.Do(func() {
+		defer ma.diag.track("index_symbols")()
+		ma.symbolIndex = buildSymbolIndex(ma.data)
+	})
+	return ma.symbolIndex
+}
+
+// buildSymbolIndex collects every function, file, and type name in data and indexes them
+func buildSymbolIndex(data *MemProData) *SymbolIndex {
+	idx := &SymbolIndex{trigrams: make(map[uint32][]int32)}
+	positions := make(map[[2]string]int)
+	add := func(kind, name string, bytes int64) {
+		if name == "" {
+			return
+		}
+		key := [2]string{kind, name}
+		i, ok := positions[key]
+		if !ok {
+			i = len(idx.symbols)
+			positions[key] = i
+			idx.symbols = append(idx.symbols, Symbol{Name: name, Kind: kind})
+		}
+		idx.symbols[i].Bytes += bytes
+	}
+
+	for _, fn := range data.Functions {
+		add("function", fn.FunctionName, fn.TotalSize)
+		add("file", fn.FileName, fn.TotalSize)
+	}
+	for _, t := range data.Types {
+		add("type", t.TypeName, t.TotalSize)
+	}
+	for _, leak := range data.Leaks {
+		add("function", leak.FunctionName, 0)
+		add("file", leak.FileName, 0)
+	}
+	for _, a := range data.Allocations {
+		add("function", a.FunctionName, 0)
+		add("file", a.FileName, 0)
+	}
+	var walk func(trees []CallTree)
+	walk = func(trees []CallTree) {
+		for _, t := range trees {
+			add("function", t.FunctionName, 0)
+			add("file", t.FileName, 0)
+			walk(t.Children)
+		}
+	}
+	walk(data.CallTrees)
+
+	idx.lower = make([]string, len(idx.symbols))
+	idx.counts = make([]int, len(idx.symbols))
+	var grams []uint32
+	for i, s := range idx.symbols {
+		idx.lower[i] = strings.ToLower(s.Name)
+		grams = appendTrigrams(grams[:0], idx.lower[i])
+		idx.counts[i] = len(grams)
+		for _, g := range grams {
+			idx.trigrams[g] = append(idx.trigrams[g], int32(i))
+		}
+	}
+	return idx
+}
+
+// appendTrigrams appends the distinct byte trigrams of s to grams, packed into integers
+func appendTrigrams(grams []uint32, s string) []uint32 {
+	start := len(grams)
+	for i := 0; i+3 <= len(s); i++ {
+		grams = append(grams, uint32(s[i])<<16|uint32(s[i+1])<<8|uint32(s[i+2]))
+	}
+	added := grams[start:]
+	slices.Sort(added)
+	return append(grams[:start], slices.Compact(added)...)
+}
+
+// candidates returns the symbols containing every trigram of every literal, or nil with
+// all=true when no literal is long enough to narrow the search
+func (idx *SymbolIndex) candidates(literals []string) (list []int32, all bool) {
+	all = true
+	for _, lit := range literals {
+		for _, g := range appendTrigrams(nil, strings.ToLower(lit)) {
+			postings := idx.trigrams[g]
+			if all {
+				list, all = postings, false
+			} else {
+				list = intersectPostings(list, postings)
+			}
+			if len(list) == 0 {
+				return nil, false
+			}
+		}
+	}
+	return list, all
+}
+
+// intersectPostings intersects two ascending posting lists
+func intersectPostings(a, b []int32) []int32 {
+	var out []int32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// requiredLiterals returns literal strings every match of re must contain
+func requiredLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var out []string
+		run := ""
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				run += string(sub.Rune)
+				continue
+			}
+			if run != "" {
+				out, run = append(out, run), ""
+			}
+			out = append(out, requiredLiterals(sub)...)
+		}
+		if run != "" {
+			out = append(out, run)
+		}
+		return out
+	}
+	return nil
+}
+
+// Search finds function, file, and type names. Mode is "substring" (default,
+// case-insensitive), "regex" (Go syntax, case-sensitive unless (?i)), or "fuzzy" (ranked
+// by shared trigrams). Kind limits the search to one of symbolKinds.
+func (idx *SymbolIndex) Search(query, mode, kind string, limit int) (*SymbolSearch, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if mode == "" {
+		mode = "substring"
+	}
+	if kind == "all" {
+		kind = ""
+	}
+	if kind != "" && !slices.Contains(symbolKinds, kind) {
+		return nil, fmt.Errorf("unknown kind %q (expected %s, or all)", kind, strings.Join(symbolKinds, ", "))
+	}
+	if limit <= 0 {
+		limit = defaultSymbolLimit
+	}
+
+	var match func(i int32) bool
+	var literals []string
+	switch mode {
+	case "substring":
+		lower := strings.ToLower(query)
+		literals = []string{lower}
+		match = func(i int32) bool { return strings.Contains(idx.lower[i], lower) }
+	case "regex":
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		parsed, err := syntax.Parse(query, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		literals = requiredLiterals(parsed.Simplify())
+		match = func(i int32) bool { return re.MatchString(idx.symbols[i].Name) }
+	case "fuzzy":
+		return idx.fuzzy(query, kind, limit), nil
+	default:
+		return nil, fmt.Errorf("unknown mode %q (expected substring, regex, or fuzzy)", mode)
+	}
+
+	result := &SymbolSearch{Query: query, Mode: mode, Matches: []Symbol{}, Indexed: len(idx.symbols)}
+	check := func(i int32) {
+		result.Checked++
+		if (kind == "" || idx.symbols[i].Kind == kind) && match(i) {
+			result.Matches = append(result.Matches, idx.symbols[i])
+		}
+	}
+	if list, all := idx.candidates(literals); all {
+		for i := range idx.symbols {
+			check(int32(i))
+		}
+	} else {
+		for _, i := range list {
+			check(i)
+		}
+	}
+
+	sort.SliceStable(result.Matches, func(i, j int) bool { return result.Matches[i].Bytes > result.Matches[j].Bytes })
+	result.Total = len(result.Matches)
+	result.Matches = result.Matches[:min(len(result.Matches), limit)]
+	return result, nil
+}
+
+// fuzzy ranks symbols by the Dice coefficient of their trigrams with the query's.
+// Queries shorter than a trigram fall back to a case-insensitive substring match.
+func (idx *SymbolIndex) fuzzy(query, kind string, limit int) *SymbolSearch {
+	result := &SymbolSearch{Query: query, Mode: "fuzzy", Matches: []Symbol{}, Indexed: len(idx.symbols)}
+	lower := strings.ToLower(query)
+	grams := appendTrigrams(nil, lower)
+
+	shared := make([]int32, len(idx.symbols))
+	var touched []int32
+	if len(grams) == 0 {
+		for i := range idx.symbols {
+			if strings.Contains(idx.lower[i], lower) {
+				touched = append(touched, int32(i))
+			}
+		}
+	}
+	for _, g := range grams {
+		for _, i := range idx.trigrams[g] {
+			if shared[i] == 0 {
+				touched = append(touched, i)
+			}
+			shared[i]++
+		}
+	}
+
+	for _, i := range touched {
+		result.Checked++
+		s := idx.symbols[i]
+		if kind != "" && s.Kind != kind {
+			continue
+		}
+		score := 1.0
+		if len(grams) > 0 {
+			score = 2 * float64(shared[i]) / float64(len(grams)+idx.counts[i])
+		}
+		if score < minFuzzyScore {
+			continue
+		}
+		s.Score = math.Round(score*1000) / 1000
+		result.Matches = append(result.Matches, s)
+	}
+
+	sort.Slice(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.Name < b.Name
+	})
+	result.Total = len(result.Matches)
+	result.Matches = result.Matches[:min(len(result.Matches), limit)]
+	return result
+}
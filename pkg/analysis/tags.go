@@ -0,0 +1,227 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tag rollup defaults
+const (
+	defaultTagTop = 3
+	tagSeparator  = "/"
+	untaggedLabel = "(untagged)"
+)
+
+// TagUsage is the memory attributed to one allocation tag
+type TagUsage struct {
+	Tag              string         `json:"tag"`
+	TotalSize        int64          `json:"total_size"`
+	AllocationCount  int            `json:"allocation_count"`
+	Share            float64        `json:"share"` // Percentage of all bytes
+	LeakCount        int            `json:"leak_count"`
+	LeakSize         int64          `json:"leak_size"`
+	Budget           int64          `json:"budget,omitempty"`
+	BudgetUsed       float64        `json:"budget_used,omitempty"` // Percentage of the budget
+	Status           string         `json:"status,omitempty"`      // over, near, or ok; only with a budget
+	TopLeakFunctions []FunctionLeak `json:"top_leak_functions,omitempty"`
+
+	leaks []Leak
+}
+
+// TagRollup reports memory per allocation tag
+type TagRollup struct {
+	Source string     `json:"source"` // Records the sizes come from: functions, types, or allocations
+	Tags   []TagUsage `json:"tags"`
+}
+
+// HasTags reports whether any record carries an allocation tag
+func (ma *MemoryAnalyzer) HasTags() bool {
+	for _, fn := range ma.data.Functions {
+		if fn.Tag != "" {
+			return true
+		}
+	}
+	for _, t := range ma.data.Types {
+		if t.Tag != "" {
+			return true
+		}
+	}
+	for _, a := range ma.data.Allocations {
+		if a.Tag != "" {
+			return true
+		}
+	}
+	for _, leak := range ma.data.Leaks {
+		if leak.Tag != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// rollupTag shortens a tag to its first depth levels; depth 0 keeps the full tag
+func rollupTag(tag string, depth int) string {
+	if tag == "" {
+		return untaggedLabel
+	}
+	if depth > 0 {
+		if parts := strings.Split(tag, tagSeparator); len(parts) > depth {
+			return strings.Join(parts[:depth], tagSeparator)
+		}
+	}
+	return tag
+}
+
+// tagTotals sums sizes and leaks per rolled-up tag. Sizes come from the first record kind
+// that carries tags: functions, then types, then live allocations, so nothing is counted twice.
+func (ma *MemoryAnalyzer) tagTotals(depth int) (map[string]*TagUsage, string) {
+	byTag := make(map[string]*TagUsage)
+	get := func(tag string) *TagUsage {
+		tag = rollupTag(tag, depth)
+		u, ok := byTag[tag]
+		if !ok {
+			u = &TagUsage{Tag: tag}
+			byTag[tag] = u
+		}
+		return u
+	}
+
+	source := ""
+	for _, fn := range ma.data.Functions {
+		if fn.Tag != "" {
+			source = "functions"
+			break
+		}
+	}
+	if source == "" {
+		for _, t := range ma.data.Types {
+			if t.Tag != "" {
+				source = "types"
+				break
+			}
+		}
+	}
+	if source == "" {
+		source = "allocations"
+	}
+
+	switch source {
+	case "functions":
+		for _, fn := range ma.data.Functions {
+			u := get(fn.Tag)
+			u.TotalSize += fn.TotalSize
+			u.AllocationCount += fn.AllocationCount
+		}
+	case "types":
+		for _, t := range ma.data.Types {
+			u := get(t.Tag)
+			u.TotalSize += t.TotalSize
+			u.AllocationCount += t.AllocationCount
+		}
+	default:
+		for _, a := range ma.data.Allocations {
+			if a.FreeTime == nil {
+				u := get(a.Tag)
+				u.TotalSize += a.Size
+				u.AllocationCount++
+			}
+		}
+	}
+
+	for _, leak := range ma.data.Leaks {
+		u := get(leak.Tag)
+		u.LeakCount += leak.LeakCount
+		u.LeakSize += leak.LeakSize
+		u.leaks = append(u.leaks, leak)
+	}
+	return byTag, source
+}
+
+// TagRollup returns memory per tag rolled up to depth levels (0 for full tags), largest first.
+// Budgets from tag_budgets apply to rows whose tag they name.
+func (ma *MemoryAnalyzer) TagRollup(depth, top int) *TagRollup {
+	if top <= 0 {
+		top = defaultTagTop
+	}
+
+	byTag, source := ma.tagTotals(depth)
+	var total int64
+	for _, u := range byTag {
+		total += u.TotalSize
+	}
+
+	result := &TagRollup{Source: source}
+	for _, u := range byTag {
+		if total > 0 {
+			u.Share = float64(u.TotalSize) / float64(total) * 100
+		}
+		u.TopLeakFunctions = ma.topLeakFunctions(u.leaks, top)
+		if ma.config != nil {
+			u.Budget = int64(ma.config.TagBudgets[u.Tag])
+		}
+		u.BudgetUsed, u.Status = budgetStatus(u.TotalSize, u.Budget)
+		result.Tags = append(result.Tags, *u)
+	}
+	sort.Slice(result.Tags, func(i, j int) bool {
+		if result.Tags[i].TotalSize != result.Tags[j].TotalSize {
+			return result.Tags[i].TotalSize > result.Tags[j].TotalSize
+		}
+		return result.Tags[i].Tag < result.Tags[j].Tag
+	})
+
+	return result
+}
+
+// AnalyzeTags reports tags over their configured budget, counting each tag's subtags
+func (ma *MemoryAnalyzer) AnalyzeTags() []MemoryIssue {
+	var issues []MemoryIssue
+
+	if ma == nil || ma.data == nil || ma.config == nil || len(ma.config.TagBudgets) == 0 || !ma.HasTags() {
+		return issues
+	}
+	defer ma.diag.track("analyze_tags")()
+
+	byTag, _ := ma.tagTotals(0)
+	budgets := make([]string, 0, len(ma.config.TagBudgets))
+	for tag := range ma.config.TagBudgets {
+		budgets = append(budgets, tag)
+	}
+	sort.Strings(budgets)
+
+	for _, tag := range budgets {
+		budget := int64(ma.config.TagBudgets[tag])
+		var size int64
+		var count int
+		for name, u := range byTag {
+			if name == tag || strings.HasPrefix(name, tag+tagSeparator) {
+				size += u.TotalSize
+				count += u.AllocationCount
+			}
+		}
+
+		used, status := budgetStatus(size, budget)
+		if status != "over" {
+			ma.diag.skip("tags_within_budget", 1)
+			continue
+		}
+		severity := "High"
+		if used >= budgetCritical {
+			severity = "Critical"
+		}
+
+		issues = append(issues, MemoryIssue{
+			ID:          issueID("budget", tag),
+			Severity:    severity,
+			Type:        "TagOverBudget",
+			Description: fmt.Sprintf("Tag %s uses %s of its %s budget (%.1f%%)", tag, FormatBytes(size), FormatBytes(budget), used),
+			Size:        size - budget,
+			Count:       count,
+			Score:       used,
+			Suggestion:  fmt.Sprintf("Reduce %s by %s to meet its budget: use get_tag_rollup to find its largest subtags, then its largest allocating functions and leaks.", tag, FormatBytes(size-budget)),
+			Tag:         tag,
+		})
+	}
+
+	return ma.applyCustomRules(issues, nil)
+}
@@ -0,0 +1,286 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// defaultThreadTop is how many leaking functions get_thread_breakdown lists per thread
+const defaultThreadTop = 3
+
+// ThreadUsage is one thread's share of leaks and allocations
+type ThreadUsage struct {
+	ThreadId         int            `json:"thread_id"` // 0 for records without a thread
+	ThreadName       string         `json:"thread_name,omitempty"`
+	LeakCount        int            `json:"leak_count"`
+	LeakSize         int64          `json:"leak_size"`
+	LeakShare        float64        `json:"leak_share"` // Percentage of all leaked bytes
+	Allocations      int            `json:"allocations,omitempty"`
+	AllocatedBytes   int64          `json:"allocated_bytes,omitempty"`
+	LiveAllocations  int            `json:"live_allocations,omitempty"`
+	TopLeakFunctions []FunctionLeak `json:"top_leak_functions,omitempty"`
+}
+
+// FunctionLeak is the leaked total of one function
+type FunctionLeak struct {
+	FunctionName string `json:"functionName"`
+	LeakSize     int64  `json:"leak_size"`
+	LeakCount    int    `json:"leak_count"`
+}
+
+// ThreadBreakdown splits leaks and allocations by the thread that made them
+type ThreadBreakdown struct {
+	Threads []ThreadUsage `json:"threads"`
+}
+
+// HasThreads reports whether any leak or allocation record is attributed to a thread, or
+// the export counts allocations per thread
+func (ma *MemoryAnalyzer) HasThreads() bool {
+	for _, leak := range ma.data.Leaks {
+		if leak.ThreadId != 0 {
+			return true
+		}
+	}
+	for _, a := range ma.data.Allocations {
+		if a.ThreadId != 0 {
+			return true
+		}
+	}
+	for _, t := range ma.data.Threads {
+		if t.AllocationCount != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// threadLabel names a thread for display, e.g. "RenderThread (4120)"; empty when unattributed
+func (ma *MemoryAnalyzer) threadLabel(id int) string {
+	if id == 0 {
+		return ""
+	}
+	for _, t := range ma.data.Threads {
+		if t.ThreadId == id && t.ThreadName != "" {
+			return fmt.Sprintf("%s (%d)", t.ThreadName, id)
+		}
+	}
+	return strconv.Itoa(id)
+}
+
+// ThreadBreakdown returns per-thread leak and allocation totals, largest leaker first
+func (ma *MemoryAnalyzer) ThreadBreakdown(top int) *ThreadBreakdown {
+	if top <= 0 {
+		top = defaultThreadTop
+	}
+
+	names := make(map[int]string)
+	for _, t := range ma.data.Threads {
+		names[t.ThreadId] = t.ThreadName
+	}
+
+	byID := make(map[int]*ThreadUsage)
+	leaksByThread := make(map[int][]Leak)
+	get := func(id int) *ThreadUsage {
+		u, ok := byID[id]
+		if !ok {
+			u = &ThreadUsage{ThreadId: id, ThreadName: names[id]}
+			byID[id] = u
+		}
+		return u
+	}
+
+	var totalLeaked int64
+	for _, leak := range ma.data.Leaks {
+		u := get(leak.ThreadId)
+		u.LeakCount += leak.LeakCount
+		u.LeakSize += leak.LeakSize
+		totalLeaked += leak.LeakSize
+		leaksByThread[leak.ThreadId] = append(leaksByThread[leak.ThreadId], leak)
+	}
+	for _, a := range ma.data.Allocations {
+		u := get(a.ThreadId)
+		u.Allocations++
+		u.AllocatedBytes += a.Size
+		if a.FreeTime == nil {
+			u.LiveAllocations++
+		}
+	}
+
+	result := &ThreadBreakdown{}
+	for id, u := range byID {
+		if totalLeaked > 0 {
+			u.LeakShare = float64(u.LeakSize) / float64(totalLeaked) * 100
+		}
+		u.TopLeakFunctions = ma.topLeakFunctions(leaksByThread[id], top)
+		result.Threads = append(result.Threads, *u)
+	}
+	sort.Slice(result.Threads, func(i, j int) bool {
+		a, b := result.Threads[i], result.Threads[j]
+		if a.LeakSize != b.LeakSize {
+			return a.LeakSize > b.LeakSize
+		}
+		if a.AllocatedBytes != b.AllocatedBytes {
+			return a.AllocatedBytes > b.AllocatedBytes
+		}
+		return a.ThreadId < b.ThreadId
+	})
+
+	return result
+}
+
+// topLeakFunctions sums leaks by blamed function and returns the top largest
+func (ma *MemoryAnalyzer) topLeakFunctions(leaks []Leak, top int) []FunctionLeak {
+	byName := make(map[string]*FunctionLeak)
+	for _, leak := range leaks {
+		name := ma.blame(leak)
+		f, ok := byName[name]
+		if !ok {
+			f = &FunctionLeak{FunctionName: name}
+			byName[name] = f
+		}
+		f.LeakSize += leak.LeakSize
+		f.LeakCount += leak.LeakCount
+	}
+
+	functions := make([]FunctionLeak, 0, len(byName))
+	for _, f := range byName {
+		functions = append(functions, *f)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].LeakSize != functions[j].LeakSize {
+			return functions[i].LeakSize > functions[j].LeakSize
+		}
+		return functions[i].FunctionName < functions[j].FunctionName
+	})
+	return functions[:min(len(functions), top)]
+}
+
+// Thread analysis defaults
+const (
+	defaultNeverFreeMinAllocations = 10
+	threadLeakerShare              = 50.0 // Percent of all leaked bytes
+)
+
+// ThreadStat is one thread's allocations, frees, and leaks
+type ThreadStat struct {
+	ThreadId        int      `json:"thread_id"` // 0 for records without a thread
+	ThreadName      string   `json:"thread_name,omitempty"`
+	Allocations     int      `json:"allocations"`
+	AllocatedBytes  int64    `json:"allocated_bytes"`
+	Frees           int      `json:"frees"`
+	FreedBytes      int64    `json:"freed_bytes"`
+	LiveAllocations int      `json:"live_allocations"`
+	LiveBytes       int64    `json:"live_bytes"`
+	FreeRate        *float64 `json:"free_rate,omitempty"` // Percentage of allocations freed; omitted without allocations
+	LeakCount       int      `json:"leak_count"`
+	LeakSize        int64    `json:"leak_size"`
+	LeakShare       float64  `json:"leak_share"`      // Percentage of all leaked bytes
+	Flags           []string `json:"flags,omitempty"` // never_frees, top_leaker
+}
+
+// ThreadAnalysis reports allocation and leak bytes per thread, and the threads that keep
+// everything they allocate
+type ThreadAnalysis struct {
+	Source      string       `json:"source"` // allocations (per-record), threads (per-thread totals), or leaks
+	Threads     []ThreadStat `json:"threads"`
+	NeverFrees  []string     `json:"never_frees,omitempty"` // Threads flagged never_frees, by label
+	LeakedBytes int64        `json:"leaked_bytes"`
+}
+
+// AnalyzeThreads totals allocations, frees, and leaks per thread. Allocation records give
+// exact figures; otherwise the per-thread totals of the Threads section are used. A thread
+// with at least minAllocations allocations and no frees is flagged never_frees: typically a
+// worker that fills buffers and exits or parks without releasing them.
+func (ma *MemoryAnalyzer) AnalyzeThreads(minAllocations int) *ThreadAnalysis {
+	if minAllocations <= 0 {
+		minAllocations = defaultNeverFreeMinAllocations
+	}
+
+	byID := make(map[int]*ThreadStat)
+	var order []int
+	get := func(id int) *ThreadStat {
+		s, ok := byID[id]
+		if !ok {
+			s = &ThreadStat{ThreadId: id}
+			byID[id] = s
+			order = append(order, id)
+		}
+		return s
+	}
+
+	result := &ThreadAnalysis{Source: "leaks"}
+	for _, t := range ma.data.Threads {
+		s := get(t.ThreadId)
+		s.ThreadName = t.ThreadName
+		if len(ma.data.Allocations) > 0 || t.AllocationCount == 0 {
+			continue
+		}
+		result.Source = "threads"
+		s.Allocations = t.AllocationCount
+		s.AllocatedBytes = t.TotalSize
+		s.Frees = t.FreedCount
+		s.FreedBytes = t.FreedSize
+	}
+	if len(ma.data.Allocations) > 0 {
+		result.Source = "allocations"
+		for _, a := range ma.data.Allocations {
+			s := get(a.ThreadId)
+			s.Allocations++
+			s.AllocatedBytes += a.Size
+			if a.FreeTime != nil {
+				s.Frees++
+				s.FreedBytes += a.Size
+			}
+		}
+	}
+	for _, leak := range ma.data.Leaks {
+		s := get(leak.ThreadId)
+		s.LeakCount += leak.LeakCount
+		s.LeakSize += leak.LeakSize
+		result.LeakedBytes += leak.LeakSize
+	}
+
+	for _, id := range order {
+		s := byID[id]
+		s.LiveAllocations = max(s.Allocations-s.Frees, 0)
+		s.LiveBytes = max(s.AllocatedBytes-s.FreedBytes, 0)
+		if s.Allocations > 0 {
+			rate := float64(s.Frees) / float64(s.Allocations) * 100
+			s.FreeRate = &rate
+		}
+		if result.LeakedBytes > 0 {
+			s.LeakShare = float64(s.LeakSize) / float64(result.LeakedBytes) * 100
+		}
+		result.Threads = append(result.Threads, *s)
+		if id == 0 {
+			continue // Unattributed records are not one thread
+		}
+		t := &result.Threads[len(result.Threads)-1]
+		if t.Allocations >= minAllocations && t.Frees == 0 {
+			t.Flags = append(t.Flags, "never_frees")
+			result.NeverFrees = append(result.NeverFrees, ma.threadLabel(id))
+		}
+		if t.LeakShare >= threadLeakerShare {
+			t.Flags = append(t.Flags, "top_leaker")
+		}
+	}
+
+	// Threads that never free first, then by leaked and live bytes
+	sort.SliceStable(result.Threads, func(i, j int) bool {
+		a, b := result.Threads[i], result.Threads[j]
+		if neverA, neverB := slices.Contains(a.Flags, "never_frees"), slices.Contains(b.Flags, "never_frees"); neverA != neverB {
+			return neverA
+		}
+		if a.LeakSize != b.LeakSize {
+			return a.LeakSize > b.LeakSize
+		}
+		if a.LiveBytes != b.LiveBytes {
+			return a.LiveBytes > b.LiveBytes
+		}
+		return a.ThreadId < b.ThreadId
+	})
+	sort.Strings(result.NeverFrees)
+	return result
+}
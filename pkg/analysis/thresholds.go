@@ -0,0 +1,84 @@
+package analysis
+
+import "fmt"
+
+// Thresholds are the severity boundaries of the leak, large allocation, and fragmentation
+// detectors. Zero fields keep the defaults in defaultThresholds.
+type Thresholds struct {
+	LeakCriticalBytes   int64   `json:"leak_critical_bytes"` // Suspect leaks above this are Critical
+	LeakHighBytes       int64   `json:"leak_high_bytes"`
+	LeakMediumBytes     int64   `json:"leak_medium_bytes"`
+	LeakMediumCount     int     `json:"leak_medium_count"`
+	LargeAverageBytes   float64 `json:"large_average_bytes"`
+	LargeMaxBytes       int64   `json:"large_max_bytes"`
+	LargeHighBytes      int64   `json:"large_high_bytes"`
+	FragmentationHigh   float64 `json:"fragmentation_high"` // Percent, also used for heap fragmentation
+	FragmentationMedium float64 `json:"fragmentation_medium"`
+}
+
+// defaultThresholds are the built-in severity boundaries
+var defaultThresholds = Thresholds{
+	LeakCriticalBytes:   100000,
+	LeakHighBytes:       50000,
+	LeakMediumBytes:     10000,
+	LeakMediumCount:     100,
+	LargeAverageBytes:   10000,
+	LargeMaxBytes:       50000,
+	LargeHighBytes:      100000,
+	FragmentationHigh:   80,
+	FragmentationMedium: 50,
+}
+
+// orDefault returns def when v is unset
+func orDefault[T int | int64 | float64](v, def T) T {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// withDefaults fills unset thresholds from defaultThresholds
+func (t Thresholds) withDefaults() Thresholds {
+	d := defaultThresholds
+	return Thresholds{
+		LeakCriticalBytes:   orDefault(t.LeakCriticalBytes, d.LeakCriticalBytes),
+		LeakHighBytes:       orDefault(t.LeakHighBytes, d.LeakHighBytes),
+		LeakMediumBytes:     orDefault(t.LeakMediumBytes, d.LeakMediumBytes),
+		LeakMediumCount:     orDefault(t.LeakMediumCount, d.LeakMediumCount),
+		LargeAverageBytes:   orDefault(t.LargeAverageBytes, d.LargeAverageBytes),
+		LargeMaxBytes:       orDefault(t.LargeMaxBytes, d.LargeMaxBytes),
+		LargeHighBytes:      orDefault(t.LargeHighBytes, d.LargeHighBytes),
+		FragmentationHigh:   orDefault(t.FragmentationHigh, d.FragmentationHigh),
+		FragmentationMedium: orDefault(t.FragmentationMedium, d.FragmentationMedium),
+	}
+}
+
+// check rejects negative thresholds and severity bands out of order, after defaults
+// are filled in
+func (t Thresholds) check() error {
+	if t.LeakCriticalBytes < 0 || t.LeakHighBytes < 0 || t.LeakMediumBytes < 0 || t.LeakMediumCount < 0 ||
+		t.LargeAverageBytes < 0 || t.LargeMaxBytes < 0 || t.LargeHighBytes < 0 ||
+		t.FragmentationHigh < 0 || t.FragmentationMedium < 0 {
+		return fmt.Errorf("thresholds: must not be negative")
+	}
+
+	t = t.withDefaults()
+	switch {
+	case t.LeakHighBytes > t.LeakCriticalBytes:
+		return fmt.Errorf("thresholds: leak_high_bytes (%d) must not exceed leak_critical_bytes (%d)", t.LeakHighBytes, t.LeakCriticalBytes)
+	case t.LeakMediumBytes > t.LeakHighBytes:
+		return fmt.Errorf("thresholds: leak_medium_bytes (%d) must not exceed leak_high_bytes (%d)", t.LeakMediumBytes, t.LeakHighBytes)
+	case t.LargeMaxBytes > t.LargeHighBytes:
+		return fmt.Errorf("thresholds: large_max_bytes (%d) must not exceed large_high_bytes (%d)", t.LargeMaxBytes, t.LargeHighBytes)
+	case t.FragmentationHigh > 100:
+		return fmt.Errorf("thresholds: fragmentation_high (%g) must not exceed 100", t.FragmentationHigh)
+	case t.FragmentationMedium > t.FragmentationHigh:
+		return fmt.Errorf("thresholds: fragmentation_medium (%g) must not exceed fragmentation_high (%g)", t.FragmentationMedium, t.FragmentationHigh)
+	}
+	return nil
+}
+
+// EffectiveThresholds returns the configured severity thresholds with defaults filled in
+func (c *Config) EffectiveThresholds() Thresholds {
+	return c.Thresholds.withDefaults()
+}
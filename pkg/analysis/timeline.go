@@ -0,0 +1,196 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Timeline thresholds. A series is flagged when it never shrinks across at least
+// timelineMinSnapshots snapshots and grows by at least growthMinBytes overall.
+const (
+	defaultTimelineTop   = 10
+	timelineMinSnapshots = 3
+	growthMinBytes       = 64 * 1024         // 64 KB
+	growthMediumBytes    = 1024 * 1024       // 1 MB
+	growthHighBytes      = 16 * 1024 * 1024  // 16 MB
+	growthCriticalBytes  = 256 * 1024 * 1024 // 256 MB
+)
+
+// TimelinePoint is the live memory at one snapshot and its change since the previous one
+type TimelinePoint struct {
+	Snapshot    int     `json:"snapshot"`
+	Name        string  `json:"name,omitempty"`
+	Time        float64 `json:"time_ms,omitempty"`
+	Allocations int     `json:"allocations"`
+	Size        int64   `json:"size"`
+	Growth      int64   `json:"growth"` // Since the previous snapshot
+}
+
+// GrowthTrend is how one series (the whole heap or one function) changed across snapshots
+type GrowthTrend struct {
+	FunctionName    string  `json:"functionName,omitempty"`
+	Start           int64   `json:"start"`
+	End             int64   `json:"end"`
+	Growth          int64   `json:"growth"`
+	GrowthPerSecond float64 `json:"growth_per_second,omitempty"` // When snapshots carry times
+	GrowingSteps    int     `json:"growing_steps"`               // Snapshot-to-snapshot increases
+	ShrinkingSteps  int     `json:"shrinking_steps"`
+	Monotonic       bool    `json:"monotonic"` // Never shrinks and grows overall
+	Leaks           bool    `json:"leaks,omitempty"`
+}
+
+// Timeline is the result of analyze_timeline
+type Timeline struct {
+	Snapshots        int             `json:"snapshots"`
+	Points           []TimelinePoint `json:"points"`
+	Overall          GrowthTrend     `json:"overall"`
+	GrowingFunctions []GrowthTrend   `json:"growing_functions"`
+	Issues           []MemoryIssue   `json:"issues"`
+}
+
+// snapshotOrder returns the capture's snapshots ordered by index
+func (ma *MemoryAnalyzer) snapshotOrder() []Snapshot {
+	snapshots := append([]Snapshot(nil), ma.data.Snapshots...)
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].SnapshotIndex < snapshots[j].SnapshotIndex
+	})
+	return snapshots
+}
+
+// growthTrend summarizes a series of sizes taken at the given times (zero when unknown)
+func growthTrend(sizes []int64, times []float64) GrowthTrend {
+	t := GrowthTrend{Start: sizes[0], End: sizes[len(sizes)-1]}
+	t.Growth = t.End - t.Start
+	for i := 1; i < len(sizes); i++ {
+		switch {
+		case sizes[i] > sizes[i-1]:
+			t.GrowingSteps++
+		case sizes[i] < sizes[i-1]:
+			t.ShrinkingSteps++
+		}
+	}
+	t.Monotonic = t.ShrinkingSteps == 0 && t.Growth > 0
+	if elapsed := times[len(times)-1] - times[0]; elapsed > 0 {
+		t.GrowthPerSecond = math.Round(float64(t.Growth) / elapsed * 1000)
+	}
+	return t
+}
+
+// growthSeverity grades monotonic growth by size
+func growthSeverity(growth int64) string {
+	switch {
+	case growth >= growthCriticalBytes:
+		return "Critical"
+	case growth >= growthHighBytes:
+		return "High"
+	case growth >= growthMediumBytes:
+		return "Medium"
+	}
+	return "Low"
+}
+
+// Timeline reports live memory across the capture's snapshots and flags the heap and the
+// functions whose memory never shrinks from one snapshot to the next, the signature of a
+// leak that a single snapshot cannot show. Functions are listed by growth, top first.
+func (ma *MemoryAnalyzer) Timeline(top int) (*Timeline, error) {
+	if top <= 0 {
+		top = defaultTimelineTop
+	}
+	snapshots := ma.snapshotOrder()
+	if len(snapshots) == 0 {
+		if ma.data.TotalSnapshots > 0 {
+			return nil, fmt.Errorf("the capture took %d snapshots but exported no Snapshots array", ma.data.TotalSnapshots)
+		}
+		return nil, fmt.Errorf("the capture has no snapshots")
+	}
+	defer ma.diag.track("analyze_timeline")()
+
+	result := &Timeline{Snapshots: len(snapshots), GrowingFunctions: []GrowthTrend{}, Issues: []MemoryIssue{}}
+	sizes := make([]int64, len(snapshots))
+	times := make([]float64, len(snapshots))
+	functions := make(map[string][]int64)
+	var names []string
+	for i, s := range snapshots {
+		point := TimelinePoint{Snapshot: s.SnapshotIndex, Name: s.Name, Time: s.Time, Allocations: s.TotalAllocations, Size: s.TotalSize}
+		if i > 0 {
+			point.Growth = s.TotalSize - snapshots[i-1].TotalSize
+		}
+		result.Points = append(result.Points, point)
+		sizes[i], times[i] = s.TotalSize, s.Time
+
+		for _, f := range s.Functions {
+			series, ok := functions[f.FunctionName]
+			if !ok {
+				series = make([]int64, len(snapshots))
+				names = append(names, f.FunctionName)
+			}
+			series[i] += f.TotalSize
+			functions[f.FunctionName] = series
+		}
+	}
+	result.Overall = growthTrend(sizes, times)
+
+	leaking := make(map[string]bool)
+	for _, leak := range ma.data.Leaks {
+		if leak.LeakSize > 0 {
+			leaking[leak.FunctionName] = true
+			leaking[ma.blame(leak)] = true
+		}
+	}
+	for _, name := range names {
+		trend := growthTrend(functions[name], times)
+		if trend.Growth <= 0 {
+			continue
+		}
+		trend.FunctionName = name
+		trend.Leaks = leaking[name]
+		result.GrowingFunctions = append(result.GrowingFunctions, trend)
+	}
+	sort.SliceStable(result.GrowingFunctions, func(i, j int) bool {
+		a, b := result.GrowingFunctions[i], result.GrowingFunctions[j]
+		if a.Growth != b.Growth {
+			return a.Growth > b.Growth
+		}
+		return a.FunctionName < b.FunctionName
+	})
+
+	if len(snapshots) >= timelineMinSnapshots {
+		if t := result.Overall; t.Monotonic && t.Growth >= growthMinBytes {
+			result.Issues = append(result.Issues, MemoryIssue{
+				ID:          issueID("growth"),
+				Severity:    growthSeverity(t.Growth),
+				Type:        "MonotonicGrowth",
+				Description: fmt.Sprintf("Live memory grew by %s across %d snapshots without ever shrinking", FormatBytes(t.Growth), len(snapshots)),
+				Size:        t.Growth,
+				Count:       t.GrowingSteps,
+				Suggestion:  "Memory that only ever grows is consistent with a leak or an unbounded cache. Look at growing_functions and analyze_leaks, and check caches and queues for eviction.",
+			})
+		}
+		for _, t := range result.GrowingFunctions {
+			if !t.Monotonic || t.Growth < growthMinBytes {
+				continue
+			}
+			suggestion := "This function's live memory never shrank between snapshots. Check whether its allocations are released when the data they hold is no longer needed, and bound any cache or container it fills."
+			if t.Leaks {
+				suggestion = "This function's live memory never shrank between snapshots and it also has leak records, which makes a leak likely. Start with its entries in analyze_leaks."
+			}
+			result.Issues = append(result.Issues, MemoryIssue{
+				ID:           issueID("growth", t.FunctionName),
+				Severity:     growthSeverity(t.Growth),
+				Type:         "MonotonicGrowth",
+				Description:  fmt.Sprintf("Function's live memory grew by %s across %d snapshots without ever shrinking", FormatBytes(t.Growth), len(snapshots)),
+				FunctionName: t.FunctionName,
+				Size:         t.Growth,
+				Count:        t.GrowingSteps,
+				Suggestion:   suggestion,
+			})
+		}
+	} else {
+		ma.diag.note("%d snapshots are too few to judge growth (at least %d needed)", len(snapshots), timelineMinSnapshots)
+	}
+
+	result.Issues = ma.applyCustomRules(result.Issues, nil)
+	result.GrowingFunctions = result.GrowingFunctions[:min(len(result.GrowingFunctions), top)]
+	return result, nil
+}
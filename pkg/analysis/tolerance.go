@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// defaultToleranceProfile is used when neither the call nor the config names a profile
+const defaultToleranceProfile = "normal"
+
+// Metrics that tolerances apply to. Fragmentation tolerances are in percentage points.
+var toleranceMetrics = []string{"total_size", "allocation_count", "leak_size", "leak_count", "fragmentation"}
+
+// Tolerance is how much a metric may change between runs before the change counts.
+// A change is tolerated when it is within either bound.
+type Tolerance struct {
+	Percent  float64 `json:"percent"`  // Of the earlier value
+	Absolute float64 `json:"absolute"` // In the metric's unit: bytes, allocations, or percentage points
+}
+
+// builtinToleranceProfiles are the named profiles available without configuration
+var builtinToleranceProfiles = map[string]map[string]Tolerance{
+	"strict": {},
+	"normal": {
+		"total_size":       {Percent: 10},
+		"allocation_count": {Percent: 10},
+		"leak_size":        {Percent: 10},
+		"leak_count":       {Percent: 10, Absolute: 5},
+		"fragmentation":    {Absolute: 5},
+	},
+	"lenient": {
+		"total_size":       {Percent: 25},
+		"allocation_count": {Percent: 25},
+		"leak_size":        {Percent: 25, Absolute: 64 * 1024},
+		"leak_count":       {Percent: 25, Absolute: 20},
+		"fragmentation":    {Absolute: 10},
+	},
+}
+
+// ToleranceProfile is a resolved set of per-metric tolerances
+type ToleranceProfile struct {
+	Name    string
+	metrics map[string]Tolerance
+}
+
+// within reports whether a change from before to after stays inside the metric's tolerance.
+// Metrics without a tolerance tolerate no change.
+func (p *ToleranceProfile) within(metric string, before, after float64) bool {
+	t := p.metrics[metric]
+	delta := math.Abs(after - before)
+	if delta <= t.Absolute {
+		return true
+	}
+	return before != 0 && delta/math.Abs(before)*100 <= t.Percent
+}
+
+// significant returns the change from before to after, or zero when it is within tolerance
+func (p *ToleranceProfile) significant(metric string, before, after int64) int64 {
+	if p.within(metric, float64(before), float64(after)) {
+		return 0
+	}
+	return after - before
+}
+
+// Tolerance resolves a profile by name: "" selects tolerance_profile, then "normal". A
+// configured profile overrides the built-in profile of the same name metric by metric.
+func (c *Config) Tolerance(name string) (*ToleranceProfile, error) {
+	if name == "" {
+		name = c.ToleranceProfile
+	}
+	if name == "" {
+		name = defaultToleranceProfile
+	}
+
+	builtin, isBuiltin := builtinToleranceProfiles[name]
+	configured, isConfigured := c.ToleranceProfiles[name]
+	if !isBuiltin && !isConfigured {
+		return nil, fmt.Errorf("unknown tolerance profile %q (available: %s)", name, strings.Join(c.toleranceProfileNames(), ", "))
+	}
+
+	p := &ToleranceProfile{Name: name, metrics: make(map[string]Tolerance)}
+	for metric, t := range builtin {
+		p.metrics[metric] = t
+	}
+	for metric, t := range configured {
+		p.metrics[metric] = t
+	}
+	return p, nil
+}
+
+// defaultTolerance resolves the configured default profile. Loaded configs are checked, so
+// the fallback to the built-in default only guards against an unchecked config.
+func (c *Config) defaultTolerance() *ToleranceProfile {
+	if p, err := c.Tolerance(""); err == nil {
+		return p
+	}
+	p, _ := (&Config{}).Tolerance(defaultToleranceProfile)
+	return p
+}
+
+// toleranceProfileNames lists built-in and configured profile names
+func (c *Config) toleranceProfileNames() []string {
+	seen := make(map[string]bool)
+	for name := range builtinToleranceProfiles {
+		seen[name] = true
+	}
+	for name := range c.ToleranceProfiles {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkTolerances validates configured profiles and the default profile name
+func (c *Config) checkTolerances() error {
+	for name, metrics := range c.ToleranceProfiles {
+		for metric, t := range metrics {
+			if !slices.Contains(toleranceMetrics, metric) {
+				return fmt.Errorf("tolerance_profiles: %s: unknown metric %q (expected one of %s)", name, metric, strings.Join(toleranceMetrics, ", "))
+			}
+			if t.Percent < 0 || t.Absolute < 0 {
+				return fmt.Errorf("tolerance_profiles: %s: %s: tolerances must not be negative", name, metric)
+			}
+		}
+	}
+	if _, err := c.Tolerance(""); err != nil {
+		return fmt.Errorf("tolerance_profile: %w", err)
+	}
+	return nil
+}
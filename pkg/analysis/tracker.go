@@ -0,0 +1,397 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Issue export defaults
+const (
+	defaultExportTop       = 10
+	defaultGitHubAPI       = "https://api.github.com"
+	defaultGitHubTokenEnv  = "GITHUB_TOKEN"
+	defaultJiraTokenEnv    = "JIRA_API_TOKEN"
+	defaultJiraIssueType   = "Bug"
+	trackerRequestTimeout  = 30 * time.Second
+	maxExportStackFrames   = 20
+	maxTrackerErrorMessage = 500
+)
+
+// IssueTrackerConfig holds where export_issues posts issues. Tokens are read from
+// environment variables, so the config file can be shared without secrets.
+type IssueTrackerConfig struct {
+	GitHub *GitHubTrackerConfig `json:"github"`
+	Jira   *JiraTrackerConfig   `json:"jira"`
+}
+
+// GitHubTrackerConfig posts issues to one GitHub repository
+type GitHubTrackerConfig struct {
+	Repository string   `json:"repository"` // owner/name
+	APIURL     string   `json:"api_url"`    // Default https://api.github.com; https://host/api/v3 for GitHub Enterprise
+	TokenEnv   string   `json:"token_env"`  // Environment variable holding the token; default GITHUB_TOKEN
+	Labels     []string `json:"labels"`     // Added to every issue
+}
+
+// JiraTrackerConfig posts issues to one Jira project
+type JiraTrackerConfig struct {
+	URL       string   `json:"url"`        // Site, e.g. https://example.atlassian.net
+	Project   string   `json:"project"`    // Project key
+	IssueType string   `json:"issue_type"` // Default Bug
+	User      string   `json:"user"`       // Account email for Jira Cloud; empty sends the token as a bearer token (Data Center)
+	TokenEnv  string   `json:"token_env"`  // Environment variable holding the token; default JIRA_API_TOKEN
+	Labels    []string `json:"labels"`     // Added to every issue
+}
+
+// check validates the tracker settings
+func (t *IssueTrackerConfig) check() error {
+	if g := t.GitHub; g != nil {
+		if owner, name, ok := strings.Cut(g.Repository, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("issue_tracker: github: repository must be owner/name, got %q", g.Repository)
+		}
+	}
+	if j := t.Jira; j != nil {
+		if j.URL == "" {
+			return fmt.Errorf("issue_tracker: jira: missing 'url'")
+		}
+		if j.Project == "" {
+			return fmt.Errorf("issue_tracker: jira: missing 'project'")
+		}
+	}
+	return nil
+}
+
+// ExportOptions selects the issues export_issues turns into tracker payloads
+type ExportOptions struct {
+	Target      string   // github or jira
+	IDs         []string // Issues to export; empty exports the most severe ones
+	MinSeverity string   // Lowest severity exported when IDs is empty
+	Type        string   // Only issues of this type when IDs is empty
+	Top         int      // Issues exported when IDs is empty; default 10
+	Post        bool     // Create the issues through the configured tracker
+}
+
+// ExportedIssue is one issue's tracker payload and, when posted, where it was filed
+type ExportedIssue struct {
+	ID      string      `json:"id"`
+	Payload interface{} `json:"payload"`
+	URL     string      `json:"url,omitempty"`   // Filed issue, when posted
+	Key     string      `json:"key,omitempty"`   // Jira key or GitHub number, when posted
+	Error   string      `json:"error,omitempty"` // Why posting failed
+}
+
+// IssueExport is the result of export_issues
+type IssueExport struct {
+	Target  string          `json:"target"`
+	Posted  int             `json:"posted"`
+	Failed  int             `json:"failed,omitempty"`
+	Issues  []ExportedIssue `json:"issues"`
+	Omitted int             `json:"omitted,omitempty"` // Matching issues beyond top
+}
+
+// githubIssue is the body of GitHub's create-issue request
+type githubIssue struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Labels []string `json:"labels"`
+}
+
+// jiraIssue is the body of Jira's create-issue request (REST API v2)
+type jiraIssue struct {
+	Fields jiraFields `json:"fields"`
+}
+
+type jiraFields struct {
+	Project     jiraKey  `json:"project"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	IssueType   jiraName `json:"issuetype"`
+	Labels      []string `json:"labels"`
+}
+
+type jiraKey struct {
+	Key string `json:"key"`
+}
+
+type jiraName struct {
+	Name string `json:"name"`
+}
+
+// ExportIssues builds GitHub or Jira payloads for the selected issues, most severe first,
+// and posts them when opts.Post is set
+func (ma *MemoryAnalyzer) ExportIssues(opts ExportOptions) (*IssueExport, error) {
+	if opts.Target != "github" && opts.Target != "jira" {
+		return nil, fmt.Errorf("unknown target %q (expected github or jira)", opts.Target)
+	}
+	if opts.MinSeverity != "" {
+		if _, ok := severityOrder[opts.MinSeverity]; !ok {
+			return nil, fmt.Errorf("unknown severity %q (expected Critical, High, Medium, or Low)", opts.MinSeverity)
+		}
+	}
+	tracker := ma.config.IssueTracker
+	if opts.Post && (tracker == nil || opts.Target == "github" && tracker.GitHub == nil || opts.Target == "jira" && tracker.Jira == nil) {
+		return nil, fmt.Errorf("posting to %s needs an issue_tracker.%s section in the config", opts.Target, opts.Target)
+	}
+
+	groups := ma.AnalyzeAll()
+	var issues []MemoryIssue
+	omitted := 0
+	if len(opts.IDs) > 0 {
+		for _, id := range opts.IDs {
+			issue, err := findIssue(groups, id)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, *issue)
+		}
+	} else {
+		for _, group := range groups {
+			for _, issue := range group {
+				if opts.Type != "" && !strings.EqualFold(issue.Type, opts.Type) {
+					continue
+				}
+				if opts.MinSeverity != "" && severityRank(issue.Severity) > severityRank(opts.MinSeverity) {
+					continue
+				}
+				issues = append(issues, issue)
+			}
+		}
+		SortIssues(issues)
+		top := opts.Top
+		if top <= 0 {
+			top = defaultExportTop
+		}
+		if len(issues) > top {
+			omitted = len(issues) - top
+			issues = issues[:top]
+		}
+	}
+
+	result := &IssueExport{Target: opts.Target, Issues: []ExportedIssue{}, Omitted: omitted}
+	for _, issue := range issues {
+		var stack string
+		if group, ok := ma.leakGroup(issue.ID); ok && issue.Type == "MemoryLeak" {
+			stack = group.Leak.CallStack
+		}
+
+		exported := ExportedIssue{ID: issue.ID}
+		if opts.Target == "github" {
+			var labels []string
+			if tracker != nil && tracker.GitHub != nil {
+				labels = tracker.GitHub.Labels
+			}
+			exported.Payload = githubIssue{
+				Title:  ma.issueTitle(issue),
+				Body:   ma.issueBody(issue, stack, false),
+				Labels: issueLabels(issue, labels),
+			}
+		} else {
+			fields := jiraFields{
+				Summary:     ma.issueTitle(issue),
+				Description: ma.issueBody(issue, stack, true),
+				IssueType:   jiraName{Name: defaultJiraIssueType},
+			}
+			var labels []string
+			if tracker != nil && tracker.Jira != nil {
+				fields.Project.Key = tracker.Jira.Project
+				if tracker.Jira.IssueType != "" {
+					fields.IssueType.Name = tracker.Jira.IssueType
+				}
+				labels = tracker.Jira.Labels
+			}
+			fields.Labels = issueLabels(issue, labels)
+			exported.Payload = jiraIssue{Fields: fields}
+		}
+
+		if opts.Post {
+			var err error
+			if opts.Target == "github" {
+				exported.URL, exported.Key, err = tracker.GitHub.post(exported.Payload)
+			} else {
+				exported.URL, exported.Key, err = tracker.Jira.post(exported.Payload)
+			}
+			if err != nil {
+				exported.Error = err.Error()
+				result.Failed++
+			} else {
+				result.Posted++
+			}
+		}
+		result.Issues = append(result.Issues, exported)
+	}
+	return result, nil
+}
+
+// issueTitle summarizes an issue in one line, e.g. "MemoryLeak in Mesh::Load (1.20 MB)"
+func (ma *MemoryAnalyzer) issueTitle(issue MemoryIssue) string {
+	title := issue.Type
+	if issue.FunctionName != "" {
+		title += " in " + issue.FunctionName
+	} else if ma.data.SessionName != "" {
+		title += " in " + ma.data.SessionName
+	}
+	if issue.Size > 0 {
+		title += " (" + FormatBytes(issue.Size) + ")"
+	}
+	return title
+}
+
+// issueLabels labels an issue by tool, type, and severity, after the configured labels
+func issueLabels(issue MemoryIssue, configured []string) []string {
+	labels := append([]string{}, configured...)
+	labels = append(labels, "mempro", "severity:"+strings.ToLower(issue.Severity), strings.ToLower(issue.Type))
+	if issue.Library != "" {
+		// Jira labels cannot contain spaces
+		labels = append(labels, "library:"+strings.ReplaceAll(strings.ToLower(issue.Library), " ", "-"))
+	}
+	return labels
+}
+
+// issueBody renders an issue as Markdown, or as Jira wiki markup when jira is set. The issue
+// ID is included so a filed issue can be matched to later captures.
+func (ma *MemoryAnalyzer) issueBody(issue MemoryIssue, stack string, jira bool) string {
+	heading, code, endCode := "### ", "```\n", "```\n"
+	if jira {
+		heading, code, endCode = "h3. ", "{code}\n", "{code}\n"
+	}
+	inline := func(s string) string {
+		if jira {
+			return "{{" + s + "}}"
+		}
+		return "`" + s + "`"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", issue.Description)
+	fmt.Fprintf(&b, "- Issue ID: %s\n", inline(issue.ID))
+	fmt.Fprintf(&b, "- Session: %s\n", ma.data.SessionName)
+	fmt.Fprintf(&b, "- Type: %s\n", issue.Type)
+	fmt.Fprintf(&b, "- Severity: %s\n", issue.Severity)
+	if issue.FunctionName != "" {
+		fmt.Fprintf(&b, "- Function: %s\n", inline(issue.FunctionName))
+	}
+	if issue.FileName != "" {
+		fmt.Fprintf(&b, "- Location: %s\n", inline(fmt.Sprintf("%s:%d", issue.FileName, issue.LineNumber)))
+	}
+	if issue.Size > 0 {
+		fmt.Fprintf(&b, "- Size: %s in %d allocations\n", FormatBytes(issue.Size), issue.Count)
+	}
+	for _, label := range []struct{ name, value string }{{"Thread", issue.Thread}, {"Heap", issue.Heap}, {"Tag", issue.Tag}} {
+		if label.value != "" {
+			fmt.Fprintf(&b, "- %s: %s\n", label.name, label.value)
+		}
+	}
+
+	if frames := parseCallStack(stack); len(frames) > 0 {
+		fmt.Fprintf(&b, "\n%sCall stack\n\n%s", heading, code)
+		for i, frame := range frames {
+			if i == maxExportStackFrames {
+				fmt.Fprintf(&b, "... %d more frames\n", len(frames)-i)
+				break
+			}
+			fmt.Fprintf(&b, "%s\n", frame)
+		}
+		b.WriteString(endCode)
+	}
+	if issue.Suggestion != "" {
+		fmt.Fprintf(&b, "\n%sSuggestion\n\n%s\n", heading, issue.Suggestion)
+	}
+	return b.String()
+}
+
+// post creates a GitHub issue, returning its URL and number
+func (g *GitHubTrackerConfig) post(payload interface{}) (string, string, error) {
+	token, err := trackerToken(g.TokenEnv, defaultGitHubTokenEnv)
+	if err != nil {
+		return "", "", err
+	}
+	api := strings.TrimSuffix(g.APIURL, "/")
+	if api == "" {
+		api = defaultGitHubAPI
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+		Number  int    `json:"number"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}, "Accept": {"application/vnd.github+json"}}
+	if err := postTrackerJSON(api+"/repos/"+g.Repository+"/issues", header, payload, &created); err != nil {
+		return "", "", err
+	}
+	return created.HTMLURL, fmt.Sprintf("#%d", created.Number), nil
+}
+
+// post creates a Jira issue, returning its browse URL and key
+func (j *JiraTrackerConfig) post(payload interface{}) (string, string, error) {
+	token, err := trackerToken(j.TokenEnv, defaultJiraTokenEnv)
+	if err != nil {
+		return "", "", err
+	}
+	site := strings.TrimSuffix(j.URL, "/")
+
+	header := http.Header{"Authorization": {"Bearer " + token}}
+	if j.User != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(j.User+":"+token)))
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := postTrackerJSON(site+"/rest/api/2/issue", header, payload, &created); err != nil {
+		return "", "", err
+	}
+	return site + "/browse/" + created.Key, created.Key, nil
+}
+
+// trackerToken reads a tracker's API token from the configured environment variable
+func trackerToken(env, fallback string) (string, error) {
+	if env == "" {
+		env = fallback
+	}
+	token := os.Getenv(env)
+	if token == "" {
+		return "", fmt.Errorf("no API token: set %s", env)
+	}
+	return token, nil
+}
+
+// postTrackerJSON sends payload as JSON and decodes the response into result, turning
+// non-2xx statuses into errors that carry the start of the response body
+func postTrackerJSON(url string, header http.Header, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: trackerRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := strings.TrimSpace(string(respBody))
+		if len(message) > maxTrackerErrorMessage {
+			message = message[:maxTrackerErrorMessage] + "..."
+		}
+		return fmt.Errorf("%s: %s", resp.Status, message)
+	}
+	return json.Unmarshal(respBody, result)
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import "mempromcp/pkg/mempro"
+
+// Capture records are defined by the mempro package
+type (
+	MemProData       = mempro.Data
+	Snapshot         = mempro.Snapshot
+	SnapshotFunction = mempro.SnapshotFunction
+	Heap             = mempro.Heap
+	Thread           = mempro.Thread
+	CallTree         = mempro.CallTree
+	Function         = mempro.Function
+	Allocation       = mempro.Allocation
+	Leak             = mempro.Leak
+	PageView         = mempro.PageView
+	AllocType        = mempro.AllocType
+)
+
+// CaptureSections selects the record arrays of a capture to decode
+type CaptureSections = mempro.Sections
+
+const (
+	SectionLeaks       = mempro.SectionLeaks
+	SectionFunctions   = mempro.SectionFunctions
+	SectionCallTrees   = mempro.SectionCallTrees
+	SectionPageViews   = mempro.SectionPageViews
+	SectionTypes       = mempro.SectionTypes
+	sectionAllocations = mempro.SectionAllocations
+	SectionThreads     = mempro.SectionThreads
+	SectionHeaps       = mempro.SectionHeaps
+	SectionSnapshots   = mempro.SectionSnapshots
+
+	AllSections = mempro.AllSections
+)
+
+// MemoryIssue represents a detected memory issue for AI analysis
+type MemoryIssue struct {
+	ID           string  `json:"id"`       // Stable across captures of the same build, e.g. leak-3f2a9c1b
+	Severity     string  `json:"severity"` // Critical, High, Medium, Low
+	Type         string  `json:"type"`     // MemoryLeak, MemoryFragmentation, LargeAllocation, DuplicateAllocation, PoolCandidate, AlignmentWaste, TinyAllocation, ShortLivedChurn, LongLivedResident, HeapOverBudget, TagOverBudget, AddressSpaceExhaustion, MonotonicGrowth, AllocationChurn
+	Description  string  `json:"description"`
+	FunctionName string  `json:"functionName"`
+	FileName     string  `json:"fileName"`
+	LineNumber   int     `json:"lineNumber"`
+	Size         int64   `json:"size"`
+	Count        int     `json:"count"`
+	Score        float64 `json:"score"`
+	Suggestion   string  `json:"suggestion"`
+	Thread       string  `json:"thread,omitempty"`      // Allocating thread, when the capture records it
+	Heap         string  `json:"heap,omitempty"`        // Heap the memory came from, when the capture records it
+	Tag          string  `json:"tag,omitempty"`         // Allocation category, when the capture records it
+	Occurrences  int     `json:"occurrences,omitempty"` // Leak records merged into this leak issue by call stack
+	Library      string  `json:"library,omitempty"`     // Third-party library its frames or source file belong to
+	Source       string  `json:"source,omitempty"`      // Source lines around FileName:LineNumber, when requested
+
+	Savings *Savings `json:"savings,omitempty"` // Set by optimization detectors
+}
+
+// Savings estimates what fixing an optimization issue would save
+type Savings struct {
+	Bytes       int64 `json:"bytes"`
+	Allocations int   `json:"allocations"`
+}
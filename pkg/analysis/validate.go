@@ -0,0 +1,429 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"mempromcp/pkg/mempro"
+)
+
+// ConfigProblem describes one error or warning found while validating configuration files
+type ConfigProblem struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"` // error, warning
+	Message  string `json:"message"`
+}
+
+// ConfigValidation is the result of validating a config file and everything it references
+type ConfigValidation struct {
+	Valid    bool            `json:"valid"`
+	Files    []string        `json:"files"`
+	Problems []ConfigProblem `json:"problems"`
+}
+
+// DataValidation is the result of checking a capture file against the MemPro JSON format
+type DataValidation struct {
+	File string `json:"file"`
+	*mempro.Validation
+}
+
+// ValidateData checks a capture without analyzing it, reporting every problem found rather
+// than the first, with paths redacted
+func ValidateData(path string, red Redactor) DataValidation {
+	validation := mempro.ValidateFile(path)
+	for i := range validation.Problems {
+		validation.Problems[i].Message = red.text(validation.Problems[i].Message)
+	}
+	return DataValidation{File: red.Path(path), Validation: validation}
+}
+
+// ValidateConfig checks a config file without activating it, reporting problems with line positions
+func ValidateConfig(path string) ConfigValidation {
+	result := ConfigValidation{Files: []string{path}, Problems: []ConfigProblem{}}
+
+	var cfg Config
+	fileData, ok := result.decodeFile(path, &cfg)
+	if ok {
+		cfg.path = path
+		if err := cfg.check(); err != nil {
+			result.add(path, fileData, keyOffset(fileData, strings.SplitN(err.Error(), ":", 2)[0]), "error", err.Error())
+		}
+		result.checkRules(path, fileData, "rules", cfg.Rules)
+
+		if cfg.RulesFile != "" {
+			rulesPath := cfg.ResolvePath(cfg.RulesFile)
+			result.Files = append(result.Files, rulesPath)
+
+			var rules []CustomRule
+			if rulesData, ok := result.decodeFile(rulesPath, &rules); ok {
+				result.checkRules(rulesPath, rulesData, "", rules)
+			}
+		}
+
+		result.checkSuggestionRules(path, fileData, "suggestion_rules", cfg.SuggestionRules)
+		if cfg.SuggestionRulesFile != "" {
+			rulesPath := cfg.ResolvePath(cfg.SuggestionRulesFile)
+			result.Files = append(result.Files, rulesPath)
+
+			var rules []SuggestionRule
+			if rulesData, ok := result.decodeFile(rulesPath, &rules); ok {
+				result.checkSuggestionRules(rulesPath, rulesData, "", rules)
+			}
+		}
+
+		result.checkSuppressions(path, fileData, "suppressions", cfg.Suppressions)
+		if cfg.SuppressionsFile != "" {
+			suppressionsPath := cfg.ResolvePath(cfg.SuppressionsFile)
+			result.Files = append(result.Files, suppressionsPath)
+
+			var suppressions []Suppression
+			if suppressionsData, ok := result.decodeFile(suppressionsPath, &suppressions); ok {
+				result.checkSuppressions(suppressionsPath, suppressionsData, "", suppressions)
+			}
+		}
+	}
+
+	result.Valid = true
+	for _, p := range result.Problems {
+		if p.Severity == "error" {
+			result.Valid = false
+			break
+		}
+	}
+
+	return result
+}
+
+// decodeFile decodes a JSON file, recording syntax and type problems. Unknown fields are
+// reported as warnings: they are silently ignored at load time, which usually means a typo.
+func (v *ConfigValidation) decodeFile(path string, target interface{}) ([]byte, bool) {
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		v.add(path, fileData, -1, "error", fmt.Sprintf("failed to read file: %v", err))
+		return nil, false
+	}
+
+	// The decoder stops at the first bad size without saying where it was, so find them all
+	// first and decode a copy in which each is replaced, keeping the other fields checked
+	targetType := reflect.TypeOf(target)
+	patched, badSizes := findBadSizes(fileData, targetType)
+	for _, bad := range badSizes {
+		v.add(path, fileData, bad.offset, "error", bad.message)
+	}
+
+	if err := json.Unmarshal(patched, target); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			v.add(path, fileData, syntaxErr.Offset, "error", fmt.Sprintf("invalid JSON: %v", syntaxErr))
+		case errors.As(err, &typeErr):
+			v.add(path, fileData, typeErr.Offset, "error",
+				fmt.Sprintf("field %q must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value))
+		default:
+			v.add(path, fileData, -1, "error", err.Error())
+		}
+		return fileData, false
+	}
+
+	walkObjectKeys(fileData, func(parents []string, key string, offset int64) {
+		t, ok := jsonFieldType(targetType, parents)
+		if !ok || t.Kind() != reflect.Struct {
+			return
+		}
+		if _, ok := jsonFieldType(t, []string{key}); !ok {
+			v.add(path, fileData, offset, "warning", fmt.Sprintf("unknown field %q is ignored", key))
+		}
+	})
+
+	return fileData, true
+}
+
+// badSize is a byte size that failed to parse, with the 1-based offset of its value
+type badSize struct {
+	offset  int64
+	message string
+}
+
+// findBadSizes reports every ByteSize value in data that does not parse, naming its field,
+// and returns a copy of data in which each is replaced by a valid size of the same length.
+func findBadSizes(data []byte, target reflect.Type) ([]byte, []badSize) {
+	sizeType := reflect.TypeOf(ByteSize(0))
+	patched := data
+	var bad []badSize
+
+	walkObjectKeys(data, func(parents []string, key string, offset int64) {
+		fieldPath := append(append([]string{}, parents...), key)
+		if t, ok := jsonValueType(target, fieldPath); !ok || t != sizeType {
+			return
+		}
+
+		// offset is the key's opening quote; the value follows the colon
+		start := offset - 1 + int64(len(key)) + 2
+		for start < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n:"), data[start]) >= 0 {
+			start++
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(bytes.NewReader(data[start:])).Decode(&raw); err != nil {
+			return
+		}
+		if _, err := decodeByteSize(raw); err != nil {
+			bad = append(bad, badSize{
+				offset:  start + 1,
+				message: fmt.Sprintf("field %q: %v", strings.Join(fieldPath, "."), err),
+			})
+			if len(bad) == 1 {
+				patched = bytes.Clone(data)
+			}
+			copy(patched[start:], append([]byte("0"), bytes.Repeat([]byte(" "), len(raw)-1)...))
+		}
+	})
+
+	return patched, bad
+}
+
+// jsonValueType is jsonFieldType that also follows map values, returning the type that
+// the value under the last key of path decodes into.
+func jsonValueType(t reflect.Type, path []string) (reflect.Type, bool) {
+	t = elemType(t)
+	for _, key := range path {
+		switch t.Kind() {
+		case reflect.Map:
+			t = elemType(t.Elem())
+		case reflect.Struct:
+			var ok bool
+			if t, ok = jsonFieldType(t, []string{key}); !ok {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+// jsonFieldType follows a path of JSON object keys through struct fields, looking
+// through pointers, slices, and maps, and returns the type found at the end.
+func jsonFieldType(t reflect.Type, path []string) (reflect.Type, bool) {
+	t = elemType(t)
+	for _, key := range path {
+		if t.Kind() != reflect.Struct {
+			return t, t.Kind() == reflect.Map || t.Kind() == reflect.Interface
+		}
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = field.Name
+			}
+			if strings.EqualFold(name, key) {
+				t = elemType(field.Type)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t
+}
+
+// walkObjectKeys calls visit for every object key in a JSON document with the keys of its
+// enclosing objects (array levels are skipped) and the key's 1-based byte offset.
+func walkObjectKeys(data []byte, visit func(parents []string, key string, offset int64)) {
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var stack []frame
+	valueDone := func() {
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].expectKey = true
+		}
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, frame{object: true, expectKey: true})
+			case '[':
+				stack = append(stack, frame{})
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			n := len(stack)
+			if n > 0 && stack[n-1].object && stack[n-1].expectKey {
+				stack[n-1].expectKey = false
+				stack[n-1].key = t
+
+				var parents []string
+				for _, f := range stack[:n-1] {
+					if f.object {
+						parents = append(parents, f.key)
+					}
+				}
+				// InputOffset sits just past the closing quote of the key
+				visit(parents, t, dec.InputOffset()-int64(len(t))-1)
+				continue
+			}
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
+
+// keyOffset returns the offset of the first top-level occurrence of key, or -1
+func keyOffset(data []byte, key string) int64 {
+	offset := int64(-1)
+	walkObjectKeys(data, func(parents []string, k string, o int64) {
+		if offset < 0 && len(parents) == 0 && strings.EqualFold(k, key) {
+			offset = o
+		}
+	})
+	return offset
+}
+
+// checkRules compiles each rule, attributing failures to the line where the rule starts
+func (v *ConfigValidation) checkRules(path string, fileData []byte, key string, rules []CustomRule) {
+	offsets := arrayElementOffsets(fileData, key)
+	for i, rule := range rules {
+		if _, err := compileRule(i, rule); err != nil {
+			offset := int64(-1)
+			if i < len(offsets) {
+				offset = offsets[i] + 1
+			}
+			v.add(path, fileData, offset, "error", err.Error())
+		}
+	}
+}
+
+// checkSuggestionRules is checkRules for suggestion rules
+func (v *ConfigValidation) checkSuggestionRules(path string, fileData []byte, key string, rules []SuggestionRule) {
+	offsets := arrayElementOffsets(fileData, key)
+	for i, rule := range rules {
+		if _, err := compileSuggestionRule(i, rule); err != nil {
+			offset := int64(-1)
+			if i < len(offsets) {
+				offset = offsets[i] + 1
+			}
+			v.add(path, fileData, offset, "error", err.Error())
+		}
+	}
+}
+
+// checkSuppressions is checkRules for suppressions
+func (v *ConfigValidation) checkSuppressions(path string, fileData []byte, key string, suppressions []Suppression) {
+	offsets := arrayElementOffsets(fileData, key)
+	for i, s := range suppressions {
+		if _, err := compileSuppression(i, s); err != nil {
+			offset := int64(-1)
+			if i < len(offsets) {
+				offset = offsets[i] + 1
+			}
+			v.add(path, fileData, offset, "error", err.Error())
+		}
+	}
+}
+
+// add records a problem, converting a byte offset into line and column (offset < 0 means unknown)
+func (v *ConfigValidation) add(path string, fileData []byte, offset int64, severity, message string) {
+	problem := ConfigProblem{File: path, Severity: severity, Message: message}
+	if offset >= 0 {
+		problem.Line, problem.Column = lineColumn(fileData, offset)
+	}
+	v.Problems = append(v.Problems, problem)
+}
+
+// lineColumn converts a 1-based byte offset (as reported by encoding/json) into a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line, col := 1, 1
+	for _, b := range data[:max(offset-1, 0)] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// arrayElementOffsets returns the byte offset of each element in the array stored under
+// key in a top-level object, or of the top-level array itself when key is empty.
+func arrayElementOffsets(data []byte, key string) []int64 {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if key != "" {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil
+		}
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil
+			}
+			if name, ok := tok.(string); ok && strings.EqualFold(name, key) {
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil
+			}
+		}
+	}
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil
+	}
+
+	var offsets []int64
+	for dec.More() {
+		// InputOffset points just past the previous token; skip separators to the element itself
+		offset := dec.InputOffset()
+		for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+			offset++
+		}
+		offsets = append(offsets, offset)
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			break
+		}
+	}
+	return offsets
+}
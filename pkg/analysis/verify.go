@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newCapturePlaceholder stands for the capture taken after the fix when no path is given
+const newCapturePlaceholder = "{after_path}"
+
+// detectorTools names the tool that reports each issue type
+var detectorTools = map[string]string{
+	"MemoryLeak":             "analyze_leaks",
+	"MemoryFragmentation":    "analyze_fragmentation",
+	"LargeAllocation":        "find_large_allocations",
+	"DuplicateAllocation":    "find_duplicate_allocations",
+	"PoolCandidate":          "find_pool_candidates",
+	"AlignmentWaste":         "find_alignment_waste",
+	"TinyAllocation":         "find_tiny_allocations",
+	"ShortLivedChurn":        "analyze_lifetimes",
+	"LongLivedResident":      "analyze_lifetimes",
+	"HeapOverBudget":         "get_heap_breakdown",
+	"TagOverBudget":          "get_tag_rollup",
+	"AddressSpaceExhaustion": "get_address_space_headroom",
+}
+
+// VerificationStep is one action that confirms a fix, usually a tool call
+type VerificationStep struct {
+	Action    string                 `json:"action"`
+	Tool      string                 `json:"tool,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Expect    string                 `json:"expect"`
+}
+
+// ExpectedChange is a metric the fix should move
+type ExpectedChange struct {
+	Metric  string  `json:"metric"`
+	Current float64 `json:"current"`
+	Target  string  `json:"target"`
+}
+
+// VerificationPlan lists how to confirm that an issue is fixed after the next capture
+type VerificationPlan struct {
+	Issue    MemoryIssue        `json:"issue"`
+	Capture  string             `json:"capture"` // What the next capture has to exercise
+	Steps    []VerificationStep `json:"steps"`
+	Expected []ExpectedChange   `json:"expected"`
+}
+
+// findIssue looks up an issue by ID across the detector groups
+func findIssue(groups [][]MemoryIssue, id string) (*MemoryIssue, error) {
+	for _, candidates := range groups {
+		for i := range candidates {
+			if candidates[i].ID == id {
+				return &candidates[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no issue with ID %q; IDs are listed by analyze_leaks and get_all_issues", id)
+}
+
+// SuggestVerification plans how to confirm a fix for issue id. capturePath is this
+// capture, used as the baseline; afterPath is the capture taken after the fix, or empty
+// to leave a placeholder.
+func (ma *MemoryAnalyzer) SuggestVerification(id, capturePath, afterPath string) (*VerificationPlan, error) {
+	issue, err := findIssue(ma.AnalyzeAll(), id)
+	if err != nil {
+		return nil, err
+	}
+	if afterPath == "" {
+		afterPath = newCapturePlaceholder
+	}
+
+	plan := &VerificationPlan{Issue: *issue}
+	plan.Capture = verificationCapture(*issue, ma.data)
+
+	tool := detectorTools[issue.Type]
+	if tool == "" {
+		tool = "get_all_issues"
+	}
+	plan.Steps = append(plan.Steps, VerificationStep{
+		Action:    "Re-run the detector on the new capture",
+		Tool:      tool,
+		Arguments: map[string]interface{}{"json_path": afterPath},
+		Expect:    fmt.Sprintf("No issue with ID %s; IDs are stable across captures of the same build", issue.ID),
+	})
+
+	switch issue.Type {
+	case "MemoryFragmentation":
+		plan.Expected = append(plan.Expected, ExpectedChange{Metric: "fragmentation_percent", Current: issue.Score, Target: fmt.Sprintf("below %g", ma.config.EffectiveThresholds().FragmentationMedium)})
+	case "AddressSpaceExhaustion":
+		if issue.ID == issueID("vas", "block") {
+			plan.Expected = append(plan.Expected, ExpectedChange{Metric: "largest_free_block", Current: float64(issue.Size), Target: "at least " + FormatBytes(minFreeBlock32)})
+		} else {
+			plan.Expected = append(plan.Expected, ExpectedChange{Metric: "address_space_used_percent", Current: issue.Score, Target: fmt.Sprintf("below %.0f", addressSpaceHigh)})
+		}
+	case "HeapOverBudget", "TagOverBudget":
+		metric, name := "heap_size", issue.Heap
+		if issue.Type == "TagOverBudget" {
+			metric, name = "tag_size", issue.Tag
+		}
+		plan.Expected = append(plan.Expected, ExpectedChange{Metric: metric, Current: float64(issue.Size), Target: name + " at or under its configured budget"})
+	default:
+		plan.Expected = append(plan.Expected, expectedSizeChanges(*issue)...)
+	}
+
+	if fn := issue.FunctionName; fn != "" && fn != "Unknown Function" {
+		plan.Steps = append(plan.Steps, VerificationStep{
+			Action: fmt.Sprintf("Compare %s between the baseline and the new capture", fn),
+			Tool:   "compare_function",
+			Arguments: map[string]interface{}{
+				"function":    fn,
+				"before_path": capturePath,
+				"after_path":  afterPath,
+			},
+			Expect: functionExpectation(*issue),
+		})
+	}
+	if issue.FileName != "" {
+		args := map[string]interface{}{"json_path": afterPath, "file": issue.FileName}
+		if issue.LineNumber > 0 {
+			args["start_line"], args["end_line"] = issue.LineNumber, issue.LineNumber
+		}
+		plan.Steps = append(plan.Steps, VerificationStep{
+			Action:    "Check the fixed source location for remaining issues",
+			Tool:      "get_issues_for_file",
+			Arguments: args,
+			Expect:    "No issues of type " + issue.Type + " at this location",
+		})
+	}
+	plan.Steps = append(plan.Steps, VerificationStep{
+		Action:    "Confirm the fix did not move the problem elsewhere",
+		Tool:      "top_growth_between",
+		Arguments: map[string]interface{}{"before_path": capturePath, "after_path": afterPath},
+		Expect:    "No function grew by a comparable amount",
+	})
+
+	return plan, nil
+}
+
+// verificationCapture describes what the next capture has to exercise for the comparison
+// to be meaningful
+func verificationCapture(issue MemoryIssue, data *MemProData) string {
+	var b strings.Builder
+	b.WriteString("Capture the same scenario with the same build configuration")
+	if data.SessionDuration > 0 {
+		fmt.Fprintf(&b, ", running at least as long as the baseline (%.0f s)", data.SessionDuration/1000)
+	}
+	switch issue.Type {
+	case "MemoryLeak":
+		b.WriteString(", and exercise the code path that leaked until the process exits normally so leaks are recorded")
+	case "ShortLivedChurn", "LongLivedResident":
+		b.WriteString(", with allocation timing enabled so lifetimes are recorded")
+	case "AddressSpaceExhaustion", "MemoryFragmentation":
+		b.WriteString(", with page views enabled, taken at the same point in the session")
+	case "HeapOverBudget", "TagOverBudget":
+		b.WriteString(", with heap and tag attribution enabled")
+	}
+	if issue.Thread != "" {
+		fmt.Fprintf(&b, "; the issue was seen on thread %s", issue.Thread)
+	}
+	return b.String()
+}
+
+// expectedSizeChanges is what fixing a size-based issue should do to its footprint
+func expectedSizeChanges(issue MemoryIssue) []ExpectedChange {
+	if issue.Type == "MemoryLeak" {
+		return []ExpectedChange{
+			{Metric: "leak_size", Current: float64(issue.Size), Target: "0"},
+			{Metric: "leak_count", Current: float64(issue.Count), Target: "0"},
+		}
+	}
+	if issue.Savings != nil {
+		return []ExpectedChange{
+			{Metric: "total_size", Current: float64(issue.Size), Target: fmt.Sprintf("down by about %s", FormatBytes(issue.Savings.Bytes))},
+			{Metric: "allocation_count", Current: float64(issue.Count), Target: fmt.Sprintf("down by about %d", issue.Savings.Allocations)},
+		}
+	}
+	return []ExpectedChange{{Metric: "total_size", Current: float64(issue.Size), Target: "lower than now"}}
+}
+
+// functionExpectation is the compare_function verdict a fix should produce
+func functionExpectation(issue MemoryIssue) string {
+	if issue.Type == "MemoryLeak" {
+		return "Verdict improved, with leak_size down to 0"
+	}
+	if issue.Savings != nil {
+		return fmt.Sprintf("Verdict improved, with total_size down by about %s", FormatBytes(issue.Savings.Bytes))
+	}
+	return "Verdict improved or unchanged, never regressed"
+}
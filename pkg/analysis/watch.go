@@ -0,0 +1,365 @@
+package analysis
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Watch defaults and limits
+const (
+	DefaultWatchInterval = 30 * time.Second
+	MinWatchInterval     = time.Second
+	maxWatchChanges      = 10
+)
+
+// SessionSnapshot is the state of a capture at one poll
+type SessionSnapshot struct {
+	Taken            time.Time `json:"taken"`
+	Session          string    `json:"session"`
+	TotalAllocations int       `json:"total_allocations"`
+	TotalSize        int64     `json:"total_size"`
+	LeakCount        int       `json:"leak_count"`
+	LeakSize         int64     `json:"leak_size"`
+	Fragmentation    float64   `json:"fragmentation"`
+
+	leaks map[string]MemoryIssue
+}
+
+// LeakChange is a leak present in both snapshots whose size changed
+type LeakChange struct {
+	ID           string `json:"id"`
+	FunctionName string `json:"functionName"`
+	Before       int64  `json:"before"`
+	After        int64  `json:"after"`
+	Delta        int64  `json:"delta"`
+}
+
+// SessionDiff describes what changed in a capture between two snapshots
+type SessionDiff struct {
+	From             time.Time     `json:"from"`
+	To               time.Time     `json:"to"`
+	Changed          bool          `json:"changed"`
+	Significant      bool          `json:"significant"` // Some total changed beyond the tolerance profile
+	Tolerance        string        `json:"tolerance"`
+	Regressions      []string      `json:"regressions,omitempty"`  // Totals that grew beyond tolerance
+	Improvements     []string      `json:"improvements,omitempty"` // Totals that shrank beyond tolerance
+	TotalAllocations int           `json:"total_allocations_delta"`
+	TotalSize        int64         `json:"total_size_delta"`
+	LeakCount        int           `json:"leak_count_delta"`
+	LeakSize         int64         `json:"leak_size_delta"`
+	Fragmentation    float64       `json:"fragmentation_delta"`
+	NewLeaks         []MemoryIssue `json:"new_leaks,omitempty"`
+	ResolvedLeaks    []MemoryIssue `json:"resolved_leaks,omitempty"`
+	ChangedLeaks     []LeakChange  `json:"changed_leaks,omitempty"`
+	Omitted          int           `json:"omitted,omitempty"` // Leak entries beyond the per-list limit
+}
+
+// WatchStatus is the externally visible state of a watcher
+type WatchStatus struct {
+	Path      string           `json:"path"`
+	Interval  string           `json:"interval"`
+	Polls     int              `json:"polls"`
+	Reloads   int              `json:"reloads"`
+	LastError string           `json:"last_error,omitempty"`
+	Latest    *SessionSnapshot `json:"latest,omitempty"`
+	Pending   *SessionDiff     `json:"pending,omitempty"` // Changes not yet reported by watch_session
+}
+
+// SessionWatcher polls one capture path. Changes accumulate against the baseline until
+// they are reported, so nothing is lost between reports no matter how often the file is polled.
+type SessionWatcher struct {
+	mu        sync.Mutex
+	path      string
+	interval  time.Duration
+	stop      chan struct{}
+	modTime   time.Time
+	size      int64
+	polls     int
+	reloads   int
+	lastError string
+	baseline  *SessionSnapshot
+	latest    *SessionSnapshot
+	notified  *SessionSnapshot // Last snapshot announced; tolerated changes accumulate against it
+}
+
+var (
+	watchersMu sync.Mutex
+	watchers   = make(map[string]*SessionWatcher)
+)
+
+// StartWatch begins polling path, or changes the interval of an existing watcher
+func StartWatch(path string, interval time.Duration, n Logger) (*SessionWatcher, error) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+
+	if w, ok := watchers[path]; ok {
+		w.mu.Lock()
+		restart := w.interval != interval
+		w.mu.Unlock()
+		if !restart {
+			return w, nil
+		}
+		close(w.stop)
+		delete(watchers, path)
+	}
+
+	w := &SessionWatcher{path: path, interval: interval, stop: make(chan struct{})}
+	if err := w.Poll(); err != nil {
+		return nil, err
+	}
+	watchers[path] = w
+	go w.run(n)
+	return w, nil
+}
+
+// FindWatch returns the watcher polling path, if any
+func FindWatch(path string) (*SessionWatcher, bool) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	w, ok := watchers[path]
+	return w, ok
+}
+
+// StopWatch stops polling path; it reports whether a watcher existed
+func StopWatch(path string) bool {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+
+	w, ok := watchers[path]
+	if ok {
+		close(w.stop)
+		delete(watchers, path)
+	}
+	return ok
+}
+
+// WatchStatuses returns the state of every watcher, ordered by path
+func WatchStatuses() []WatchStatus {
+	watchersMu.Lock()
+	list := make([]*SessionWatcher, 0, len(watchers))
+	for _, w := range watchers {
+		list = append(list, w)
+	}
+	watchersMu.Unlock()
+
+	statuses := make([]WatchStatus, 0, len(list))
+	for _, w := range list {
+		statuses = append(statuses, w.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses
+}
+
+func (w *SessionWatcher) run(n Logger) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		w.mu.Lock()
+		previousError := w.lastError
+		w.mu.Unlock()
+
+		if err := w.Poll(); err != nil {
+			// Report each distinct failure once rather than on every poll
+			if err.Error() != previousError {
+				n.Log("warning", "watch", fmt.Sprintf("%s: %v", CurrentConfig().Redactor().Path(w.path), err))
+			}
+			continue
+		}
+
+		// Changes within the tolerance profile are run-to-run noise, not worth an alert
+		w.mu.Lock()
+		latest, notified := w.latest, w.notified
+		w.mu.Unlock()
+		if latest != notified {
+			diff := diffSnapshots(notified, latest, CurrentConfig().defaultTolerance())
+			if !diff.Significant {
+				continue
+			}
+			w.mu.Lock()
+			w.notified = latest
+			w.mu.Unlock()
+			msg := fmt.Sprintf("%s changed: leak size %+d bytes, %d new and %d resolved leaks",
+				CurrentConfig().Redactor().Path(w.path), diff.LeakSize, len(diff.NewLeaks), len(diff.ResolvedLeaks))
+			log.Print(msg)
+			n.Log("info", "watch", msg)
+		}
+	}
+}
+
+// Poll re-reads the capture if it changed on disk. A failed read keeps the previous snapshot,
+// since MemPro may be in the middle of re-exporting the file.
+func (w *SessionWatcher) Poll() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.polls++
+
+	red := CurrentConfig().Redactor()
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.lastError = red.Error(err).Error()
+		return red.Error(err)
+	}
+	if w.latest != nil && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil
+	}
+
+	analyzer, err := NewMemoryAnalyzer(w.path)
+	if err != nil {
+		w.lastError = err.Error()
+		return err
+	}
+
+	w.modTime, w.size = info.ModTime(), info.Size()
+	w.lastError = ""
+	w.reloads++
+	w.latest = takeSnapshot(analyzer)
+	if w.baseline == nil {
+		w.baseline, w.notified = w.latest, w.latest
+	}
+	return nil
+}
+
+// Report returns the changes since the last report and starts a new baseline
+func (w *SessionWatcher) Report() *SessionDiff {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	diff := diffSnapshots(w.baseline, w.latest, CurrentConfig().defaultTolerance())
+	w.baseline = w.latest
+	return diff
+}
+
+func (w *SessionWatcher) Status() WatchStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	status := WatchStatus{
+		Path:      CurrentConfig().Redactor().Path(w.path),
+		Interval:  w.interval.String(),
+		Polls:     w.polls,
+		Reloads:   w.reloads,
+		LastError: w.lastError,
+		Latest:    w.latest,
+	}
+	if w.baseline != w.latest {
+		status.Pending = diffSnapshots(w.baseline, w.latest, CurrentConfig().defaultTolerance())
+	}
+	return status
+}
+
+func takeSnapshot(ma *MemoryAnalyzer) *SessionSnapshot {
+	s := &SessionSnapshot{
+		Taken:            time.Now(),
+		Session:          ma.data.SessionName,
+		TotalAllocations: ma.data.TotalAllocations,
+		TotalSize:        ma.data.TotalSize,
+		LeakCount:        ma.data.LeakCount,
+		LeakSize:         ma.data.LeakSize,
+		Fragmentation:    ma.data.MemoryFragmentation,
+		leaks:            make(map[string]MemoryIssue),
+	}
+	for _, issue := range ma.AnalyzeLeaks() {
+		s.leaks[issue.ID] = issue
+	}
+	return s
+}
+
+// diffSnapshots compares two snapshots; leak lists are ordered by impact and capped.
+// Totals that changed beyond tol make the diff significant.
+func diffSnapshots(before, after *SessionSnapshot, tol *ToleranceProfile) *SessionDiff {
+	diff := &SessionDiff{Tolerance: tol.Name}
+	if before == nil || after == nil {
+		return diff
+	}
+
+	diff.From, diff.To = before.Taken, after.Taken
+	diff.TotalAllocations = after.TotalAllocations - before.TotalAllocations
+	diff.TotalSize = after.TotalSize - before.TotalSize
+	diff.LeakCount = after.LeakCount - before.LeakCount
+	diff.LeakSize = after.LeakSize - before.LeakSize
+	diff.Fragmentation = after.Fragmentation - before.Fragmentation
+
+	for id, issue := range after.leaks {
+		old, ok := before.leaks[id]
+		switch {
+		case !ok:
+			diff.NewLeaks = append(diff.NewLeaks, issue)
+		case old.Size != issue.Size:
+			diff.ChangedLeaks = append(diff.ChangedLeaks, LeakChange{
+				ID:           id,
+				FunctionName: issue.FunctionName,
+				Before:       old.Size,
+				After:        issue.Size,
+				Delta:        issue.Size - old.Size,
+			})
+		}
+	}
+	for id, issue := range before.leaks {
+		if _, ok := after.leaks[id]; !ok {
+			diff.ResolvedLeaks = append(diff.ResolvedLeaks, issue)
+		}
+	}
+
+	SortIssues(diff.NewLeaks)
+	SortIssues(diff.ResolvedLeaks)
+	sort.Slice(diff.ChangedLeaks, func(i, j int) bool {
+		return abs64(diff.ChangedLeaks[i].Delta) > abs64(diff.ChangedLeaks[j].Delta)
+	})
+
+	diff.NewLeaks = capWatchList(diff.NewLeaks, &diff.Omitted)
+	diff.ResolvedLeaks = capWatchList(diff.ResolvedLeaks, &diff.Omitted)
+	diff.ChangedLeaks = capWatchList(diff.ChangedLeaks, &diff.Omitted)
+
+	diff.Changed = diff.TotalAllocations != 0 || diff.TotalSize != 0 || diff.LeakCount != 0 ||
+		diff.LeakSize != 0 || diff.Fragmentation != 0 || diff.Omitted > 0 ||
+		len(diff.NewLeaks) > 0 || len(diff.ResolvedLeaks) > 0 || len(diff.ChangedLeaks) > 0
+
+	totals := []struct {
+		metric        string
+		before, after float64
+	}{
+		{"allocation_count", float64(before.TotalAllocations), float64(after.TotalAllocations)},
+		{"total_size", float64(before.TotalSize), float64(after.TotalSize)},
+		{"leak_count", float64(before.LeakCount), float64(after.LeakCount)},
+		{"leak_size", float64(before.LeakSize), float64(after.LeakSize)},
+		{"fragmentation", before.Fragmentation, after.Fragmentation},
+	}
+	for _, t := range totals {
+		if tol.within(t.metric, t.before, t.after) {
+			continue
+		}
+		diff.Significant = true
+		if t.after > t.before {
+			diff.Regressions = append(diff.Regressions, t.metric)
+		} else {
+			diff.Improvements = append(diff.Improvements, t.metric)
+		}
+	}
+	return diff
+}
+
+func capWatchList[T any](items []T, omitted *int) []T {
+	if len(items) <= maxWatchChanges {
+		return items
+	}
+	*omitted += len(items) - maxWatchChanges
+	return items[:maxWatchChanges]
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sessionNamePattern keeps session names distinct from file paths
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// WorkspaceSession is a capture registered under a short name by load_session. Tools accept
+// the name wherever they take a capture path.
+type WorkspaceSession struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Active    bool      `json:"active"` // Set on copies handed out
+	Loaded    time.Time `json:"loaded"`
+	Session   string    `json:"session"` // MemPro session name when loaded
+	TotalSize int64     `json:"total_size"`
+	LeakSize  int64     `json:"leak_size"`
+}
+
+// workspace holds the named sessions and which one tools use when given no json_path
+var (
+	workspaceMu   sync.Mutex
+	workspace     = make(map[string]*WorkspaceSession)
+	activeSession string
+)
+
+// LoadSession loads the capture at path with load and registers it as name, replacing a
+// session of the same name. The first session becomes active, as does any loaded with activate.
+func LoadSession(name, path string, activate bool, load func(path string) (*MemoryAnalyzer, error)) (*WorkspaceSession, error) {
+	if !sessionNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid session name %q (use letters, digits, '_' and '-', starting with a letter)", name)
+	}
+
+	path = SessionPath(path)
+	analyzer, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+
+	s := &WorkspaceSession{
+		Name:      name,
+		Path:      path,
+		Loaded:    time.Now().UTC(),
+		Session:   analyzer.data.SessionName,
+		TotalSize: analyzer.data.TotalSize,
+		LeakSize:  analyzer.data.LeakSize,
+	}
+	workspace[name] = s
+	if activate || activeSession == "" {
+		activeSession = name
+	}
+	return s.withActive(), nil
+}
+
+// SetActiveSession makes the named session the one tools use when given no json_path
+func SetActiveSession(name string) (*WorkspaceSession, error) {
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+
+	s, ok := workspace[name]
+	if !ok {
+		return nil, fmt.Errorf("no session named %q; load it with load_session", name)
+	}
+	activeSession = name
+	return s.withActive(), nil
+}
+
+// WorkspaceSessions returns the sessions ordered by name
+func WorkspaceSessions() []WorkspaceSession {
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+
+	list := make([]WorkspaceSession, 0, len(workspace))
+	for _, s := range workspace {
+		list = append(list, *s.withActive())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// withActive returns a copy of s marked whether it is active; workspaceMu must be held
+func (s *WorkspaceSession) withActive() *WorkspaceSession {
+	c := *s
+	c.Active = s.Name == activeSession
+	return &c
+}
+
+// SessionPath returns the capture path of the named session, or name itself when no
+// session has that name, so capture path arguments accept session names
+func SessionPath(name string) string {
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+
+	if s, ok := workspace[name]; ok {
+		return s.Path
+	}
+	return name
+}
+
+// ActiveSessionPath returns the capture path of the active session, if any
+func ActiveSessionPath() (string, bool) {
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+
+	if s, ok := workspace[activeSession]; ok {
+		return s.Path, true
+	}
+	return "", false
+}
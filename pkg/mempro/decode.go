@@ -0,0 +1,364 @@
+package mempro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Sections selects the record arrays of a capture to decode; the scalar header fields are
+// always decoded
+type Sections uint
+
+const (
+	SectionLeaks Sections = 1 << iota
+	SectionFunctions
+	SectionCallTrees
+	SectionPageViews
+	SectionTypes
+	SectionAllocations
+	SectionThreads
+	SectionHeaps
+	SectionSnapshots
+
+	AllSections Sections = 1<<iota - 1
+)
+
+// sectionKeys maps the lower-cased JSON key of each record array to its section
+var sectionKeys = map[string]Sections{
+	"leaks":       SectionLeaks,
+	"functions":   SectionFunctions,
+	"calltrees":   SectionCallTrees,
+	"pageviews":   SectionPageViews,
+	"types":       SectionTypes,
+	"allocations": SectionAllocations,
+	"threads":     SectionThreads,
+	"heaps":       SectionHeaps,
+	"snapshots":   SectionSnapshots,
+}
+
+// FormatError reports where a capture departs from the MemPro JSON format
+type FormatError struct {
+	Path    string // Location of the bad value, e.g. Leaks[12].LeakSize; empty for the document
+	Offset  int64  // Byte offset into the uncompressed JSON
+	Message string
+}
+
+func (e *FormatError) Error() string {
+	message := e.Message
+	if e.Path != "" {
+		message = e.Path + ": " + message
+	}
+	if e.Offset > 0 {
+		message += fmt.Sprintf(" (near byte %d)", e.Offset)
+	}
+	return message
+}
+
+// Decode decodes a MemPro JSON capture from r without holding the whole file in memory: the
+// record arrays are decoded one element at a time, and arrays outside sections are skipped
+// token by token. Skipped arrays that are present are returned by name. Malformed input is
+// reported as a *FormatError naming the bad field, e.g. a size that is not a number, a
+// truncated file, or JSON that is not a MemPro export at all.
+func Decode(r io.Reader, sections Sections) (*Data, []string, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, nil, formatError(dec, err, "", 0)
+	}
+
+	var data Data
+	var skipped []string
+	header := make(map[string]json.RawMessage)
+	headerOffsets := make(map[string]int64)
+	conv := newConverter()
+	known := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, formatError(dec, err, "", 0)
+		}
+		key, _ := tok.(string)
+		if _, ok := dataFieldsByName[strings.ToLower(key)]; ok {
+			known = true
+		}
+		if strings.ToLower(key) == schemaVersionKey {
+			offset := dec.InputOffset()
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, nil, formatError(dec, err, key, offset)
+			}
+			if conv.explicit, err = parseSchemaVersion(value); err != nil {
+				return nil, nil, &FormatError{Path: key, Offset: offset, Message: err.Error()}
+			}
+			known = true
+			continue
+		}
+
+		section, isSection := sectionKeys[strings.ToLower(key)]
+		switch {
+		case !isSection:
+			offset := dec.InputOffset()
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, nil, formatError(dec, err, key, offset)
+			}
+			header[key] = value
+			headerOffsets[strings.ToLower(key)] = offset
+		case sections&section == 0:
+			if err := skipValue(dec); err != nil {
+				return nil, nil, formatError(dec, err, key, 0)
+			}
+			skipped = append(skipped, key)
+		default:
+			if err := decodeSection(dec, key, section, conv, &data); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, nil, formatError(dec, err, "", 0)
+	}
+	if !known {
+		return nil, nil, &FormatError{Message: "not a MemPro export: none of its fields (SessionName, Leaks, Functions, ...) were found"}
+	}
+
+	// Header values are few and small, so they are decoded the usual way
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if encoded, err = conv.header(encoded); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			// Offsets into the re-encoded header are meaningless; point at the key instead
+			return nil, nil, &FormatError{Path: typeErr.Field, Offset: headerOffsets[strings.ToLower(typeErr.Field)], Message: typeMessage(typeErr)}
+		}
+		return nil, nil, err
+	}
+	data.Schema = conv.version()
+	sort.Strings(skipped)
+	return &data, skipped, nil
+}
+
+// decodeSection decodes one record array, found under key, into its field of data
+func decodeSection(dec *json.Decoder, key string, section Sections, conv *converter, data *Data) error {
+	switch section {
+	case SectionLeaks:
+		return decodeElements(dec, key, section, conv, &data.Leaks)
+	case SectionFunctions:
+		return decodeElements(dec, key, section, conv, &data.Functions)
+	case SectionCallTrees:
+		return decodeElements(dec, key, section, conv, &data.CallTrees)
+	case SectionPageViews:
+		return decodeElements(dec, key, section, conv, &data.PageViews)
+	case SectionTypes:
+		return decodeElements(dec, key, section, conv, &data.Types)
+	case SectionAllocations:
+		return decodeElements(dec, key, section, conv, &data.Allocations)
+	case SectionThreads:
+		return decodeElements(dec, key, section, conv, &data.Threads)
+	case SectionHeaps:
+		return decodeElements(dec, key, section, conv, &data.Heaps)
+	case SectionSnapshots:
+		return decodeElements(dec, key, section, conv, &data.Snapshots)
+	}
+	return skipValue(dec)
+}
+
+// decodeElements decodes a JSON array, or null, one element at a time. Elements of older or
+// newer layouts are translated to the current one.
+func decodeElements[T any](dec *json.Decoder, key string, section Sections, conv *converter, list *[]T) error {
+	offset := dec.InputOffset()
+	tok, err := dec.Token()
+	if err != nil {
+		return formatError(dec, err, key, offset)
+	}
+	if tok == nil {
+		return nil
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return &FormatError{Path: key, Offset: offset, Message: fmt.Sprintf("expected an array of records, got %s", tokenKind(tok))}
+	}
+	for dec.More() {
+		offset := dec.InputOffset()
+		var v T
+		if conv.direct(section) {
+			if err := dec.Decode(&v); err != nil {
+				return formatError(dec, err, elementPath(key, len(*list)), offset)
+			}
+		} else {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return formatError(dec, err, elementPath(key, len(*list)), offset)
+			}
+			if err := conv.decode(section, raw, &v); err != nil {
+				return convertedError(err, elementPath(key, len(*list)), offset)
+			}
+		}
+		*list = append(*list, v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return formatError(dec, err, key, 0)
+	}
+	return nil
+}
+
+// formatError describes a decoding error at path. offset is where the value being decoded
+// starts, which type errors are relative to.
+func formatError(dec *json.Decoder, err error, path string, offset int64) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &syntaxErr) && syntaxErr.Error() == "unexpected end of JSON input":
+		return &FormatError{Path: path, Offset: dec.InputOffset(), Message: "the file ends early; the export looks truncated"}
+	case errors.As(err, &syntaxErr):
+		return &FormatError{Path: path, Offset: syntaxErr.Offset, Message: fmt.Sprintf("invalid JSON: %v", syntaxErr)}
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			path = joinPath(path, typeErr.Field)
+		}
+		return &FormatError{Path: path, Offset: offset + typeErr.Offset, Message: typeMessage(typeErr)}
+	}
+	var formatErr *FormatError
+	if errors.As(err, &formatErr) {
+		return err
+	}
+	return &FormatError{Path: path, Offset: dec.InputOffset(), Message: err.Error()}
+}
+
+// convertedError describes an error decoding a translated record; offsets into the
+// translation are meaningless, so it points at the record
+func convertedError(err error, path string, offset int64) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field != "" {
+			path = joinPath(path, typeErr.Field)
+		}
+		return &FormatError{Path: path, Offset: offset, Message: typeMessage(typeErr)}
+	}
+	return &FormatError{Path: path, Offset: offset, Message: err.Error()}
+}
+
+// typeMessage describes a value of the wrong JSON type
+func typeMessage(err *json.UnmarshalTypeError) string {
+	return fmt.Sprintf("expected %s, got %s", typeName(err.Type), err.Value)
+}
+
+// typeName names the JSON value expected for a Go type
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	}
+	return t.String()
+}
+
+// tokenKind names the JSON value a token starts
+func tokenKind(tok json.Token) string {
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			return "an object"
+		}
+		return "an array"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
+
+// elementPath names the element at index of the array under key
+func elementPath(key string, index int) string {
+	return fmt.Sprintf("%s[%d]", key, index)
+}
+
+// joinPath appends a dotted field path to a record path
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// jsonName returns the JSON name of a struct field, or "" for unexported and ignored ones
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// skipValue consumes the next value without keeping it. Arrays and objects are skipped
+// one member at a time, so a skipped section is never held in memory as a whole.
+func skipValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+	return skipRest(dec, delim)
+}
+
+// skipRest consumes the rest of an array or object whose opening delim was just read
+func skipRest(dec *json.Decoder, delim json.Delim) error {
+	for dec.More() {
+		if delim == '{' {
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+		}
+		var member json.RawMessage
+		if err := dec.Decode(&member); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// expectDelim consumes the next token, which must be delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %q, got %s", rune(delim), tokenKind(tok))
+	}
+	return nil
+}
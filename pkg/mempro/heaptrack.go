@@ -0,0 +1,339 @@
+package mempro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Capture formats recognized by their first bytes
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// maxHeaptrackLine bounds a heaptrack line; demangled template symbols can be very long
+const maxHeaptrackLine = 16 << 20
+
+// Open returns a reader over a capture's uncompressed content and whether it holds heaptrack
+// data rather than MemPro JSON. gzip is unpacked; zstd is refused with a hint.
+func Open(r io.Reader) (content io.Reader, heaptrack bool, err error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(head, zstdMagic):
+		return nil, false, fmt.Errorf("zstd-compressed captures are not supported; decompress it first (zstd -d)")
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, false, err
+		}
+		br = bufio.NewReader(gz)
+		head, _ = br.Peek(2)
+	}
+	return br, bytes.HasPrefix(head, []byte("v ")), nil
+}
+
+// heaptrackFrame is a resolved source location
+type heaptrackFrame struct {
+	function string
+	file     string
+	line     int
+}
+
+// heaptrackIP is an instruction pointer: its own frame first, then the frames it was inlined into
+type heaptrackIP struct {
+	ip     uint64
+	module string
+	frames []heaptrackFrame
+}
+
+// heaptrackTrace is a node of heaptrack's trace tree; indexes are 1-based, 0 is the root
+type heaptrackTrace struct {
+	ip, parent int
+}
+
+// heaptrackAllocation is an allocation info: a size allocated from a trace
+type heaptrackAllocation struct {
+	size  int64
+	trace int
+}
+
+// heaptrackData is the raw content of a heaptrack capture
+type heaptrackData struct {
+	command     string
+	duration    float64 // Milliseconds
+	strings     []string
+	ips         []heaptrackIP
+	traces      []heaptrackTrace
+	allocations []heaptrackAllocation
+	allocated   []int // Allocations per allocation info
+	freed       []int // Deallocations per allocation info
+}
+
+// DecodeHeaptrack reads heaptrack data (as written by heaptrack_interpret) and converts it to
+// the MemPro model: leaks are allocations never freed, grouped by trace; functions and call
+// trees count every allocation by its allocating frame and its callers. name is the session
+// name used when the capture records no command line.
+func DecodeHeaptrack(r io.Reader, name string) (*Data, error) {
+	raw, err := readHeaptrack(r)
+	if err != nil {
+		return nil, err
+	}
+	return raw.convert(name), nil
+}
+
+func readHeaptrack(r io.Reader) (*heaptrackData, error) {
+	d := &heaptrackData{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxHeaptrackLine)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(line) < 2 || line[1] != ' ' {
+			continue
+		}
+		tag, rest := line[0], line[2:]
+		fields := strings.Fields(rest)
+		hex := func(i int) (uint64, bool) {
+			if i >= len(fields) {
+				return 0, false
+			}
+			v, err := strconv.ParseUint(fields[i], 16, 64)
+			return v, err == nil
+		}
+		bad := func() error {
+			return fmt.Errorf("heaptrack line %d: malformed %q record", lineNumber, string(tag))
+		}
+
+		switch tag {
+		case 'v':
+			if fileVersion, ok := hex(1); ok && fileVersion < 1 {
+				return nil, fmt.Errorf("heaptrack file version %d is not supported; record with heaptrack 1.1 or later", fileVersion)
+			}
+		case 'X':
+			d.command = rest
+		case 's':
+			// Newer versions prefix the string with its length
+			if n, ok := hex(0); ok {
+				if s := strings.TrimPrefix(rest, fields[0]+" "); uint64(len(s)) == n {
+					rest = s
+				}
+			}
+			d.strings = append(d.strings, rest)
+		case 'i':
+			ip, ok1 := hex(0)
+			module, ok2 := hex(1)
+			if !ok1 || !ok2 {
+				return nil, bad()
+			}
+			entry := heaptrackIP{ip: ip, module: d.str(module)}
+			for i := 2; i+2 < len(fields); i += 3 {
+				function, _ := hex(i)
+				file, _ := hex(i + 1)
+				line, _ := hex(i + 2)
+				entry.frames = append(entry.frames, heaptrackFrame{function: d.str(function), file: d.str(file), line: int(line)})
+			}
+			d.ips = append(d.ips, entry)
+		case 't':
+			ip, ok1 := hex(0)
+			parent, ok2 := hex(1)
+			if !ok1 || !ok2 {
+				return nil, bad()
+			}
+			d.traces = append(d.traces, heaptrackTrace{ip: int(ip), parent: int(parent)})
+		case 'a':
+			size, ok1 := hex(0)
+			trace, ok2 := hex(1)
+			if !ok1 || !ok2 {
+				return nil, bad()
+			}
+			d.allocations = append(d.allocations, heaptrackAllocation{size: int64(size), trace: int(trace)})
+			d.allocated = append(d.allocated, 0)
+			d.freed = append(d.freed, 0)
+		case '+', '-':
+			index, ok := hex(0)
+			if !ok || len(fields) != 1 || index >= uint64(len(d.allocations)) {
+				return nil, bad()
+			}
+			if tag == '+' {
+				d.allocated[index]++
+			} else {
+				d.freed[index]++
+			}
+		case 'c':
+			if ms, ok := hex(0); ok {
+				d.duration = float64(ms)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read heaptrack data: %w", err)
+	}
+	return d, nil
+}
+
+// str returns the interned string at a 1-based index; 0 is the empty string
+func (d *heaptrackData) str(index uint64) string {
+	if index == 0 || index > uint64(len(d.strings)) {
+		return ""
+	}
+	return d.strings[index-1]
+}
+
+// stack returns the frames of a trace, innermost first. Instruction pointers without symbols
+// are named by module and address, e.g. libfoo.so!0x7f12.
+func (d *heaptrackData) stack(trace int) []heaptrackFrame {
+	var frames []heaptrackFrame
+	for seen := 0; trace > 0 && trace <= len(d.traces) && seen < len(d.traces); seen++ {
+		t := d.traces[trace-1]
+		if t.ip > 0 && t.ip <= len(d.ips) {
+			ip := d.ips[t.ip-1]
+			if len(ip.frames) == 0 || ip.frames[0].function == "" {
+				frames = append(frames, heaptrackFrame{function: fmt.Sprintf("%s!0x%x", filepath.Base(ip.module), ip.ip)})
+			} else {
+				frames = append(frames, ip.frames...)
+			}
+		}
+		trace = t.parent
+	}
+	return frames
+}
+
+// convert builds the MemPro model from the raw capture
+func (d *heaptrackData) convert(name string) *Data {
+	data := &Data{
+		SessionName:     d.command,
+		SessionDuration: d.duration,
+		CallTrees:       []CallTree{},
+		Functions:       []Function{},
+		Leaks:           []Leak{},
+		PageViews:       []PageView{},
+		Types:           []AllocType{},
+	}
+	if data.SessionName == "" {
+		data.SessionName = name
+	}
+
+	type traceTotals struct {
+		count, leaked    int
+		size, leakedSize int64
+		minSize, maxSize int64
+	}
+	totals := make(map[int]*traceTotals)
+	var order []int
+	for i, a := range d.allocations {
+		count := d.allocated[i]
+		if count == 0 {
+			continue
+		}
+		t, ok := totals[a.trace]
+		if !ok {
+			t = &traceTotals{minSize: a.size}
+			totals[a.trace] = t
+			order = append(order, a.trace)
+		}
+		t.count += count
+		t.size += a.size * int64(count)
+		t.minSize = min(t.minSize, a.size)
+		t.maxSize = max(t.maxSize, a.size)
+		if leaked := count - d.freed[i]; leaked > 0 {
+			t.leaked += leaked
+			t.leakedSize += a.size * int64(leaked)
+		}
+		data.TotalAllocations += count
+		data.TotalSize += a.size * int64(count)
+	}
+	sort.Ints(order)
+
+	functions := make(map[string]*Function)
+	var functionOrder []string
+	root := &CallTree{}
+	for _, trace := range order {
+		t := totals[trace]
+		frames := d.stack(trace)
+		site := heaptrackFrame{function: "Unknown Function"}
+		if len(frames) > 0 {
+			site = frames[0]
+		}
+
+		if t.leaked > 0 {
+			names := make([]string, len(frames))
+			for i, f := range frames {
+				names[i] = f.function
+			}
+			data.Leaks = append(data.Leaks, Leak{
+				FunctionName: site.function,
+				FileName:     site.file,
+				LineNumber:   site.line,
+				LeakSize:     t.leakedSize,
+				LeakCount:    t.leaked,
+				CallStack:    strings.Join(names, " <- "),
+			})
+			data.LeakCount += t.leaked
+			data.LeakSize += t.leakedSize
+		}
+
+		f, ok := functions[site.function]
+		if !ok {
+			f = &Function{FunctionName: site.function, FileName: site.file, LineNumber: site.line, MinSize: t.minSize}
+			functions[site.function] = f
+			functionOrder = append(functionOrder, site.function)
+		}
+		f.AllocationCount += t.count
+		f.TotalSize += t.size
+		f.MinSize = min(f.MinSize, t.minSize)
+		f.MaxSize = max(f.MaxSize, t.maxSize)
+
+		node := root
+		for i := len(frames) - 1; i >= 0; i-- {
+			node = heaptrackChild(node, frames[i])
+			node.AllocationCount += t.count
+			node.InclusiveSize += t.size
+		}
+		node.SelfSize += t.size
+	}
+
+	for _, name := range functionOrder {
+		f := functions[name]
+		f.AverageSize = float64(f.TotalSize) / float64(f.AllocationCount)
+		if data.TotalSize > 0 {
+			f.Percentage = float64(f.TotalSize) / float64(data.TotalSize) * 100
+		}
+		data.Functions = append(data.Functions, *f)
+	}
+	data.CallTrees = finishHeaptrackTree(root.Children)
+	return data
+}
+
+// heaptrackChild returns the child of node for frame, adding it when missing
+func heaptrackChild(node *CallTree, frame heaptrackFrame) *CallTree {
+	for i := range node.Children {
+		if node.Children[i].FunctionName == frame.function {
+			return &node.Children[i]
+		}
+	}
+	node.Children = append(node.Children, CallTree{FunctionName: frame.function, FileName: frame.file, LineNumber: frame.line})
+	return &node.Children[len(node.Children)-1]
+}
+
+// finishHeaptrackTree sets the total sizes and orders every level largest first
+func finishHeaptrackTree(trees []CallTree) []CallTree {
+	if trees == nil {
+		return []CallTree{}
+	}
+	for i := range trees {
+		trees[i].TotalSize = trees[i].InclusiveSize
+		trees[i].Children = finishHeaptrackTree(trees[i].Children)
+	}
+	sort.SliceStable(trees, func(i, j int) bool { return trees[i].InclusiveSize > trees[j].InclusiveSize })
+	return trees
+}
@@ -0,0 +1,35 @@
+package mempro
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ParseFile reads the capture at path: a MemPro JSON export, optionally gzip-compressed, or
+// heaptrack data. Of a MemPro export only the record arrays in sections are decoded, and the
+// arrays skipped are returned by name; heaptrack data is always converted whole.
+func ParseFile(path string, sections Sections) (*Data, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+	defer file.Close()
+
+	r, heaptrack, err := Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+	if heaptrack {
+		data, err := DecodeHeaptrack(r, filepath.Base(path))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse heaptrack data: %w", err)
+		}
+		return data, nil, nil
+	}
+	data, skipped, err := Decode(r, sections)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return data, skipped, nil
+}
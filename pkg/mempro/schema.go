@@ -0,0 +1,356 @@
+package mempro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Export layout versions. Exports may say which layout they use with a top-level
+// SchemaVersion; otherwise it is detected from the first record of each section.
+const (
+	SchemaShortNames = 1 // MemProReader 1.x: short field names such as Function, Size, and Stack
+	CurrentSchema    = 2 // The layout of Data
+	SchemaNested     = 3 // Newer exports: locations, statistics, and totals in nested objects
+
+	LatestSchema = SchemaNested
+)
+
+// fieldRename moves the value at from, a dotted path into nested objects, to the current
+// field to
+type fieldRename struct {
+	from, to string
+}
+
+// recordLayout describes how one kind of record differs from the current layout
+type recordLayout struct {
+	renames []fieldRename
+	nested  map[string]*recordLayout // Array fields, by current name, whose elements have a layout of their own
+}
+
+// schema is an export layout other than the current one
+type schema struct {
+	version int
+	header  *recordLayout
+	records map[Sections]*recordLayout
+}
+
+var schemas = []*schema{schemaNested, schemaShortNames}
+
+var schemaShortNames = func() *schema {
+	location := []fieldRename{{"Function", "FunctionName"}, {"File", "FileName"}, {"Line", "LineNumber"}}
+	stats := []fieldRename{{"Count", "AllocationCount"}, {"Size", "TotalSize"}, {"Average", "AverageSize"}, {"Min", "MinSize"}, {"Max", "MaxSize"}}
+
+	callTree := &recordLayout{renames: concat(location, []fieldRename{
+		{"Count", "AllocationCount"}, {"Size", "TotalSize"}, {"Self", "SelfSize"}, {"Inclusive", "InclusiveSize"},
+	})}
+	callTree.nested = map[string]*recordLayout{"Children": callTree}
+
+	return &schema{
+		version: SchemaShortNames,
+		header: &recordLayout{renames: []fieldRename{
+			{"Session", "SessionName"}, {"SnapshotCount", "TotalSnapshots"}, {"AllocationCount", "TotalAllocations"},
+			{"Size", "TotalSize"}, {"Fragmentation", "MemoryFragmentation"}, {"Duration", "SessionDuration"},
+		}},
+		records: map[Sections]*recordLayout{
+			SectionLeaks: {renames: concat(location, []fieldRename{
+				{"Size", "LeakSize"}, {"Count", "LeakCount"}, {"Score", "LeakScore"}, {"Stack", "CallStack"}, {"Suspect", "IsSuspect"},
+			})},
+			SectionFunctions:   {renames: concat(location, stats)},
+			SectionCallTrees:   callTree,
+			SectionPageViews:   {renames: []fieldRename{{"Function", "FunctionName"}, {"Stack", "CallStack"}, {"Count", "AllocationCount"}}},
+			SectionTypes:       {renames: concat([]fieldRename{{"Name", "TypeName"}}, stats)},
+			SectionAllocations: {renames: location},
+			SectionSnapshots: {
+				renames: []fieldRename{{"Index", "SnapshotIndex"}, {"Count", "TotalAllocations"}, {"Size", "TotalSize"}},
+				nested: map[string]*recordLayout{"Functions": {renames: []fieldRename{
+					{"Function", "FunctionName"}, {"Count", "AllocationCount"}, {"Size", "TotalSize"},
+				}}},
+			},
+		},
+	}
+}()
+
+var schemaNested = func() *schema {
+	location := []fieldRename{{"Location.Function", "FunctionName"}, {"Location.File", "FileName"}, {"Location.Line", "LineNumber"}}
+	stats := []fieldRename{
+		{"Stats.Count", "AllocationCount"}, {"Stats.Size", "TotalSize"}, {"Stats.Average", "AverageSize"},
+		{"Stats.Min", "MinSize"}, {"Stats.Max", "MaxSize"}, {"Stats.Percentage", "Percentage"},
+	}
+
+	callTree := &recordLayout{renames: concat(location, []fieldRename{
+		{"Stats.Count", "AllocationCount"}, {"Stats.Size", "TotalSize"}, {"Stats.Self", "SelfSize"}, {"Stats.Inclusive", "InclusiveSize"},
+	})}
+	callTree.nested = map[string]*recordLayout{"Children": callTree}
+
+	return &schema{
+		version: SchemaNested,
+		header: &recordLayout{renames: []fieldRename{
+			{"Session.Name", "SessionName"}, {"Session.Duration", "SessionDuration"},
+			{"Totals.Snapshots", "TotalSnapshots"}, {"Totals.Allocations", "TotalAllocations"}, {"Totals.Size", "TotalSize"},
+			{"Totals.LeakCount", "LeakCount"}, {"Totals.LeakSize", "LeakSize"}, {"Totals.Fragmentation", "MemoryFragmentation"},
+			{"Process.AddressSpaceSize", "AddressSpaceSize"},
+		}},
+		records: map[Sections]*recordLayout{
+			SectionLeaks: {renames: concat(location, []fieldRename{
+				{"Leak.Size", "LeakSize"}, {"Leak.Count", "LeakCount"}, {"Leak.Score", "LeakScore"}, {"Leak.Suspect", "IsSuspect"},
+			})},
+			SectionFunctions:   {renames: concat(location, stats)},
+			SectionCallTrees:   callTree,
+			SectionTypes:       {renames: concat(stats, []fieldRename{{"MostCommon.Function", "MostCommonFunction"}, {"MostCommon.File", "MostCommonFile"}, {"MostCommon.Line", "MostCommonLine"}})},
+			SectionAllocations: {renames: location},
+		},
+	}
+}()
+
+func concat(lists ...[]fieldRename) []fieldRename {
+	var all []fieldRename
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// schemaByVersion returns the layout of version, or nil for the current layout and
+// versions this package does not know
+func schemaByVersion(version int) *schema {
+	for _, s := range schemas {
+		if s.version == version {
+			return s
+		}
+	}
+	return nil
+}
+
+// layout returns the schema's layout of the header (section 0) or of a section's records
+func (s *schema) layout(section Sections) *recordLayout {
+	if section == 0 {
+		return s.header
+	}
+	return s.records[section]
+}
+
+// converter tracks the layout of each section of one export and translates its records
+// into the current layout
+type converter struct {
+	explicit int                  // SchemaVersion from the header, 0 when absent or not yet read
+	layouts  map[Sections]*schema // Layout decided per section, nil for the current one
+	decided  map[Sections]bool    // Sections whose layout is known
+	detected int                  // First layout other than the current one found
+}
+
+func newConverter() *converter {
+	return &converter{layouts: map[Sections]*schema{}, decided: map[Sections]bool{}}
+}
+
+// declared returns the layout the export's SchemaVersion names, if it is one this package
+// knows; sections of other versions are detected like those of exports without one
+func (c *converter) declared() (*schema, bool) {
+	if c.explicit == CurrentSchema {
+		return nil, true
+	}
+	s := schemaByVersion(c.explicit)
+	return s, s != nil
+}
+
+// version returns the layout the export was converted from
+func (c *converter) version() int {
+	switch {
+	case c.explicit != 0:
+		return c.explicit
+	case c.detected != 0:
+		return c.detected
+	}
+	return CurrentSchema
+}
+
+// direct reports whether records of section are known to be in the current layout, so they
+// can be decoded without translation
+func (c *converter) direct(section Sections) bool {
+	if s, ok := c.declared(); ok && !c.decided[section] {
+		c.decide(section, s)
+	}
+	return c.decided[section] && c.layouts[section] == nil
+}
+
+func (c *converter) decide(section Sections, s *schema) {
+	if s != nil && c.detected == 0 {
+		c.detected = s.version
+	}
+	if s != nil && s.layout(section) == nil {
+		s = nil // The section did not change in this layout
+	}
+	c.decided[section] = true
+	c.layouts[section] = s
+}
+
+// translate rewrites a record of section (0 for the header) into the current layout,
+// detecting the section's layout from its first record when the export does not declare it
+func (c *converter) translate(section Sections, record map[string]interface{}) {
+	if !c.decided[section] {
+		if s, ok := c.declared(); ok {
+			c.decide(section, s)
+		} else {
+			c.decide(section, detectSchema(section, record))
+		}
+	}
+	if s := c.layouts[section]; s != nil {
+		s.layout(section).apply(record)
+	}
+}
+
+// decode decodes one raw record of section into v, translating it from an older or newer
+// layout first
+func (c *converter) decode(section Sections, raw json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var record interface{}
+	if err := dec.Decode(&record); err != nil {
+		return err
+	}
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		if !c.decided[section] {
+			c.decide(section, nil)
+		}
+		return json.Unmarshal(raw, v)
+	}
+	c.translate(section, object)
+	if c.layouts[section] == nil {
+		return json.Unmarshal(raw, v)
+	}
+	translated, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(translated, v)
+}
+
+// header translates the encoded header fields into the current layout
+func (c *converter) header(encoded []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var header map[string]interface{}
+	if err := dec.Decode(&header); err != nil {
+		return nil, err
+	}
+	c.translate(0, header)
+	if c.layouts[0] == nil {
+		return encoded, nil
+	}
+	return json.Marshal(header)
+}
+
+// detectSchema finds the layout a record is in: one whose renamed fields it has, under names
+// the current layout does not use
+func detectSchema(section Sections, record map[string]interface{}) *schema {
+	current := currentFields(section)
+	for _, s := range schemas {
+		layout := s.layout(section)
+		if layout == nil {
+			continue
+		}
+		for _, r := range layout.renames {
+			root, _, nested := strings.Cut(r.from, ".")
+			key, value, ok := lookup(record, root)
+			if !ok {
+				continue
+			}
+			if _, isCurrent := current[strings.ToLower(key)]; isCurrent {
+				continue
+			}
+			if _, isObject := value.(map[string]interface{}); nested && !isObject {
+				continue
+			}
+			return s
+		}
+	}
+	return nil
+}
+
+// currentFields returns the current fields of the header (section 0) or of a section's records
+func currentFields(section Sections) map[string]reflect.StructField {
+	if section == 0 {
+		return dataFieldsByName
+	}
+	for key, s := range sectionKeys {
+		if s == section {
+			return fieldsByName(dataFieldsByName[key].Type.Elem())
+		}
+	}
+	return nil
+}
+
+// apply moves the record's renamed fields to their current names, leaving fields already
+// present under the current name alone, then translates nested records
+func (l *recordLayout) apply(record map[string]interface{}) {
+	for _, r := range l.renames {
+		value, ok := take(record, strings.Split(r.from, "."))
+		if !ok {
+			continue
+		}
+		if _, _, exists := lookup(record, r.to); !exists {
+			record[r.to] = value
+		}
+	}
+	for name, nested := range l.nested {
+		_, value, ok := lookup(record, name)
+		if !ok {
+			continue
+		}
+		items, _ := value.([]interface{})
+		for _, item := range items {
+			if object, ok := item.(map[string]interface{}); ok {
+				nested.apply(object)
+			}
+		}
+	}
+}
+
+// take removes and returns the value at path, dropping nested objects it leaves empty
+func take(object map[string]interface{}, path []string) (interface{}, bool) {
+	key, value, ok := lookup(object, path[0])
+	if !ok {
+		return nil, false
+	}
+	if len(path) == 1 {
+		delete(object, key)
+		return value, true
+	}
+	nested, isObject := value.(map[string]interface{})
+	if !isObject {
+		return nil, false
+	}
+	value, ok = take(nested, path[1:])
+	if len(nested) == 0 {
+		delete(object, key)
+	}
+	return value, ok
+}
+
+// lookup finds a key case-insensitively, as encoding/json matches field names
+func lookup(object map[string]interface{}, name string) (string, interface{}, bool) {
+	if value, ok := object[name]; ok {
+		return name, value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return key, value, true
+		}
+	}
+	return "", nil, false
+}
+
+// schemaVersionKey is the top-level field naming an export's layout
+const schemaVersionKey = "schemaversion"
+
+// parseSchemaVersion reads a SchemaVersion value
+func parseSchemaVersion(raw json.RawMessage) (int, error) {
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil || version <= 0 {
+		return 0, errors.New("expected a positive integer")
+	}
+	return version, nil
+}
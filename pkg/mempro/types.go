@@ -0,0 +1,169 @@
+// Package mempro defines the records of a MemPro capture and decodes them from MemPro JSON
+// exports and heaptrack data.
+package mempro
+
+// Data represents the complete MemPro analysis JSON structure
+type Data struct {
+	SessionName         string      `json:"SessionName"`
+	TotalSnapshots      int         `json:"TotalSnapshots"`
+	TotalAllocations    int         `json:"TotalAllocations"`
+	TotalSize           int64       `json:"TotalSize"`
+	LeakCount           int         `json:"LeakCount"`
+	LeakSize            int64       `json:"LeakSize"`
+	MemoryFragmentation float64     `json:"MemoryFragmentation"`
+	CallTrees           []CallTree  `json:"CallTrees"`
+	Functions           []Function  `json:"Functions"`
+	Leaks               []Leak      `json:"Leaks"`
+	PageViews           []PageView  `json:"PageViews"`
+	Types               []AllocType `json:"Types"`
+
+	// Timing data, present only in exports that record allocation times
+	SessionDuration float64      `json:"SessionDuration,omitempty"` // Milliseconds
+	Allocations     []Allocation `json:"Allocations,omitempty"`
+
+	// Thread names, present only in exports that attribute records to threads
+	Threads []Thread `json:"Threads,omitempty"`
+
+	// Named heaps, present only in exports that partition memory into several heaps
+	Heaps []Heap `json:"Heaps,omitempty"`
+
+	// Size of the process's user address space in bytes, when the export records it
+	AddressSpaceSize int64 `json:"AddressSpaceSize,omitempty"`
+
+	// Live memory at each snapshot, present only in exports that record snapshot data
+	Snapshots []Snapshot `json:"Snapshots,omitempty"`
+
+	// Export layout the records were converted from (see CurrentSchema); 0 for heaptrack data
+	Schema int `json:"-"`
+}
+
+// Snapshot is the live memory at one point of the session
+type Snapshot struct {
+	SnapshotIndex    int                `json:"SnapshotIndex"`
+	Name             string             `json:"Name,omitempty"`
+	Time             float64            `json:"Time,omitempty"` // Milliseconds since session start
+	TotalAllocations int                `json:"TotalAllocations"`
+	TotalSize        int64              `json:"TotalSize"`
+	Functions        []SnapshotFunction `json:"Functions,omitempty"`
+}
+
+// SnapshotFunction is one function's live memory at a snapshot
+type SnapshotFunction struct {
+	FunctionName    string `json:"FunctionName"`
+	AllocationCount int    `json:"AllocationCount"`
+	TotalSize       int64  `json:"TotalSize"`
+}
+
+// Heap describes a heap that records refer to by HeapId
+type Heap struct {
+	HeapId              int     `json:"HeapId"`
+	HeapName            string  `json:"HeapName"`
+	TotalSize           int64   `json:"TotalSize,omitempty"`
+	MemoryFragmentation float64 `json:"MemoryFragmentation,omitempty"`
+	Budget              int64   `json:"Budget,omitempty"` // Bytes; heap_budgets in the config takes precedence
+}
+
+// Thread names a thread that leak and allocation records refer to by ThreadId
+type Thread struct {
+	ThreadId   int    `json:"ThreadId"`
+	ThreadName string `json:"ThreadName"`
+
+	// Allocation totals, present only in exports that count them per thread
+	AllocationCount int   `json:"AllocationCount,omitempty"`
+	TotalSize       int64 `json:"TotalSize,omitempty"`
+	FreedCount      int   `json:"FreedCount,omitempty"`
+	FreedSize       int64 `json:"FreedSize,omitempty"`
+}
+
+// CallTree represents a call tree entry with allocation information
+type CallTree struct {
+	FunctionName    string     `json:"FunctionName"`
+	FileName        string     `json:"FileName"`
+	LineNumber      int        `json:"LineNumber"`
+	AllocationCount int        `json:"AllocationCount"`
+	TotalSize       int64      `json:"TotalSize"`
+	SelfSize        int64      `json:"SelfSize"`
+	InclusiveSize   int64      `json:"InclusiveSize"`
+	Children        []CallTree `json:"Children"`
+}
+
+// Function represents function-level allocation statistics
+type Function struct {
+	FunctionName    string  `json:"FunctionName"`
+	FileName        string  `json:"FileName"`
+	LineNumber      int     `json:"LineNumber"`
+	AllocationCount int     `json:"AllocationCount"`
+	TotalSize       int64   `json:"TotalSize"`
+	AverageSize     float64 `json:"AverageSize"`
+	MinSize         int64   `json:"MinSize"`
+	MaxSize         int64   `json:"MaxSize"`
+	Percentage      float64 `json:"Percentage"`
+
+	// Lifetime statistics over freed allocations, present only in exports with timing data
+	FreedCount      int     `json:"FreedCount,omitempty"`
+	AverageLifetime float64 `json:"AverageLifetime,omitempty"` // Milliseconds
+	MaxLifetime     float64 `json:"MaxLifetime,omitempty"`     // Milliseconds
+
+	HeapId int    `json:"HeapId,omitempty"` // 0 when not attributed
+	Tag    string `json:"Tag,omitempty"`    // Allocation category, e.g. "Textures/UI"
+}
+
+// Allocation is a single allocation record with its timing
+type Allocation struct {
+	FunctionName string   `json:"FunctionName"`
+	FileName     string   `json:"FileName"`
+	LineNumber   int      `json:"LineNumber"`
+	Size         int64    `json:"Size"`
+	AllocTime    float64  `json:"AllocTime"`          // Milliseconds since session start
+	FreeTime     *float64 `json:"FreeTime,omitempty"` // Milliseconds since session start; nil while live
+	ThreadId     int      `json:"ThreadId,omitempty"` // 0 when not attributed
+	HeapId       int      `json:"HeapId,omitempty"`   // 0 when not attributed
+	Tag          string   `json:"Tag,omitempty"`
+}
+
+// Leak represents a memory leak with suspect information
+type Leak struct {
+	FunctionName string  `json:"FunctionName"`
+	FileName     string  `json:"FileName"`
+	LineNumber   int     `json:"LineNumber"`
+	LeakSize     int64   `json:"LeakSize"`
+	LeakCount    int     `json:"LeakCount"`
+	LeakScore    float64 `json:"LeakScore"`
+	CallStack    string  `json:"CallStack"`
+	IsSuspect    bool    `json:"IsSuspect"`
+	ThreadId     int     `json:"ThreadId,omitempty"` // 0 when not attributed
+	HeapId       int     `json:"HeapId,omitempty"`   // 0 when not attributed
+	Tag          string  `json:"Tag,omitempty"`
+	StackId      int     `json:"StackId,omitempty"` // Same ID space as PageView.StackId; 0 when absent
+}
+
+// PageView represents memory page usage information
+type PageView struct {
+	Address         int64  `json:"Address"`
+	State           string `json:"State"`
+	Type            string `json:"Type"`
+	Protection      int    `json:"Protection"`
+	StackId         int    `json:"StackId"`
+	Usage           int    `json:"Usage"`
+	AllocationCount int    `json:"AllocationCount"`
+	TotalSize       int64  `json:"TotalSize"`
+	FunctionName    string `json:"FunctionName"`
+	CallStack       string `json:"CallStack"`
+	HeapId          int    `json:"HeapId,omitempty"` // 0 when not attributed
+	Size            int64  `json:"Size,omitempty"`   // Region size in bytes; a single 4 KB page when absent
+}
+
+// AllocType represents allocation type statistics
+type AllocType struct {
+	TypeName           string  `json:"TypeName"`
+	AllocationCount    int     `json:"AllocationCount"`
+	TotalSize          int64   `json:"TotalSize"`
+	AverageSize        float64 `json:"AverageSize"`
+	MinSize            int64   `json:"MinSize"`
+	MaxSize            int64   `json:"MaxSize"`
+	Percentage         float64 `json:"Percentage"`
+	MostCommonFunction string  `json:"MostCommonFunction"`
+	MostCommonFile     string  `json:"MostCommonFile"`
+	MostCommonLine     int     `json:"MostCommonLine"`
+	Tag                string  `json:"Tag,omitempty"`
+}
@@ -0,0 +1,427 @@
+package mempro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// maxProblems bounds the distinct problems a validation lists
+const maxProblems = 100
+
+// Problem is one way a capture departs from the MemPro JSON format. A problem found in many
+// records of a section is listed once, at its first record, with the number of records.
+type Problem struct {
+	Severity string `json:"severity"`         // error: loading fails; warning: loads, but results may be incomplete
+	Path     string `json:"path,omitempty"`   // e.g. Leaks[12].LeakSize
+	Offset   int64  `json:"offset,omitempty"` // Byte offset into the uncompressed JSON
+	Message  string `json:"message"`
+	Count    int    `json:"count,omitempty"` // Occurrences, when more than one
+}
+
+// Validation is the result of checking a capture against the MemPro JSON format
+type Validation struct {
+	Valid         bool           `json:"valid"`
+	Format        string         `json:"format"`                   // "MemPro JSON" or "heaptrack"
+	SchemaVersion int            `json:"schema_version,omitempty"` // Layout of a MemPro export, see CurrentSchema
+	Records       map[string]int `json:"records"`                  // Records per section present
+	Problems      []Problem      `json:"problems"`
+}
+
+// dataFieldsByName maps the lower-cased JSON name of each field of Data to the field; keys
+// match them case-insensitively, as encoding/json does
+var dataFieldsByName = fieldsByName(reflect.TypeOf(Data{}))
+
+// ValidateFile checks the capture at path: a MemPro JSON export, optionally gzip-compressed,
+// or heaptrack data, which is checked by converting it.
+func ValidateFile(path string) *Validation {
+	v := &Validation{Format: "MemPro JSON", Records: map[string]int{}, Problems: []Problem{}}
+	file, err := os.Open(path)
+	if err != nil {
+		v.Problems = append(v.Problems, Problem{Severity: "error", Message: fmt.Sprintf("failed to read capture: %v", err)})
+		return v
+	}
+	defer file.Close()
+
+	r, heaptrack, err := Open(file)
+	if err != nil {
+		v.Problems = append(v.Problems, Problem{Severity: "error", Message: fmt.Sprintf("failed to read capture: %v", err)})
+		return v
+	}
+	if !heaptrack {
+		return Validate(r)
+	}
+
+	v.Format = "heaptrack"
+	data, err := DecodeHeaptrack(r, filepath.Base(path))
+	if err != nil {
+		v.Problems = append(v.Problems, Problem{Severity: "error", Message: fmt.Sprintf("failed to parse heaptrack data: %v", err)})
+		return v
+	}
+	v.Valid = true
+	v.Records["Leaks"] = len(data.Leaks)
+	v.Records["Functions"] = len(data.Functions)
+	v.Records["CallTrees"] = len(data.CallTrees)
+	return v
+}
+
+// Validate checks uncompressed MemPro JSON read from r. Unlike Decode it does not stop at
+// the first malformed record: every field of the wrong type, unknown field, and missing
+// field or section is reported, up to a syntax error or the end of a truncated file.
+// Records of older and newer layouts are checked after translation to the current one.
+func Validate(r io.Reader) *Validation {
+	c := &validator{
+		v:     &Validation{Format: "MemPro JSON", Records: map[string]int{}, Problems: []Problem{}},
+		index: make(map[string]int),
+		conv:  newConverter(),
+	}
+	c.document(r)
+
+	for i := range c.v.Problems {
+		if c.v.Problems[i].Count == 1 {
+			c.v.Problems[i].Count = 0
+		}
+	}
+	if c.dropped > 0 {
+		c.v.Problems = append(c.v.Problems, Problem{Severity: "warning", Message: fmt.Sprintf("%d more kinds of problems are not listed", c.dropped)})
+	}
+	sort.SliceStable(c.v.Problems, func(i, j int) bool {
+		return c.v.Problems[i].Severity == "error" && c.v.Problems[j].Severity != "error"
+	})
+
+	c.v.Valid = true
+	for _, p := range c.v.Problems {
+		if p.Severity == "error" {
+			c.v.Valid = false
+			break
+		}
+	}
+	return c.v
+}
+
+// validator collects problems, merging those with the same key
+type validator struct {
+	v       *Validation
+	conv    *converter
+	index   map[string]int // Problem key to its index in v.Problems
+	dropped int            // Distinct problems beyond maxProblems
+}
+
+// add records a problem. Problems with the same key, e.g. the same field of every record of
+// a section, are counted at the first one.
+func (c *validator) add(severity, key, path string, offset int64, message string) {
+	if i, ok := c.index[key]; ok {
+		if i >= 0 {
+			c.v.Problems[i].Count++
+		}
+		return
+	}
+	if len(c.v.Problems) >= maxProblems {
+		c.dropped++
+		c.index[key] = -1
+		return
+	}
+	c.index[key] = len(c.v.Problems)
+	c.v.Problems = append(c.v.Problems, Problem{Severity: severity, Path: path, Offset: offset, Message: message, Count: 1})
+}
+
+// fatal records an error after which the rest of the document cannot be read
+func (c *validator) fatal(dec *json.Decoder, err error, path string, offset int64) {
+	e := formatError(dec, err, path, offset).(*FormatError)
+	c.add("error", "fatal", e.Path, e.Offset, e.Message)
+}
+
+// document checks the top-level object and each of its fields
+func (c *validator) document(r io.Reader) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		c.fatal(dec, err, "", 0)
+		return
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		c.add("error", "document", "", 0, fmt.Sprintf("expected a JSON object, got %s", tokenKind(tok)))
+		return
+	}
+
+	// Header fields are checked at the end, once their layout is known
+	seen := make(map[string]bool)
+	header := make(map[string]interface{})
+	offsets := make(map[string]int64)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			c.fatal(dec, err, "", 0)
+			return
+		}
+		key, _ := tok.(string)
+		offset := dec.InputOffset()
+
+		if strings.ToLower(key) == schemaVersionKey {
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				c.fatal(dec, err, key, offset)
+				return
+			}
+			if c.conv.explicit, err = parseSchemaVersion(value); err != nil {
+				c.add("error", "schema", key, offset, err.Error())
+			} else if _, ok := c.conv.declared(); !ok {
+				c.add("warning", "schema", key, offset, fmt.Sprintf("schema version %d is not one this version knows (%d-%d); the layout of each section is detected instead", c.conv.explicit, SchemaShortNames, LatestSchema))
+			}
+			seen[schemaVersionKey] = true
+			continue
+		}
+
+		section, isSection := sectionKeys[strings.ToLower(key)]
+		if !isSection {
+			var value interface{}
+			if err := dec.Decode(&value); err != nil {
+				c.fatal(dec, err, key, offset)
+				return
+			}
+			header[key] = value
+			offsets[strings.ToLower(key)] = offset
+			continue
+		}
+		seen[strings.ToLower(key)] = true
+		if !c.section(dec, key, section, dataFieldsByName[strings.ToLower(key)].Type.Elem()) {
+			return
+		}
+	}
+	c.conv.translate(0, header)
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, known := dataFieldsByName[strings.ToLower(key)]
+		if !known {
+			c.add("warning", "unknown:"+strings.ToLower(key), key, offsets[strings.ToLower(key)], fmt.Sprintf("unknown field %q is ignored", key))
+			continue
+		}
+		seen[strings.ToLower(key)] = true
+		c.value(header[key], field.Type, key, key, offsets[strings.ToLower(key)])
+	}
+	c.v.SchemaVersion = c.conv.version()
+
+	if _, err := dec.Token(); err != nil {
+		c.fatal(dec, err, "", 0)
+		return
+	}
+	if dec.More() {
+		c.add("warning", "trailing", "", dec.InputOffset(), "data after the end of the capture is ignored")
+	}
+
+	if len(seen) == 0 {
+		c.add("error", "document", "", 0, "not a MemPro export: none of its fields (SessionName, Leaks, Functions, ...) were found")
+		return
+	}
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Data{})) {
+		name := jsonName(field)
+		if _, isSection := sectionKeys[strings.ToLower(name)]; !isSection || seen[strings.ToLower(name)] || omitEmpty(field) {
+			continue
+		}
+		if name == "Leaks" {
+			c.add("warning", "missing:"+name, name, 0, "missing: leak analysis will find no leaks; the export may be partial")
+		} else {
+			c.add("warning", "missing:"+name, name, 0, fmt.Sprintf("missing: tools that read %s will report nothing; the export may be partial", name))
+		}
+	}
+}
+
+// section checks a record array one record at a time, and reports whether the rest of the
+// document can still be read
+func (c *validator) section(dec *json.Decoder, key string, section Sections, elem reflect.Type) bool {
+	offset := dec.InputOffset()
+	tok, err := dec.Token()
+	if err != nil {
+		c.fatal(dec, err, key, offset)
+		return false
+	}
+	if tok == nil {
+		c.v.Records[key] = 0
+		return true
+	}
+	d, ok := tok.(json.Delim)
+	if !ok || d != '[' {
+		c.add("error", "section:"+key, key, offset, fmt.Sprintf("expected an array of records, got %s", tokenKind(tok)))
+		if ok {
+			if err := skipRest(dec, d); err != nil {
+				c.fatal(dec, err, key, offset)
+				return false
+			}
+		}
+		return true
+	}
+
+	n := 0
+	for dec.More() {
+		path := fmt.Sprintf("%s[%d]", key, n)
+		offset := dec.InputOffset()
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			c.v.Records[key] = n
+			c.fatal(dec, err, path, offset)
+			return false
+		}
+		if record, ok := value.(map[string]interface{}); ok {
+			c.conv.translate(section, record)
+		}
+		c.value(value, elem, path, key+"[]", offset)
+		n++
+	}
+	c.v.Records[key] = n
+	if _, err := dec.Token(); err != nil {
+		c.fatal(dec, err, key, 0)
+		return false
+	}
+	return true
+}
+
+// value checks a decoded value against the Go type it is loaded into. pattern is path with
+// array indexes removed, so the same field of every record shares one problem.
+func (c *validator) value(value interface{}, t reflect.Type, path, pattern string, offset int64) {
+	if value == nil {
+		return // null leaves the zero value
+	}
+	mismatch := func() {
+		c.add("error", pattern+":type:"+valueKind(value), path, offset,
+			fmt.Sprintf("expected %s, got %s", typeName(t), valueExcerpt(value)))
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		c.value(value, t.Elem(), path, pattern, offset)
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			mismatch()
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			mismatch()
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(json.Number); !ok {
+			mismatch()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(json.Number)
+		if !ok {
+			mismatch()
+			return
+		}
+		i, err := n.Int64()
+		if err != nil {
+			mismatch()
+			return
+		}
+		if i < 0 && strings.HasSuffix(pattern, "Size") {
+			c.add("warning", pattern+":negative", path, offset, fmt.Sprintf("negative size %d", i))
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		for i, item := range items {
+			c.value(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), pattern+"[]", offset)
+		}
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		c.object(object, t, path, pattern, offset)
+	}
+}
+
+// object checks a record's fields, reporting unknown fields and missing ones that every
+// export includes
+func (c *validator) object(object map[string]interface{}, t reflect.Type, path, pattern string, offset int64) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := fieldsByName(t)
+	present := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		field, ok := fields[strings.ToLower(key)]
+		if !ok {
+			c.add("warning", pattern+":unknown:"+strings.ToLower(key), joinPath(path, key), offset,
+				fmt.Sprintf("unknown field %q is ignored", key))
+			continue
+		}
+		present[strings.ToLower(key)] = true
+		c.value(object[key], field.Type, joinPath(path, key), joinPath(pattern, jsonName(field)), offset)
+	}
+
+	for _, field := range reflect.VisibleFields(t) {
+		name := jsonName(field)
+		// A missing array is read as an empty one, e.g. the Children of a leaf
+		if name == "" || present[strings.ToLower(name)] || omitEmpty(field) || field.Type.Kind() == reflect.Slice {
+			continue
+		}
+		c.add("warning", joinPath(pattern, name)+":missing", joinPath(path, name), offset, "missing; read as zero or empty")
+	}
+}
+
+// fieldsByName maps the lower-cased JSON names of a struct type's fields to the fields
+func fieldsByName(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for _, field := range reflect.VisibleFields(t) {
+		if name := jsonName(field); name != "" {
+			fields[strings.ToLower(name)] = field
+		}
+	}
+	return fields
+}
+
+// omitEmpty reports whether a field is optional in exports
+func omitEmpty(field reflect.StructField) bool {
+	_, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return strings.Contains(options, "omitempty")
+}
+
+// valueKind names the JSON type of a decoded value
+func valueKind(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+// valueExcerpt describes a decoded value of the wrong type, quoting short scalars
+func valueExcerpt(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if len(v) > 40 {
+			v = v[:40] + "..."
+		}
+		return fmt.Sprintf("string %q", v)
+	case json.Number:
+		return "number " + v.String()
+	case bool:
+		return fmt.Sprintf("%t", v)
+	}
+	return valueKind(value)
+}